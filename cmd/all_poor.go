@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	allPoorResponseKeep    = "keep"
+	allPoorResponseUniform = "uniform"
+)
+
+var allPoorThreshold, allPoorScore int
+var allPoorResponse string
+
+func init() {
+	flag.IntVar(&allPoorThreshold, "all-poor-threshold", 0, "When every node scores below this threshold the request is counted as all-poor, a sign the cluster lacks a good fit for the pod. 0 disables the detection")
+	flag.StringVar(&allPoorResponse, "all-poor-response", allPoorResponseKeep, "The scores answered for an all-poor request, one of: keep, the computed scores, uniform, -all-poor-score for every node so no node looks like a clear winner")
+	flag.IntVar(&allPoorScore, "all-poor-score", 0, "The score of every node of an all-poor request with -all-poor-response=uniform")
+}
+
+// validateAllPoor makes sure the all-poor flags hold valid values
+func validateAllPoor() error {
+	switch allPoorResponse {
+	case allPoorResponseKeep, allPoorResponseUniform:
+	default:
+		return fmt.Errorf("unknown -all-poor-response %q, expecting one of: %v, %v", allPoorResponse, allPoorResponseKeep, allPoorResponseUniform)
+	}
+	if allPoorThreshold < 0 || allPoorThreshold > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -all-poor-threshold flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, allPoorThreshold)
+	}
+	if allPoorScore < 0 || allPoorScore > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -all-poor-score flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, allPoorScore)
+	}
+	return nil
+}
+
+var allPoorLock sync.Mutex
+
+// allPoorCounts counts the all-poor requests per priority method, exposed on /metrics
+var allPoorCounts = make(map[string]int)
+
+// flattenPoorScores detects the requests where every node not vetoed scores below -all-poor-threshold,
+// logging and counting them, and with -all-poor-response=uniform answers -all-poor-score for each of
+// these nodes. The vetoed nodes keep the UnfitScore sentinel
+func flattenPoorScores(methodName, podName string, list schedulingapi.HostPriorityList) schedulingapi.HostPriorityList {
+	if allPoorThreshold == 0 {
+		return list
+	}
+	var best, scored int
+	for _, hp := range list {
+		if hp.Score == UnfitScore {
+			continue
+		}
+		if scored == 0 || hp.Score > best {
+			best = hp.Score
+		}
+		scored++
+	}
+	if scored == 0 || best >= allPoorThreshold {
+		return list
+	}
+	glog.Warningf("priorityMethod %v scored every node below %v for pod %v, the best score is %v", methodName, allPoorThreshold, podName, best)
+	allPoorLock.Lock()
+	allPoorCounts[methodName]++
+	allPoorLock.Unlock()
+	if allPoorResponse != allPoorResponseUniform {
+		return list
+	}
+	flattened := make(schedulingapi.HostPriorityList, len(list))
+	for i, hp := range list {
+		if hp.Score != UnfitScore {
+			hp.Score = allPoorScore
+		}
+		flattened[i] = hp
+	}
+	return flattened
+}
+
+// writeAllPoorMetrics writes the number of all-poor requests per priority method
+func writeAllPoorMetrics(w io.Writer) {
+	allPoorLock.Lock()
+	defer allPoorLock.Unlock()
+	methods := make([]string, 0, len(allPoorCounts))
+	for method := range allPoorCounts {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	samples := make([]metricSample, len(methods))
+	for i, method := range methods {
+		samples[i] = metricSample{fmt.Sprintf("method=%q", method), float64(allPoorCounts[method])}
+	}
+	writeMetric(w, "extender_all_poor_total", "counter", "Requests where every node scored below -all-poor-threshold.", samples...)
+}
@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withAllPoor sets the all-poor flags for the test, the counts of the test are dropped at its end
+func withAllPoor(t *testing.T, threshold int, response string, score int) {
+	savedThreshold, savedResponse, savedScore := allPoorThreshold, allPoorResponse, allPoorScore
+	t.Cleanup(func() {
+		allPoorThreshold, allPoorResponse, allPoorScore = savedThreshold, savedResponse, savedScore
+		allPoorLock.Lock()
+		allPoorCounts = make(map[string]int)
+		allPoorLock.Unlock()
+	})
+	allPoorThreshold, allPoorResponse, allPoorScore = threshold, response, score
+}
+
+func TestValidateAllPoor(t *testing.T) {
+	tests := []struct {
+		threshold int
+		response  string
+		score     int
+		valid     bool
+	}{
+		{0, allPoorResponseKeep, 0, true},
+		{3, allPoorResponseUniform, 5, true},
+		{10, allPoorResponseUniform, 10, true},
+		{3, "flat", 0, false},
+		{-1, allPoorResponseKeep, 0, false},
+		{11, allPoorResponseKeep, 0, false},
+		{3, allPoorResponseUniform, -1, false},
+		{3, allPoorResponseUniform, 11, false},
+	}
+	for _, test := range tests {
+		withAllPoor(t, test.threshold, test.response, test.score)
+		if err := validateAllPoor(); (err == nil) != test.valid {
+			t.Errorf("validateAllPoor(%v, %q, %v) returned %v", test.threshold, test.response, test.score, err)
+		}
+	}
+}
+
+func TestFlattenPoorScores(t *testing.T) {
+	poor := schedulingapi.HostPriorityList{{Host: "a", Score: 2}, {Host: "b", Score: 1}, {Host: "c", Score: UnfitScore}}
+	good := schedulingapi.HostPriorityList{{Host: "a", Score: 2}, {Host: "b", Score: 4}}
+	vetoed := schedulingapi.HostPriorityList{{Host: "a", Score: UnfitScore}}
+	tests := []struct {
+		name      string
+		threshold int
+		response  string
+		list      schedulingapi.HostPriorityList
+		expected  map[string]int
+		counted   int
+	}{
+		{"disabled", 0, allPoorResponseUniform, poor, map[string]int{"a": 2, "b": 1, "c": UnfitScore}, 0},
+		{"kept", 3, allPoorResponseKeep, poor, map[string]int{"a": 2, "b": 1, "c": UnfitScore}, 1},
+		{"uniform", 3, allPoorResponseUniform, poor, map[string]int{"a": 5, "b": 5, "c": UnfitScore}, 1},
+		{"one good node", 4, allPoorResponseUniform, good, map[string]int{"a": 2, "b": 4}, 0},
+		{"every node vetoed", 3, allPoorResponseUniform, vetoed, map[string]int{"a": UnfitScore}, 0},
+		{"empty", 3, allPoorResponseUniform, nil, map[string]int{}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withAllPoor(t, test.threshold, test.response, 5)
+			checkScores(t, flattenPoorScores("m", "p", test.list), test.expected)
+			if allPoorCounts["m"] != test.counted {
+				t.Errorf("counted %v all-poor requests, expected %v", allPoorCounts["m"], test.counted)
+			}
+		})
+	}
+	if poor[0].Score != 2 {
+		t.Errorf("flattenPoorScores modified its input %v", poor)
+	}
+}
+
+func TestAllPoorRoute(t *testing.T) {
+	withAllPoor(t, 5, allPoorResponseUniform, 3)
+	router := newTestRouter(t, digitPriority)
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("n1", "n4")), map[string]int{"n1": 3, "n4": 3})
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("n1", "n6")), map[string]int{"n1": 1, "n6": 6})
+
+	var metrics bytes.Buffer
+	writeAllPoorMetrics(&metrics)
+	if expected := `extender_all_poor_total{method="digit"} 1`; !strings.Contains(metrics.String(), expected) {
+		t.Errorf("the metrics are missing %v:\n%v", expected, metrics.String())
+	}
+}
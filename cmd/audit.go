@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// auditSchemaVersion is bumped whenever a field of AuditRecord changes meaning or goes away
+const auditSchemaVersion = 1
+
+var auditLogFile string
+var auditLogMaxBytes int64
+
+func init() {
+	flag.StringVar(&auditLogFile, "audit-log-file", "", "Write an audit record of every prioritize request as a JSON line in this file, - means stdout, disabled when empty")
+	flag.Int64Var(&auditLogMaxBytes, "audit-log-max-bytes", 100*1024*1024, "The size after which the audit log file is rotated, 0 disables the rotation")
+}
+
+// AuditPod identifies the pod of an audit record
+type AuditPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// AuditRecord is the audit trail of a prioritize request, its JSON schema is stable, see auditSchemaVersion
+type AuditRecord struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Time          time.Time `json:"time"`
+	// Endpoint is the priority method requested, or combined for the combined priorities
+	Endpoint  string   `json:"endpoint"`
+	Pod       AuditPod `json:"pod"`
+	Scheduler string   `json:"scheduler"`
+	// Candidates are the nodes sent by the scheduler
+	Candidates []string `json:"candidates"`
+	// MethodScores are the scores of each method run, by method then by node
+	MethodScores map[string]map[string]int `json:"methodScores"`
+	// MethodVersions are the versions of the scoring logic of the methods run
+	MethodVersions map[string]int `json:"methodVersions"`
+	// Ranking is the answer to the scheduler, highest score first
+	Ranking schedulingapi.HostPriorityList `json:"ranking"`
+	Errors  []MethodError                  `json:"errors,omitempty"`
+}
+
+// auditLogger writes the audit records synchronously, each one flushed before the request is answered
+// so no decision goes unaudited, unlike the score log which drops records under load
+type auditLogger struct {
+	lock   sync.Mutex
+	out    io.WriteCloser
+	writer *bufio.Writer
+}
+
+// auditLog is the audit logger in use, nil when -audit-log-file is not set
+var auditLog *auditLogger
+
+// startAuditLog opens the -audit-log-file
+func startAuditLog() {
+	switch auditLogFile {
+	case "":
+		return
+	case "-":
+		auditLog = newAuditLogger(nopCloser{os.Stdout})
+		return
+	}
+	file, err := newRotatingFile(auditLogFile, auditLogMaxBytes)
+	if err != nil {
+		fatalf("failed to open the -audit-log-file: %v", err)
+	}
+	auditLog = newAuditLogger(file)
+}
+
+func newAuditLogger(out io.WriteCloser) *auditLogger {
+	return &auditLogger{out: out, writer: bufio.NewWriter(out)}
+}
+
+// record writes the audit record of a request, methodScores are the scores of each method run and
+// ranking the scores answered
+func (a *auditLogger) record(endpoint string, extenderArgs schedulingapi.ExtenderArgs, methodScores map[string]schedulingapi.HostPriorityList, ranking schedulingapi.HostPriorityList, errors []MethodError) {
+	if a == nil {
+		return
+	}
+	record := AuditRecord{
+		SchemaVersion:  auditSchemaVersion,
+		Time:           time.Now().UTC(),
+		Endpoint:       endpoint,
+		Pod:            AuditPod{Namespace: extenderArgs.Pod.Namespace, Name: extenderArgs.Pod.Name, UID: string(extenderArgs.Pod.UID)},
+		Scheduler:      extenderArgs.Pod.Spec.SchedulerName,
+		Candidates:     []string{},
+		MethodScores:   make(map[string]map[string]int, len(methodScores)),
+		MethodVersions: make(map[string]int, len(methodScores)),
+		Ranking:        append(schedulingapi.HostPriorityList{}, ranking...),
+		Errors:         errors,
+	}
+	if extenderArgs.Nodes != nil {
+		for _, node := range extenderArgs.Nodes.Items {
+			record.Candidates = append(record.Candidates, node.Name)
+		}
+	}
+	for method, list := range methodScores {
+		scores := make(map[string]int, len(list))
+		for _, hp := range list {
+			scores[hp.Host] = hp.Score
+		}
+		record.MethodScores[method] = scores
+		if registered, ok := registeredMethod(method); ok {
+			record.MethodVersions[method] = methodVersion(registered)
+		}
+	}
+	sort.SliceStable(record.Ranking, func(i, j int) bool {
+		return record.Ranking[i].Score > record.Ranking[j].Score
+	})
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := json.NewEncoder(a.writer).Encode(record); err != nil {
+		glog.Errorf("failed to write the audit record of pod %v: %v", extenderArgs.Pod.Name, err)
+		return
+	}
+	if err := a.writer.Flush(); err != nil {
+		glog.Errorf("failed to flush the audit record of pod %v: %v", extenderArgs.Pod.Name, err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withAuditLog writes the audit records to the returned buffer until the end of the test
+func withAuditLog(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+	saved := auditLog
+	t.Cleanup(func() { auditLog = saved })
+	auditLog = newAuditLogger(nopCloser{&buffer})
+	return &buffer
+}
+
+// auditRecords decodes the audit records, one per line
+func auditRecords(t *testing.T, buffer *bytes.Buffer) []AuditRecord {
+	t.Helper()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid audit record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditRecord(t *testing.T) {
+	newTestRouter(t, ImagePriority)
+	buffer := withAuditLog(t)
+	pod := testPod("default", "p", nil)
+	pod.UID = "uid-p"
+	pod.Spec.SchedulerName = "custom"
+	scores := schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 8}, {Host: "c", Score: 5}}
+	auditLog.record(ImagePriority.Name, extenderArgsOf(pod, testNodes("a", "b", "c")), map[string]schedulingapi.HostPriorityList{ImagePriority.Name: scores}, scores, nil)
+
+	records := auditRecords(t, buffer)
+	if len(records) != 1 {
+		t.Fatalf("wrote %v records, expected 1", len(records))
+	}
+	record := records[0]
+	if record.SchemaVersion != auditSchemaVersion || record.Endpoint != ImagePriority.Name || record.Scheduler != "custom" || record.Time.IsZero() {
+		t.Errorf("wrote the record %+v", record)
+	}
+	if record.Pod != (AuditPod{Namespace: "default", Name: "p", UID: "uid-p"}) {
+		t.Errorf("audited the pod %+v", record.Pod)
+	}
+	if !reflect.DeepEqual(record.Candidates, []string{"a", "b", "c"}) {
+		t.Errorf("audited the candidates %v", record.Candidates)
+	}
+	if !reflect.DeepEqual(record.MethodScores, map[string]map[string]int{ImagePriority.Name: {"a": 3, "b": 8, "c": 5}}) {
+		t.Errorf("audited the method scores %v", record.MethodScores)
+	}
+	if _, ok := record.MethodVersions[ImagePriority.Name]; !ok {
+		t.Errorf("audited the method versions %v", record.MethodVersions)
+	}
+	expected := schedulingapi.HostPriorityList{{Host: "b", Score: 8}, {Host: "c", Score: 5}, {Host: "a", Score: 3}}
+	if !reflect.DeepEqual(record.Ranking, expected) {
+		t.Errorf("audited the ranking %v, expected %v", record.Ranking, expected)
+	}
+	if scores[0].Host != "a" {
+		t.Errorf("the audit reordered the answered scores %v", scores)
+	}
+}
+
+func TestAuditRoutes(t *testing.T) {
+	router := newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7), failingPriority("broken"))
+	AddCombinedRoute(router)
+	nodes := testNodes("n1", "n2")
+	tests := []struct {
+		name   string
+		query  string
+		scores map[string]map[string]int
+		errors []string
+	}{
+		{"method", "/a", map[string]map[string]int{"a": {"n1": 3, "n2": 3}}, nil},
+		{"failing method", "/broken", map[string]map[string]int{}, []string{"broken"}},
+		{"combined", "", map[string]map[string]int{"a": {"n1": 3, "n2": 3}, "b": {"n1": 7, "n2": 7}}, []string{"broken"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := withAuditLog(t)
+			combine(t, router, test.query, nodes)
+			records := auditRecords(t, buffer)
+			if len(records) != 1 {
+				t.Fatalf("wrote %v records, expected 1", len(records))
+			}
+			if !reflect.DeepEqual(records[0].MethodScores, test.scores) {
+				t.Errorf("audited the method scores %v, expected %v", records[0].MethodScores, test.scores)
+			}
+			for method := range test.scores {
+				if records[0].MethodVersions[method] != 1 {
+					t.Errorf("audited the method versions %v", records[0].MethodVersions)
+				}
+			}
+			var errors []string
+			for _, methodError := range records[0].Errors {
+				errors = append(errors, methodError.Method)
+			}
+			if !reflect.DeepEqual(errors, test.errors) {
+				t.Errorf("audited the errors %v, expected %v", records[0].Errors, test.errors)
+			}
+		})
+	}
+}
+
+func TestAuditLogDisabled(t *testing.T) {
+	saved := auditLog
+	t.Cleanup(func() { auditLog = saved })
+	auditLog = nil
+	// without -audit-log-file the routes record through a nil logger
+	auditLog.record("a", extenderArgsOf(testPod("default", "p", nil), testNodes("a")), nil, nil, nil)
+}
+
+func TestStartAuditLog(t *testing.T) {
+	savedLog, savedFile := auditLog, auditLogFile
+	t.Cleanup(func() { auditLog, auditLogFile = savedLog, savedFile })
+	auditLog, auditLogFile = nil, filepath.Join(t.TempDir(), "audit.log")
+	startAuditLog()
+	auditLog.record("a", extenderArgsOf(testPod("default", "p", nil), testNodes("a")), nil, schedulingapi.HostPriorityList{{Host: "a", Score: 1}}, nil)
+	content, err := ioutil.ReadFile(auditLogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records := auditRecords(t, bytes.NewBuffer(content)); len(records) != 1 || records[0].Endpoint != "a" {
+		t.Errorf("the audit log file holds %q", content)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+)
+
+var authTokenFile string
+var authAll bool
+
+// authToken is the bearer token expected by the protected routes, auth is disabled when empty
+var authToken []byte
+
+func init() {
+	flag.StringVar(&authTokenFile, "auth-token-file", "", "A file holding the bearer token the scheduler must send in the Authorization header, auth is disabled when empty")
+	flag.BoolVar(&authAll, "auth-all", false, "Also require the bearer token on the informational endpoints, e.g. /priorities")
+}
+
+// loadAuthToken reads the bearer token from the -auth-token-file file
+func loadAuthToken() {
+	if authTokenFile == "" {
+		return
+	}
+	content, err := ioutil.ReadFile(authTokenFile)
+	if err != nil {
+		fatalf("failed to read the -auth-token-file %v: %v", authTokenFile, err)
+	}
+	authToken = []byte(strings.TrimSpace(string(content)))
+	if len(authToken) == 0 {
+		fatalf("the -auth-token-file %v is empty", authTokenFile)
+	}
+	glog.V(0).Infof("bearer token auth enabled (auth-all=%v)\n", authAll)
+}
+
+// authorized checks the bearer token of the request in constant time
+func authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(header, "Bearer "))
+	return subtle.ConstantTimeCompare(token, authToken) == 1
+}
+
+// requireAuth wraps an API handle so it answers 401 to requests without the expected bearer token
+func requireAuth(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if len(authToken) > 0 && !authorized(r) {
+			glog.Warningf("rejected unauthorized request to %v from %v", r.URL.Path, r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handle(w, r, ps)
+	}
+}
+
+// informational wraps a health, metrics or listing handle, those stay open unless -auth-all is set
+func informational(handle httprouter.Handle) httprouter.Handle {
+	if !authAll {
+		return handle
+	}
+	return requireAuth(handle)
+}
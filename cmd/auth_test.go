@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// withAuthToken loads the token, written with a trailing newline as secrets often are, until the end of the test
+func withAuthToken(t *testing.T, token string, all bool) {
+	savedFile, savedToken, savedAll := authTokenFile, authToken, authAll
+	t.Cleanup(func() { authTokenFile, authToken, authAll = savedFile, savedToken, savedAll })
+	authToken, authAll = nil, all
+	if token == "" {
+		return
+	}
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	authTokenFile = filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(authTokenFile, []byte(token+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loadAuthToken()
+}
+
+func TestRequireAuth(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) { w.WriteHeader(http.StatusOK) }
+	tests := []struct {
+		name          string
+		token         string
+		all           bool
+		authorization string
+		api, info     int
+	}{
+		{"auth disabled", "", false, "", http.StatusOK, http.StatusOK},
+		{"valid token", "s3cret", false, "Bearer s3cret", http.StatusOK, http.StatusOK},
+		{"missing token", "s3cret", false, "", http.StatusUnauthorized, http.StatusOK},
+		{"wrong token", "s3cret", false, "Bearer s3cre", http.StatusUnauthorized, http.StatusOK},
+		{"not a bearer token", "s3cret", false, "Basic s3cret", http.StatusUnauthorized, http.StatusOK},
+		{"auth-all without token", "s3cret", true, "", http.StatusUnauthorized, http.StatusUnauthorized},
+		{"auth-all with token", "s3cret", true, "Bearer s3cret", http.StatusOK, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withAuthToken(t, test.token, test.all)
+			for handle, expected := range map[string]int{"api": test.api, "informational": test.info} {
+				wrapped := requireAuth(ok)
+				if handle == "informational" {
+					wrapped = informational(ok)
+				}
+				r := httptest.NewRequest(http.MethodPost, "/", nil)
+				if test.authorization != "" {
+					r.Header.Set("Authorization", test.authorization)
+				}
+				w := httptest.NewRecorder()
+				wrapped(w, r, nil)
+				if w.Code != expected {
+					t.Errorf("the %v handle answered %v, expected %v", handle, w.Code, expected)
+				}
+			}
+		})
+	}
+}
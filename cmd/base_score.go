@@ -0,0 +1,97 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var baseScoreAnnotation string
+var baseScoreWeight float64
+
+func init() {
+	flag.StringVar(&baseScoreAnnotation, "base-score-annotation", "scheduler.example.com/base-score", "The node annotation holding a baseline desirability of the node set by other tooling, from 0 to 10")
+	flag.Float64Var(&baseScoreWeight, "base-score-weight", 0, "The weight, from 0 to 1, of the node base score blended into the score of each priority, 0 disables the blending")
+}
+
+// validateBaseScoreWeight makes sure the -base-score-weight flag is between 0 and 1
+func validateBaseScoreWeight() error {
+	if baseScoreWeight < 0 || baseScoreWeight > 1 {
+		return fmt.Errorf("the -base-score-weight flag value must be between 0 and 1, got %v", baseScoreWeight)
+	}
+	return nil
+}
+
+// blendBaseScores blends the base score of the nodes into the scores: score*(1-weight) + base*weight,
+// so the result stays within the score range. Vetoes and the nodes without a valid base score are kept
+// as they are
+func blendBaseScores(list schedulingapi.HostPriorityList, nodes []v1.Node) schedulingapi.HostPriorityList {
+	if baseScoreWeight == 0 {
+		return list
+	}
+	bases := make(map[string]float64)
+	for _, node := range nodes {
+		if base, ok := nodeBaseScore(node); ok {
+			bases[node.Name] = base
+		}
+	}
+	blended := make(schedulingapi.HostPriorityList, len(list))
+	for i, hp := range list {
+		blended[i] = hp
+		if base, ok := bases[hp.Host]; ok {
+			blended[i] = blendBase(hp, base)
+		}
+	}
+	return blended
+}
+
+// blendBaseScore blends the base score of the node into a single score as blendBaseScores does
+func blendBaseScore(hp schedulingapi.HostPriority, node v1.Node) schedulingapi.HostPriority {
+	if baseScoreWeight == 0 {
+		return hp
+	}
+	if base, ok := nodeBaseScore(node); ok {
+		return blendBase(hp, base)
+	}
+	return hp
+}
+
+// blendBase blends the base into the score, a veto is kept as it is
+func blendBase(hp schedulingapi.HostPriority, base float64) schedulingapi.HostPriority {
+	if hp.Score != UnfitScore {
+		hp.Score = clampScore(int(math.Round(float64(hp.Score)*(1-baseScoreWeight) + base*baseScoreWeight)))
+	}
+	return hp
+}
+
+// nodeBaseScore returns the base score annotated on the node, clamped to the score range
+func nodeBaseScore(node v1.Node) (float64, bool) {
+	value, ok := node.Annotations[baseScoreAnnotation]
+	if !ok {
+		return 0, false
+	}
+	base, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(base) {
+		glog.Warningf("ignoring invalid %v annotation %q on node %v", baseScoreAnnotation, value, node.Name)
+		return 0, false
+	}
+	return math.Max(0, math.Min(schedulingapi.MaxPriority, base)), true
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withBaseScoreWeight sets -base-score-weight until the end of the test
+func withBaseScoreWeight(t *testing.T, weight float64) {
+	saved := baseScoreWeight
+	t.Cleanup(func() { baseScoreWeight = saved })
+	baseScoreWeight = weight
+}
+
+// baseNode returns a node annotated with the base score, not annotated when empty
+func baseNode(name, base string) v1.Node {
+	if base == "" {
+		return annotatedNode(name, nil)
+	}
+	return annotatedNode(name, map[string]string{baseScoreAnnotation: base})
+}
+
+func TestValidateBaseScoreWeight(t *testing.T) {
+	for weight, valid := range map[float64]bool{0: true, 0.5: true, 1: true, -0.1: false, 1.5: false} {
+		withBaseScoreWeight(t, weight)
+		if err := validateBaseScoreWeight(); (err == nil) != valid {
+			t.Errorf("validateBaseScoreWeight(%v) returned %v", weight, err)
+		}
+	}
+}
+
+func TestNodeBaseScore(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected float64
+		ok       bool
+	}{
+		{"7", 7, true},
+		{"2.5", 2.5, true},
+		{"15", 10, true},
+		{"-3", 0, true},
+		{"+Inf", 10, true},
+		{"NaN", 0, false},
+		{"high", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		if base, ok := nodeBaseScore(baseNode("n", test.base)); base != test.expected || ok != test.ok {
+			t.Errorf("read the base score %q as %v (%v), expected %v (%v)", test.base, base, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestBlendBaseScores(t *testing.T) {
+	nodes := []v1.Node{baseNode("high", "10"), baseNode("low", "0"), baseNode("vetoed", "10"), baseNode("unannotated", ""), baseNode("malformed", "x")}
+	list := schedulingapi.HostPriorityList{{Host: "high", Score: 4}, {Host: "low", Score: 4}, {Host: "vetoed", Score: UnfitScore}, {Host: "unannotated", Score: 4}, {Host: "malformed", Score: 4}}
+	tests := []struct {
+		weight   float64
+		expected []int
+	}{
+		{0, []int{4, 4, UnfitScore, 4, 4}},
+		{0.3, []int{6, 3, UnfitScore, 4, 4}},
+		{0.6, []int{8, 2, UnfitScore, 4, 4}},
+		{1, []int{10, 0, UnfitScore, 4, 4}},
+	}
+	for _, test := range tests {
+		withBaseScoreWeight(t, test.weight)
+		var scores, single []int
+		for i, hp := range blendBaseScores(list, nodes) {
+			scores = append(scores, hp.Score)
+			single = append(single, blendBaseScore(list[i], nodes[i]).Score)
+		}
+		if !reflect.DeepEqual(scores, test.expected) || !reflect.DeepEqual(single, test.expected) {
+			t.Errorf("blended with a weight of %v into %v and %v, expected %v", test.weight, scores, single, test.expected)
+		}
+	}
+	if list[0].Score != 4 {
+		t.Errorf("blending changed the scores of the caller")
+	}
+}
+
+func TestBaseScoreRoute(t *testing.T) {
+	router := newTestRouter(t, constantPriority("constant", 1, 4))
+	nodes := []v1.Node{baseNode("boosted", "10"), baseNode("plain", "")}
+	for weight, boosted := range map[float64]int{0: 4, 0.3: 6, 0.6: 8} {
+		withBaseScoreWeight(t, weight)
+		checkScores(t, prioritize(t, router, "constant", testPod("default", "p", nil), nodes), map[string]int{"boosted": boosted, "plain": 4})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var capabilitiesAnnotation, capabilityLabelPrefix string
+
+func init() {
+	flag.StringVar(&capabilitiesAnnotation, "capabilities-annotation", "scheduler.extender/capabilities", "The pod annotation listing, comma separated, the node capabilities the pod would like, e.g. sgx,avx512")
+	flag.StringVar(&capabilityLabelPrefix, "capability-label-prefix", "capability.example.com/", "The prefix of the node labels advertising a capability, a node has the capability when the label is set to anything but false")
+}
+
+// NodeCapabilitiesPriority softly prefers the nodes advertising the capabilities the pod asks for: the
+// nodes matching the most capabilities get the max score, the others a score proportional to their
+// matches. Pods without the annotation, or candidates with no capability at all, get the neutral score
+var NodeCapabilitiesPriority = PrioritizeMethod{
+	Name: "node_capabilities",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		wanted := podCapabilities(pod)
+		matched := make(map[string]int, len(nodes))
+		var maxMatched int
+		for _, node := range nodes {
+			for _, capability := range wanted {
+				if nodeHasCapability(node, capability) {
+					matched[node.Name]++
+				}
+			}
+			if matched[node.Name] > maxMatched {
+				maxMatched = matched[node.Name]
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if maxMatched == 0 {
+				return neutralScore, nil
+			}
+			return schedulingapi.MaxPriority * matched[node.Name] / maxMatched, nil
+		}
+	},
+}
+
+// podCapabilities returns the capabilities listed by the pod annotation
+func podCapabilities(pod v1.Pod) []string {
+	var capabilities []string
+	for _, capability := range strings.Split(pod.Annotations[capabilitiesAnnotation], ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return capabilities
+}
+
+// nodeHasCapability reports whether the node advertises the capability
+func nodeHasCapability(node v1.Node, capability string) bool {
+	value, ok := node.Labels[capabilityLabelPrefix+capability]
+	return ok && value != "false"
+}
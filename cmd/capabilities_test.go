@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestPodCapabilities(t *testing.T) {
+	tests := []struct {
+		annotation string
+		expected   []string
+	}{
+		{"sgx,avx512", []string{"sgx", "avx512"}},
+		{" sgx , avx512 ,", []string{"sgx", "avx512"}},
+		{",,", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		pod := annotatedPod(map[string]string{capabilitiesAnnotation: test.annotation})
+		if capabilities := podCapabilities(pod); !reflect.DeepEqual(capabilities, test.expected) {
+			t.Errorf("read %q as %v, expected %v", test.annotation, capabilities, test.expected)
+		}
+	}
+}
+
+func TestNodeCapabilitiesPriority(t *testing.T) {
+	capable := func(name string, labels map[string]string) v1.Node {
+		prefixed := make(map[string]string, len(labels))
+		for capability, value := range labels {
+			prefixed[capabilityLabelPrefix+capability] = value
+		}
+		return labeledNode(name, prefixed)
+	}
+	nodes := []v1.Node{
+		capable("both", map[string]string{"sgx": "true", "avx512": ""}),
+		capable("sgx", map[string]string{"sgx": "true", "hugepages-1g": "true"}),
+		capable("disabled", map[string]string{"sgx": "false", "avx512": "false"}),
+		labeledNode("unprefixed", map[string]string{"sgx": "true", "avx512": "true"}),
+	}
+	tests := []struct {
+		name       string
+		annotation map[string]string
+		expected   map[string]int
+	}{
+		{"two capabilities", map[string]string{capabilitiesAnnotation: "sgx,avx512"}, map[string]int{"both": 10, "sgx": 5, "disabled": 0, "unprefixed": 0}},
+		{"one capability", map[string]string{capabilitiesAnnotation: "sgx"}, map[string]int{"both": 10, "sgx": 10, "disabled": 0, "unprefixed": 0}},
+		{"no node capable", map[string]string{capabilitiesAnnotation: "gpu"}, map[string]int{"both": neutralScore, "sgx": neutralScore, "disabled": neutralScore, "unprefixed": neutralScore}},
+		{"malformed annotation", map[string]string{capabilitiesAnnotation: " , "}, map[string]int{"both": neutralScore, "sgx": neutralScore, "disabled": neutralScore, "unprefixed": neutralScore}},
+		{"no annotation", nil, map[string]int{"both": neutralScore, "sgx": neutralScore, "disabled": neutralScore, "unprefixed": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, NodeCapabilitiesPriority, annotatedPod(test.annotation), nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+var circuitErrorThreshold int
+var circuitWindow, circuitCooldown time.Duration
+
+func init() {
+	flag.IntVar(&circuitErrorThreshold, "circuit-error-threshold", 0, "The number of errors of a priority method within -circuit-window opening its circuit, the method then returns the neutral score for -circuit-cooldown. 0 never opens the circuits")
+	flag.DurationVar(&circuitWindow, "circuit-window", time.Minute, "The sliding window the errors of a priority method are counted over")
+	flag.DurationVar(&circuitCooldown, "circuit-cooldown", 30*time.Second, "How long an open circuit skips its priority method before trying it again")
+}
+
+// validateCircuit makes sure the circuit flags hold valid values
+func validateCircuit() error {
+	if circuitErrorThreshold < 0 {
+		return fmt.Errorf("the -circuit-error-threshold flag value must not be negative, got %v", circuitErrorThreshold)
+	}
+	if circuitWindow <= 0 {
+		return fmt.Errorf("the -circuit-window flag value must be positive, got %v", circuitWindow)
+	}
+	if circuitCooldown <= 0 {
+		return fmt.Errorf("the -circuit-cooldown flag value must be positive, got %v", circuitCooldown)
+	}
+	return nil
+}
+
+// methodCircuit isolates a failing priority method: once it errors -circuit-error-threshold times within
+// -circuit-window the circuit opens and the method is skipped until the cooldown ends, when it is tried again
+type methodCircuit struct {
+	lock      sync.Mutex
+	errors    []time.Time
+	openUntil time.Time
+	opened    int
+	skipped   int
+}
+
+var circuitsLock sync.Mutex
+var circuits = make(map[string]*methodCircuit)
+
+// circuitFor returns the circuit of the priority method
+func circuitFor(method string) *methodCircuit {
+	circuitsLock.Lock()
+	defer circuitsLock.Unlock()
+	circuit, ok := circuits[method]
+	if !ok {
+		circuit = &methodCircuit{}
+		circuits[method] = circuit
+	}
+	return circuit
+}
+
+// allow returns whether the method may run, false while the circuit is open
+func (c *methodCircuit) allow(now time.Time) bool {
+	if circuitErrorThreshold == 0 {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if now.Before(c.openUntil) {
+		c.skipped++
+		return false
+	}
+	return true
+}
+
+// record counts the outcome of a run of the method and opens the circuit when the errors reach the threshold
+func (c *methodCircuit) record(err error, now time.Time) bool {
+	if circuitErrorThreshold == 0 || err == nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	recent := c.errors[:0]
+	for _, at := range c.errors {
+		if now.Sub(at) < circuitWindow {
+			recent = append(recent, at)
+		}
+	}
+	c.errors = append(recent, now)
+	if len(c.errors) < circuitErrorThreshold {
+		return false
+	}
+	c.errors = nil
+	c.openUntil = now.Add(circuitCooldown)
+	c.opened++
+	return true
+}
+
+// open returns whether the circuit is open
+func (c *methodCircuit) open(now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return now.Before(c.openUntil)
+}
+
+// writeCircuitMetrics writes the state of the circuits of the methods scored at least once
+func writeCircuitMetrics(w io.Writer) {
+	circuitsLock.Lock()
+	names := make([]string, 0, len(circuits))
+	for name := range circuits {
+		names = append(names, name)
+	}
+	circuitsLock.Unlock()
+	sort.Strings(names)
+
+	now := time.Now()
+	var open, opened, skipped []metricSample
+	for _, name := range names {
+		circuit := circuitFor(name)
+		labels := fmt.Sprintf("method=%q", name)
+		open = append(open, metricSample{labels, boolValue(circuit.open(now))})
+		circuit.lock.Lock()
+		opened = append(opened, metricSample{labels, float64(circuit.opened)})
+		skipped = append(skipped, metricSample{labels, float64(circuit.skipped)})
+		circuit.lock.Unlock()
+	}
+	writeMetric(w, "extender_circuit_open", "gauge", "Whether the circuit of the priority method is open, the method being skipped.", open...)
+	writeMetric(w, "extender_circuit_opened_total", "counter", "Times the circuit of the priority method opened.", opened...)
+	writeMetric(w, "extender_circuit_skipped_total", "counter", "Requests the priority method was skipped for while its circuit was open.", skipped...)
+}
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withCircuit sets the circuit flags for the test, the circuits opened by the test are dropped at its end
+func withCircuit(t *testing.T, threshold int, window, cooldown time.Duration) {
+	savedThreshold, savedWindow, savedCooldown := circuitErrorThreshold, circuitWindow, circuitCooldown
+	t.Cleanup(func() {
+		circuitErrorThreshold, circuitWindow, circuitCooldown = savedThreshold, savedWindow, savedCooldown
+		circuitsLock.Lock()
+		circuits = make(map[string]*methodCircuit)
+		circuitsLock.Unlock()
+	})
+	circuitErrorThreshold, circuitWindow, circuitCooldown = threshold, window, cooldown
+}
+
+func TestValidateCircuit(t *testing.T) {
+	tests := []struct {
+		threshold        int
+		window, cooldown time.Duration
+		valid            bool
+	}{
+		{0, time.Minute, 30 * time.Second, true},
+		{5, time.Second, time.Second, true},
+		{-1, time.Minute, 30 * time.Second, false},
+		{5, 0, 30 * time.Second, false},
+		{5, time.Minute, -time.Second, false},
+	}
+	for _, test := range tests {
+		withCircuit(t, test.threshold, test.window, test.cooldown)
+		if err := validateCircuit(); (err == nil) != test.valid {
+			t.Errorf("validateCircuit(%v, %v, %v) returned %v", test.threshold, test.window, test.cooldown, err)
+		}
+	}
+}
+
+func TestMethodCircuit(t *testing.T) {
+	withCircuit(t, 3, time.Minute, 30*time.Second)
+	failure := errors.New("failure")
+	start := time.Now()
+	steps := []struct {
+		at     time.Duration
+		err    error
+		opens  bool
+		allows bool
+	}{
+		{0, failure, false, true},
+		{10 * time.Second, nil, false, true},
+		{20 * time.Second, failure, false, true},
+		// the first error left the window
+		{70 * time.Second, failure, false, true},
+		{75 * time.Second, failure, true, false},
+		{100 * time.Second, nil, false, false},
+		// the cooldown ended, the errors counted before the circuit opened are forgotten
+		{110 * time.Second, failure, false, true},
+		{115 * time.Second, failure, false, true},
+	}
+	circuit := &methodCircuit{}
+	for i, step := range steps {
+		now := start.Add(step.at)
+		if opens := circuit.record(step.err, now); opens != step.opens {
+			t.Errorf("step %v: record returned %v", i, opens)
+		}
+		if allows := circuit.allow(now); allows != step.allows {
+			t.Errorf("step %v: allow returned %v", i, allows)
+		}
+	}
+	if circuit.opened != 1 || circuit.skipped != 2 {
+		t.Errorf("the circuit opened %v times and skipped %v requests, expected 1 and 2", circuit.opened, circuit.skipped)
+	}
+}
+
+func TestMethodCircuitDisabled(t *testing.T) {
+	withCircuit(t, 0, time.Minute, 30*time.Second)
+	circuit := &methodCircuit{}
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if circuit.record(errors.New("failure"), now) || !circuit.allow(now) {
+			t.Fatalf("the circuit opened with -circuit-error-threshold 0")
+		}
+	}
+}
+
+func TestCircuitRoute(t *testing.T) {
+	withCircuit(t, 2, time.Minute, time.Hour)
+	withNeutralScore(t, 5)
+	router := newTestRouter(t, failingPriority("flaky"))
+	nodes := testNodes("a", "b")
+	for i, expected := range []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK} {
+		if w := combine(t, router, "/flaky", nodes); w.Code != expected {
+			t.Errorf("request %v answered %v, expected %v", i, w.Code, expected)
+		}
+	}
+	checkScores(t, prioritize(t, router, "flaky", testPod("default", "p", nil), nodes), map[string]int{"a": 5, "b": 5})
+
+	var metrics bytes.Buffer
+	writeCircuitMetrics(&metrics)
+	for _, expected := range []string{`extender_circuit_open{method="flaky"} 1`, `extender_circuit_opened_total{method="flaky"} 1`, `extender_circuit_skipped_total{method="flaky"} 2`} {
+		if !strings.Contains(metrics.String(), expected) {
+			t.Errorf("the metrics are missing %v:\n%v", expected, metrics.String())
+		}
+	}
+}
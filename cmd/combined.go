@@ -0,0 +1,242 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// combinedMethodName is the name the combined endpoint records its scores under
+const combinedMethodName = "combined"
+
+// extenderErrorsHeader carries the per-method errors of the combined endpoint, the body has to stay a
+// HostPriorityList for the scheduler to decode it
+const extenderErrorsHeader = "X-Extender-Errors"
+
+// errorsParameter is the query parameter asking the combined endpoint for a CombinedResult body
+const errorsParameter = "errors"
+
+var allFailedStatus int
+
+func init() {
+	flag.IntVar(&allFailedStatus, "all-failed-status", http.StatusInternalServerError, "The status returned by the combined endpoint when every method failed: 500, or 200 with neutral scores")
+}
+
+// MethodError names a priority method that failed while serving a combined request
+type MethodError struct {
+	Method string `json:"method"`
+	Error  string `json:"error"`
+}
+
+// CombinedResult is the body answered by the combined endpoint to the requests with ?errors=true, the
+// scores along with the methods that failed. The scheduler expects a bare HostPriorityList, the result
+// is meant for the other clients
+type CombinedResult struct {
+	Scores schedulingapi.HostPriorityList `json:"scores"`
+	Errors []MethodError                  `json:"errors"`
+}
+
+// errorsInBody reports whether the request asks for a CombinedResult body
+func errorsInBody(r *http.Request) bool {
+	inBody, _ := strconv.ParseBool(r.URL.Query().Get(errorsParameter))
+	return inBody
+}
+
+// validateAllFailedStatus makes sure the -all-failed-status flag holds a supported status
+func validateAllFailedStatus() error {
+	if allFailedStatus != http.StatusOK && allFailedStatus != http.StatusInternalServerError {
+		return fmt.Errorf("the -all-failed-status flag value must be 200 or 500, got %v", allFailedStatus)
+	}
+	return nil
+}
+
+// safeRunPriority runs the priority method turning a panic into an error, so one method can't fail the others
+func safeRunPriority(ctx context.Context, priorityMethod PrioritizeMethod, extenderArgs schedulingapi.ExtenderArgs, warnings *requestWarnings) (list schedulingapi.HostPriorityList, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recordPanic(priorityMethod.Name, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return runPriority(ctx, priorityMethod, extenderArgs, warnings, nil)
+}
+
+// combineScores returns the weighted average of the scores of each host over the lists scoring it, a
+// host missing from a list, e.g. sampled out, is not pulled down by it. A host vetoed by any method
+// stays vetoed
+func combineScores(lists []schedulingapi.HostPriorityList, weights []int) schedulingapi.HostPriorityList {
+	var hosts []string
+	sums := make(map[string]int)
+	hostWeights := make(map[string]int)
+	vetoed := make(map[string]bool)
+	for i, list := range lists {
+		for _, hp := range list {
+			if _, seen := sums[hp.Host]; !seen && !vetoed[hp.Host] {
+				hosts = append(hosts, hp.Host)
+			}
+			if hp.Score == UnfitScore {
+				vetoed[hp.Host] = true
+				continue
+			}
+			sums[hp.Host] += weights[i] * hp.Score
+			hostWeights[hp.Host] += weights[i]
+		}
+	}
+	combined := make(schedulingapi.HostPriorityList, len(hosts))
+	for i, host := range hosts {
+		score := UnfitScore
+		if !vetoed[host] {
+			score = sums[host] / hostWeights[host]
+		}
+		combined[i] = schedulingapi.HostPriority{Host: host, Score: score}
+	}
+	return combined
+}
+
+// CombinedRoute scores the request with every registered method and returns their weighted average.
+// A failing method is left out of the average and named in the X-Extender-Errors header, and in the
+// errors of the CombinedResult with ?errors=true. When all of them fail the -all-failed-status flag
+// decides between a 500 and neutral scores
+func CombinedRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	snapshot := currentSnapshot()
+	timing := newServerTiming()
+	if !checkRequestBody(w, r) {
+		glog.Warning("received empty request!")
+		return
+	}
+	extenderArgs, err := decodeExtenderArgs(r.Body)
+	if err != nil {
+		glog.Warningf("combined priorities received an invalid request: %v", err)
+		writeError(w, err)
+		return
+	}
+	timing.phase("decode", "")
+	defer recoverFailOpenScores(w, combinedMethodName, extenderArgs)
+	methods := snapshot.methodsFor(extenderArgs.Pod.Namespace)
+	if warmupMode == warmupModeUnavailable {
+		for _, priorityMethod := range methods {
+			if warmingUp(priorityMethod) {
+				writeError(w, newError(ErrUnavailable, "priority method %v is warming up, the informers are not synced", priorityMethod.Name))
+				return
+			}
+		}
+	}
+
+	warnings := newRequestWarnings()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	var lists []schedulingapi.HostPriorityList
+	var weights []int
+	methodErrors := []MethodError{}
+	methodScores := make(map[string]schedulingapi.HostPriorityList, len(methods))
+	for _, priorityMethod := range methods {
+		list, err := safeRunPriority(ctx, priorityMethod, extenderArgs, warnings)
+		timing.phase("compute", priorityMethod.Name)
+		if err != nil {
+			glog.Warningf("priority method %v failed for pod %v: %v%v", priorityMethod.Name, extenderArgs.Pod.Name, err, logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+			methodErrors = append(methodErrors, MethodError{Method: priorityMethod.Name, Error: err.Error()})
+			continue
+		}
+		lists = append(lists, list)
+		weights = append(weights, methodWeight(priorityMethod))
+		methodScores[priorityMethod.Name] = list
+	}
+
+	if len(methodErrors) > 0 {
+		errorsHeader, _ := json.Marshal(methodErrors)
+		w.Header().Set(extenderErrorsHeader, string(errorsHeader))
+	}
+	var hostPriorityList schedulingapi.HostPriorityList
+	if len(lists) == 0 {
+		if allFailedStatus != http.StatusOK && !failOpen {
+			auditLog.record(combinedMethodName, extenderArgs, methodScores, nil, methodErrors)
+			if errorsInBody(r) {
+				writeCombinedResult(w, http.StatusInternalServerError, CombinedResult{Errors: methodErrors})
+				return
+			}
+			http.Error(w, "all the priority methods failed", http.StatusInternalServerError)
+			return
+		}
+		if allFailedStatus != http.StatusOK {
+			glog.Errorf("all the priority methods failed for pod %v, answering neutral scores as -fail-open is set%v", extenderArgs.Pod.Name, logFields("pod", podLogName(extenderArgs.Pod), "method", combinedMethodName))
+			countFailOpen(combinedMethodName)
+		}
+		warnings.add(combinedMethodName, warningAllFailed, "all the priority methods failed, neutral scores")
+		if extenderArgs.Nodes != nil {
+			hostPriorityList = neutralScores(extenderArgs.Nodes.Items)
+		}
+	} else {
+		hostPriorityList = topK(breakTies(translateVetoes(combinedMethodName, extenderArgs.Pod.Name, applyScoreFloor(flattenPoorScores(combinedMethodName, extenderArgs.Pod.Name, combineScores(lists, weights))))))
+	}
+	timing.phase("combine", "")
+	recordScores(combinedMethodName, extenderArgs.Pod, hostPriorityList)
+	decisionFeed.publish(combinedMethodName, extenderArgs.Pod, hostPriorityList)
+	recentRecommendations.observe(hostPriorityList, time.Now())
+	scoreAnnotations.observe(combinedMethodName, extenderArgs.Pod, hostPriorityList, time.Now())
+	auditLog.record(combinedMethodName, extenderArgs, methodScores, hostPriorityList, methodErrors)
+
+	if errorsInBody(r) {
+		timing.write(w)
+		warnings.emit(w, combinedMethodName, extenderArgs.Pod.Name)
+		glog.V(4).Infof("combined priorities, %v hosts scored, %v methods failed%v\n", len(hostPriorityList), len(methodErrors), logFields("pod", podLogName(extenderArgs.Pod), "method", combinedMethodName))
+		writeCombinedResult(w, http.StatusOK, CombinedResult{Scores: hostPriorityList, Errors: methodErrors})
+		return
+	}
+	if streamResponses {
+		timing.write(w)
+		warnings.emit(w, combinedMethodName, extenderArgs.Pod.Name)
+		glog.V(4).Infof("combined priorities, streaming the scores of %v hosts%v\n", len(hostPriorityList), logFields("pod", podLogName(extenderArgs.Pod), "method", combinedMethodName))
+		streamScores(w, hostPriorityList)
+		return
+	}
+	resultBody, err := json.Marshal(hostPriorityList)
+	if err != nil {
+		panic(err)
+	}
+	timing.phase("encode", "")
+	timing.write(w)
+	warnings.emit(w, combinedMethodName, extenderArgs.Pod.Name)
+	glog.V(4).Infof("combined priorities, hostPriorityList = %v%v\n ", string(resultBody), logFields("pod", podLogName(extenderArgs.Pod), "method", combinedMethodName))
+	writeScores(w, r, extenderArgs.Pod, resultBody)
+}
+
+// writeCombinedResult answers the result with the status
+func writeCombinedResult(w http.ResponseWriter, status int, result CombinedResult) {
+	resultBody, err := json.Marshal(result)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resultBody)
+}
+
+// AddCombinedRoute adding the combined route, served at the priorities prefix itself, to the router
+func AddCombinedRoute(router *httprouter.Router) {
+	handle := requireAuth(countPanics(combinedMethodName, CombinedRoute))
+	for _, path := range prefixedPaths(prioritiesPrefix) {
+		router.POST(path, handle)
+		glog.V(2).Infof("added combined priorities at path: %v\n", path)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// constantPriority scores every node with the score
+func constantPriority(name string, weight, score int) PrioritizeMethod {
+	return PrioritizeMethod{
+		Name:   name,
+		Weight: weight,
+		Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+			return func(pod v1.Pod, node v1.Node) (int, error) {
+				return score, nil
+			}
+		},
+	}
+}
+
+// failingPriority fails on every request
+func failingPriority(name string) PrioritizeMethod {
+	return PrioritizeMethod{
+		Name: name,
+		Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+			return func(pod v1.Pod, node v1.Node) (int, error) {
+				return 0, errors.New("unavailable backend")
+			}
+		},
+	}
+}
+
+func TestCombineScores(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		lists    []schedulingapi.HostPriorityList
+		weights  []int
+		expected map[string]int
+	}{
+		{
+			name: "weighted average",
+			lists: []schedulingapi.HostPriorityList{
+				{{Host: "a", Score: 10}, {Host: "b", Score: 0}},
+				{{Host: "a", Score: 4}, {Host: "b", Score: 6}},
+			},
+			weights:  []int{1, 2},
+			expected: map[string]int{"a": 6, "b": 4},
+		},
+		{
+			name: "host missing from a list",
+			lists: []schedulingapi.HostPriorityList{
+				{{Host: "a", Score: 8}, {Host: "b", Score: 8}},
+				{{Host: "a", Score: 2}},
+			},
+			weights:  []int{1, 1},
+			expected: map[string]int{"a": 5, "b": 8},
+		},
+		{
+			name: "vetoed by one method",
+			lists: []schedulingapi.HostPriorityList{
+				{{Host: "a", Score: UnfitScore}, {Host: "b", Score: 3}},
+				{{Host: "a", Score: 10}, {Host: "b", Score: 5}},
+			},
+			weights:  []int{1, 1},
+			expected: map[string]int{"a": UnfitScore, "b": 4},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if scores := scoresByHost(combineScores(test.lists, test.weights)); !reflect.DeepEqual(scores, test.expected) {
+				t.Errorf("combined scores %v, expected %v", scores, test.expected)
+			}
+		})
+	}
+}
+
+// combine posts the pod and the nodes to the combined route
+func combine(t *testing.T, router http.Handler, query string, nodes []v1.Node) *httptest.ResponseRecorder {
+	t.Helper()
+	pod := testPod("default", "p", nil)
+	body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+query, bytes.NewReader(body)))
+	return w
+}
+
+func TestCombinedRouteErrors(t *testing.T) {
+	nodes := testNodes("a", "b")
+	for _, test := range []struct {
+		name     string
+		methods  []PrioritizeMethod
+		status   int
+		expected map[string]int
+		failed   []string
+	}{
+		{
+			name:     "one method failing",
+			methods:  []PrioritizeMethod{constantPriority("high", 1, 9), failingPriority("broken"), constantPriority("low", 2, 3)},
+			status:   http.StatusOK,
+			expected: map[string]int{"a": 5, "b": 5},
+			failed:   []string{"broken"},
+		},
+		{
+			name:     "no method failing",
+			methods:  []PrioritizeMethod{constantPriority("high", 1, 9)},
+			status:   http.StatusOK,
+			expected: map[string]int{"a": 9, "b": 9},
+		},
+		{
+			name:    "all methods failing",
+			methods: []PrioritizeMethod{failingPriority("broken"), failingPriority("down")},
+			status:  http.StatusInternalServerError,
+			failed:  []string{"broken", "down"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, test.methods...)
+			AddCombinedRoute(router)
+
+			w := combine(t, router, "?errors=true", nodes)
+			if w.Code != test.status {
+				t.Fatalf("answered %v: %v, expected %v", w.Code, w.Body.String(), test.status)
+			}
+			var result CombinedResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("answered an invalid result %q: %v", w.Body.String(), err)
+			}
+			var failed []string
+			for _, methodError := range result.Errors {
+				failed = append(failed, methodError.Method)
+			}
+			if !reflect.DeepEqual(failed, test.failed) {
+				t.Errorf("failed methods %v, expected %v", failed, test.failed)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			checkScores(t, result.Scores, test.expected)
+
+			// the scheduler gets the same scores as a bare list, the errors in the header
+			w = combine(t, router, "", nodes)
+			var list schedulingapi.HostPriorityList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			checkScores(t, list, test.expected)
+			if header := w.Header().Get(extenderErrorsHeader); (header != "") != (len(test.failed) > 0) {
+				t.Errorf("%v header %q, expected the errors of %v", extenderErrorsHeader, header, test.failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/golang/glog"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var configFile string
+
+func init() {
+	flag.StringVar(&configFile, "config", "", "A YAML file selecting the active priority methods and their options, reloaded on SIGHUP, all the methods are active when empty")
+}
+
+// extenderConfig is the content of the -config file, e.g.
+//
+//	priorities:
+//	- name: image_score
+//	  weight: 2
+//	- name: node_bias
+//	  invert: true
+//	  schedulerNames: [scheduler-a]
+//	instanceTypePrices:
+//	  m5.large: 0.096
+//	defaultInstancePrice: 0.1
+//	entitlements:
+//	  oracle-db: license.example.com/oracle
+//	podCountCaps:
+//	  latency-critical: 30
+//	namespaces:
+//	  team-a:
+//	    priorities:
+//	    - name: image_score
+//	      weight: 5
+type extenderConfig struct {
+	Priorities []priorityConfig `json:"priorities"`
+	// Namespaces replace the priorities for the pods of a namespace, the other namespaces use Priorities
+	Namespaces map[string]namespaceConfig `json:"namespaces,omitempty"`
+	// InstanceTypePrices maps the instance types to their hourly price, for instance_cost
+	InstanceTypePrices map[string]float64 `json:"instanceTypePrices,omitempty"`
+	// DefaultInstancePrice is the price of the instance types missing from InstanceTypePrices
+	DefaultInstancePrice float64 `json:"defaultInstancePrice,omitempty"`
+	// Entitlements maps the entitlements to the node label or annotation key granting them, for the entitlement filter
+	Entitlements map[string]string `json:"entitlements,omitempty"`
+	// PodCountCaps maps the workload classes to the most pods a node may run to take a pod of the class, for pod_count_cap
+	PodCountCaps map[string]int `json:"podCountCaps,omitempty"`
+}
+
+// namespaceConfig selects the active priority methods and their options for the pods of a namespace
+type namespaceConfig struct {
+	Priorities []priorityConfig `json:"priorities"`
+}
+
+// priorityConfig activates a registered priority method and sets its options
+type priorityConfig struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight,omitempty"`
+	Invert bool   `json:"invert,omitempty"`
+	// SchedulerNames restricts the method to the pods of these schedulers
+	SchedulerNames []string `json:"schedulerNames,omitempty"`
+}
+
+// apply returns the priority method with the configured options
+func (pc priorityConfig) apply(method PrioritizeMethod) PrioritizeMethod {
+	if pc.Weight != 0 {
+		method.Weight = pc.Weight
+	}
+	if pc.Invert {
+		method.Invert = true
+	}
+	if len(pc.SchedulerNames) > 0 {
+		method.SchedulerNames = pc.SchedulerNames
+	}
+	return method
+}
+
+// configSnapshot is an immutable view of the config in use, resolved against the registered methods.
+// A request captures the snapshot once when it starts, so a reload swapping the snapshot never lets it
+// observe a mix of the old and the new config
+type configSnapshot struct {
+	config  *extenderConfig
+	methods []PrioritizeMethod
+	// namespaces are the methods active for the namespaces with their own priorities
+	namespaces map[string][]PrioritizeMethod
+}
+
+// activeSnapshot holds the *configSnapshot in use
+var activeSnapshot atomic.Value
+
+// currentSnapshot returns the snapshot in use, empty until the config is started
+func currentSnapshot() *configSnapshot {
+	if snapshot, ok := activeSnapshot.Load().(*configSnapshot); ok {
+		return snapshot
+	}
+	return &configSnapshot{}
+}
+
+// currentConfig returns the config file in use, nil when no -config file is set
+func currentConfig() *extenderConfig {
+	return currentSnapshot().config
+}
+
+// newSnapshot resolves the config against the registered methods, all of them are active for a nil
+// config or a config without priorities
+func newSnapshot(config *extenderConfig) *configSnapshot {
+	snapshot := &configSnapshot{config: config}
+	if config == nil || len(config.Priorities) == 0 {
+		registryLock.RLock()
+		snapshot.methods = make([]PrioritizeMethod, len(registeredMethods))
+		copy(snapshot.methods, registeredMethods)
+		registryLock.RUnlock()
+	} else {
+		snapshot.methods = resolveMethods(config.Priorities)
+	}
+	if config != nil {
+		snapshot.namespaces = make(map[string][]PrioritizeMethod, len(config.Namespaces))
+		for namespace, nc := range config.Namespaces {
+			snapshot.namespaces[namespace] = resolveMethods(nc.Priorities)
+		}
+	}
+	return snapshot
+}
+
+// resolveMethods returns the registered methods listed by the priorities, with their options
+func resolveMethods(priorities []priorityConfig) []PrioritizeMethod {
+	var methods []PrioritizeMethod
+	for _, pc := range priorities {
+		if method, ok := registeredMethod(pc.Name); ok {
+			methods = append(methods, pc.apply(method))
+		}
+	}
+	return methods
+}
+
+// methodsFor returns the priority methods active for the pods of the namespace
+func (s *configSnapshot) methodsFor(namespace string) []PrioritizeMethod {
+	if methods, ok := s.namespaces[namespace]; ok {
+		return methods
+	}
+	return s.methods
+}
+
+// method returns the priority method with the given name active for the pods of the namespace
+func (s *configSnapshot) method(namespace, name string) (PrioritizeMethod, bool) {
+	for _, method := range s.methodsFor(namespace) {
+		if method.Name == name {
+			return method, true
+		}
+	}
+	return PrioritizeMethod{}, false
+}
+
+// inactiveMethod stands in for a method left out for the namespace of the pod, it has no opinion about
+// the nodes and gives them the neutral score
+func inactiveMethod(name string) PrioritizeMethod {
+	return PrioritizeMethod{
+		Name: name,
+		Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			list := neutralScores(nodes)
+			return &list, nil
+		},
+	}
+}
+
+// serves reports whether the method is active for the pods of any namespace
+func (s *configSnapshot) serves(name string) bool {
+	if _, ok := s.method("", name); ok {
+		return true
+	}
+	for namespace := range s.namespaces {
+		if _, ok := s.method(namespace, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig reads and validates the config file
+func loadConfig(path string) (*extenderConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config extenderConfig
+	disallowUnknownFields := func(d *json.Decoder) *json.Decoder {
+		d.DisallowUnknownFields()
+		return d
+	}
+	if err := yaml.Unmarshal(content, &config, disallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %v", path, err)
+	}
+	if err := validateConfig(&config); err != nil {
+		return nil, fmt.Errorf("invalid config %v: %v", path, err)
+	}
+	return &config, nil
+}
+
+// validateConfig makes sure the config only refers to registered methods, once, with valid options
+func validateConfig(config *extenderConfig) error {
+	if err := validatePriorities(config.Priorities); err != nil {
+		return err
+	}
+	for namespace, nc := range config.Namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace name %q: %v", namespace, strings.Join(errs, ", "))
+		}
+		if len(nc.Priorities) == 0 {
+			return fmt.Errorf("namespace %q lists no priority method", namespace)
+		}
+		if err := validatePriorities(nc.Priorities); err != nil {
+			return fmt.Errorf("namespace %q: %v", namespace, err)
+		}
+	}
+	for instanceType, price := range config.InstanceTypePrices {
+		if price < 0 {
+			return fmt.Errorf("instance type %q has a negative price", instanceType)
+		}
+	}
+	if config.DefaultInstancePrice < 0 {
+		return fmt.Errorf("the defaultInstancePrice is negative")
+	}
+	for class, limit := range config.PodCountCaps {
+		if limit <= 0 {
+			return fmt.Errorf("workload class %q has a pod count cap of %v, it must be positive", class, limit)
+		}
+	}
+	return nil
+}
+
+// validatePriorities makes sure the priorities only refer to registered methods, once, with valid options
+func validatePriorities(priorities []priorityConfig) error {
+	seen := make(map[string]bool)
+	for _, pc := range priorities {
+		if _, ok := registeredMethod(pc.Name); !ok {
+			return fmt.Errorf("unknown priority method %q", pc.Name)
+		}
+		if seen[pc.Name] {
+			return fmt.Errorf("priority method %q is listed twice", pc.Name)
+		}
+		seen[pc.Name] = true
+		if pc.Weight < 0 || pc.Weight > schedulingapi.MaxWeight {
+			return fmt.Errorf("priority method %q has an invalid weight %v", pc.Name, pc.Weight)
+		}
+	}
+	return nil
+}
+
+// reloadConfig reads the config file again and swaps the active snapshot, the current one is kept when
+// the new config is invalid
+func reloadConfig(path string) error {
+	config, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	activeSnapshot.Store(newSnapshot(config))
+	return nil
+}
+
+// startConfig loads the -config file, if any, and reloads it whenever the process receives SIGHUP.
+// Without a config file all the registered methods are active
+func startConfig() {
+	if configFile == "" {
+		activeSnapshot.Store(newSnapshot(nil))
+		return
+	}
+	path := configFile
+	if err := reloadConfig(path); err != nil {
+		fatal(err)
+	}
+	glog.V(0).Infof("loaded config %v\n", path)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloadConfig(path); err != nil {
+				glog.Errorf("keeping the current config, reload failed: %v", err)
+				continue
+			}
+			glog.V(0).Infof("reloaded config %v\n", path)
+		}
+	}()
+}
@@ -0,0 +1,322 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withConfigFile writes the content to the -config file until the end of the test
+func withConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saved := configFile
+	configFile = path
+	t.Cleanup(func() { configFile = saved })
+	return path
+}
+
+// rewriteConfig replaces the config file at once, as a ConfigMap update does, so a concurrent reload
+// never reads a truncated file
+func rewriteConfig(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path+".new", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7))
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"empty", "", ""},
+		{"priorities", "priorities:\n- name: a\n  weight: 2\n- name: b\n  invert: true\n", ""},
+		{"namespaces", "namespaces:\n  team-a:\n    priorities:\n    - name: b\n", ""},
+		{"malformed", "priorities: [", "failed to parse"},
+		{"unknown field", "priorities:\n- name: a\n  wieght: 2\n", "failed to parse"},
+		{"unknown method", "priorities:\n- name: c\n", `unknown priority method "c"`},
+		{"listed twice", "priorities:\n- name: a\n- name: a\n", `"a" is listed twice`},
+		{"negative weight", "priorities:\n- name: a\n  weight: -1\n", "invalid weight -1"},
+		{"weight too large", "priorities:\n- name: a\n  weight: 1000000000000000000\n", "invalid weight"},
+		{"invalid namespace", "namespaces:\n  Team_A:\n    priorities:\n    - name: a\n", `invalid namespace name "Team_A"`},
+		{"namespace without priorities", "namespaces:\n  team-a: {}\n", `"team-a" lists no priority method`},
+		{"namespace unknown method", "namespaces:\n  team-a:\n    priorities:\n    - name: c\n", `namespace "team-a": unknown priority method`},
+		{"negative price", "instanceTypePrices:\n  m5.large: -1\n", `"m5.large" has a negative price`},
+		{"negative default price", "defaultInstancePrice: -0.1\n", "defaultInstancePrice is negative"},
+		{"zero pod count cap", "podCountCaps:\n  batch: 0\n", `"batch" has a pod count cap of 0`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := withConfigFile(t, test.content)
+			_, err := loadConfig(path)
+			if test.err == "" && err != nil {
+				t.Errorf("rejected the config: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("returned %v, expected an error with %q", err, test.err)
+			}
+		})
+	}
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("loaded a missing config file")
+	}
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7))
+	snapshot := newSnapshot(&extenderConfig{
+		Priorities: []priorityConfig{{Name: "a", Weight: 2}},
+		Namespaces: map[string]namespaceConfig{"team-a": {Priorities: []priorityConfig{{Name: "b", Invert: true}}}},
+	})
+	tests := []struct {
+		namespace, name string
+		weight          int
+		invert, active  bool
+	}{
+		{"default", "a", 2, false, true},
+		{"default", "b", 0, false, false},
+		{"team-a", "a", 0, false, false},
+		{"team-a", "b", 1, true, true},
+	}
+	for _, test := range tests {
+		method, ok := snapshot.method(test.namespace, test.name)
+		if ok != test.active || method.Weight != test.weight || method.Invert != test.invert {
+			t.Errorf("%v in %v: active %v with weight %v and invert %v", test.name, test.namespace, ok, method.Weight, method.Invert)
+		}
+	}
+	if !snapshot.serves("a") || !snapshot.serves("b") {
+		t.Errorf("the snapshot does not serve every configured method")
+	}
+	if all := newSnapshot(nil); len(all.methods) != 2 || all.serves("c") {
+		t.Errorf("without config the snapshot has %v methods", len(all.methods))
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7))
+	path := withConfigFile(t, "priorities:\n- name: a\n")
+	if err := reloadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	before := currentSnapshot()
+	if !before.serves("a") || before.serves("b") {
+		t.Fatalf("the loaded config is not active")
+	}
+	rewriteConfig(t, path, "priorities:\n- name: c\n")
+	if err := reloadConfig(path); err == nil {
+		t.Errorf("reloaded an invalid config")
+	}
+	if currentSnapshot() != before {
+		t.Errorf("an invalid config replaced the active one")
+	}
+	rewriteConfig(t, path, "priorities:\n- name: b\n  weight: 4\n")
+	if err := reloadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if method, ok := currentSnapshot().method("", "b"); !ok || method.Weight != 4 || currentSnapshot().serves("a") {
+		t.Errorf("the reloaded config is not active")
+	}
+	if !before.serves("a") || before.serves("b") {
+		t.Errorf("the reload changed the previous snapshot")
+	}
+}
+
+func TestConfigSIGHUP(t *testing.T) {
+	newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7))
+	path := withConfigFile(t, "priorities:\n- name: a\n")
+	startConfig()
+	// waitFor polls the active snapshot, the reload happens asynchronously
+	waitFor := func(name string) bool {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if currentSnapshot().serves(name) {
+				return true
+			}
+		}
+		return false
+	}
+	if !waitFor("a") {
+		t.Fatalf("the config is not active")
+	}
+	rewriteConfig(t, path, "priorities: [")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	rewriteConfig(t, path, "priorities:\n- name: b\n")
+	// the invalid config was either rejected or not read yet, a is served until the next valid reload
+	if !currentSnapshot().serves("a") && !currentSnapshot().serves("b") {
+		t.Errorf("the invalid config disrupted the active one")
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	if !waitFor("b") || currentSnapshot().serves("a") {
+		t.Errorf("SIGHUP did not reload the config")
+	}
+}
+
+// slowPriority gives every node the score, slowly enough for reloads to happen during the request
+func slowPriority(name string, score int) PrioritizeMethod {
+	return PrioritizeMethod{
+		Name: name,
+		Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+			return func(pod v1.Pod, node v1.Node) (int, error) {
+				time.Sleep(50 * time.Microsecond)
+				return score, nil
+			}
+		},
+	}
+}
+
+func TestReloadDuringRequests(t *testing.T) {
+	router := newTestRouter(t, slowPriority("low", 2), slowPriority("high", 8))
+	AddCombinedRoute(router)
+	// each config gives a distinct combined score, a request mixing two configs would get another one
+	configs := map[string]int{
+		"priorities:\n- name: low\n- name: high\n":              5,
+		"priorities:\n- name: low\n  weight: 3\n- name: high\n": 3,
+		"priorities:\n- name: high\n":                           8,
+		"priorities:\n- name: low\n":                            2,
+	}
+	var contents []string
+	for content := range configs {
+		contents = append(contents, content)
+	}
+	path := withConfigFile(t, contents[0])
+	if err := reloadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := testPod("default", "p", nil)
+	body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: numberedNodes(20)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	reloaded := make(chan int)
+	go func() {
+		reloads := 0
+		defer func() { reloaded <- reloads }()
+		for i := 1; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			rewriteConfig(t, path, contents[i%len(contents)])
+			if err := reloadConfig(path); err != nil {
+				t.Error(err)
+				return
+			}
+			reloads++
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix, bytes.NewReader(body)))
+				var list schedulingapi.HostPriorityList
+				if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+					t.Errorf("answered %v: %v", w.Code, w.Body.String())
+					return
+				}
+				consistent := false
+				for _, score := range configs {
+					consistent = consistent || list[0].Score == score
+				}
+				for _, hp := range list {
+					if !consistent || hp.Score != list[0].Score {
+						t.Errorf("scored %v, not the scores of a single config", list)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	if reloads := <-reloaded; reloads == 0 {
+		t.Errorf("the config was not reloaded during the requests")
+	}
+}
+
+func TestNamespacePriorities(t *testing.T) {
+	withNeutralScore(t, 5)
+	router := newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 9))
+	AddCombinedRoute(router)
+	withConfig(t, &extenderConfig{
+		Priorities: []priorityConfig{{Name: "a"}, {Name: "b"}},
+		Namespaces: map[string]namespaceConfig{
+			"team-a": {Priorities: []priorityConfig{{Name: "b"}}},
+			"team-b": {Priorities: []priorityConfig{{Name: "a", Weight: 3}, {Name: "b"}}},
+		},
+	})
+	nodes := testNodes("n1", "n2")
+	tests := []struct {
+		namespace string
+		path      string
+		score     int
+	}{
+		{"default", "", 6},
+		{"team-a", "", 9},
+		{"team-b", "", 4},
+		{"default", "/a", 3},
+		// a is left out for team-a, it has no opinion about its pods
+		{"team-a", "/a", 5},
+		{"team-a", "/b", 9},
+	}
+	for _, test := range tests {
+		pod := testPod(test.namespace, "p", nil)
+		body, err := json.Marshal(extenderArgsOf(pod, nodes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+test.path, bytes.NewReader(body)))
+		var list schedulingapi.HostPriorityList
+		if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("%v%v answered %v: %v", test.namespace, test.path, w.Code, w.Body.String())
+		}
+		for _, hp := range list {
+			if hp.Score != test.score {
+				t.Errorf("a pod of %v scored %v on %v, expected %v", test.namespace, list, prioritiesPrefix+test.path, test.score)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var cycleTTL time.Duration
+
+func init() {
+	flag.DurationVar(&cycleTTL, "cycle-ttl", 30*time.Second, "How long the data derived for a pod is shared between the filter and prioritize calls of its scheduling cycle")
+}
+
+// podCycle is the data the filter and prioritize calls of a pod share within a scheduling cycle: the
+// data derived from the pod, computed once for every method, and the nodes the extender filters rejected
+type podCycle struct {
+	qosClass v1.PodQOSClass
+	requests map[v1.ResourceName]int64
+	created  time.Time
+
+	lock   sync.Mutex
+	failed map[string]string
+}
+
+func newPodCycle(pod v1.Pod, now time.Time) *podCycle {
+	cycle := &podCycle{
+		qosClass: podQOSClass(pod),
+		requests: make(map[v1.ResourceName]int64),
+		created:  now,
+		failed:   make(map[string]string),
+	}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage} {
+		cycle.requests[name] = podRequest(pod, name)
+	}
+	return cycle
+}
+
+// request returns the pod request of the resource, for cpu, memory and ephemeral storage
+func (c *podCycle) request(name v1.ResourceName) int64 {
+	return c.requests[name]
+}
+
+// reject records the nodes a filter rejected among the candidates of its call. Within a scheduling cycle
+// the scheduler never sends again a node an extender filter rejected, so candidates holding a rejected
+// node mean the pod is being scheduled anew: the rejections of the previous attempt are dropped, the nodes
+// may have become feasible since
+func (c *podCycle) reject(candidates []v1.Node, failed schedulingapi.FailedNodesMap) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, node := range candidates {
+		if _, ok := c.failed[normalizeNodeName(node.Name)]; ok {
+			c.failed = make(map[string]string)
+			break
+		}
+	}
+	for node, reason := range failed {
+		c.failed[normalizeNodeName(node)] = reason
+	}
+}
+
+// rejected returns why a filter rejected the node, if it did
+func (c *podCycle) rejected(node string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	reason, ok := c.failed[normalizeNodeName(node)]
+	return reason, ok
+}
+
+// cycleCache keeps the cycles of the pods by UID for -cycle-ttl
+type cycleCache struct {
+	lock   sync.Mutex
+	cycles map[types.UID]*podCycle
+	stats  *cacheStats
+}
+
+// podCycles is the cycle cache shared by the requests
+var podCycles = &cycleCache{cycles: make(map[types.UID]*podCycle), stats: registerCacheStats("pod_cycles")}
+
+// get returns the cycle of the pod, started by the first call about the pod within the TTL. A pod
+// without UID gets a cycle of its own at each call
+func (c *cycleCache) get(pod v1.Pod, now time.Time) *podCycle {
+	if pod.UID == "" {
+		return newPodCycle(pod, now)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for uid, cycle := range c.cycles {
+		if now.Sub(cycle.created) > cycleTTL {
+			delete(c.cycles, uid)
+		}
+	}
+	cycle, ok := c.cycles[pod.UID]
+	c.stats.record(ok)
+	if !ok {
+		cycle = newPodCycle(pod, now)
+		c.cycles[pod.UID] = cycle
+	}
+	return cycle
+}
+
+// flush empties the cache and returns the number of entries dropped
+func (c *cycleCache) flush() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	count := len(c.cycles)
+	c.cycles = make(map[types.UID]*podCycle)
+	return count
+}
@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+func TestPodCycleReject(t *testing.T) {
+	cycle := newPodCycle(testPod("default", "p", nil), time.Now())
+	cycle.reject(testNodes("a", "b", "c"), schedulingapi.FailedNodesMap{"a": "no daemon"})
+	// a second filter of the same cycle only sees the nodes the first one let through
+	cycle.reject(testNodes("b", "c"), schedulingapi.FailedNodesMap{"b": "host port"})
+	for node, expected := range map[string]bool{"a": true, "b": true, "c": false} {
+		if _, rejected := cycle.rejected(node); rejected != expected {
+			t.Errorf("node %v rejected = %v within the cycle, expected %v", node, rejected, expected)
+		}
+	}
+	// the next attempt sends a rejected node again, the previous rejections no longer apply
+	cycle.reject(testNodes("a", "b", "c"), schedulingapi.FailedNodesMap{"c": "no daemon"})
+	for node, expected := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, rejected := cycle.rejected(node); rejected != expected {
+			t.Errorf("node %v rejected = %v in the next attempt, expected %v", node, rejected, expected)
+		}
+	}
+}
+
+func TestPodCycleRejectionsAcrossAttempts(t *testing.T) {
+	defer podCycles.flush()
+	rejecting := map[string]bool{"b": true}
+	filterMethod := FilterMethod{
+		Name: "test_rejecting",
+		Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			return !rejecting[node.Name], "rejected by the test", nil
+		},
+	}
+	priorityMethod := PrioritizeMethod{
+		Name: "test_constant",
+		Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			return scoreNodes("test_constant", pod, nodes, func(pod v1.Pod, node v1.Node) (int, error) {
+				return 8, nil
+			})
+		},
+	}
+	pod := testPod("default", "p", nil)
+	pod.UID = types.UID("uid-p")
+	nodes := testNodes("a", "b", "c")
+
+	// the scheduler may still send the rejected node to prioritize, e.g. from another extender path
+	filterNodes(t, filterMethod, pod, nodes)
+	list, err := runPriority(context.Background(), priorityMethod, extenderArgsOf(pod, nodes), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScores(t, list, map[string]int{"a": 8, "b": UnfitScore, "c": 8})
+
+	// b became feasible: the filter of the next attempt lets it through and its score is not vetoed
+	delete(rejecting, "b")
+	filterNodes(t, filterMethod, pod, nodes)
+	list, err = runPriority(context.Background(), priorityMethod, extenderArgsOf(pod, nodes), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScores(t, list, map[string]int{"a": 8, "b": 8, "c": 8})
+}
+
+func TestCycleCacheExpiry(t *testing.T) {
+	defer podCycles.flush()
+	pod := testPod("default", "p", nil)
+	pod.UID = types.UID("uid-expiry")
+	now := time.Now()
+	first := podCycles.get(pod, now)
+	if podCycles.get(pod, now.Add(cycleTTL/2)) != first {
+		t.Error("expected the cycle to be shared within the TTL")
+	}
+	if podCycles.get(pod, now.Add(2*cycleTTL)) == first {
+		t.Error("expected a new cycle after the TTL")
+	}
+	pod.UID = ""
+	if podCycles.get(pod, now) == podCycles.get(pod, now) {
+		t.Error("expected a pod without UID to get a cycle per call")
+	}
+}
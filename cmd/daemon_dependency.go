@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	daemonDependencyModeFilter   = "filter"
+	daemonDependencyModePriority = "priority"
+)
+
+var daemonSelector, daemonDependencyMode string
+
+// daemonLabelSelector is the parsed -daemon-selector, nil when the dependency is not configured
+var daemonLabelSelector labels.Selector
+
+func init() {
+	flag.StringVar(&daemonSelector, "daemon-selector", "", "The label selector of the node-local daemon pods the workloads depend on, e.g. app=csi-node, empty disables daemon_dependency")
+	flag.StringVar(&daemonDependencyMode, "daemon-dependency-mode", daemonDependencyModePriority, "How daemon_dependency treats nodes without a healthy daemon, one of: filter, priority")
+}
+
+// parseDaemonSelector validates the daemon dependency flags and parses the selector
+func parseDaemonSelector() error {
+	switch daemonDependencyMode {
+	case daemonDependencyModeFilter, daemonDependencyModePriority:
+	default:
+		return fmt.Errorf("unknown -daemon-dependency-mode %q, expecting one of: %v, %v", daemonDependencyMode, daemonDependencyModeFilter, daemonDependencyModePriority)
+	}
+	daemonLabelSelector = nil
+	if daemonSelector == "" {
+		return nil
+	}
+	selector, err := labels.Parse(daemonSelector)
+	if err != nil {
+		return fmt.Errorf("invalid -daemon-selector %q: %v", daemonSelector, err)
+	}
+	daemonLabelSelector = selector
+	return nil
+}
+
+// DaemonDependencyPriority favors the nodes running a healthy daemon pod matching -daemon-selector,
+// served when -daemon-dependency-mode is priority. Every node gets the neutral score without a selector
+var DaemonDependencyPriority = PrioritizeMethod{
+	Name:              "daemon_dependency",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		healthy := healthyDaemonNodes(podLister)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			switch {
+			case daemonLabelSelector == nil:
+				return neutralScore, nil
+			case healthy[normalizeNodeName(node.Name)]:
+				return schedulingapi.MaxPriority, nil
+			}
+			return 0, nil
+		}
+	},
+}
+
+// DaemonDependencyFilter rejects the nodes not running a healthy daemon pod matching -daemon-selector,
+// served when -daemon-dependency-mode is filter. Every node passes without a selector
+var DaemonDependencyFilter = FilterMethod{
+	Name:              "daemon_dependency",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod) NodeFilter {
+		healthy := healthyDaemonNodes(podLister)
+		return func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			if daemonLabelSelector == nil || healthy[normalizeNodeName(node.Name)] {
+				return true, "", nil
+			}
+			return false, fmt.Sprintf("node runs no healthy daemon pod matching %v", daemonSelector), nil
+		}
+	},
+}
+
+// healthyDaemonNodes returns the normalized names of the nodes running a ready daemon pod
+func healthyDaemonNodes(lister PodLister) map[string]bool {
+	healthy := make(map[string]bool)
+	if daemonLabelSelector == nil {
+		return healthy
+	}
+	for _, pod := range lister.List() {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != v1.PodRunning || !daemonLabelSelector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if ready, _ := podReadySince(pod); ready {
+			healthy[normalizeNodeName(pod.Spec.NodeName)] = true
+		}
+	}
+	return healthy
+}
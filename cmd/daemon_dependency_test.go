@@ -0,0 +1,149 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// daemonPod returns a daemon pod of the node, running and ready as asked
+func daemonPod(node string, running, ready bool) v1.Pod {
+	pod := testPod("kube-system", "csi-"+node, map[string]string{"app": "csi-node"})
+	pod.Spec.NodeName = node
+	if running {
+		pod.Status.Phase = v1.PodRunning
+	}
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: status}}
+	return pod
+}
+
+// withDaemonSelector sets -daemon-selector until the end of the test
+func withDaemonSelector(t *testing.T, selector string) {
+	savedSelector, savedParsed := daemonSelector, daemonLabelSelector
+	t.Cleanup(func() { daemonSelector, daemonLabelSelector = savedSelector, savedParsed })
+	daemonSelector = selector
+	if err := parseDaemonSelector(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDaemonDependency(t *testing.T) {
+	pods := []v1.Pod{
+		daemonPod("healthy", true, true),
+		daemonPod("unready", true, false),
+		daemonPod("pending", false, true),
+		testPod("default", "web", map[string]string{"app": "web"}),
+	}
+	pods[3].Spec.NodeName = "other"
+	pods[3].Status.Phase = v1.PodRunning
+	withPods(t, pods...)
+	nodes := testNodes("healthy", "unready", "pending", "other", "empty")
+
+	tests := []struct {
+		selector string
+		scores   map[string]int
+		passed   []string
+	}{
+		{"app=csi-node", map[string]int{"healthy": 10, "unready": 0, "pending": 0, "other": 0, "empty": 0}, []string{"healthy"}},
+		{"", map[string]int{"healthy": neutralScore, "unready": neutralScore, "pending": neutralScore, "other": neutralScore, "empty": neutralScore}, []string{"healthy", "unready", "pending", "other", "empty"}},
+	}
+	for _, test := range tests {
+		withDaemonSelector(t, test.selector)
+		checkScores(t, scoreMethod(t, DaemonDependencyPriority, testPod("default", "p", nil), nodes), test.scores)
+		_, result := filterNodes(t, DaemonDependencyFilter, testPod("default", "p", nil), nodes)
+		if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+			t.Errorf("selector %q: expected %v to pass, got %v", test.selector, test.passed, passed)
+		}
+		if len(result.FailedNodes)+len(test.passed) != len(nodes) {
+			t.Errorf("selector %q: expected every rejected node to have a reason, got %v", test.selector, result.FailedNodes)
+		}
+	}
+}
+
+func TestDaemonDependencyFilterWarmup(t *testing.T) {
+	withDaemonSelector(t, "app=csi-node")
+	saved, savedMode := podLister, warmupMode
+	defer func() { podLister, warmupMode = saved, savedMode }()
+	nodes := testNodes("a", "b")
+
+	for _, lister := range []PodLister{nil, &testPodLister{unsynced: true}} {
+		podLister = lister
+		warmupMode = warmupModeNeutral
+		w, result := filterNodes(t, DaemonDependencyFilter, testPod("default", "p", nil), nodes)
+		if w.Code != http.StatusOK || !reflect.DeepEqual(passedNodes(result), []string{"a", "b"}) || len(result.FailedNodes) != 0 {
+			t.Errorf("expected every node to pass while warming up, got %v %v", w.Code, w.Body.String())
+		}
+		warmupMode = warmupModeUnavailable
+		w, _ = filterNodes(t, DaemonDependencyFilter, testPod("default", "p", nil), nodes)
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+			t.Errorf("expected a 503 with Retry-After while warming up, got %v %v", w.Code, w.Header())
+		}
+	}
+}
+
+func TestParseDaemonSelector(t *testing.T) {
+	saved, savedMode, savedParsed := daemonSelector, daemonDependencyMode, daemonLabelSelector
+	defer func() { daemonSelector, daemonDependencyMode, daemonLabelSelector = saved, savedMode, savedParsed }()
+	tests := []struct {
+		selector, mode string
+		valid          bool
+		parsed         labels.Selector
+	}{
+		{"app=csi-node", daemonDependencyModeFilter, true, labels.SelectorFromSet(labels.Set{"app": "csi-node"})},
+		{"", daemonDependencyModePriority, true, nil},
+		{"app in (", daemonDependencyModePriority, false, nil},
+		{"app=csi-node", "strict", false, nil},
+	}
+	for _, test := range tests {
+		daemonSelector, daemonDependencyMode, daemonLabelSelector = test.selector, test.mode, nil
+		err := parseDaemonSelector()
+		if (err == nil) != test.valid {
+			t.Errorf("parseDaemonSelector(%q, %q) returned %v", test.selector, test.mode, err)
+		}
+		if test.valid && (daemonLabelSelector == nil) != (test.parsed == nil) {
+			t.Errorf("parseDaemonSelector(%q) parsed %v", test.selector, daemonLabelSelector)
+		}
+	}
+}
+
+// BenchmarkDaemonDependencyFilter checks the pods are indexed once per request, not once per node
+func BenchmarkDaemonDependencyFilter(b *testing.B) {
+	saved, savedSelector, savedParsed := podLister, daemonSelector, daemonLabelSelector
+	defer func() { podLister, daemonSelector, daemonLabelSelector = saved, savedSelector, savedParsed }()
+	daemonSelector = "app=csi-node"
+	parseDaemonSelector()
+	var pods []v1.Pod
+	var names []string
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		names = append(names, name)
+		pods = append(pods, daemonPod(name, true, true))
+	}
+	podLister = &testPodLister{pods: pods}
+	nodes := testNodes(names...)
+	pod := testPod("default", "p", nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DaemonDependencyFilter.Handler(extenderArgsOf(pod, nodes))
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// requestDeadlineHeader carries the deadline of the scheduling cycle, as an RFC3339 time or as the
+// milliseconds left, e.g. X-Request-Deadline: 2019-05-01T10:00:00.250Z or X-Request-Deadline: 250
+const requestDeadlineHeader = "X-Request-Deadline"
+
+// parseRequestDeadline parses the X-Request-Deadline value, relative milliseconds being counted from now
+func parseRequestDeadline(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return now.Add(time.Duration(ms) * time.Millisecond), true
+	}
+	if deadline, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return deadline, true
+	}
+	return time.Time{}, false
+}
+
+// requestContext returns the context the methods score the request in: the request one, bounded by the
+// X-Request-Deadline header when the scheduler sends it. A malformed header is ignored
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	value := r.Header.Get(requestDeadlineHeader)
+	if value == "" {
+		return context.WithCancel(r.Context())
+	}
+	deadline, ok := parseRequestDeadline(value, time.Now())
+	if !ok {
+		glog.Warningf("ignoring the malformed %v header %q from %v", requestDeadlineHeader, value, r.RemoteAddr)
+		return context.WithCancel(r.Context())
+	}
+	return context.WithDeadline(r.Context(), deadline)
+}
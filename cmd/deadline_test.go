@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+func TestParseRequestDeadline(t *testing.T) {
+	now := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{"250", now.Add(250 * time.Millisecond), true},
+		{" 250 ", now.Add(250 * time.Millisecond), true},
+		{"0", now, true},
+		{"-5", now.Add(-5 * time.Millisecond), true},
+		{"2019-05-01T10:00:00.250Z", now.Add(250 * time.Millisecond), true},
+		{"2019-05-01T12:00:01+02:00", now.Add(time.Second), true},
+		{"", time.Time{}, false},
+		{"250ms", time.Time{}, false},
+		{"2019-05-01 10:00:00", time.Time{}, false},
+	} {
+		deadline, ok := parseRequestDeadline(test.value, now)
+		if ok != test.valid || !deadline.Equal(test.expected) {
+			t.Errorf("%q parsed as %v (valid %v), expected %v (valid %v)", test.value, deadline, ok, test.expected, test.valid)
+		}
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		header   string
+		deadline bool
+	}{
+		{"no header", "", false},
+		{"malformed header", "soon", false},
+		{"milliseconds left", "250", true},
+		{"rfc3339 time", time.Now().Add(time.Minute).Format(time.RFC3339Nano), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if test.header != "" {
+				r.Header.Set(requestDeadlineHeader, test.header)
+			}
+			ctx, cancel := requestContext(r)
+			defer cancel()
+			if _, deadline := ctx.Deadline(); deadline != test.deadline {
+				t.Errorf("context deadline %v, expected %v", deadline, test.deadline)
+			}
+		})
+	}
+}
+
+// prioritizeWithDeadline posts the pod and the nodes to the route with the X-Request-Deadline header,
+// none when empty
+func prioritizeWithDeadline(t *testing.T, router http.Handler, path, deadline string, nodes []v1.Node) *httptest.ResponseRecorder {
+	t.Helper()
+	pod := testPod("default", "p", nil)
+	body, err := json.Marshal(extenderArgsOf(pod, nodes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+path, bytes.NewReader(body))
+	if deadline != "" {
+		r.Header.Set(requestDeadlineHeader, deadline)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestRequestDeadlineRoutes(t *testing.T) {
+	withWarnings(t, true, 0, time.Second)
+	router := newTestRouter(t, blockedPriority(t, "slow", time.Minute, 9), blockedPriority(t, "slower", time.Minute, 9), constantPriority("fast", 1, 9))
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b")
+	// once the deadline is past, the later methods of a combined request, even the fast one, answer
+	// neutral scores right away
+	for _, test := range []struct {
+		name     string
+		path     string
+		deadline string
+		score    int
+		warnings string
+	}{
+		{"deadline ahead", "/slow", "50", neutralScore, "1"},
+		{"deadline past", "/slow", "-5", neutralScore, "1"},
+		{"past rfc3339 time", "/slow", time.Now().Add(-time.Second).Format(time.RFC3339Nano), neutralScore, "1"},
+		{"combined past its deadline", "", "50", neutralScore, "3"},
+		{"no header", "/fast", "", 9, ""},
+		{"malformed header", "/fast", "soon", 9, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			w := prioritizeWithDeadline(t, router, test.path, test.deadline, nodes)
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("the request waited %v past its deadline", elapsed)
+			}
+			var list schedulingapi.HostPriorityList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatalf("answered an invalid list %q: %v", w.Body.String(), err)
+			}
+			checkScores(t, list, map[string]int{"a": test.score, "b": test.score})
+			if header := w.Header().Get(extenderWarningsHeader); header != test.warnings {
+				t.Errorf("%v header %q, expected %q", extenderWarningsHeader, header, test.warnings)
+			}
+		})
+	}
+}
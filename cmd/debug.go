@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/url"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+)
+
+var enableDebug bool
+
+// sensitiveFlags are the flags whose value is never dumped, a flag taking a credential must be added here
+var sensitiveFlags = map[string]bool{
+	"auth-token-file": true,
+}
+
+// urlFlags are the flags holding a URL, dumped without their user info and query which may carry credentials
+var urlFlags = map[string]bool{
+	"kube-api-server":   true,
+	"node-health-url":   true,
+	"panic-webhook-url": true,
+}
+
+func init() {
+	flag.BoolVar(&enableDebug, "enable-debug", false, "Expose the /debug endpoints, e.g. /debug/config, and /simulate")
+}
+
+// debugConfig is the resolved configuration returned by /debug/config
+type debugConfig struct {
+	Flags      map[string]string `json:"flags"`
+	File       *extenderConfig   `json:"file,omitempty"`
+	Priorities []priorityInfo    `json:"priorities"`
+}
+
+// redactFlag hides the value of flags that may hold credentials
+func redactFlag(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case sensitiveFlags[name]:
+		return "<redacted>"
+	case urlFlags[name]:
+		u, err := url.Parse(value)
+		if err != nil {
+			return "<redacted>"
+		}
+		u.User, u.RawQuery, u.Fragment = nil, "", ""
+		return u.String()
+	}
+	return value
+}
+
+// resolvedConfig collects the values of all the flags, after normalization, the config file and the active priorities
+func resolvedConfig() debugConfig {
+	config := debugConfig{
+		Flags:      make(map[string]string),
+		File:       currentConfig(),
+		Priorities: listPriorities(),
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		config.Flags[f.Name] = redactFlag(f.Name, f.Value.String())
+	})
+	return config
+}
+
+// DebugConfigRoute returns an http handle dumping the resolved configuration as JSON
+func DebugConfigRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	resultBody, err := json.Marshal(resolvedConfig())
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
+
+// cacheFlushers empty the internal caches, returning the number of entries dropped
+var cacheFlushers = map[string]func() int{
+	"owner_placements":       ownerPlacements.flush,
+	"recent_recommendations": recentRecommendations.flush,
+	"image_inventory":        nodeImageInventory.flush,
+	"pod_cycles":             podCycles.flush,
+	"node_agent":             nodeAgents.flush,
+}
+
+// DebugCacheFlushRoute empties the internal caches so the next requests recompute from fresh data, it
+// returns the number of entries dropped per cache
+func DebugCacheFlushRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flushed := make(map[string]int, len(cacheFlushers))
+	for name, flush := range cacheFlushers {
+		flushed[name] = flush()
+	}
+	glog.V(0).Infof("internal caches flushed: %v\n", flushed)
+	resultBody, err := json.Marshal(flushed)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
+
+// AddDebugRoutes adding the debug routes to the router when they are enabled
+func AddDebugRoutes(router *httprouter.Router) {
+	if !enableDebug {
+		return
+	}
+	router.GET("/debug/config", requireAuth(gzipped(DebugConfigRoute)))
+	router.GET("/debug/explain", requireAuth(gzipped(DebugExplainRoute)))
+	router.GET("/debug/explain/:uid", requireAuth(gzipped(DebugExplainRoute)))
+	router.GET("/debug/stream", requireAuth(DebugStreamRoute))
+	router.POST("/debug/cache/flush", requireAuth(DebugCacheFlushRoute))
+	router.POST("/simulate", requireAuth(SimulateRoute))
+	glog.V(2).Infof("added debug routes under /debug and /simulate\n")
+}
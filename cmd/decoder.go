@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// argsDecoder decodes the ExtenderArgs sent by a version of the scheduler into the form the methods
+// score: the pod and the node objects in ExtenderArgs.Nodes
+type argsDecoder func(body io.Reader) (schedulingapi.ExtenderArgs, error)
+
+// argsDecoders are the decoders selectable with -extender-api-version
+var argsDecoders = map[string]argsDecoder{
+	"v1":           decodeNodeObjectsArgs,
+	"v1-nodecache": decodeNodeNamesArgs,
+}
+
+var extenderAPIVersion string
+
+func init() {
+	flag.StringVar(&extenderAPIVersion, "extender-api-version", "v1", fmt.Sprintf("The ExtenderArgs payload sent by the scheduler, one of: %v. v1 carries the node objects, v1-nodecache the node names only, for nodeCacheCapable extenders, resolved through the informers", decoderNames()))
+}
+
+func decoderNames() []string {
+	names := make([]string, 0, len(argsDecoders))
+	for name := range argsDecoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateExtenderAPIVersion makes sure -extender-api-version names a decoder
+func validateExtenderAPIVersion() error {
+	if _, ok := argsDecoders[extenderAPIVersion]; !ok {
+		return fmt.Errorf("unknown -extender-api-version %q, expecting one of: %v", extenderAPIVersion, decoderNames())
+	}
+	return nil
+}
+
+// decodeNodeObjectsArgs decodes a payload carrying the node objects, it is already in the canonical form
+func decodeNodeObjectsArgs(body io.Reader) (schedulingapi.ExtenderArgs, error) {
+	var extenderArgs schedulingapi.ExtenderArgs
+	if err := json.NewDecoder(body).Decode(&extenderArgs); err != nil {
+		return extenderArgs, newError(ErrBadRequest, "failed to decode the ExtenderArgs: %v", err)
+	}
+	return extenderArgs, nil
+}
+
+// nodeMetadataArgs is the lighter form of the ExtenderArgs decoded for the NodeNamesOnly methods, the
+// spec and most of the status of the nodes, their bulk with the images and conditions, are skipped.
+// The allocatable resources are kept for the most-allocatable node sampling, which runs before the method
+type nodeMetadataArgs struct {
+	Pod   *v1.Pod
+	Nodes *struct {
+		Items []struct {
+			ObjectMeta metav1.ObjectMeta `json:"metadata"`
+			Status     struct {
+				Allocatable v1.ResourceList `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	NodeNames *[]string
+}
+
+// decodeNodeMetadataArgs decodes a payload carrying the node objects, keeping only their metadata and
+// allocatable resources
+func decodeNodeMetadataArgs(body io.Reader) (schedulingapi.ExtenderArgs, error) {
+	var light nodeMetadataArgs
+	if err := json.NewDecoder(body).Decode(&light); err != nil {
+		return schedulingapi.ExtenderArgs{}, newError(ErrBadRequest, "failed to decode the ExtenderArgs: %v", err)
+	}
+	extenderArgs := schedulingapi.ExtenderArgs{Pod: light.Pod, NodeNames: light.NodeNames}
+	if light.Nodes != nil {
+		extenderArgs.Nodes = &v1.NodeList{Items: make([]v1.Node, len(light.Nodes.Items))}
+		for i, item := range light.Nodes.Items {
+			extenderArgs.Nodes.Items[i].ObjectMeta = item.ObjectMeta
+			extenderArgs.Nodes.Items[i].Status.Allocatable = item.Status.Allocatable
+		}
+	}
+	return extenderArgs, nil
+}
+
+// argsDecoderFor returns the decoder of the requests of the method, the lighter one for the NodeNamesOnly
+// methods when the scheduler sends the node objects
+func argsDecoderFor(priorityMethod PrioritizeMethod) argsDecoder {
+	if priorityMethod.NodeNamesOnly && extenderAPIVersion == "v1" {
+		return decodeNodeMetadataArgs
+	}
+	return argsDecoders[extenderAPIVersion]
+}
+
+// decodeNodeNamesArgs decodes a payload carrying the node names, the nodes are looked up in the node
+// informer, the unknown ones, or all of them without -enable-informers, are left with their name only.
+// The node names are kept so the filters can tell they can't answer such a scheduler
+func decodeNodeNamesArgs(body io.Reader) (schedulingapi.ExtenderArgs, error) {
+	extenderArgs, err := decodeNodeObjectsArgs(body)
+	if err != nil || extenderArgs.NodeNames == nil || extenderArgs.Nodes != nil {
+		return extenderArgs, err
+	}
+	known := make(map[string]v1.Node)
+	for _, node := range nodeLister.List() {
+		known[node.Name] = node
+	}
+	nodes := &v1.NodeList{Items: make([]v1.Node, len(*extenderArgs.NodeNames))}
+	for i, name := range *extenderArgs.NodeNames {
+		node, ok := known[name]
+		if !ok {
+			node = v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		}
+		nodes.Items[i] = node
+	}
+	extenderArgs.Nodes = nodes
+	return extenderArgs, nil
+}
@@ -0,0 +1,281 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// heavyNodes returns n nodes with the labels, images and conditions of real nodes, and n cpus
+// allocatable on the node n
+func heavyNodes(n int) []v1.Node {
+	nodes := make([]v1.Node, n)
+	for i := range nodes {
+		node := &nodes[i]
+		node.Name = fmt.Sprintf("node-%v", i)
+		node.Labels = map[string]string{"kubernetes.io/hostname": node.Name, "zone": fmt.Sprint(i % 3)}
+		node.Status.Allocatable = v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewQuantity(int64(i), resource.DecimalSI),
+			v1.ResourceMemory: resource.MustParse("16Gi"),
+		}
+		for j := 0; j < 20; j++ {
+			node.Status.Images = append(node.Status.Images, v1.ContainerImage{
+				Names:     []string{fmt.Sprintf("registry.example.com/team/image-%v@sha256:%064x", j, j), fmt.Sprintf("registry.example.com/team/image-%v:v%v", j, j)},
+				SizeBytes: int64(j) << 20,
+			})
+		}
+		for _, condition := range []v1.NodeConditionType{v1.NodeReady, v1.NodeMemoryPressure, v1.NodeDiskPressure, v1.NodePIDPressure} {
+			node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: condition, Status: v1.ConditionFalse, Reason: "KubeletHasSufficientResources", Message: "kubelet has sufficient resources available"})
+		}
+	}
+	return nodes
+}
+
+// argsBody encodes the ExtenderArgs of the pod and the nodes
+func argsBody(t testing.TB, pod v1.Pod, nodes []v1.Node) []byte {
+	body, err := json.Marshal(extenderArgsOf(pod, nodes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestDecodeNodeMetadataArgs(t *testing.T) {
+	nodes := heavyNodes(3)
+	tests := []struct {
+		name  string
+		body  string
+		nodes []string
+		err   bool
+	}{
+		{"node objects", string(argsBody(t, testPod("default", "p", nil), nodes)), []string{"node-0", "node-1", "node-2"}, false},
+		{"node names", `{"pod": {"metadata": {"name": "p"}}, "nodenames": ["a", "b"]}`, nil, false},
+		{"malformed", `{"pod": `, nil, true},
+	}
+	for _, test := range tests {
+		extenderArgs, err := decodeNodeMetadataArgs(bytes.NewReader([]byte(test.body)))
+		if (err != nil) != test.err {
+			t.Errorf("%v: decodeNodeMetadataArgs returned %v", test.name, err)
+			continue
+		}
+		if test.nodes == nil {
+			if extenderArgs.Nodes != nil {
+				t.Errorf("%v: expected no node objects, got %v", test.name, extenderArgs.Nodes.Items)
+			}
+			continue
+		}
+		for i, node := range extenderArgs.Nodes.Items {
+			if node.Name != test.nodes[i] || node.Labels["zone"] != nodes[i].Labels["zone"] {
+				t.Errorf("%v: expected the metadata of %v, got %v", test.name, test.nodes[i], node.ObjectMeta)
+			}
+			if cpu := node.Status.Allocatable.Cpu(); cpu.Cmp(*nodes[i].Status.Allocatable.Cpu()) != 0 {
+				t.Errorf("%v: expected the allocatable cpu of %v to be kept, got %v", test.name, node.Name, cpu)
+			}
+			if len(node.Status.Images) != 0 || len(node.Status.Conditions) != 0 {
+				t.Errorf("%v: expected the images and conditions of %v to be skipped", test.name, node.Name)
+			}
+		}
+	}
+}
+
+// TestNodeNamesOnlyScores checks a name-only method scores the nodes as it would the full objects, the
+// most-allocatable sampling included
+func TestNodeNamesOnlyScores(t *testing.T) {
+	savedMax, savedStrategy := maxNodesScored, nodeSamplingStrategy
+	defer func() { maxNodesScored, nodeSamplingStrategy = savedMax, savedStrategy }()
+	maxNodesScored, nodeSamplingStrategy = 2, "most-allocatable"
+	byName := func(_ v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+		list := make(schedulingapi.HostPriorityList, len(nodes))
+		for i, node := range nodes {
+			list[i] = schedulingapi.HostPriority{Host: node.Name, Score: len(node.Name) + len(node.Labels["zone"])}
+		}
+		return &list, nil
+	}
+	router := newTestRouter(t,
+		PrioritizeMethod{Name: "full", Func: byName},
+		PrioritizeMethod{Name: "names", Func: byName, NodeNamesOnly: true})
+	nodes := heavyNodes(4)
+	pod := testPod("default", "p", nil)
+	full := prioritize(t, router, "full", pod, nodes)
+	names := prioritize(t, router, "names", pod, nodes)
+	// node-2 and node-3, the most allocatable, are scored, the others get the neutral score
+	checkScores(t, full, map[string]int{"node-0": neutralScore, "node-1": neutralScore, "node-2": 7, "node-3": 7})
+	checkScores(t, names, scoresByHost(full))
+}
+
+func TestDecodeAllocations(t *testing.T) {
+	body := argsBody(t, testPod("default", "p", nil), heavyNodes(100))
+	allocs := func(decode argsDecoder) float64 {
+		return testing.AllocsPerRun(5, func() {
+			if _, err := decode(bytes.NewReader(body)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	full, light := allocs(decodeNodeObjectsArgs), allocs(decodeNodeMetadataArgs)
+	if light > full/2 {
+		t.Errorf("expected the metadata decode to allocate less than half of the full decode, got %v against %v", light, full)
+	}
+}
+
+// withExtenderAPIVersion sets -extender-api-version for the test
+func withExtenderAPIVersion(t *testing.T, version string) {
+	saved := extenderAPIVersion
+	t.Cleanup(func() { extenderAPIVersion = saved })
+	extenderAPIVersion = version
+}
+
+func TestValidateExtenderAPIVersion(t *testing.T) {
+	for version, valid := range map[string]bool{"v1": true, "v1-nodecache": true, "v2": false, "": false} {
+		withExtenderAPIVersion(t, version)
+		if err := validateExtenderAPIVersion(); (err == nil) != valid {
+			t.Errorf("validateExtenderAPIVersion(%q) returned %v", version, err)
+		}
+	}
+}
+
+func TestDecodeNodeNamesArgs(t *testing.T) {
+	nodeLister.replace([]v1.Node{labeledNode("known", map[string]string{"zone": "a"})})
+	t.Cleanup(func() { nodeLister.replace(nil) })
+	tests := []struct {
+		name  string
+		body  string
+		nodes []string
+		zones []string
+		err   bool
+	}{
+		{"node names", `{"pod": {"metadata": {"name": "p"}}, "nodenames": ["known", "unknown"]}`, []string{"known", "unknown"}, []string{"a", ""}, false},
+		{"node objects", `{"pod": {"metadata": {"name": "p"}}, "nodes": {"items": [{"metadata": {"name": "known"}}]}}`, []string{"known"}, []string{""}, false},
+		{"no node", `{"pod": {"metadata": {"name": "p"}}}`, nil, nil, false},
+		{"malformed", `{"pod": `, nil, nil, true},
+	}
+	for _, test := range tests {
+		extenderArgs, err := decodeNodeNamesArgs(strings.NewReader(test.body))
+		if (err != nil) != test.err {
+			t.Errorf("%v: decodeNodeNamesArgs returned %v", test.name, err)
+			continue
+		}
+		if extenderArgs.Nodes == nil {
+			if test.nodes != nil {
+				t.Errorf("%v: expected the nodes %v, got none", test.name, test.nodes)
+			}
+			continue
+		}
+		var zones []string
+		for _, node := range extenderArgs.Nodes.Items {
+			zones = append(zones, node.Labels["zone"])
+		}
+		if names := nodeNames(extenderArgs.Nodes.Items); !reflect.DeepEqual(names, test.nodes) || !reflect.DeepEqual(zones, test.zones) {
+			t.Errorf("%v: decoded the nodes %v in the zones %v, expected %v in %v", test.name, names, zones, test.nodes, test.zones)
+		}
+	}
+}
+
+func TestNodeCacheRoutes(t *testing.T) {
+	withExtenderAPIVersion(t, "v1-nodecache")
+	withClusterNodes(t, "n1", "n2")
+	router := newTestRouter(t, digitPriority)
+	body := `{"pod": {"metadata": {"name": "p"}}, "nodenames": ["n1", "n2"]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+digitPriority.Name, strings.NewReader(body)))
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+	}
+	checkScores(t, list, map[string]int{"n1": 1, "n2": 2})
+
+	// the filters can't answer a scheduler sending node names
+	w = httptest.NewRecorder()
+	FilterRoute(FilterMethod{Name: "any", Func: func(v1.Pod, v1.Node) (bool, string, error) { return true, "", nil }})(w, httptest.NewRequest(http.MethodPost, "/filter", strings.NewReader(body)), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("the filter answered %v: %v", w.Code, w.Body.String())
+	}
+}
+
+func TestDedupNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []string
+		expected []string
+	}{
+		{"no duplicate", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"duplicates", []string{"a", "b", "a", "c", "b", "a"}, []string{"a", "b", "c"}},
+		{"empty", nil, nil},
+	}
+	for _, test := range tests {
+		if deduped := nodeNames(dedupNodes("p", testNodes(test.nodes...))); !reflect.DeepEqual(deduped, test.expected) {
+			t.Errorf("%v: deduped %v to %v, expected %v", test.name, test.nodes, deduped, test.expected)
+		}
+	}
+}
+
+func TestDedupNodesKeepsFirst(t *testing.T) {
+	nodes := []v1.Node{labeledNode("a", map[string]string{"entry": "first"}), labeledNode("a", map[string]string{"entry": "second"})}
+	if deduped := dedupNodes("p", nodes); len(deduped) != 1 || deduped[0].Labels["entry"] != "first" {
+		t.Errorf("deduped to %v, expected the first entry", deduped)
+	}
+}
+
+func TestDecodeExtenderArgs(t *testing.T) {
+	extenderArgs, err := decodeExtenderArgs(strings.NewReader(`{"pod": {"metadata": {"name": "p"}}, "nodes": {"items": [{"metadata": {"name": "a"}}, {"metadata": {"name": "a"}}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := nodeNames(extenderArgs.Nodes.Items); !reflect.DeepEqual(names, []string{"a"}) {
+		t.Errorf("decoded the nodes %v", names)
+	}
+	if _, err := decodeExtenderArgs(strings.NewReader(`{"nodes": {"items": []}}`)); err == nil {
+		t.Errorf("decoded ExtenderArgs without pod")
+	}
+}
+
+func TestDuplicateNodesRoute(t *testing.T) {
+	router := newTestRouter(t, digitPriority)
+	list := prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("n1", "n2", "n1"))
+	if len(list) != 2 {
+		t.Errorf("scored %v, expected a single entry per node", list)
+	}
+	checkScores(t, list, map[string]int{"n1": 1, "n2": 2})
+}
+
+func BenchmarkDecodeArgs(b *testing.B) {
+	body := argsBody(b, testPod("default", "p", nil), heavyNodes(1000))
+	for _, decoder := range []struct {
+		name   string
+		decode argsDecoder
+	}{
+		{"objects", decodeNodeObjectsArgs},
+		{"metadata", decodeNodeMetadataArgs},
+	} {
+		b.Run(decoder.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decoder.decode(bytes.NewReader(body)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+var entitlementAnnotation, entitlementLabelPrefix string
+
+func init() {
+	flag.StringVar(&entitlementAnnotation, "entitlement-annotation", "scheduler.extender/entitlement", "The pod annotation naming the entitlement, e.g. a license, the node must hold")
+	flag.StringVar(&entitlementLabelPrefix, "entitlement-label-prefix", "entitlement.example.com/", "The prefix of the node label or annotation granting an entitlement missing from the config file entitlements")
+}
+
+// EntitlementFilter rejects the nodes lacking the entitlement the pod annotation requires. A node holds
+// an entitlement when it has its key, as a label or an annotation, set to anything but false
+var EntitlementFilter = FilterMethod{
+	Name: "entitlement",
+	Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+		required, ok := pod.Annotations[entitlementAnnotation]
+		if !ok || required == "" {
+			return true, "", nil
+		}
+		key := currentConfig().entitlementKey(required)
+		if value, ok := node.Labels[key]; ok && value != "false" {
+			return true, "", nil
+		}
+		if value, ok := node.Annotations[key]; ok && value != "false" {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("node lacks the %v entitlement required by the pod, granted by the %v label or annotation", required, key), nil
+	},
+}
+
+// entitlementKey returns the node label or annotation key granting the entitlement
+func (c *extenderConfig) entitlementKey(entitlement string) string {
+	if c != nil {
+		if key, ok := c.Entitlements[entitlement]; ok {
+			return key
+		}
+	}
+	return entitlementLabelPrefix + entitlement
+}
@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestEntitlementFilter(t *testing.T) {
+	entitled := func(name string, labels, annotations map[string]string) v1.Node {
+		node := labeledNode(name, labels)
+		node.Annotations = annotations
+		return node
+	}
+	nodes := []v1.Node{
+		entitled("licensed", map[string]string{entitlementLabelPrefix + "oracle-db": "true"}, nil),
+		entitled("annotated", nil, map[string]string{entitlementLabelPrefix + "oracle-db": "seat-4"}),
+		entitled("revoked", map[string]string{entitlementLabelPrefix + "oracle-db": "false"}, nil),
+		entitled("mapped", map[string]string{"license.example.com/oracle": ""}, nil),
+		entitled("unlicensed", nil, nil),
+	}
+	mapping := &extenderConfig{Entitlements: map[string]string{"oracle-db": "license.example.com/oracle"}}
+	tests := []struct {
+		name     string
+		config   *extenderConfig
+		pod      v1.Pod
+		expected []string
+	}{
+		{"label prefix", nil, annotatedPod(map[string]string{entitlementAnnotation: "oracle-db"}), []string{"licensed", "annotated"}},
+		{"configured key", mapping, annotatedPod(map[string]string{entitlementAnnotation: "oracle-db"}), []string{"mapped"}},
+		{"unmapped entitlement", mapping, annotatedPod(map[string]string{entitlementAnnotation: "sap"}), nil},
+		{"no entitlement", nil, annotatedPod(nil), []string{"licensed", "annotated", "revoked", "mapped", "unlicensed"}},
+		{"empty entitlement", nil, annotatedPod(map[string]string{entitlementAnnotation: ""}), []string{"licensed", "annotated", "revoked", "mapped", "unlicensed"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withConfig(t, test.config)
+			w, result := filterNodes(t, EntitlementFilter, test.pod, nodes)
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.expected) {
+				t.Errorf("passed %v, expected %v", passed, test.expected)
+			}
+			if len(result.FailedNodes) != len(nodes)-len(test.expected) {
+				t.Errorf("failed %v", result.FailedNodes)
+			}
+			for node, reason := range result.FailedNodes {
+				if !strings.Contains(reason, "entitlement required by the pod") {
+					t.Errorf("rejected %v with the unclear reason %q", node, reason)
+				}
+			}
+		})
+	}
+}
+
+func TestEntitlementKey(t *testing.T) {
+	config := &extenderConfig{Entitlements: map[string]string{"oracle-db": "license.example.com/oracle"}}
+	tests := []struct {
+		config      *extenderConfig
+		entitlement string
+		expected    string
+	}{
+		{config, "oracle-db", "license.example.com/oracle"},
+		{config, "sap", entitlementLabelPrefix + "sap"},
+		{nil, "oracle-db", entitlementLabelPrefix + "oracle-db"},
+	}
+	for _, test := range tests {
+		if key := test.config.entitlementKey(test.entitlement); key != test.expected {
+			t.Errorf("%v is granted by %v, expected %v", test.entitlement, key, test.expected)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// EphemeralStoragePriority prefers, for pods requesting ephemeral storage, the nodes with the most free
+// ephemeral storage: the score is (allocatable - requested on the node) / allocatable. Pods without an
+// ephemeral-storage request, or nodes not reporting their allocatable ephemeral storage, get the neutral score
+var EphemeralStoragePriority = PrioritizeMethod{
+	Name:              "ephemeral_storage",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		requested := podCycles.get(pod, time.Now()).request(v1.ResourceEphemeralStorage)
+		byNode := podsByNode(podLister)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			allocatable := nodeAllocatable(node, v1.ResourceEphemeralStorage)
+			if requested <= 0 || allocatable <= 0 {
+				return neutralScore, nil
+			}
+			free := allocatable - sumRequests(byNode.on(node.Name), v1.ResourceEphemeralStorage)
+			if free <= 0 {
+				return 0, nil
+			}
+			return int(schedulingapi.MaxPriority * free / allocatable), nil
+		}
+	},
+}
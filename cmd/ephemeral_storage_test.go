@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// storagePod returns a pod bound to the node requesting the ephemeral storage, none when empty
+func storagePod(name, node, request string) v1.Pod {
+	requests := make(v1.ResourceList)
+	if request != "" {
+		requests[v1.ResourceEphemeralStorage] = resource.MustParse(request)
+	}
+	return resourcePod(name, node, requests, nil)
+}
+
+// storageNode returns a node with the allocatable ephemeral storage, none when empty
+func storageNode(name, allocatable string) v1.Node {
+	node := testNodes(name)[0]
+	if allocatable != "" {
+		node.Status.Allocatable = v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse(allocatable)}
+	}
+	return node
+}
+
+func TestEphemeralStoragePriority(t *testing.T) {
+	withPods(t,
+		storagePod("a1", "light", "20Gi"),
+		storagePod("b1", "heavy", "50Gi"),
+		storagePod("b2", "heavy", "40Gi"),
+		storagePod("c1", "full", "120Gi"),
+		storagePod("d1", "unreported", "20Gi"),
+		storagePod("e1", "empty", ""),
+	)
+	nodes := []v1.Node{
+		storageNode("light", "100Gi"),
+		storageNode("heavy", "100Gi"),
+		storageNode("full", "100Gi"),
+		storageNode("unreported", ""),
+		storageNode("empty", "50Gi"),
+	}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"storage request", storagePod("p", "", "10Gi"), map[string]int{"light": 8, "heavy": 1, "full": 0, "unreported": neutralScore, "empty": 10}},
+		{"no storage request", storagePod("p", "", ""), map[string]int{"light": neutralScore, "heavy": neutralScore, "full": neutralScore, "unreported": neutralScore, "empty": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, EphemeralStoragePriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// The error kinds a priority method can return, each maps to the status the scheduler receives so it
+// can tell a permanent failure from a retryable one
+var (
+	// ErrBadRequest the request can't be served as is, mapped to 400
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnavailable a dependency is temporarily unavailable, mapped to 503
+	ErrUnavailable = errors.New("unavailable")
+	// ErrInternal the method failed, mapped to 500 like any other error
+	ErrInternal = errors.New("internal error")
+)
+
+// kindError is an error message tagged with one of the error kinds
+type kindError struct {
+	kind    error
+	message string
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.message
+}
+
+// newError returns an error of the given kind, e.g. newError(ErrUnavailable, "cache not synced")
+func newError(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// annotateError prefixes the message of the error with the context, keeping its kind
+func annotateError(err error, format string, args ...interface{}) error {
+	context := fmt.Sprintf(format, args...)
+	switch e := err.(type) {
+	case *kindError:
+		return &kindError{kind: e.kind, message: context + ": " + e.message}
+	}
+	if err == ErrBadRequest || err == ErrUnavailable {
+		return &kindError{kind: err, message: context}
+	}
+	return fmt.Errorf("%v: %v", context, err)
+}
+
+// errorKind returns the kind of the error, ErrInternal for untagged errors
+func errorKind(err error) error {
+	switch e := err.(type) {
+	case *kindError:
+		return e.kind
+	}
+	if err == ErrBadRequest || err == ErrUnavailable {
+		return err
+	}
+	return ErrInternal
+}
+
+// statusForError maps the kind of the error to an http status
+func statusForError(err error) int {
+	switch errorKind(err) {
+	case ErrBadRequest:
+		return http.StatusBadRequest
+	case ErrUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError answers the request with the status matching the error, an unavailable error tells the
+// scheduler to retry after -warmup-retry-after seconds
+func writeError(w http.ResponseWriter, err error) {
+	status := statusForError(err)
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", strconv.Itoa(warmupRetryAfter))
+	}
+	http.Error(w, err.Error(), status)
+}
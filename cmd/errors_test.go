@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{newError(ErrBadRequest, "no pod"), http.StatusBadRequest},
+		{newError(ErrUnavailable, "cache not synced"), http.StatusServiceUnavailable},
+		{newError(ErrInternal, "bug"), http.StatusInternalServerError},
+		{ErrBadRequest, http.StatusBadRequest},
+		{ErrUnavailable, http.StatusServiceUnavailable},
+		{errors.New("untagged"), http.StatusInternalServerError},
+		{fmt.Errorf("wrapped: %v", ErrUnavailable), http.StatusInternalServerError},
+		{annotateError(newError(ErrUnavailable, "cache not synced"), "node a"), http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		if status := statusForError(test.err); status != test.status {
+			t.Errorf("statusForError(%v) = %v, expected %v", test.err, status, test.status)
+		}
+	}
+}
+
+func TestErrorStatusThroughRoute(t *testing.T) {
+	failing := func(err error) PrioritizeMethod {
+		return PrioritizeMethod{
+			Name: "failing",
+			Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+				return func(pod v1.Pod, node v1.Node) (int, error) {
+					return 0, err
+				}
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter bool
+	}{
+		{"unavailable", newError(ErrUnavailable, "backend down"), http.StatusServiceUnavailable, true},
+		{"bad request", newError(ErrBadRequest, "unsupported pod"), http.StatusBadRequest, false},
+		{"untagged", errors.New("bug"), http.StatusInternalServerError, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, failing(test.err))
+			body, err := json.Marshal(extenderArgsOf(testPod("default", "p", nil), testNodes("a")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/failing", bytes.NewReader(body)))
+			if w.Code != test.status {
+				t.Errorf("answered %v: %v, expected %v", w.Code, w.Body.String(), test.status)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); (retryAfter == strconv.Itoa(warmupRetryAfter)) != test.retryAfter {
+				t.Errorf("Retry-After %q, expected it set = %v", retryAfter, test.retryAfter)
+			}
+		})
+	}
+}
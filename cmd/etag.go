@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"net/http"
+
+	"k8s.io/api/core/v1"
+)
+
+var enableETag bool
+
+func init() {
+	flag.BoolVar(&enableETag, "enable-etag", false, "Return an ETag with the scores and answer 304 to a request carrying it in If-None-Match")
+}
+
+// scoresETag hashes the pod UID and the scores, which hold the node set, into a strong ETag
+func scoresETag(pod *v1.Pod, resultBody []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(pod.UID))
+	hash.Write([]byte{0})
+	hash.Write(resultBody)
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// writeScores writes the JSON encoded scores, when -enable-etag is set a repeated request of the same
+// scheduling cycle carrying the ETag in If-None-Match gets a 304 without the body
+func writeScores(w http.ResponseWriter, r *http.Request, pod *v1.Pod, resultBody []byte) {
+	if enableETag {
+		etag := scoresETag(pod, resultBody)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
@@ -0,0 +1,101 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withETag sets -enable-etag until the end of the test
+func withETag(t *testing.T, enabled bool) {
+	saved := enableETag
+	t.Cleanup(func() { enableETag = saved })
+	enableETag = enabled
+}
+
+// prioritizeIfNoneMatch posts the request with the If-None-Match header, unless empty
+func prioritizeIfNoneMatch(t *testing.T, router http.Handler, pod v1.Pod, nodes []v1.Node, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(extenderArgsOf(pod, nodes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+digitPriority.Name, bytes.NewReader(body))
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestScoresETag(t *testing.T) {
+	withETag(t, true)
+	router := newTestRouter(t, digitPriority)
+	pod := testPod("default", "p", nil)
+	pod.UID = "cycle"
+	nodes := testNodes("node-1", "node-2")
+	first := prioritizeIfNoneMatch(t, router, pod, nodes, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("answered %v with the ETag %q and Cache-Control %q", first.Code, etag, first.Header().Get("Cache-Control"))
+	}
+
+	otherPod := pod
+	otherPod.UID = "other"
+	tests := []struct {
+		name   string
+		pod    v1.Pod
+		nodes  []v1.Node
+		etag   string
+		status int
+		same   bool
+	}{
+		{"same request", pod, nodes, etag, http.StatusNotModified, true},
+		{"stale etag", pod, nodes, `"stale"`, http.StatusOK, true},
+		{"other nodes", pod, testNodes("node-1", "node-3"), etag, http.StatusOK, false},
+		{"other pod", otherPod, nodes, etag, http.StatusOK, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := prioritizeIfNoneMatch(t, router, test.pod, test.nodes, test.etag)
+			if w.Code != test.status {
+				t.Fatalf("answered %v, expected %v", w.Code, test.status)
+			}
+			if test.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("answered a body with the 304: %q", w.Body.String())
+			}
+			if test.status == http.StatusOK && w.Body.Len() == 0 {
+				t.Errorf("answered no body with the 200")
+			}
+			if same := w.Header().Get("ETag") == etag; same != test.same {
+				t.Errorf("answered the ETag %q, expected the first one %q = %v", w.Header().Get("ETag"), etag, test.same)
+			}
+		})
+	}
+}
+
+func TestScoresETagDisabled(t *testing.T) {
+	withETag(t, false)
+	router := newTestRouter(t, digitPriority)
+	w := prioritizeIfNoneMatch(t, router, testPod("default", "p", nil), testNodes("node-1"), `"anything"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Errorf("answered %v with the ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var evictionWindow time.Duration
+var evictionPenalty int
+var stabilitySensitiveLabel string
+
+func init() {
+	flag.DurationVar(&evictionWindow, "eviction-window", time.Hour, "How far back eviction_rate counts the evictions of a node")
+	flag.IntVar(&evictionPenalty, "eviction-penalty", 2, "The score a node loses in eviction_rate for each eviction within the window")
+	flag.StringVar(&stabilitySensitiveLabel, "stability-sensitive-label", "scheduler.extender/stability-sensitive", "The pod label marking, when set to true, a pod that should avoid the nodes evicting pods")
+}
+
+// validateEvictionPenalty makes sure the -eviction-penalty flag is not negative, a negative penalty would
+// favor the nodes evicting pods
+func validateEvictionPenalty() error {
+	if evictionPenalty < 0 {
+		return fmt.Errorf("the -eviction-penalty flag value must not be negative, got %v", evictionPenalty)
+	}
+	return nil
+}
+
+// evictionInformer keeps the recent kubelet eviction events by node
+type evictionInformer struct {
+	client    *apiClient
+	lock      sync.RWMutex
+	evictions map[string][]time.Time
+	synced    bool
+}
+
+// evictionHistory is the eviction view used by eviction_rate, nil when -enable-informers is not set
+var evictionHistory *evictionInformer
+
+func newEvictionInformer(client *apiClient) *evictionInformer {
+	return &evictionInformer{client: client}
+}
+
+// refresh lists the eviction events from the api-server, the previous view is kept when it fails, e.g.
+// when the service account is not allowed to list the events
+func (e *evictionInformer) refresh() error {
+	var list v1.EventList
+	if err := e.client.get("/api/v1/events?fieldSelector=reason=Evicted", &list); err != nil {
+		return err
+	}
+	e.load(list.Items)
+	return nil
+}
+
+// load replaces the view with the eviction events of the list
+func (e *evictionInformer) load(events []v1.Event) {
+	evictions := make(map[string][]time.Time)
+	for _, event := range events {
+		if event.Reason != "Evicted" || event.Source.Host == "" {
+			continue
+		}
+		when := event.LastTimestamp.Time
+		if when.IsZero() {
+			when = event.EventTime.Time
+		}
+		node := normalizeNodeName(event.Source.Host)
+		evictions[node] = append(evictions[node], when)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.evictions = evictions
+	e.synced = true
+}
+
+// run refreshes the view until the stop channel is closed
+func (e *evictionInformer) run(stop <-chan struct{}) {
+	poll("eviction events", e.refresh, stop)
+}
+
+// recent returns the number of evictions of the node within the window, false when the events were
+// never listed
+func (e *evictionInformer) recent(node string, now time.Time) (int, bool) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	var count int
+	for _, when := range e.evictions[normalizeNodeName(node)] {
+		if now.Sub(when) <= evictionWindow {
+			count++
+		}
+	}
+	return count, e.synced
+}
+
+// EvictionRatePriority keeps the stability sensitive pods away from the nodes recently evicting pods,
+// each eviction within -eviction-window costing -eviction-penalty points off the max score. The other
+// pods, or every pod while the eviction events are unavailable, get the neutral score
+var EvictionRatePriority = PrioritizeMethod{
+	Name:              "eviction_rate",
+	RequiresInformers: true,
+	NodeNamesOnly:     true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		now := time.Now()
+		sensitive := pod.Labels[stabilitySensitiveLabel] == "true"
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if !sensitive || evictionHistory == nil {
+				return neutralScore, nil
+			}
+			evictions, synced := evictionHistory.recent(node.Name, now)
+			if !synced {
+				return neutralScore, nil
+			}
+			return clampScore(schedulingapi.MaxPriority - evictionPenalty*evictions), nil
+		}
+	},
+}
@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withEvictionHistory sets the eviction view until the end of the test
+func withEvictionHistory(t *testing.T, history *evictionInformer) {
+	saved := evictionHistory
+	t.Cleanup(func() { evictionHistory = saved })
+	evictionHistory = history
+}
+
+// evictionEvent returns a kubelet eviction event of the node
+func evictionEvent(node string, when time.Time) v1.Event {
+	return v1.Event{Reason: "Evicted", Source: v1.EventSource{Host: node}, LastTimestamp: metav1.NewTime(when)}
+}
+
+func TestValidateEvictionPenalty(t *testing.T) {
+	defer func(saved int) { evictionPenalty = saved }(evictionPenalty)
+	for penalty, valid := range map[int]bool{0: true, 2: true, 10: true, -1: false} {
+		evictionPenalty = penalty
+		if err := validateEvictionPenalty(); (err == nil) != valid {
+			t.Errorf("validateEvictionPenalty(%v) returned %v", penalty, err)
+		}
+	}
+}
+
+func TestEvictionInformer(t *testing.T) {
+	defer func(saved time.Duration) { evictionWindow = saved }(evictionWindow)
+	evictionWindow = time.Hour
+	now := time.Now()
+	microEvent := v1.Event{Reason: "Evicted", Source: v1.EventSource{Host: "b"}, EventTime: metav1.NewMicroTime(now.Add(-time.Minute))}
+	history := newEvictionInformer(nil)
+	if _, synced := history.recent("a", now); synced {
+		t.Errorf("the eviction view is synced before the first listing")
+	}
+	history.load([]v1.Event{
+		evictionEvent("a", now.Add(-time.Minute)),
+		evictionEvent("a", now.Add(-30*time.Minute)),
+		evictionEvent("a", now.Add(-2*time.Hour)),
+		microEvent,
+		{Reason: "Killing", Source: v1.EventSource{Host: "c"}, LastTimestamp: metav1.NewTime(now)},
+		evictionEvent("", now),
+	})
+	tests := []struct {
+		node     string
+		expected int
+	}{
+		{"a", 2},
+		{"b", 1},
+		{"c", 0},
+		{"unknown", 0},
+	}
+	for _, test := range tests {
+		count, synced := history.recent(test.node, now)
+		if count != test.expected || !synced {
+			t.Errorf("counted %v recent evictions on %v (synced %v), expected %v", count, test.node, synced, test.expected)
+		}
+	}
+}
+
+func TestEvictionInformerRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fieldSelector") != "reason=Evicted" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(v1.EventList{Items: []v1.Event{evictionEvent("a", time.Now())}})
+	}))
+	defer server.Close()
+	history := newEvictionInformer(&apiClient{server: server.URL, client: server.Client()})
+	if err := history.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if count, synced := history.recent("a", time.Now()); count != 1 || !synced {
+		t.Errorf("counted %v evictions (synced %v) after the refresh", count, synced)
+	}
+
+	// the events can't be listed, the previous view is kept
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	if err := history.refresh(); err == nil {
+		t.Errorf("refreshed the view without access to the events")
+	}
+	if count, synced := history.recent("a", time.Now()); count != 1 || !synced {
+		t.Errorf("the failed refresh dropped the view, counted %v evictions (synced %v)", count, synced)
+	}
+}
+
+func TestEvictionRatePriority(t *testing.T) {
+	defer func(window time.Duration, penalty int) { evictionWindow, evictionPenalty = window, penalty }(evictionWindow, evictionPenalty)
+	evictionWindow, evictionPenalty = time.Hour, 2
+	now := time.Now()
+	synced := newEvictionInformer(nil)
+	synced.load([]v1.Event{
+		evictionEvent("evicting", now), evictionEvent("evicting", now),
+		evictionEvent("unstable", now), evictionEvent("unstable", now), evictionEvent("unstable", now),
+		evictionEvent("unstable", now), evictionEvent("unstable", now), evictionEvent("unstable", now),
+	})
+	nodes := testNodes("stable", "evicting", "unstable")
+	sensitive := testPod("default", "p", map[string]string{stabilitySensitiveLabel: "true"})
+	neutral := map[string]int{"stable": neutralScore, "evicting": neutralScore, "unstable": neutralScore}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		history  *evictionInformer
+		expected map[string]int
+	}{
+		{"stability sensitive", sensitive, synced, map[string]int{"stable": 10, "evicting": 6, "unstable": 0}},
+		{"not stability sensitive", testPod("default", "p", nil), synced, neutral},
+		{"events never listed", sensitive, newEvictionInformer(nil), neutral},
+		{"informers disabled", sensitive, nil, neutral},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withEvictionHistory(t, test.history)
+			checkScores(t, scoreMethod(t, EvictionRatePriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var explainBufferSize int
+
+func init() {
+	flag.IntVar(&explainBufferSize, "explain-buffer-size", 100, "The number of scoring decisions, with the reason of each node score, kept for /debug/explain with -enable-debug")
+}
+
+// explainBuffered returns whether the decisions are kept, only for the /debug/explain endpoint of -enable-debug
+func explainBuffered() bool {
+	return enableDebug && explainBufferSize > 0
+}
+
+// NodeExplanation is the score of a node and the reason behind it
+type NodeExplanation struct {
+	Host   string `json:"host"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// DecisionExplanation explains the scores given by a priority method to the nodes of a request.
+// The scheduler API has no room for a reason, so the explanations are logged and kept in the explain buffer
+type DecisionExplanation struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// MethodVersion is the version of the scoring logic of the method
+	MethodVersion int               `json:"methodVersion"`
+	PodNamespace  string            `json:"podNamespace"`
+	PodName       string            `json:"podName"`
+	PodUID        string            `json:"podUID"`
+	Nodes         []NodeExplanation `json:"nodes"`
+}
+
+// explainBuffer is a ring of the last decisions
+type explainBuffer struct {
+	lock      sync.Mutex
+	decisions []DecisionExplanation
+	next      int
+}
+
+// explanations holds the last -explain-buffer-size decisions
+var explanations = &explainBuffer{}
+
+// add stores the decision, overwriting the oldest one once the buffer is full
+func (b *explainBuffer) add(decision DecisionExplanation) {
+	if !explainBuffered() {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.decisions) < explainBufferSize {
+		b.decisions = append(b.decisions, decision)
+		return
+	}
+	b.decisions[b.next] = decision
+	b.next = (b.next + 1) % explainBufferSize
+}
+
+// list returns the decisions from the oldest to the newest, only the ones of the pod when podUID is set
+func (b *explainBuffer) list(podUID string) []DecisionExplanation {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ordered := append(append([]DecisionExplanation{}, b.decisions[b.next:]...), b.decisions[:b.next]...)
+	if podUID == "" {
+		return ordered
+	}
+	var decisions []DecisionExplanation
+	for _, decision := range ordered {
+		if decision.PodUID == podUID {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions
+}
+
+// explainScores logs the reason of each node score at V(4) and keeps the decision in the explain buffer.
+// Explaining calls the Explain of the method for every node, it is skipped unless one of the two is wanted
+func explainScores(priorityMethod PrioritizeMethod, pod v1.Pod, nodes []v1.Node, list schedulingapi.HostPriorityList) {
+	if !explainBuffered() && !bool(glog.V(4)) {
+		return
+	}
+	byName := make(map[string]v1.Node, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+	decision := DecisionExplanation{
+		Time:          time.Now(),
+		Method:        priorityMethod.Name,
+		MethodVersion: methodVersion(priorityMethod),
+		PodNamespace:  pod.Namespace,
+		PodName:       pod.Name,
+		PodUID:        string(pod.UID),
+		Nodes:         make([]NodeExplanation, len(list)),
+	}
+	for i, hp := range list {
+		reason := fmt.Sprintf("scored %v by %v", hp.Score, priorityMethod.Name)
+		if node, scored := byName[hp.Host]; !scored {
+			reason = "left out by the node sampling, neutral score"
+		} else if priorityMethod.Explain != nil {
+			reason = priorityMethod.Explain(pod, node)
+		}
+		decision.Nodes[i] = NodeExplanation{Host: hp.Host, Score: hp.Score, Reason: reason}
+		glog.V(4).Infof("priorityMethod %v gave node %v a score of %v for pod %v: %v\n", priorityMethod.Name, hp.Host, hp.Score, pod.Name, reason)
+	}
+	explanations.add(decision)
+}
+
+// DebugExplainRoute returns the last decisions as JSON, only the ones of a pod when its UID is in the path
+func DebugExplainRoute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	resultBody, err := json.Marshal(explanations.list(ps.ByName("uid")))
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withExplainBuffer sets -enable-debug and -explain-buffer-size on an empty buffer until the end of the test
+func withExplainBuffer(t *testing.T, debug bool, size int) {
+	savedDebug, savedSize, savedBuffer := enableDebug, explainBufferSize, explanations
+	t.Cleanup(func() { enableDebug, explainBufferSize, explanations = savedDebug, savedSize, savedBuffer })
+	enableDebug, explainBufferSize, explanations = debug, size, &explainBuffer{}
+}
+
+func TestExplainScores(t *testing.T) {
+	tests := []struct {
+		name      string
+		debug     bool
+		size      int
+		explained bool
+	}{
+		{"debug disabled", false, 100, false},
+		{"empty buffer", true, 0, false},
+		{"debug enabled", true, 100, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withExplainBuffer(t, test.debug, test.size)
+			calls := 0
+			method := PrioritizeMethod{Name: "explained", Explain: func(_ v1.Pod, node v1.Node) string {
+				calls++
+				return "because of " + node.Name
+			}}
+			pod := testPod("default", "p", nil)
+			pod.UID = "uid"
+			list := schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 5}}
+			explainScores(method, pod, testNodes("a"), list)
+			decisions := explanations.list("")
+			if !test.explained {
+				if calls != 0 || len(decisions) != 0 {
+					t.Fatalf("expected no explanation, got %v calls and %v", calls, decisions)
+				}
+				return
+			}
+			if len(decisions) != 1 || len(decisions[0].Nodes) != 2 {
+				t.Fatalf("expected a decision over both nodes, got %v", decisions)
+			}
+			if reason := decisions[0].Nodes[0].Reason; reason != "because of a" {
+				t.Errorf("expected the reason of the method, got %q", reason)
+			}
+			if reason := decisions[0].Nodes[1].Reason; reason != "left out by the node sampling, neutral score" {
+				t.Errorf("expected the node left out by the sampling to be explained, got %q", reason)
+			}
+		})
+	}
+}
+
+func TestExplainMethodVersion(t *testing.T) {
+	withExplainBuffer(t, true, 10)
+	list := schedulingapi.HostPriorityList{{Host: "a", Score: 3}}
+	for _, method := range []PrioritizeMethod{{Name: "unversioned"}, {Name: "versioned", Version: 4}} {
+		explainScores(method, testPod("default", "p", nil), testNodes("a"), list)
+	}
+	decisions := explanations.list("")
+	if len(decisions) != 2 || decisions[0].MethodVersion != 1 || decisions[1].MethodVersion != 4 {
+		t.Errorf("expected the decisions to carry the method versions 1 and 4, got %+v", decisions)
+	}
+}
+
+func TestExplainBufferRing(t *testing.T) {
+	withExplainBuffer(t, true, 3)
+	for i := 0; i < 5; i++ {
+		explanations.add(DecisionExplanation{PodName: fmt.Sprint(i), PodUID: fmt.Sprint(i % 2)})
+	}
+	var names []string
+	for _, decision := range explanations.list("") {
+		names = append(names, decision.PodName)
+	}
+	if fmt.Sprint(names) != "[2 3 4]" {
+		t.Errorf("expected the last three decisions from the oldest, got %v", names)
+	}
+	if decisions := explanations.list("1"); len(decisions) != 1 || decisions[0].PodName != "3" {
+		t.Errorf("expected the decisions of the pod, got %v", decisions)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"golang.org/x/net/http2"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// externalScorerMethod is the full gRPC method name of ExternalScorer.Score in proto/external_scorer.proto
+const externalScorerMethod = "/extender.scorer.v1.ExternalScorer/Score"
+
+var externalScorerAddr string
+var externalScorerTimeout time.Duration
+
+func init() {
+	flag.StringVar(&externalScorerAddr, "external-scorer-addr", "", "The host:port of the external scoring service implementing the ExternalScorer gRPC service, empty disables the external priority")
+	flag.DurationVar(&externalScorerTimeout, "external-scorer-timeout", time.Second, "How long the external priority waits for the external scoring service before answering the neutral score")
+}
+
+// validateExternalScorer makes sure the external scorer flags hold valid values
+func validateExternalScorer() error {
+	if externalScorerTimeout <= 0 {
+		return fmt.Errorf("the -external-scorer-timeout flag value must be positive, got %v", externalScorerTimeout)
+	}
+	if externalScorerAddr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(externalScorerAddr); err != nil {
+		return fmt.Errorf("invalid -external-scorer-addr %q, expecting host:port: %v", externalScorerAddr, err)
+	}
+	return nil
+}
+
+// scoreRequest, nodeScore and scoreResponse are the messages of proto/external_scorer.proto, written by
+// hand as the extender vendors gogo/protobuf but not the code generator
+type scoreRequest struct {
+	PodNamespace string            `protobuf:"bytes,1,opt,name=pod_namespace,json=podNamespace,proto3"`
+	PodName      string            `protobuf:"bytes,2,opt,name=pod_name,json=podName,proto3"`
+	PodUID       string            `protobuf:"bytes,3,opt,name=pod_uid,json=podUid,proto3"`
+	PodLabels    map[string]string `protobuf:"bytes,4,rep,name=pod_labels,json=podLabels,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	NodeNames    []string          `protobuf:"bytes,5,rep,name=node_names,json=nodeNames,proto3"`
+}
+
+func (m *scoreRequest) Reset()         { *m = scoreRequest{} }
+func (m *scoreRequest) String() string { return proto.CompactTextString(m) }
+func (*scoreRequest) ProtoMessage()    {}
+
+type nodeScore struct {
+	NodeName string `protobuf:"bytes,1,opt,name=node_name,json=nodeName,proto3"`
+	Score    int32  `protobuf:"varint,2,opt,name=score,proto3"`
+}
+
+func (m *nodeScore) Reset()         { *m = nodeScore{} }
+func (m *nodeScore) String() string { return proto.CompactTextString(m) }
+func (*nodeScore) ProtoMessage()    {}
+
+type scoreResponse struct {
+	Scores []*nodeScore `protobuf:"bytes,1,rep,name=scores,proto3"`
+}
+
+func (m *scoreResponse) Reset()         { *m = scoreResponse{} }
+func (m *scoreResponse) String() string { return proto.CompactTextString(m) }
+func (*scoreResponse) ProtoMessage()    {}
+
+// externalScorerClient is a thin gRPC client of the ExternalScorer service: unary calls over cleartext
+// HTTP/2 with the length-prefixed protobuf messages of the gRPC wire format
+type externalScorerClient struct {
+	addr   string
+	client *http.Client
+}
+
+// newExternalScorerClient returns a client of the service listening at addr
+func newExternalScorerClient(addr string) *externalScorerClient {
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	return &externalScorerClient{addr: addr, client: &http.Client{Transport: transport}}
+}
+
+// Score calls ExternalScorer.Score, the deadline of the context is sent along as the grpc-timeout
+func (c *externalScorerClient) Score(ctx context.Context, in *scoreRequest) (*scoreResponse, error) {
+	payload, err := proto.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	req, err := http.NewRequest(http.MethodPost, "http://"+c.addr+externalScorerMethod, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", time.Until(deadline)/time.Millisecond))
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v returned %v", externalScorerMethod, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// a failed call may answer the status in the headers alone, the trailers are read once the body is
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status != "0" {
+		message := resp.Trailer.Get("Grpc-Message")
+		if message == "" {
+			message = resp.Header.Get("Grpc-Message")
+		}
+		return nil, fmt.Errorf("%v failed with grpc status %q: %v", externalScorerMethod, status, message)
+	}
+	if len(body) < 5 || body[0] != 0 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		return nil, fmt.Errorf("%v returned a malformed or compressed message", externalScorerMethod)
+	}
+	out := &scoreResponse{}
+	if err := proto.Unmarshal(body[5:], out); err != nil {
+		return nil, fmt.Errorf("%v returned an invalid message: %v", externalScorerMethod, err)
+	}
+	return out, nil
+}
+
+// externalScorer scores the nodes with the external scoring service, falling back on the neutral score
+type externalScorer struct {
+	client *externalScorerClient
+}
+
+func (s *externalScorer) Name() string {
+	return "external"
+}
+
+// Score sends the pod and the node names to the service, bounded by -external-scorer-timeout. Every node
+// gets the neutral score when the call fails, and the nodes the service leaves out when it succeeds
+func (s *externalScorer) Score(ctx context.Context, pod v1.Pod, nodes []v1.Node) (schedulingapi.HostPriorityList, error) {
+	list := neutralScores(nodes)
+	if s.client == nil {
+		return list, nil
+	}
+	in := &scoreRequest{PodNamespace: pod.Namespace, PodName: pod.Name, PodUID: string(pod.UID), PodLabels: pod.Labels}
+	for _, node := range nodes {
+		in.NodeNames = append(in.NodeNames, node.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, externalScorerTimeout)
+	defer cancel()
+	out, err := s.client.Score(ctx, in)
+	if err != nil {
+		glog.Warningf("the external scorer failed for pod %v, scoring neutral: %v", pod.Name, err)
+		return list, nil
+	}
+	scores := make(map[string]int, len(out.Scores))
+	for _, score := range out.Scores {
+		if score != nil {
+			scores[score.NodeName] = clampScore(int(score.Score))
+		}
+	}
+	for i := range list {
+		if score, found := scores[list[i].Host]; found {
+			list[i].Score = score
+		}
+	}
+	return list, nil
+}
+
+// ExternalPriority returns the scores of the external scoring service at -external-scorer-addr, turning
+// the extender into an adapter for a placement service computing its scores from org-wide signals. It
+// is served when the flag is set, main then gives it a client of the service
+var ExternalPriority = PrioritizeMethod{
+	Name:          "external",
+	NodeNamesOnly: true,
+	Scorer:        &externalScorer{},
+}
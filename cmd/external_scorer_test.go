@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"golang.org/x/net/http2"
+)
+
+// stubScorer is an ExternalScorer gRPC server over cleartext HTTP/2 answering with the handler
+func stubScorer(t *testing.T, handler http.HandlerFunc) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		server := &http2.Server{}
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// grpcScores answers the Score call with the scores, echoing the nodes of the request after the node
+// names mapped in scores
+func grpcScores(t *testing.T, scores map[string]int32, delay time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != externalScorerMethod || r.Header.Get("Content-Type") != "application/grpc+proto" {
+			t.Errorf("unexpected call %v with content type %v", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		in := &scoreRequest{}
+		if len(body) < 5 || proto.Unmarshal(body[5:], in) != nil {
+			t.Errorf("malformed request %v", body)
+		}
+		time.Sleep(delay)
+		out := &scoreResponse{}
+		for _, name := range in.NodeNames {
+			if score, found := scores[name]; found {
+				out.Scores = append(out.Scores, &nodeScore{NodeName: name, Score: score})
+			}
+		}
+		payload, _ := proto.Marshal(out)
+		frame := make([]byte, 5+len(payload))
+		binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+		copy(frame[5:], payload)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(frame)
+		w.Header().Set("Grpc-Status", "0")
+	}
+}
+
+func TestExternalScorer(t *testing.T) {
+	defer func(timeout time.Duration) { externalScorerTimeout = timeout }(externalScorerTimeout)
+	externalScorerTimeout = 200 * time.Millisecond
+	failing := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "14")
+		w.Header().Set("Grpc-Message", "unavailable")
+	}
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		addr     string
+		expected map[string]int
+	}{
+		{"scores of the service", stubScorer(t, grpcScores(t, map[string]int32{"a": 9, "b": 2, "c": 0}, 0)), map[string]int{"a": 9, "b": 2, "c": 0}},
+		{"nodes left out are neutral", stubScorer(t, grpcScores(t, map[string]int32{"a": 7}, 0)), map[string]int{"a": 7, "b": neutralScore, "c": neutralScore}},
+		{"scores out of range are clamped", stubScorer(t, grpcScores(t, map[string]int32{"a": 42, "b": -3, "c": 4}, 0)), map[string]int{"a": 10, "b": 0, "c": 4}},
+		{"grpc error is neutral", stubScorer(t, failing), map[string]int{"a": neutralScore, "b": neutralScore, "c": neutralScore}},
+		{"timeout is neutral", stubScorer(t, grpcScores(t, map[string]int32{"a": 9}, time.Second)), map[string]int{"a": neutralScore, "b": neutralScore, "c": neutralScore}},
+		{"unreachable service is neutral", closedAddr, map[string]int{"a": neutralScore, "b": neutralScore, "c": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scorer := &externalScorer{client: newExternalScorerClient(test.addr)}
+			start := time.Now()
+			list, err := scorer.Score(context.Background(), testPod("default", "p", map[string]string{"app": "web"}), testNodes("a", "b", "c"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*externalScorerTimeout {
+				t.Fatalf("the call took %v, beyond the timeout %v", elapsed, externalScorerTimeout)
+			}
+			checkScores(t, list, test.expected)
+		})
+	}
+}
+
+func TestExternalScorerWithoutAddr(t *testing.T) {
+	list, err := (&externalScorer{}).Score(context.Background(), testPod("default", "p", nil), testNodes("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScores(t, list, map[string]int{"a": neutralScore, "b": neutralScore})
+}
+
+func TestValidateExternalScorer(t *testing.T) {
+	defer func(addr string, timeout time.Duration) {
+		externalScorerAddr, externalScorerTimeout = addr, timeout
+	}(externalScorerAddr, externalScorerTimeout)
+	tests := []struct {
+		addr    string
+		timeout time.Duration
+		valid   bool
+	}{
+		{"", time.Second, true},
+		{"scorer.placement:9000", time.Second, true},
+		{"scorer.placement", time.Second, false},
+		{"scorer.placement:9000", 0, false},
+	}
+	for _, test := range tests {
+		externalScorerAddr, externalScorerTimeout = test.addr, test.timeout
+		if err := validateExternalScorer(); (err == nil) != test.valid {
+			t.Errorf("validateExternalScorer with %q and %v returned %v", test.addr, test.timeout, err)
+		}
+	}
+}
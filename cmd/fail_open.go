@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var failOpen bool
+
+func init() {
+	flag.BoolVar(&failOpen, "fail-open", false, "When a request fails, error or panic, answer neutral scores to the prioritize requests and let every node pass the filters, so an extender bug never blocks the scheduling. Implies -filter-fail-open")
+}
+
+var failOpenLock sync.Mutex
+
+// failOpenCounts counts the requests answered by -fail-open per route, exposed on /metrics
+var failOpenCounts = make(map[string]int)
+
+// countFailOpen counts a failed request answered with neutral scores or every node passing
+func countFailOpen(route string) {
+	failOpenLock.Lock()
+	defer failOpenLock.Unlock()
+	failOpenCounts[route]++
+}
+
+// writeFailOpenScores answers a failed prioritize request with the neutral score for every node
+func writeFailOpenScores(w http.ResponseWriter, route string, extenderArgs schedulingapi.ExtenderArgs, cause interface{}) {
+	glog.Errorf("%v failed for pod %v, answering neutral scores as -fail-open is set: %v", route, extenderArgs.Pod.Name, cause)
+	countFailOpen(route)
+	list := schedulingapi.HostPriorityList{}
+	if extenderArgs.Nodes != nil {
+		list = neutralScores(extenderArgs.Nodes.Items)
+	}
+	resultBody, err := json.Marshal(list)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
+
+// recoverFailOpenScores is deferred by the prioritize routes, with -fail-open it turns a panic of the
+// route into neutral scores, otherwise the panic goes on
+func recoverFailOpenScores(w http.ResponseWriter, route string, extenderArgs schedulingapi.ExtenderArgs) {
+	if !failOpen {
+		return
+	}
+	if r := recover(); r != nil {
+		// an aborted response already sent its status, it can't be answered anymore
+		if r == http.ErrAbortHandler {
+			panic(r)
+		}
+		recordPanic(route, r)
+		writeFailOpenScores(w, route, extenderArgs, fmt.Sprintf("panic: %v", r))
+	}
+}
+
+// recoverFailOpenFilter is deferred by the filter routes, with -fail-open it turns a panic of the route
+// into every node passing, otherwise the panic goes on
+func recoverFailOpenFilter(w http.ResponseWriter, route string, extenderArgs schedulingapi.ExtenderArgs) {
+	if !failOpen {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+	recordPanic(route, r)
+	glog.Errorf("%v failed for pod %v, letting every node pass as -fail-open is set: panic: %v", route, extenderArgs.Pod.Name, r)
+	countFailOpen(route)
+	resultBody, err := json.Marshal(filterFailure(extenderArgs, fmt.Errorf("panic: %v", r)))
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
+
+// writeFailOpenMetrics writes the number of requests answered by -fail-open per route
+func writeFailOpenMetrics(w io.Writer) {
+	failOpenLock.Lock()
+	defer failOpenLock.Unlock()
+	routes := make([]string, 0, len(failOpenCounts))
+	for route := range failOpenCounts {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	samples := make([]metricSample, len(routes))
+	for i, route := range routes {
+		samples[i] = metricSample{fmt.Sprintf("route=%q", route), float64(failOpenCounts[route])}
+	}
+	writeMetric(w, "extender_fail_open_total", "counter", "Failed requests answered with neutral scores or every node passing, as -fail-open or -filter-fail-open is set.", samples...)
+}
@@ -0,0 +1,110 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withFailOpenCounts drops the fail open counts of the test at its end
+func withFailOpenCounts(t *testing.T) {
+	t.Cleanup(func() {
+		failOpenLock.Lock()
+		failOpenCounts = make(map[string]int)
+		failOpenLock.Unlock()
+	})
+}
+
+// panickingPriority panics on every request
+func panickingPriority(name string) PrioritizeMethod {
+	return PrioritizeMethod{
+		Name: name,
+		Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			panic("boom")
+		},
+	}
+}
+
+func TestFailOpenPrioritize(t *testing.T) {
+	withNeutralScore(t, 5)
+	withFailOpenCounts(t)
+	router := newTestRouter(t, failingPriority("failing"), panickingPriority("panicking"))
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b")
+	tests := []struct {
+		name     string
+		failOpen bool
+		query    string
+		status   int
+	}{
+		{"error failing closed", false, "/failing", http.StatusInternalServerError},
+		{"error with -fail-open", true, "/failing", http.StatusOK},
+		{"panic with -fail-open", true, "/panicking", http.StatusOK},
+		{"combined failing closed", false, "", http.StatusInternalServerError},
+		{"combined with -fail-open", true, "", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withFilterFailOpen(t, false, test.failOpen)
+			w := combine(t, router, test.query, nodes)
+			if w.Code != test.status {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var list schedulingapi.HostPriorityList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatalf("answered an invalid list %q: %v", w.Body.String(), err)
+			}
+			checkScores(t, list, map[string]int{"a": 5, "b": 5})
+		})
+	}
+
+	var metrics bytes.Buffer
+	writeFailOpenMetrics(&metrics)
+	for _, expected := range []string{`extender_fail_open_total{route="failing"} 1`, `extender_fail_open_total{route="panicking"} 1`, `extender_fail_open_total{route="combined"} 1`} {
+		if !strings.Contains(metrics.String(), expected) {
+			t.Errorf("the metrics are missing %v:\n%v", expected, metrics.String())
+		}
+	}
+}
+
+func TestWriteFailOpenScores(t *testing.T) {
+	withFailOpenCounts(t)
+	pod := testPod("default", "p", nil)
+	tests := []struct {
+		name         string
+		extenderArgs schedulingapi.ExtenderArgs
+		expected     string
+	}{
+		{"nodes", extenderArgsOf(pod, testNodes("a")), `[{"Host":"a","Score":` + fmt.Sprint(neutralScore) + `}]`},
+		{"node names", schedulingapi.ExtenderArgs{Pod: &pod}, "[]"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		writeFailOpenScores(w, "route", test.extenderArgs, "failure")
+		if w.Code != http.StatusOK || w.Body.String() != test.expected {
+			t.Errorf("%v: answered %v %v, expected %v", test.name, w.Code, w.Body.String(), test.expected)
+		}
+	}
+}
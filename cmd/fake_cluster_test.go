@@ -0,0 +1,199 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// fakeCluster is the state served by a fake api-server, the informers started by startFakeCluster list it
+// exactly as they list a real cluster
+type fakeCluster struct {
+	Pods       []v1.Pod
+	Nodes      []v1.Node
+	Events     []v1.Event
+	ConfigMaps []v1.ConfigMap
+
+	lock    sync.Mutex
+	patches map[string][]byte
+}
+
+// ServeHTTP answers the api-server calls of the informers and of the score annotator
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var object interface{}
+	switch path := r.URL.Path; {
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/api/v1/nodes/"):
+		patch, _ := ioutil.ReadAll(r.Body)
+		if c.patches == nil {
+			c.patches = make(map[string][]byte)
+		}
+		c.patches[strings.TrimPrefix(path, "/api/v1/nodes/")] = patch
+		object = struct{}{}
+	case r.Method != http.MethodGet:
+		http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+		return
+	case path == "/api/v1/pods":
+		list := v1.PodList{}
+		for _, pod := range c.Pods {
+			if pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+				list.Items = append(list.Items, pod)
+			}
+		}
+		object = list
+	case path == "/api/v1/nodes":
+		object = v1.NodeList{Items: c.Nodes}
+	case path == "/api/v1/events":
+		list := v1.EventList{}
+		for _, event := range c.Events {
+			if event.Reason == "Evicted" {
+				list.Items = append(list.Items, event)
+			}
+		}
+		object = list
+	case strings.HasPrefix(path, "/api/v1/namespaces/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/api/v1/namespaces/"), "/")
+		for _, configMap := range c.ConfigMaps {
+			if len(parts) == 3 && parts[1] == "configmaps" && configMap.Namespace == parts[0] && configMap.Name == parts[2] {
+				object = configMap
+			}
+		}
+	}
+	if object == nil {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(object)
+}
+
+// patched returns the merge patch last applied to the node
+func (c *fakeCluster) patched(node string) []byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.patches[node]
+}
+
+// startFakeCluster serves the cluster from a fake api-server and starts the informers on it, as
+// -enable-informers does, waiting for their first listing. The informer views are reset by the test cleanup
+func startFakeCluster(t *testing.T, cluster *fakeCluster) {
+	t.Helper()
+	server := httptest.NewServer(cluster)
+	defer func(enabled bool, server string) {
+		enableInformers, kubeAPIServer = enabled, server
+	}(enableInformers, kubeAPIServer)
+	enableInformers, kubeAPIServer = true, server.URL
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+		server.Close()
+		podLister, evictionHistory = nil, nil
+		nodeLister.replace(nil)
+		nodeImageInventory.flush()
+	})
+	startInformers(stop)
+	deadline := time.Now().Add(5 * time.Second)
+	for !fakeClusterSynced(len(cluster.Nodes)) {
+		if time.Now().After(deadline) {
+			t.Fatal("the informers did not sync with the fake cluster")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeClusterSynced reports whether the pod, eviction and node informers listed the fake cluster
+func fakeClusterSynced(nodes int) bool {
+	if podLister == nil || !podLister.HasSynced() || len(nodeLister.List()) != nodes {
+		return false
+	}
+	evictionHistory.lock.RLock()
+	defer evictionHistory.lock.RUnlock()
+	return evictionHistory.synced
+}
+
+// evictedOn returns an eviction event of the node, ago before now
+func evictedOn(node string, ago time.Duration) v1.Event {
+	return v1.Event{
+		Reason:        "Evicted",
+		Source:        v1.EventSource{Host: node},
+		LastTimestamp: metav1.NewTime(time.Now().Add(-ago)),
+	}
+}
+
+// replicaOn returns a running pod of the owner bound to the node
+func replicaOn(owner, name, node string) v1.Pod {
+	controller := true
+	pod := testPod("default", name, nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner, UID: k8stypes.UID("uid-" + owner), Controller: &controller}}
+	pod.Spec.NodeName = node
+	pod.Status.Phase = v1.PodRunning
+	return pod
+}
+
+func TestFakeClusterEndToEnd(t *testing.T) {
+	cluster := &fakeCluster{
+		Nodes: testNodes("node-a", "node-b", "node-c"),
+		Pods: []v1.Pod{
+			replicaOn("web", "web-1", "node-a"),
+			replicaOn("db", "db-1", "node-b"),
+		},
+		Events: []v1.Event{
+			evictedOn("node-a", time.Minute),
+			evictedOn("node-a", 10*time.Minute),
+			evictedOn("node-a", 20*time.Minute),
+			evictedOn("node-c", 2*time.Hour),
+			{Reason: "Killing", Source: v1.EventSource{Host: "node-b"}, LastTimestamp: metav1.Now()},
+		},
+	}
+	router := newTestRouter(t, EvictionRatePriority, OwnerStickinessPriority)
+
+	sensitive := testPod("default", "sensitive", map[string]string{stabilitySensitiveLabel: "true"})
+	web := replicaOn("web", "web-2", "")
+	web.Status.Phase = ""
+	tests := []struct {
+		name     string
+		method   string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"eviction_rate penalizes the recent evictions", "eviction_rate", sensitive, map[string]int{"node-a": 10 - 3*evictionPenalty, "node-b": 10, "node-c": 10}},
+		{"eviction_rate ignores the insensitive pods", "eviction_rate", testPod("default", "p", nil), map[string]int{"node-a": neutralScore, "node-b": neutralScore, "node-c": neutralScore}},
+		{"owner_stickiness favors the nodes of the owner", "owner_stickiness", web, map[string]int{"node-a": clampScore(neutralScore + ownerStickiness), "node-b": neutralScore, "node-c": neutralScore}},
+		{"owner_stickiness ignores the pods without owner", "owner_stickiness", testPod("default", "p", nil), map[string]int{"node-a": neutralScore, "node-b": neutralScore, "node-c": neutralScore}},
+	}
+
+	// while the informers have not synced, the informer backed priorities answer the neutral score
+	for _, test := range tests {
+		list := prioritize(t, router, test.method, test.pod, testNodes("node-a", "node-b", "node-c"))
+		checkScores(t, list, map[string]int{"node-a": neutralScore, "node-b": neutralScore, "node-c": neutralScore})
+	}
+
+	startFakeCluster(t, cluster)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := prioritize(t, router, test.method, test.pod, testNodes("node-a", "node-b", "node-c"))
+			checkScores(t, list, test.expected)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var filtersPrefix string
+var filterFailOpen bool
+
+func init() {
+	flag.StringVar(&filtersPrefix, "filters-prefix", "/my_new_filters", "The filters prefix path, e.g. /a_new_filters")
+	flag.BoolVar(&filterFailOpen, "filter-fail-open", false, "When a filter fails, let every node pass instead of failing them all")
+}
+
+// normalizeFiltersPrefix makes the filters prefix an absolute path, it is served under each api prefix
+func normalizeFiltersPrefix() {
+	if !strings.HasPrefix(filtersPrefix, "/") {
+		filtersPrefix = "/" + filtersPrefix
+		glog.Warningf("the -filters-prefix flag value was missing a `/`, it was automatically added -> %v", filtersPrefix)
+	}
+}
+
+// NodeFilter checks a single node, it returns whether the pod fits the node and, when it doesn't, the
+// reason reported to the scheduler
+type NodeFilter func(pod v1.Pod, node v1.Node) (bool, string, error)
+
+// FilterMethod defines the name of the filter. this name should much the one specified in the
+// scheduler config file, since it is part of the URL to be called by the scheduler.
+// Func is called for each node, concurrently across requests
+type FilterMethod struct {
+	Name string
+	Func NodeFilter
+	// Prepare replaces Func for the filters checking the nodes against a view of the cluster: it is called
+	// once per request, e.g. to index the pods of the lister by node, and returns the check of each node
+	Prepare func(pod v1.Pod) NodeFilter
+	// RequiresInformers is set for methods that need cluster state beyond the ExtenderArgs
+	RequiresInformers bool
+}
+
+// Handler takes as input the pod and a list of nodes and returns the nodes where the pod fits
+func (f FilterMethod) Handler(args schedulingapi.ExtenderArgs) (*schedulingapi.ExtenderFilterResult, error) {
+	check := f.Func
+	if f.Prepare != nil {
+		check = f.Prepare(*args.Pod)
+	}
+	var fitting []v1.Node
+	failed := make(schedulingapi.FailedNodesMap)
+	for _, node := range args.Nodes.Items {
+		fits, reason, err := check(*args.Pod, node)
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			fitting = append(fitting, node)
+			continue
+		}
+		failed[node.Name] = reason
+		glog.V(6).Infof("filter %v rejected node %v for pod %v: %v\n", f.Name, node.Name, args.Pod.Name, reason)
+	}
+	return &schedulingapi.ExtenderFilterResult{
+		Nodes:       &v1.NodeList{Items: fitting},
+		FailedNodes: failed,
+	}, nil
+}
+
+// FilterRoute returns an http handle
+func FilterRoute(filterMethod FilterMethod) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if !checkRequestBody(w, r) {
+			glog.Warning("received empty request!")
+			return
+		}
+		extenderArgs, err := decodeExtenderArgs(r.Body)
+		if err == nil && (extenderArgs.Nodes == nil || extenderArgs.NodeNames != nil) {
+			err = newError(ErrBadRequest, "the ExtenderArgs carry node names, filters need nodeCacheCapable to be false")
+		}
+		if err != nil {
+			glog.Warningf("filterMethod %v received an invalid request: %v", filterMethod.Name, err)
+			writeError(w, err)
+			return
+		}
+
+		if filterWarmingUp(filterMethod) {
+			if warmupMode == warmupModeUnavailable {
+				writeError(w, newError(ErrUnavailable, "filter method %v is warming up, the informers are not synced", filterMethod.Name))
+				return
+			}
+			glog.V(4).Infof("filterMethod %v is warming up, every node passes\n", filterMethod.Name)
+			writeFilterResult(w, filterMethod.Name, &schedulingapi.ExtenderFilterResult{
+				Nodes:       extenderArgs.Nodes,
+				FailedNodes: make(schedulingapi.FailedNodesMap),
+			})
+			return
+		}
+
+		defer recoverFailOpenFilter(w, filterMethod.Name, extenderArgs)
+		result, err := safeRunFilter(filterMethod, extenderArgs)
+		if err != nil {
+			glog.Errorf("filterMethod %v failed for pod %v (fail-open=%v): %v", filterMethod.Name, extenderArgs.Pod.Name, filterFailOpen || failOpen, err)
+			result = filterFailure(extenderArgs, err)
+			if filterFailOpen || failOpen {
+				countFailOpen(filterMethod.Name)
+			}
+		} else {
+			podCycles.get(*extenderArgs.Pod, time.Now()).reject(extenderArgs.Nodes.Items, result.FailedNodes)
+		}
+		writeFilterResult(w, filterMethod.Name, result)
+	}
+}
+
+// writeFilterResult answers the result of the filter
+func writeFilterResult(w http.ResponseWriter, name string, result *schedulingapi.ExtenderFilterResult) {
+	resultBody, err := json.Marshal(result)
+	if err != nil {
+		panic(err)
+	}
+	glog.V(4).Infof("filterMethod %v, failedNodes = %v\n ", name, result.FailedNodes)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
+
+// safeRunFilter runs the filter, turning its panics into errors
+func safeRunFilter(filterMethod FilterMethod, extenderArgs schedulingapi.ExtenderArgs) (result *schedulingapi.ExtenderFilterResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			recordPanic(filterMethod.Name, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return filterMethod.Handler(extenderArgs)
+}
+
+// filterFailure is the well-formed result answered when a filter fails. With -filter-fail-open, or
+// -fail-open, every node passes, otherwise the result carries the error, no node and every node failed
+// with the error, so the scheduler sees a failed extender whether it reads the error or the nodes
+func filterFailure(extenderArgs schedulingapi.ExtenderArgs, err error) *schedulingapi.ExtenderFilterResult {
+	if filterFailOpen || failOpen {
+		return &schedulingapi.ExtenderFilterResult{
+			Nodes:       extenderArgs.Nodes,
+			FailedNodes: make(schedulingapi.FailedNodesMap),
+		}
+	}
+	failed := make(schedulingapi.FailedNodesMap)
+	for _, node := range extenderArgs.Nodes.Items {
+		failed[node.Name] = "the filter failed: " + err.Error()
+	}
+	return &schedulingapi.ExtenderFilterResult{
+		Nodes:       &v1.NodeList{},
+		FailedNodes: failed,
+		Error:       err.Error(),
+	}
+}
+
+// AddFilterFunc adding the route path to the router
+func AddFilterFunc(router *httprouter.Router, filterMethod FilterMethod) {
+	handle := requireAuth(countPanics(filterMethod.Name, FilterRoute(filterMethod)))
+	for _, path := range prefixedPaths(filtersPrefix + "/" + filterMethod.Name) {
+		router.POST(path, handle)
+		glog.V(2).Infof("added filter method: %v at path: %v\n", filterMethod.Name, path)
+	}
+}
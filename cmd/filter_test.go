@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withFilterFailOpen sets -filter-fail-open and -fail-open until the end of the test
+func withFilterFailOpen(t *testing.T, filterOpen, open bool) {
+	savedFilterOpen, savedOpen := filterFailOpen, failOpen
+	t.Cleanup(func() { filterFailOpen, failOpen = savedFilterOpen, savedOpen })
+	filterFailOpen, failOpen = filterOpen, open
+}
+
+func TestFilterFailure(t *testing.T) {
+	nodes := testNodes("a", "b", "c")
+	failing := FilterMethod{
+		Name: "failing",
+		Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			if node.Name == "b" {
+				return false, "", errors.New("lookup failed")
+			}
+			return true, "", nil
+		},
+	}
+	panicking := FilterMethod{
+		Name: "panicking",
+		Func: func(pod v1.Pod, node v1.Node) (bool, string, error) { panic("boom") },
+	}
+	tests := []struct {
+		name                 string
+		filter               FilterMethod
+		filterOpen, failOpen bool
+		err                  string
+	}{
+		{"error failing closed", failing, false, false, "lookup failed"},
+		{"error with -filter-fail-open", failing, true, false, ""},
+		{"error with -fail-open", failing, false, true, ""},
+		{"panic failing closed", panicking, false, false, "panic: boom"},
+		{"panic with -filter-fail-open", panicking, true, false, ""},
+		{"panic with -fail-open", panicking, false, true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withFilterFailOpen(t, test.filterOpen, test.failOpen)
+			w, result := filterNodes(t, test.filter, testPod("default", "p", nil), nodes)
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v, expected a well-formed result", w.Code, w.Body.String())
+			}
+			if test.err == "" {
+				if passed := passedNodes(result); !reflect.DeepEqual(passed, []string{"a", "b", "c"}) || result.Error != "" || len(result.FailedNodes) != 0 {
+					t.Errorf("failing open passed %v, failed %v with error %q", passed, result.FailedNodes, result.Error)
+				}
+				return
+			}
+			if !strings.Contains(result.Error, test.err) || result.Nodes == nil || len(result.Nodes.Items) != 0 {
+				t.Errorf("failing closed answered %+v, expected no node and the error %q", result, test.err)
+			}
+			for _, node := range nodes {
+				if reason, ok := result.FailedNodes[node.Name]; !ok || !strings.Contains(reason, test.err) {
+					t.Errorf("node %v failed with %q, expected the error %q", node.Name, reason, test.err)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterHandler(t *testing.T) {
+	even := FilterMethod{
+		Name: "even",
+		Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			return (node.Name[len(node.Name)-1]-'0')%2 == 0, "odd node", nil
+		},
+	}
+	prepared := FilterMethod{
+		Name: "prepared",
+		Prepare: func(pod v1.Pod) NodeFilter {
+			return func(pod v1.Pod, node v1.Node) (bool, string, error) {
+				return node.Name == pod.Name, "not the pod name", nil
+			}
+		},
+	}
+	tests := []struct {
+		filter FilterMethod
+		passed []string
+		failed map[string]string
+	}{
+		{even, []string{"n2", "n4"}, map[string]string{"n1": "odd node", "n3": "odd node"}},
+		{prepared, []string{"n3"}, map[string]string{"n1": "not the pod name", "n2": "not the pod name", "n4": "not the pod name"}},
+	}
+	for _, test := range tests {
+		_, result := filterNodes(t, test.filter, testPod("default", "n3", nil), testNodes("n1", "n2", "n3", "n4"))
+		if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+			t.Errorf("%v passed %v, expected %v", test.filter.Name, passed, test.passed)
+		}
+		if !reflect.DeepEqual(map[string]string(result.FailedNodes), test.failed) {
+			t.Errorf("%v failed %v, expected %v", test.filter.Name, result.FailedNodes, test.failed)
+		}
+	}
+}
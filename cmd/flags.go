@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// informerFlags maps the flags configuring a method that needs the informers to the method name
+var informerFlags = map[string]string{
+	"daemon-selector":         "daemon_dependency",
+	"daemon-dependency-mode":  "daemon_dependency",
+	"eviction-window":         "eviction_rate",
+	"eviction-penalty":        "eviction_rate",
+	"gang-annotation":         "gang_locality",
+	"gang-topology-key":       "gang_locality",
+	"hypervisor-label":        "hypervisor_spread",
+	"image-popularity-mode":   "image_popularity",
+	"image-popularity-weight": "image_popularity",
+	"hypervisor-max-skew":     "hypervisor_spread",
+	"nodegroup-label":         "pool_density",
+	"outcome-ready-threshold": "placement_outcome",
+	"overcommit-tolerance":    "limits_overcommit",
+	"outcome-window":          "placement_outcome",
+	"owner-stickiness":        "owner_stickiness",
+	"owner-placement-window":  "owner_stickiness",
+	"score-table-configmap":   "score_table",
+	"shared-volume-bonus":     "shared_volumes",
+	"spread-topology-key":     "topology_spread",
+	"spread-max-skew":         "topology_spread",
+}
+
+// dependentFlags maps the flags only used along another flag to the flag they depend on
+var dependentFlags = map[string]string{
+	"informer-resync":           "enable-informers",
+	"stale-cache-age":           "enable-informers",
+	"panic-webhook-interval":    "panic-webhook-url",
+	"audit-log-max-bytes":       "audit-log-file",
+	"node-agent-path":           "node-agent-port",
+	"node-agent-timeout":        "node-agent-port",
+	"node-agent-ttl":            "node-agent-port",
+	"node-agent-weight":         "node-agent-port",
+	"all-poor-response":         "all-poor-threshold",
+	"all-poor-score":            "all-poor-threshold",
+	"circuit-window":            "circuit-error-threshold",
+	"circuit-cooldown":          "circuit-error-threshold",
+	"score-table-key":           "score-table-configmap",
+	"score-annotation":          "annotate-scores",
+	"score-annotation-interval": "annotate-scores",
+	"score-annotation-qps":      "annotate-scores",
+	"node-health-ttl":           "node-health-url",
+	"daemon-dependency-mode":    "daemon-selector",
+	"filter-fail-open":          "enable-filter",
+	"filters-prefix":            "enable-filter",
+	"prioritize-top-k":          "enable-prioritize",
+	"stable-tiebreak":           "enable-prioritize",
+	"annotate-scores":           "enable-prioritize",
+	"explain-buffer-size":       "enable-debug",
+}
+
+// validateFlags checks the flags set on the command line work together. Each flag is validated on its
+// own beforehand, this catches the combinations that would otherwise be silently ignored or fail at runtime
+func validateFlags() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return validateFlagSet(set)
+}
+
+// validateFlagSet checks the combinations of the named flags explicitly set with the current flag values
+func validateFlagSet(set map[string]bool) error {
+	var problems []string
+	for name := range set {
+		if method, ok := informerFlags[name]; ok && !enableInformers {
+			problems = append(problems, fmt.Sprintf("-%v configures %v, which needs -enable-informers", name, method))
+		}
+		if required, ok := dependentFlags[name]; ok && !flagEnabled(required) {
+			problems = append(problems, fmt.Sprintf("-%v has no effect without -%v", name, required))
+		}
+	}
+	if !enableFilter && !enablePrioritize {
+		problems = append(problems, "-enable-filter and -enable-prioritize are both false, the extender would serve no implemented verb")
+	}
+	if streamResponses && enableETag {
+		problems = append(problems, "-enable-etag hashes the whole response before sending it, it can not be used with -stream-responses")
+	}
+	if logFormat == "json" && !flagEnabled("logtostderr") && !flagEnabled("alsologtostderr") {
+		problems = append(problems, "-log-format=json converts the stderr logs, it needs -logtostderr or -alsologtostderr")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid flag combination:\n\t%v", strings.Join(problems, "\n\t"))
+}
+
+// flagEnabled returns whether the flag holds a value other than its zero value, e.g. a true bool or a non empty string
+func flagEnabled(name string) bool {
+	f := flag.Lookup(name)
+	if f == nil {
+		return false
+	}
+	switch f.Value.String() {
+	case "", "false", "0", "0s":
+		return false
+	}
+	return true
+}
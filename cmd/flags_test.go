@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+// withFlags sets the flag values through the flag package until the end of the test
+func withFlags(t *testing.T, values map[string]string) {
+	for name, value := range values {
+		f := flag.Lookup(name)
+		if f == nil {
+			t.Fatalf("unknown flag -%v", name)
+		}
+		saved := f.Value.String()
+		t.Cleanup(func() { f.Value.Set(saved) })
+		if err := f.Value.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidateFlagSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		set     []string
+		problem string
+	}{
+		{"defaults", nil, nil, ""},
+		{"informer method flag", map[string]string{"shared-volume-bonus": "2"}, []string{"shared-volume-bonus"}, "-shared-volume-bonus configures shared_volumes, which needs -enable-informers"},
+		{"informer method flag with informers", map[string]string{"shared-volume-bonus": "2", "enable-informers": "true"}, []string{"shared-volume-bonus", "enable-informers"}, ""},
+		{"dependent flag", map[string]string{"explain-buffer-size": "10"}, []string{"explain-buffer-size"}, "-explain-buffer-size has no effect without -enable-debug"},
+		{"dependent flag enabled", map[string]string{"explain-buffer-size": "10", "enable-debug": "true"}, []string{"explain-buffer-size", "enable-debug"}, ""},
+		{"filter only", map[string]string{"enable-prioritize": "false", "enable-filter": "true"}, []string{"enable-filter"}, ""},
+		{"dependent flag on a disabled verb", map[string]string{"enable-prioritize": "false", "enable-filter": "true"}, []string{"prioritize-top-k"}, "-prioritize-top-k has no effect without -enable-prioritize"},
+		{"no verb", map[string]string{"enable-prioritize": "false"}, nil, "the extender would serve no implemented verb"},
+		{"bind and preempt only", map[string]string{"enable-prioritize": "false", "enable-bind": "true", "enable-preempt": "true"}, nil, "the extender would serve no implemented verb"},
+		{"etag and streaming", map[string]string{"enable-etag": "true", "stream-responses": "true"}, nil, "it can not be used with -stream-responses"},
+		{"json logs to stderr", map[string]string{"log-format": "json"}, nil, ""},
+		{"json logs without stderr", map[string]string{"log-format": "json", "logtostderr": "false"}, nil, "-log-format=json converts the stderr logs"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withFlags(t, map[string]string{"logtostderr": "true"})
+			withFlags(t, test.values)
+			set := make(map[string]bool)
+			for _, name := range test.set {
+				set[name] = true
+			}
+			err := validateFlagSet(set)
+			switch {
+			case test.problem == "" && err != nil:
+				t.Errorf("validateFlagSet returned %v", err)
+			case test.problem != "" && (err == nil || !strings.Contains(err.Error(), test.problem)):
+				t.Errorf("validateFlagSet returned %v, expected %q", err, test.problem)
+			}
+		})
+	}
+}
+
+func TestValidateFlagSetProblems(t *testing.T) {
+	withFlags(t, map[string]string{"enable-prioritize": "false"})
+	err := validateFlagSet(map[string]bool{"spread-max-skew": true, "audit-log-max-bytes": true})
+	if err == nil {
+		t.Fatal("validateFlagSet accepted the combination")
+	}
+	lines := strings.Split(err.Error(), "\n\t")
+	if len(lines) != 4 {
+		t.Fatalf("validateFlagSet returned %q, expected the 3 problems on their own line", err)
+	}
+	for i := 2; i < len(lines); i++ {
+		if lines[i-1] > lines[i] {
+			t.Errorf("the problems are not sorted: %q", err)
+		}
+	}
+}
+
+func TestFlagEnabled(t *testing.T) {
+	withFlags(t, map[string]string{"enable-debug": "false", "prioritize-top-k": "0", "audit-log-file": "", "node-agent-port": "9100"})
+	for name, expected := range map[string]bool{"enable-debug": false, "prioritize-top-k": false, "audit-log-file": false, "node-agent-port": true, "unknown-flag": false} {
+		if enabled := flagEnabled(name); enabled != expected {
+			t.Errorf("flagEnabled(%v) returned %v", name, enabled)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var gangAnnotation, gangTopologyKey string
+
+func init() {
+	flag.StringVar(&gangAnnotation, "gang-annotation", "scheduler.extender/gang", "The pod annotation naming the gang, the group of pods of a namespace scheduled together, gang_locality places its members close together")
+	flag.StringVar(&gangTopologyKey, "gang-topology-key", "topology.kubernetes.io/zone", "The node label defining the topology domains gang_locality gathers the members of a gang in, e.g. a rack label")
+}
+
+// GangLocalityPriority places the members of a gang in the same domain of -gang-topology-key: the nodes
+// of the domain holding the most placed members get the max score, the other domains a score proportional
+// to their members. Pods outside a gang, the first members of a gang and the nodes without the topology
+// key get the neutral score
+var GangLocalityPriority = PrioritizeMethod{
+	Name:              "gang_locality",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		counts := gangDomainCounts(pod, nodes)
+		var maxCount int
+		for _, count := range counts {
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			domain, ok := node.Labels[gangTopologyKey]
+			if !ok || maxCount == 0 {
+				return neutralScore, nil
+			}
+			return schedulingapi.MaxPriority * counts[domain] / maxCount, nil
+		}
+	},
+}
+
+// gangDomainCounts counts the placed members of the gang of the pod in each domain, the domain of a
+// member comes from the candidate nodes or, when its node is not a candidate, from the node informer
+func gangDomainCounts(pod v1.Pod, nodes []v1.Node) map[string]int {
+	counts := make(map[string]int)
+	gang := pod.Annotations[gangAnnotation]
+	if gang == "" {
+		return counts
+	}
+	nodeDomains := make(map[string]string)
+	for _, known := range [][]v1.Node{nodeLister.List(), nodes} {
+		for _, node := range known {
+			if domain, ok := node.Labels[gangTopologyKey]; ok {
+				nodeDomains[normalizeNodeName(node.Name)] = domain
+			}
+		}
+	}
+	for _, other := range podLister.List() {
+		if other.Spec.NodeName == "" || other.Namespace != pod.Namespace || other.Annotations[gangAnnotation] != gang {
+			continue
+		}
+		if other.UID == pod.UID && pod.UID != "" {
+			continue
+		}
+		if domain, ok := nodeDomains[normalizeNodeName(other.Spec.NodeName)]; ok {
+			counts[domain]++
+		}
+	}
+	return counts
+}
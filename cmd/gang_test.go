@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// gangMember returns a pod of the gang in the namespace, bound to the node
+func gangMember(uid, namespace, gang, node string) v1.Pod {
+	pod := testPod(namespace, uid, nil)
+	pod.UID = k8stypes.UID(uid)
+	pod.Annotations = map[string]string{gangAnnotation: gang}
+	pod.Spec.NodeName = node
+	return pod
+}
+
+// domainNode returns a node in the domain of -gang-topology-key, outside any domain when empty
+func domainNode(name, domain string) v1.Node {
+	if domain == "" {
+		return testNodes(name)[0]
+	}
+	return labeledNode(name, map[string]string{gangTopologyKey: domain})
+}
+
+func TestGangDomainCounts(t *testing.T) {
+	nodeLister.replace([]v1.Node{domainNode("remote", "r3"), domainNode("r1-a", "r1")})
+	t.Cleanup(func() { nodeLister.replace(nil) })
+	withPods(t,
+		gangMember("1", "default", "g", "r1-a"),
+		gangMember("2", "default", "g", "r1-a"),
+		gangMember("3", "default", "g", "r2-a"),
+		gangMember("4", "default", "g", "remote"),
+		gangMember("5", "default", "g", "unknown"),
+		gangMember("6", "default", "g", ""),
+		gangMember("7", "other", "g", "r2-a"),
+		gangMember("8", "default", "h", "r2-a"),
+		gangMember("self", "default", "g", "r2-a"),
+	)
+	counts := gangDomainCounts(gangMember("self", "default", "g", ""), []v1.Node{domainNode("r2-a", "r2")})
+	expected := map[string]int{"r1": 2, "r2": 1, "r3": 1}
+	if len(counts) != len(expected) {
+		t.Errorf("counted %v, expected %v", counts, expected)
+	}
+	for domain, count := range expected {
+		if counts[domain] != count {
+			t.Errorf("counted %v, expected %v", counts, expected)
+			break
+		}
+	}
+}
+
+func TestGangLocalityPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	withPods(t,
+		gangMember("1", "default", "g", "r1-a"),
+		gangMember("2", "default", "g", "r1-a"),
+		gangMember("3", "default", "g", "r1-b"),
+		gangMember("4", "default", "g", "r2-a"),
+	)
+	nodes := []v1.Node{domainNode("r1-a", "r1"), domainNode("r1-b", "r1"), domainNode("r2-a", "r2"), domainNode("r3-a", "r3"), domainNode("bare", "")}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"gang member", gangMember("p", "default", "g", ""), map[string]int{"r1-a": 10, "r1-b": 10, "r2-a": 3, "r3-a": 0, "bare": 5}},
+		{"first member", gangMember("p", "default", "new", ""), map[string]int{"r1-a": 5, "r1-b": 5, "r2-a": 5, "r3-a": 5, "bare": 5}},
+		{"other namespace", gangMember("p", "other", "g", ""), map[string]int{"r1-a": 5, "r1-b": 5, "r2-a": 5, "r3-a": 5, "bare": 5}},
+		{"no gang", testPod("default", "p", nil), map[string]int{"r1-a": 5, "r1-b": 5, "r2-a": 5, "r3-a": 5, "bare": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, GangLocalityPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var acceleratorResources string
+var gpuNodePenalty int
+
+func init() {
+	flag.StringVar(&acceleratorResources, "accelerator-resources", "nvidia.com/gpu,amd.com/gpu", "The comma separated extended resources gpu_balance treats as accelerators")
+	flag.IntVar(&gpuNodePenalty, "gpu-node-penalty", 5, "The points gpu_balance takes off the GPU nodes for the pods requesting no accelerator, from 0 to 10")
+}
+
+// validateGPUBalance makes sure the penalty is a score and some accelerator resource is named
+func validateGPUBalance() error {
+	if gpuNodePenalty < 0 || gpuNodePenalty > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -gpu-node-penalty flag must be between 0 and %v, got %v", schedulingapi.MaxPriority, gpuNodePenalty)
+	}
+	if len(acceleratorResourceNames()) == 0 {
+		return fmt.Errorf("the -accelerator-resources flag names no resource")
+	}
+	return nil
+}
+
+// acceleratorResourceNames returns the resources of -accelerator-resources
+func acceleratorResourceNames() []v1.ResourceName {
+	var names []v1.ResourceName
+	for _, name := range strings.Split(acceleratorResources, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, v1.ResourceName(name))
+		}
+	}
+	return names
+}
+
+// nodeHasAccelerators reports whether the node has some accelerator allocatable
+func nodeHasAccelerators(node v1.Node) bool {
+	for _, name := range acceleratorResourceNames() {
+		if nodeAllocatable(node, name) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// podRequestsAccelerators reports whether the pod asks for some accelerator, extended resources may be set
+// as limits only
+func podRequestsAccelerators(pod v1.Pod) bool {
+	for _, name := range acceleratorResourceNames() {
+		if podRequest(pod, name) > 0 || podLimit(pod, name) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GPUBalancePriority softly reserves the GPU nodes for the GPU workloads: the pods requesting an
+// accelerator prefer the GPU nodes, the others score the GPU nodes -gpu-node-penalty points lower than
+// the nodes without accelerators. Every node gets the neutral score when none of them has accelerators
+var GPUBalancePriority = PrioritizeMethod{
+	Name: "gpu_balance",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		var anyGPUNode bool
+		for _, node := range nodes {
+			anyGPUNode = anyGPUNode || nodeHasAccelerators(node)
+		}
+		wantsGPU := podRequestsAccelerators(pod)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			gpuNode := nodeHasAccelerators(node)
+			switch {
+			case !anyGPUNode:
+				return neutralScore, nil
+			case wantsGPU && gpuNode:
+				return schedulingapi.MaxPriority, nil
+			case wantsGPU:
+				return 0, nil
+			case gpuNode:
+				return schedulingapi.MaxPriority - gpuNodePenalty, nil
+			}
+			return schedulingapi.MaxPriority, nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		return fmt.Sprintf("pod requests accelerators: %v, node has accelerators: %v", podRequestsAccelerators(pod), nodeHasAccelerators(node))
+	},
+}
@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// withGPUBalance sets -accelerator-resources and -gpu-node-penalty until the end of the test
+func withGPUBalance(t *testing.T, resources string, penalty int) {
+	savedResources, savedPenalty := acceleratorResources, gpuNodePenalty
+	t.Cleanup(func() { acceleratorResources, gpuNodePenalty = savedResources, savedPenalty })
+	acceleratorResources, gpuNodePenalty = resources, penalty
+}
+
+// gpuNode returns a node with the count of the accelerator allocatable, none when zero
+func gpuNode(name string, accelerator v1.ResourceName, count int64) v1.Node {
+	node := allocatableNode(name, "8", "32Gi")
+	if count > 0 {
+		node.Status.Allocatable[accelerator] = *resource.NewQuantity(count, resource.DecimalSI)
+	}
+	return node
+}
+
+// gpuPod returns a pod with the count of the accelerator as request and limit, or as limit only
+func gpuPod(accelerator v1.ResourceName, count int64, limitOnly bool) v1.Pod {
+	quantity := v1.ResourceList{accelerator: *resource.NewQuantity(count, resource.DecimalSI)}
+	if limitOnly {
+		return resourcePod("p", "", nil, quantity)
+	}
+	return resourcePod("p", "", quantity, quantity)
+}
+
+func TestValidateGPUBalance(t *testing.T) {
+	for _, test := range []struct {
+		resources string
+		penalty   int
+		valid     bool
+	}{
+		{"nvidia.com/gpu,amd.com/gpu", 5, true},
+		{"nvidia.com/gpu", 0, true},
+		{"nvidia.com/gpu", 10, true},
+		{"nvidia.com/gpu", -1, false},
+		{"nvidia.com/gpu", 11, false},
+		{"", 5, false},
+		{" , ", 5, false},
+	} {
+		withGPUBalance(t, test.resources, test.penalty)
+		if err := validateGPUBalance(); (err == nil) != test.valid {
+			t.Errorf("-accelerator-resources=%q -gpu-node-penalty=%v: got %v, expected valid %v", test.resources, test.penalty, err, test.valid)
+		}
+	}
+}
+
+func TestGPUBalancePriority(t *testing.T) {
+	const nvidia, amd, other = v1.ResourceName("nvidia.com/gpu"), v1.ResourceName("amd.com/gpu"), v1.ResourceName("example.com/fpga")
+	mixed := []v1.Node{gpuNode("nvidia", nvidia, 2), gpuNode("amd", amd, 1), gpuNode("cpu", nvidia, 0)}
+	cpuOnly := []v1.Node{gpuNode("a", nvidia, 0), gpuNode("b", nvidia, 0)}
+	for _, test := range []struct {
+		name     string
+		penalty  int
+		pod      v1.Pod
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{"gpu pod", 5, gpuPod(nvidia, 1, false), mixed, map[string]int{"nvidia": 10, "amd": 10, "cpu": 0}},
+		{"gpu pod with a limit only", 5, gpuPod(amd, 1, true), mixed, map[string]int{"nvidia": 10, "amd": 10, "cpu": 0}},
+		{"cpu pod", 5, testPod("default", "p", nil), mixed, map[string]int{"nvidia": 5, "amd": 5, "cpu": 10}},
+		{"cpu pod without penalty", 0, testPod("default", "p", nil), mixed, map[string]int{"nvidia": 10, "amd": 10, "cpu": 10}},
+		{"other extended resource", 5, gpuPod(other, 1, false), mixed, map[string]int{"nvidia": 5, "amd": 5, "cpu": 10}},
+		{"no gpu node", 5, gpuPod(nvidia, 1, false), cpuOnly, map[string]int{"a": 5, "b": 5}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withGPUBalance(t, "nvidia.com/gpu, amd.com/gpu", test.penalty)
+			checkScores(t, scoreMethod(t, GPUBalancePriority, test.pod, test.nodes), test.expected)
+		})
+	}
+}
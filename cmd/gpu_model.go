@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+var gpuModelAnnotation, gpuModelLabel string
+
+func init() {
+	flag.StringVar(&gpuModelAnnotation, "gpu-model-annotation", "scheduler.extender/gpu-model", "The pod annotation holding the required GPU model, e.g. A100")
+	flag.StringVar(&gpuModelLabel, "gpu-model-label", "nvidia.com/gpu.product", "The node label holding the GPU model of the node")
+}
+
+// GPUModelFilter rejects the nodes whose GPU model doesn't match the one required by the pod annotation.
+// The match is a case insensitive substring one, so A100 matches a node labeled NVIDIA-A100-SXM4-40GB.
+// Pods without the annotation fit every node
+var GPUModelFilter = FilterMethod{
+	Name: "gpu_model",
+	Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+		required, ok := pod.Annotations[gpuModelAnnotation]
+		if !ok || required == "" {
+			return true, "", nil
+		}
+		model, ok := node.Labels[gpuModelLabel]
+		if !ok {
+			return false, fmt.Sprintf("node has no %v label, pod requires GPU model %v", gpuModelLabel, required), nil
+		}
+		if !strings.Contains(strings.ToLower(model), strings.ToLower(required)) {
+			return false, fmt.Sprintf("node GPU model %v does not match the required model %v", model, required), nil
+		}
+		return true, "", nil
+	},
+}
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// annotatedPod returns a pod with the annotations
+func annotatedPod(annotations map[string]string) v1.Pod {
+	pod := testPod("default", "p", nil)
+	pod.Annotations = annotations
+	return pod
+}
+
+func TestGPUModelFilter(t *testing.T) {
+	nodes := []v1.Node{
+		labeledNode("a100", map[string]string{gpuModelLabel: "NVIDIA-A100-SXM4-40GB"}),
+		labeledNode("t4", map[string]string{gpuModelLabel: "Tesla-T4"}),
+		labeledNode("cpu", nil),
+	}
+	tests := []struct {
+		name   string
+		pod    v1.Pod
+		passed []string
+	}{
+		{"no annotation", testPod("default", "p", nil), []string{"a100", "t4", "cpu"}},
+		{"empty annotation", annotatedPod(map[string]string{gpuModelAnnotation: ""}), []string{"a100", "t4", "cpu"}},
+		{"substring match", annotatedPod(map[string]string{gpuModelAnnotation: "A100"}), []string{"a100"}},
+		{"case insensitive", annotatedPod(map[string]string{gpuModelAnnotation: "tesla-t4"}), []string{"t4"}},
+		{"no node matching", annotatedPod(map[string]string{gpuModelAnnotation: "H100"}), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := filterNodes(t, GPUModelFilter, test.pod, nodes)
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+				t.Errorf("expected %v to pass, got %v, rejected %v", test.passed, passed, result.FailedNodes)
+			}
+			for node, reason := range result.FailedNodes {
+				if reason == "" {
+					t.Errorf("node %v was rejected without a reason", node)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, expected := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"deflate, gzip":        true,
+		"gzip;q=0.5, identity": true,
+		"gzip; q=0":            false,
+		"gzip;q=0.000":         false,
+		"identity":             false,
+		"x-gzip":               false,
+		"br, GZIP":             true,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if accepts := acceptsGzip(r); accepts != expected {
+			t.Errorf("acceptsGzip(%q) returned %v", header, accepts)
+		}
+	}
+}
+
+func TestGzipped(t *testing.T) {
+	payload := strings.Repeat("extender_metric 1\n", 100)
+	handle := gzipped(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, payload)
+	})
+	tests := []struct {
+		name     string
+		encoding string
+		gzipped  bool
+	}{
+		{"gzip", "gzip", true},
+		{"identity", "identity", false},
+		{"refused gzip", "gzip;q=0", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			r.Header.Set("Accept-Encoding", test.encoding)
+			w := httptest.NewRecorder()
+			handle(w, r, nil)
+			if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("answered Vary %q", vary)
+			}
+			body := w.Body.String()
+			if test.gzipped {
+				if encoding := w.Header().Get("Content-Encoding"); encoding != "gzip" {
+					t.Fatalf("answered Content-Encoding %q", encoding)
+				}
+				if length := w.Header().Get("Content-Length"); length != "" {
+					t.Errorf("kept the uncompressed Content-Length %v", length)
+				}
+				reader, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				decompressed, err := ioutil.ReadAll(reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(decompressed)
+			} else if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+				t.Errorf("answered Content-Encoding %q", encoding)
+			}
+			if body != payload {
+				t.Errorf("answered %q", body)
+			}
+		})
+	}
+}
+
+func TestGzippedMetricsRoute(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gzipped(MetricsRoute)(w, r, nil)
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("the metrics are not gzipped: %v", err)
+	}
+	metrics, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(metrics), "# TYPE extender_") {
+		t.Errorf("the gzipped metrics hold %q", metrics)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// testNodes returns bare nodes of the given names
+func testNodes(names ...string) []v1.Node {
+	nodes := make([]v1.Node, len(names))
+	for i, name := range names {
+		nodes[i].Name = name
+	}
+	return nodes
+}
+
+// testPodLister is a PodLister over a fixed list of pods, synced unless unsynced is set
+type testPodLister struct {
+	pods     []v1.Pod
+	unsynced bool
+}
+
+func (l *testPodLister) List() []v1.Pod {
+	return l.pods
+}
+
+func (l *testPodLister) HasSynced() bool {
+	return !l.unsynced
+}
+
+// withPods serves the pods from podLister until the end of the test
+func withPods(t *testing.T, pods ...v1.Pod) {
+	saved := podLister
+	t.Cleanup(func() { podLister = saved })
+	podLister = &testPodLister{pods: pods}
+}
+
+// testPod returns a pod of the given namespace, name and labels
+func testPod(namespace, name string, labels map[string]string) v1.Pod {
+	return v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+}
+
+// scoresByHost indexes the scores of the list by host
+func scoresByHost(list schedulingapi.HostPriorityList) map[string]int {
+	scores := make(map[string]int, len(list))
+	for _, hp := range list {
+		scores[hp.Host] = hp.Score
+	}
+	return scores
+}
+
+// checkScores fails the test unless the list holds exactly the expected scores
+func checkScores(t *testing.T, list schedulingapi.HostPriorityList, expected map[string]int) {
+	t.Helper()
+	got := scoresByHost(list)
+	if len(got) != len(expected) || len(list) != len(expected) {
+		t.Fatalf("expected the scores %v, got %v", expected, list)
+	}
+	for host, score := range expected {
+		if got[host] != score {
+			t.Fatalf("expected the scores %v, got %v", expected, list)
+		}
+	}
+}
+
+// scoreMethod scores the nodes with the priority method, without the route
+func scoreMethod(t *testing.T, method PrioritizeMethod, pod v1.Pod, nodes []v1.Node) schedulingapi.HostPriorityList {
+	t.Helper()
+	list, err := method.scorer().Score(context.Background(), pod, nodes)
+	if err != nil {
+		t.Fatalf("%v failed: %v", method.Name, err)
+	}
+	return list
+}
+
+// newTestRouter serves the priority methods as main does, without a -config file. The registry and the
+// active snapshot are restored by the test cleanup
+func newTestRouter(t testing.TB, methods ...PrioritizeMethod) *httprouter.Router {
+	t.Helper()
+	registryLock.Lock()
+	savedMethods, savedPaths := registeredMethods, registeredPaths
+	registeredMethods, registeredPaths = nil, make(map[string][]string)
+	registryLock.Unlock()
+	savedSnapshot := currentSnapshot()
+	t.Cleanup(func() {
+		registryLock.Lock()
+		registeredMethods, registeredPaths = savedMethods, savedPaths
+		registryLock.Unlock()
+		activeSnapshot.Store(savedSnapshot)
+	})
+	if err := parseAPIPrefixes(); err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	for _, method := range methods {
+		AddPrioritizeFunc(router, method)
+	}
+	activeSnapshot.Store(newSnapshot(nil))
+	return router
+}
+
+// prioritize posts the pod and the nodes to the route of the priority method and decodes the scores
+func prioritize(t *testing.T, router http.Handler, method string, pod v1.Pod, nodes []v1.Node) schedulingapi.HostPriorityList {
+	t.Helper()
+	body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+method, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%v answered %v: %v", method, w.Code, w.Body.String())
+	}
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("%v answered an invalid list %q: %v", method, w.Body.String(), err)
+	}
+	return list
+}
+
+// filterNodes posts the pod and the nodes to the filter route and decodes the result
+func filterNodes(t *testing.T, filterMethod FilterMethod, pod v1.Pod, nodes []v1.Node) (*httptest.ResponseRecorder, schedulingapi.ExtenderFilterResult) {
+	t.Helper()
+	body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	FilterRoute(filterMethod)(w, httptest.NewRequest(http.MethodPost, "/filter", bytes.NewReader(body)), nil)
+	var result schedulingapi.ExtenderFilterResult
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%v answered an invalid result %q: %v", filterMethod.Name, w.Body.String(), err)
+		}
+	}
+	return w, result
+}
+
+// passedNodes returns the names of the nodes the filter let through
+func passedNodes(result schedulingapi.ExtenderFilterResult) []string {
+	var names []string
+	if result.Nodes != nil {
+		for _, node := range result.Nodes.Items {
+			names = append(names, node.Name)
+		}
+	}
+	return names
+}
+
+// imagePod returns a pod with a container running each image
+func imagePod(images ...string) v1.Pod {
+	pod := testPod("default", "p", nil)
+	for i, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: fmt.Sprintf("c%v", i), Image: image})
+	}
+	return pod
+}
+
+// imageNode returns a node holding the images, given with their size in bytes
+func imageNode(name string, images map[string]int64) v1.Node {
+	node := testNodes(name)[0]
+	for image, size := range images {
+		node.Status.Images = append(node.Status.Images, v1.ContainerImage{Names: []string{image}, SizeBytes: size})
+	}
+	return node
+}
+
+// resourceList returns the cpu and memory quantities, an empty quantity is left out
+func resourceList(cpu, memory string) v1.ResourceList {
+	list := make(v1.ResourceList)
+	if cpu != "" {
+		list[v1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[v1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+// resourcePod returns a pod bound to the node, its single container has the requests and limits
+func resourcePod(name, node string, requests, limits v1.ResourceList) v1.Pod {
+	pod := testPod("default", name, nil)
+	pod.Spec.NodeName = node
+	pod.Spec.Containers = []v1.Container{{Name: "main", Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}}
+	return pod
+}
+
+// extenderArgsOf returns the ExtenderArgs of the pod and the nodes
+func extenderArgsOf(pod v1.Pod, nodes []v1.Node) schedulingapi.ExtenderArgs {
+	return schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}}
+}
+
+// withConfig makes the config active until the end of the test
+func withConfig(t *testing.T, config *extenderConfig) {
+	saved := currentSnapshot()
+	t.Cleanup(func() { activeSnapshot.Store(saved) })
+	activeSnapshot.Store(newSnapshot(config))
+}
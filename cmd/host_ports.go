@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// HostPortsFilter rejects the nodes where a pod of the lister already claims a host port the pod
+// requests. It duplicates the scheduler's own host port predicate with the fresher view of the informers,
+// which also sees the pods bound since the scheduler cache was last updated
+var HostPortsFilter = FilterMethod{
+	Name:              "host_ports",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod) NodeFilter {
+		wanted := podHostPorts(pod)
+		var byNode nodePods
+		if len(wanted) > 0 {
+			byNode = podsByNode(podLister)
+		}
+		return func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			for _, other := range byNode.on(node.Name) {
+				if other.UID == pod.UID {
+					continue
+				}
+				for _, used := range podHostPorts(other) {
+					for _, port := range wanted {
+						if hostPortsConflict(port, used) {
+							return false, fmt.Sprintf("host port %v/%v is already used by pod %v/%v", port.Protocol, port.HostPort, other.Namespace, other.Name), nil
+						}
+					}
+				}
+			}
+			return true, "", nil
+		}
+	},
+}
+
+// podHostPorts returns the container ports of the pod bound to a host port, with the protocol defaulted
+func podHostPorts(pod v1.Pod) []v1.ContainerPort {
+	var ports []v1.ContainerPort
+	for _, ctnr := range pod.Spec.Containers {
+		for _, port := range ctnr.Ports {
+			if port.HostPort <= 0 {
+				continue
+			}
+			if port.Protocol == "" {
+				port.Protocol = v1.ProtocolTCP
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// hostPortsConflict reports whether two host ports can't be bound on the same node: same port and
+// protocol, on the same host IP or when either binds all the addresses
+func hostPortsConflict(a, b v1.ContainerPort) bool {
+	if a.HostPort != b.HostPort || a.Protocol != b.Protocol {
+		return false
+	}
+	return isWildcardIP(a.HostIP) || isWildcardIP(b.HostIP) || a.HostIP == b.HostIP
+}
+
+func isWildcardIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
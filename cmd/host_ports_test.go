@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// portsPod returns a pod bound to the node with a container of the host ports
+func portsPod(name, node string, ports ...v1.ContainerPort) v1.Pod {
+	pod := testPod("default", name, nil)
+	pod.UID = types.UID(name)
+	pod.Spec.NodeName = node
+	pod.Spec.Containers = []v1.Container{{Name: "main", Ports: ports}}
+	return pod
+}
+
+func TestHostPortsFilter(t *testing.T) {
+	withPods(t,
+		portsPod("dns", "a", v1.ContainerPort{HostPort: 53, Protocol: v1.ProtocolUDP}),
+		portsPod("web", "b", v1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.1"}),
+		portsPod("proxy", "c", v1.ContainerPort{HostPort: 8080}),
+		portsPod("plain", "d", v1.ContainerPort{ContainerPort: 8080}),
+	)
+	nodes := testNodes("a", "b", "c", "d")
+	tests := []struct {
+		name   string
+		pod    v1.Pod
+		passed []string
+	}{
+		{"no host port passes everywhere", portsPod("p", ""), []string{"a", "b", "c", "d"}},
+		{"same port other protocol", portsPod("p", "", v1.ContainerPort{HostPort: 53}), []string{"a", "b", "c", "d"}},
+		{"same port and protocol", portsPod("p", "", v1.ContainerPort{HostPort: 53, Protocol: v1.ProtocolUDP}), []string{"b", "c", "d"}},
+		{"wildcard conflicts with any host IP", portsPod("p", "", v1.ContainerPort{HostPort: 8080}), []string{"a", "d"}},
+		{"distinct host IPs", portsPod("p", "", v1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.2"}), []string{"a", "b", "d"}},
+		{"a pod does not conflict with itself", portsPod("proxy", "", v1.ContainerPort{HostPort: 8080, HostIP: "10.0.0.1"}), []string{"a", "c", "d"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := filterNodes(t, HostPortsFilter, test.pod, nodes)
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+				t.Errorf("expected %v to pass, got %v, rejected %v", test.passed, passed, result.FailedNodes)
+			}
+		})
+	}
+}
+
+func TestHostPortsConflict(t *testing.T) {
+	tests := []struct {
+		a, b     v1.ContainerPort
+		conflict bool
+	}{
+		{v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP}, v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP}, true},
+		{v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP}, v1.ContainerPort{HostPort: 81, Protocol: v1.ProtocolTCP}, false},
+		{v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "0.0.0.0"}, v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "10.0.0.1"}, true},
+		{v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "::"}, v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "10.0.0.1"}, true},
+		{v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "10.0.0.2"}, v1.ContainerPort{HostPort: 80, Protocol: v1.ProtocolTCP, HostIP: "10.0.0.1"}, false},
+	}
+	for _, test := range tests {
+		if conflict := hostPortsConflict(test.a, test.b); conflict != test.conflict {
+			t.Errorf("hostPortsConflict(%v, %v) = %v, expected %v", test.a, test.b, conflict, test.conflict)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var hypervisorLabel string
+var hypervisorMaxSkew int
+
+func init() {
+	flag.StringVar(&hypervisorLabel, "hypervisor-label", "topology.example.com/hypervisor", "The node label identifying the physical host of a virtual node, used by hypervisor_spread")
+	flag.IntVar(&hypervisorMaxSkew, "hypervisor-max-skew", 0, "How many sibling pods a hypervisor may hold above the least populated one before hypervisor_spread lowers its score")
+}
+
+// HypervisorSpreadPriority spreads the pods of an owner across the physical hosts of a virtualized cluster.
+// Several nodes share a hypervisor, so spreading across nodes alone may still put the replicas on the
+// same machine: nodes on the hypervisors holding the fewest siblings get the max score
+var HypervisorSpreadPriority = PrioritizeMethod{
+	Name:              "hypervisor_spread",
+	RequiresInformers: true,
+	Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+		list := spreadScores(pod, nodes, hypervisorLabel, hypervisorMaxSkew)
+		return &list, nil
+	},
+}
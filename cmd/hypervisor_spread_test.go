@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestHypervisorSpreadPriority(t *testing.T) {
+	savedLabel, savedSkew := hypervisorLabel, hypervisorMaxSkew
+	defer func() { hypervisorLabel, hypervisorMaxSkew = savedLabel, savedSkew }()
+	hypervisorLabel = "topology.example.com/hypervisor"
+	nodes := []v1.Node{
+		labeledNode("vm1", map[string]string{hypervisorLabel: "h1"}),
+		labeledNode("vm2", map[string]string{hypervisorLabel: "h1"}),
+		labeledNode("vm3", map[string]string{hypervisorLabel: "h2"}),
+		labeledNode("bare", nil),
+	}
+	created := time.Now()
+	pod := ownedPod("new", "rs", "", created, time.Time{}, 0)
+	tests := []struct {
+		name     string
+		siblings []v1.Pod
+		maxSkew  int
+		expected map[string]int
+	}{
+		// vm2 runs no sibling, but shares the hypervisor of vm1
+		{"shared hypervisor", []v1.Pod{ownedPod("s1", "rs", "vm1", created, time.Time{}, 0)}, 0, map[string]int{"vm1": 0, "vm2": 0, "vm3": 10, "bare": neutralScore}},
+		{"tolerated skew", []v1.Pod{ownedPod("s1", "rs", "vm1", created, time.Time{}, 0)}, 1, map[string]int{"vm1": 10, "vm2": 10, "vm3": 10, "bare": neutralScore}},
+		{"even hypervisors", []v1.Pod{ownedPod("s1", "rs", "vm2", created, time.Time{}, 0), ownedPod("s2", "rs", "vm3", created, time.Time{}, 0)}, 0, map[string]int{"vm1": 10, "vm2": 10, "vm3": 10, "bare": neutralScore}},
+		{"sibling on a node without hypervisor", []v1.Pod{ownedPod("s1", "rs", "bare", created, time.Time{}, 0)}, 0, map[string]int{"vm1": neutralScore, "vm2": neutralScore, "vm3": neutralScore, "bare": neutralScore}},
+		{"no sibling", nil, 0, map[string]int{"vm1": neutralScore, "vm2": neutralScore, "vm3": neutralScore, "bare": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hypervisorMaxSkew = test.maxSkew
+			withPods(t, test.siblings...)
+			checkScores(t, scoreMethod(t, HypervisorSpreadPriority, pod, nodes), test.expected)
+		})
+	}
+}
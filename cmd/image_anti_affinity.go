@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var avoidImagesAnnotation string
+
+func init() {
+	flag.StringVar(&avoidImagesAnnotation, "avoid-images-annotation", "scheduler.extender/avoid-images", "The pod annotation listing, comma separated, substrings of the images the pod should not share a node with")
+}
+
+// ImageAntiAffinityPriority keeps the pod away from the nodes running images it flags as risky: such
+// nodes score 0 and the others the max score. The running images come from the pods of the lister when
+// the informers are enabled, from the images present on the node otherwise. Pods without the annotation
+// get the neutral score
+var ImageAntiAffinityPriority = PrioritizeMethod{
+	Name: "image_anti_affinity",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		avoided := avoidedImages(pod)
+		var byNode nodePods
+		if podLister != nil {
+			byNode = podsByNode(podLister)
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			switch {
+			case len(avoided) == 0:
+				return neutralScore, nil
+			case nodeRunsImage(node, byNode, avoided):
+				return 0, nil
+			}
+			return schedulingapi.MaxPriority, nil
+		}
+	},
+}
+
+// avoidedImages returns the image substrings listed by the pod annotation
+func avoidedImages(pod v1.Pod) []string {
+	var images []string
+	for _, image := range strings.Split(pod.Annotations[avoidImagesAnnotation], ",") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// nodeRunsImage reports whether the node runs one of the images, it is the image matching in reverse: the
+// images of the node are matched against the avoided ones with the legacy substring matching
+func nodeRunsImage(node v1.Node, byNode nodePods, images []string) bool {
+	var running []string
+	if byNode != nil {
+		for _, pod := range byNode.on(node.Name) {
+			for _, ctnr := range pod.Spec.Containers {
+				running = append(running, ctnr.Image)
+			}
+		}
+	} else {
+		for _, image := range nodeImages(node) {
+			running = append(running, image.Names...)
+		}
+	}
+	for _, name := range running {
+		for _, image := range images {
+			if substringMatch(name, image) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestAvoidedImages(t *testing.T) {
+	tests := []struct {
+		annotation string
+		expected   []string
+	}{
+		{"cryptominer,legacy/openssl:1.0", []string{"cryptominer", "legacy/openssl:1.0"}},
+		{" cryptominer , ", []string{"cryptominer"}},
+		{" , ", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		pod := annotatedPod(map[string]string{avoidImagesAnnotation: test.annotation})
+		if images := avoidedImages(pod); !reflect.DeepEqual(images, test.expected) {
+			t.Errorf("read %q as %v, expected %v", test.annotation, images, test.expected)
+		}
+	}
+}
+
+func TestImageAntiAffinityPriority(t *testing.T) {
+	running := func(name, node string, images ...string) v1.Pod {
+		pod := imagePod(images...)
+		pod.Name, pod.Spec.NodeName = name, node
+		return pod
+	}
+	nodes := []v1.Node{
+		imageNode("flagged", map[string]int64{"docker.io/evil/cryptominer:1": mb}),
+		imageNode("clean", map[string]int64{"docker.io/library/nginx:1.19": mb}),
+		imageNode("cached", map[string]int64{"docker.io/evil/cryptominer:1": mb}),
+	}
+	pods := []v1.Pod{
+		running("miner", "flagged", "nginx:1.19", "evil/cryptominer:1"),
+		running("web", "clean", "nginx:1.19"),
+		running("other", "cached", "redis"),
+	}
+	wary := annotatedPod(map[string]string{avoidImagesAnnotation: "cryptominer, openssl:1.0"})
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		lister   PodLister
+		expected map[string]int
+	}{
+		// the image cached on a node is not running there
+		{"running pods", wary, &testPodLister{pods: pods}, map[string]int{"flagged": 0, "clean": 10, "cached": 10}},
+		{"node images without informers", wary, nil, map[string]int{"flagged": 0, "clean": 10, "cached": 0}},
+		{"no flagged image running", annotatedPod(map[string]string{avoidImagesAnnotation: "openssl:1.0"}), &testPodLister{pods: pods}, map[string]int{"flagged": 10, "clean": 10, "cached": 10}},
+		{"no annotation", annotatedPod(nil), &testPodLister{pods: pods}, map[string]int{"flagged": neutralScore, "clean": neutralScore, "cached": neutralScore}},
+		{"empty annotation", annotatedPod(map[string]string{avoidImagesAnnotation: ","}), nil, map[string]int{"flagged": neutralScore, "clean": neutralScore, "cached": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withPodLister(t, test.lister)
+			checkScores(t, scoreMethod(t, ImageAntiAffinityPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
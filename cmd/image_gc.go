@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var imageGCHighWatermark, imageGCLowWatermark float64
+
+func init() {
+	flag.Float64Var(&imageGCHighWatermark, "image-gc-high-watermark", 85, "The disk usage percent assumed to trigger the kubelet image garbage collection, the kubelet --image-gc-high-threshold")
+	flag.Float64Var(&imageGCLowWatermark, "image-gc-low-watermark", 80, "The disk usage percent below which the image garbage collection is assumed not to run, the kubelet --image-gc-low-threshold")
+}
+
+// validateImageGCWatermarks makes sure the watermarks are ordered percents
+func validateImageGCWatermarks() error {
+	if imageGCLowWatermark < 0 || imageGCHighWatermark > 100 || imageGCLowWatermark >= imageGCHighWatermark {
+		return fmt.Errorf("the image GC watermarks must satisfy 0 <= -image-gc-low-watermark < -image-gc-high-watermark <= 100, got %v and %v", imageGCLowWatermark, imageGCHighWatermark)
+	}
+	return nil
+}
+
+// ImageGCRiskPriority is an image locality score discounted by the image GC pressure of the node: a
+// cached image only counts fully on nodes below the low watermark, the discount grows linearly up to
+// the high watermark where the cached images are likely evicted soon and count for nothing
+var ImageGCRiskPriority = PrioritizeMethod{
+	Name: "image_gc_risk",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if len(pod.Spec.Containers) == 0 {
+				return 0, nil
+			}
+			cached := float64(nodeHasImage(pod, nodeImages(node), node.Name)) / float64(len(pod.Spec.Containers))
+			return int(schedulingapi.MaxPriority * cached * (1 - imageGCPressure(node))), nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		return fmt.Sprintf("%v of the %v container images found on the node, image GC pressure %.2f", nodeHasImage(pod, nodeImages(node), node.Name), len(pod.Spec.Containers), imageGCPressure(node))
+	},
+}
+
+// imageGCPressure estimates from 0 to 1 how close the node is to garbage collecting its images, from the
+// bytes of its images over its ephemeral storage capacity. Nodes not reporting their capacity have no pressure
+func imageGCPressure(node v1.Node) float64 {
+	capacity, ok := node.Status.Capacity[v1.ResourceEphemeralStorage]
+	if !ok || capacity.Value() <= 0 {
+		return 0
+	}
+	var imageBytes int64
+	for _, image := range nodeImages(node) {
+		imageBytes += image.SizeBytes
+	}
+	usage := 100 * float64(imageBytes) / float64(capacity.Value())
+	switch {
+	case usage <= imageGCLowWatermark:
+		return 0
+	case usage >= imageGCHighWatermark:
+		return 1
+	}
+	return (usage - imageGCLowWatermark) / (imageGCHighWatermark - imageGCLowWatermark)
+}
@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// withImageGCWatermarks sets the image GC watermarks until the end of the test
+func withImageGCWatermarks(t *testing.T, low, high float64) {
+	savedLow, savedHigh := imageGCLowWatermark, imageGCHighWatermark
+	t.Cleanup(func() { imageGCLowWatermark, imageGCHighWatermark = savedLow, savedHigh })
+	imageGCLowWatermark, imageGCHighWatermark = low, high
+}
+
+// diskNode returns a node with the ephemeral storage capacity holding images of the sizes, the first
+// one is nginx:1.19
+func diskNode(name, capacity string, sizes ...int64) v1.Node {
+	images := make(map[string]int64, len(sizes))
+	for i, size := range sizes {
+		if i == 0 {
+			images["docker.io/library/nginx:1.19"] = size
+		} else {
+			images[string(rune('a'+i))] = size
+		}
+	}
+	node := imageNode(name, images)
+	if capacity != "" {
+		node.Status.Capacity = v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse(capacity)}
+	}
+	return node
+}
+
+func TestValidateImageGCWatermarks(t *testing.T) {
+	tests := []struct {
+		low, high float64
+		valid     bool
+	}{
+		{80, 85, true},
+		{0, 100, true},
+		{85, 85, false},
+		{90, 85, false},
+		{-1, 85, false},
+		{80, 101, false},
+	}
+	for _, test := range tests {
+		withImageGCWatermarks(t, test.low, test.high)
+		if err := validateImageGCWatermarks(); (err == nil) != test.valid {
+			t.Errorf("validateImageGCWatermarks with %v and %v returned %v", test.low, test.high, err)
+		}
+	}
+}
+
+func TestImageGCPressure(t *testing.T) {
+	withImageGCWatermarks(t, 80, 85)
+	tests := []struct {
+		name     string
+		node     v1.Node
+		expected float64
+	}{
+		{"low usage", diskNode("n", "1000Mi", 100*mb, 400*mb), 0},
+		{"at the low watermark", diskNode("n", "1000Mi", 800*mb), 0},
+		{"between the watermarks", diskNode("n", "1000Mi", 500*mb, 325*mb), 0.5},
+		{"at the high watermark", diskNode("n", "1000Mi", 850*mb), 1},
+		{"above the high watermark", diskNode("n", "1000Mi", 950*mb), 1},
+		{"no capacity", diskNode("n", "", 950*mb), 0},
+		{"zero capacity", diskNode("n", "0", 950*mb), 0},
+	}
+	for _, test := range tests {
+		if pressure := imageGCPressure(test.node); pressure != test.expected {
+			t.Errorf("%v: estimated a pressure of %v, expected %v", test.name, pressure, test.expected)
+		}
+	}
+}
+
+func TestImageGCRiskPriority(t *testing.T) {
+	withImageGCWatermarks(t, 80, 85)
+	nodes := []v1.Node{
+		diskNode("low", "1000Mi", 100*mb),
+		diskNode("mid", "1000Mi", 500*mb, 325*mb),
+		diskNode("high", "1000Mi", 100*mb, 800*mb),
+		imageNode("uncached", map[string]int64{"docker.io/library/redis:6": 100 * mb}),
+	}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"cached image", imagePod("nginx:1.19"), map[string]int{"low": 10, "mid": 5, "high": 0, "uncached": 0}},
+		{"half the images cached", imagePod("nginx:1.19", "redis:6"), map[string]int{"low": 5, "mid": 2, "high": 0, "uncached": 5}},
+		{"no container", imagePod(), map[string]int{"low": 0, "mid": 0, "high": 0, "uncached": 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, ImageGCRiskPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
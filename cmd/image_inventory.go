@@ -0,0 +1,245 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// imageInventory indexes the images of the cluster nodes by normalized node name. It is updated node by
+// node as the node informer sees nodes added, updated or deleted, instead of being rebuilt at each resync
+type imageInventory struct {
+	lock     sync.RWMutex
+	images   map[string][]v1.ContainerImage
+	versions map[string]string
+}
+
+// nodeImageInventory is the image index filled by the node informer, empty when -enable-informers is not set
+var nodeImageInventory = newImageInventory()
+
+func newImageInventory() *imageInventory {
+	return &imageInventory{images: make(map[string][]v1.ContainerImage), versions: make(map[string]string)}
+}
+
+// onAdd indexes the images of a new node
+func (inv *imageInventory) onAdd(node v1.Node) {
+	inv.onUpdate(node)
+}
+
+// onUpdate replaces the images of the node, unless the node did not change since it was indexed
+func (inv *imageInventory) onUpdate(node v1.Node) {
+	name := normalizeNodeName(node.Name)
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	if version, ok := inv.versions[name]; ok && version == node.ResourceVersion && node.ResourceVersion != "" {
+		return
+	}
+	inv.images[name] = node.Status.Images
+	inv.versions[name] = node.ResourceVersion
+}
+
+// onDelete drops the images of a deleted node
+func (inv *imageInventory) onDelete(nodeName string) {
+	name := normalizeNodeName(nodeName)
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	delete(inv.images, name)
+	delete(inv.versions, name)
+}
+
+// nodes returns the normalized names of the indexed nodes
+func (inv *imageInventory) nodes() []string {
+	inv.lock.RLock()
+	defer inv.lock.RUnlock()
+	names := make([]string, 0, len(inv.images))
+	for name := range inv.images {
+		names = append(names, name)
+	}
+	return names
+}
+
+// lookup returns the indexed images of the node
+func (inv *imageInventory) lookup(nodeName string) ([]v1.ContainerImage, bool) {
+	inv.lock.RLock()
+	defer inv.lock.RUnlock()
+	images, ok := inv.images[normalizeNodeName(nodeName)]
+	return images, ok
+}
+
+// flush empties the index and returns the number of nodes dropped, the next resync fills it again
+func (inv *imageInventory) flush() int {
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	count := len(inv.images)
+	inv.images = make(map[string][]v1.ContainerImage)
+	inv.versions = make(map[string]string)
+	return count
+}
+
+// nodeImages returns the images of the node, from the inventory when the scheduler sent the node without
+// its images, e.g. with a trimmed node status
+func nodeImages(node v1.Node) []v1.ContainerImage {
+	if len(node.Status.Images) > 0 {
+		return node.Status.Images
+	}
+	images, _ := nodeImageInventory.lookup(node.Name)
+	return images
+}
+
+// nodeWatchTimeout bounds a watch of the nodes, the api-server ends the watch then and the nodes are
+// listed again
+const nodeWatchTimeout = 5 * time.Minute
+
+// nodeInformer keeps the image inventory and the node store in sync with the nodes of the api-server: the
+// nodes are listed once, then the added, modified and deleted nodes are followed by a watch
+type nodeInformer struct {
+	client    *apiClient
+	inventory *imageInventory
+	store     *nodeStore
+	// resourceVersion is the version of the last listing or event, the watch starts from it
+	resourceVersion string
+}
+
+func newNodeInformer(client *apiClient, inventory *imageInventory, store *nodeStore) *nodeInformer {
+	return &nodeInformer{client: client, inventory: inventory, store: store}
+}
+
+// refresh lists the nodes, replaces the store content and hands the added, updated and deleted nodes to the inventory
+func (n *nodeInformer) refresh() error {
+	var list v1.NodeList
+	if err := n.client.get("/api/v1/nodes", &list); err != nil {
+		return err
+	}
+	n.store.replace(list.Items)
+	seen := make(map[string]bool, len(list.Items))
+	known := make(map[string]bool)
+	for _, name := range n.inventory.nodes() {
+		known[name] = true
+	}
+	for _, node := range list.Items {
+		name := normalizeNodeName(node.Name)
+		seen[name] = true
+		if known[name] {
+			n.inventory.onUpdate(node)
+		} else {
+			n.inventory.onAdd(node)
+		}
+	}
+	for name := range known {
+		if !seen[name] {
+			n.inventory.onDelete(name)
+		}
+	}
+	n.resourceVersion = list.ResourceVersion
+	return nil
+}
+
+// nodeEvent is an event of the node watch
+type nodeEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// watch follows the node events from the last listing until the api-server ends the watch or stop is
+// closed. errResourceExpired is returned when the version to watch from is gone
+func (n *nodeInformer) watch(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	path := fmt.Sprintf("/api/v1/nodes?watch=1&resourceVersion=%v&timeoutSeconds=%v", url.QueryEscape(n.resourceVersion), int(nodeWatchTimeout.Seconds()))
+	body, err := n.client.stream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	decoder := json.NewDecoder(body)
+	for {
+		var event nodeEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if event.Type == "ERROR" {
+			var status metav1.Status
+			if err := json.Unmarshal(event.Object, &status); err == nil && status.Code == http.StatusGone {
+				return errResourceExpired
+			}
+			return fmt.Errorf("the node watch failed: %s", event.Object)
+		}
+		var node v1.Node
+		if err := json.Unmarshal(event.Object, &node); err != nil {
+			return err
+		}
+		switch event.Type {
+		case "ADDED":
+			n.store.upsert(node)
+			n.inventory.onAdd(node)
+		case "MODIFIED":
+			n.store.upsert(node)
+			n.inventory.onUpdate(node)
+		case "DELETED":
+			n.store.remove(node.Name)
+			n.inventory.onDelete(node.Name)
+		}
+		n.resourceVersion = node.ResourceVersion
+	}
+}
+
+// run lists the nodes and watches them until the stop channel is closed. The nodes are listed again when
+// a watch ends, a failure being retried after -informer-resync
+func (n *nodeInformer) run(stop <-chan struct{}) {
+	for {
+		err := n.refresh()
+		if err == nil {
+			err = n.watch(stop)
+		}
+		if err == errResourceExpired {
+			glog.V(2).Infof("the node watch expired, listing the nodes again\n")
+			err = nil
+		}
+		if err != nil {
+			glog.Warningf("failed to refresh the nodes view: %v", err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(informerResync):
+			}
+			continue
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// inventoryImages returns the image names indexed for the node, sorted
+func inventoryImages(inv *imageInventory, node string) []string {
+	images, _ := inv.lookup(node)
+	var names []string
+	for _, image := range images {
+		names = append(names, image.Names...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestImageInventory(t *testing.T) {
+	withNodeNameNormalization(t, "", "", true)
+	inv := newImageInventory()
+	node := imageNode("Node-A", map[string]int64{"nginx:1.19": mb})
+	node.ResourceVersion = "1"
+	inv.onAdd(node)
+	if images := inventoryImages(inv, "node-a"); !reflect.DeepEqual(images, []string{"nginx:1.19"}) {
+		t.Errorf("indexed %v after the add", images)
+	}
+
+	// the same resource version is not indexed again
+	unchanged := imageNode("node-a", map[string]int64{"nginx:1.19": mb, "redis:6": mb})
+	unchanged.ResourceVersion = "1"
+	inv.onUpdate(unchanged)
+	if images := inventoryImages(inv, "node-a"); !reflect.DeepEqual(images, []string{"nginx:1.19"}) {
+		t.Errorf("indexed %v after an update of the same version", images)
+	}
+	unchanged.ResourceVersion = "2"
+	inv.onUpdate(unchanged)
+	if images := inventoryImages(inv, "node-a"); !reflect.DeepEqual(images, []string{"nginx:1.19", "redis:6"}) {
+		t.Errorf("indexed %v after an update", images)
+	}
+
+	inv.onDelete("NODE-A")
+	if _, ok := inv.lookup("node-a"); ok || len(inv.nodes()) != 0 {
+		t.Errorf("the deleted node is still indexed")
+	}
+}
+
+func TestNodeInformerInventory(t *testing.T) {
+	cluster := &fakeCluster{Nodes: []v1.Node{imageNode("a", map[string]int64{"docker.io/library/nginx:1.19": 100 * mb})}}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	nodeImageInventory.flush()
+	defer nodeImageInventory.flush()
+	informer := newNodeInformer(&apiClient{server: server.URL, client: server.Client()}, nodeImageInventory, &nodeStore{})
+	router := newTestRouter(t, ImagePriority)
+	// the scheduler sends the nodes without their images, the scores come from the inventory
+	pod := imagePod("nginx:1.19", "redis:6")
+	score := func() int {
+		return prioritize(t, router, ImagePriority.Name, pod, testNodes("a"))[0].Score
+	}
+	setNodes := func(nodes ...v1.Node) {
+		cluster.lock.Lock()
+		cluster.Nodes = nodes
+		cluster.lock.Unlock()
+		if err := informer.refresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unknown := score()
+	setNodes(cluster.Nodes...)
+	oneImage := score()
+	pulled := imageNode("a", map[string]int64{"docker.io/library/nginx:1.19": 100 * mb, "docker.io/library/redis:6": 100 * mb})
+	pulled.ResourceVersion = "2"
+	setNodes(pulled, imageNode("b", nil))
+	bothImages := score()
+	if !(unknown < oneImage && oneImage < bothImages) {
+		t.Errorf("scored %v without inventory, %v with one image and %v with both, expected increasing scores", unknown, oneImage, bothImages)
+	}
+	if names := nodeImageInventory.nodes(); len(names) != 2 {
+		t.Errorf("indexed the nodes %v, expected a and b", names)
+	}
+
+	setNodes(imageNode("b", nil))
+	if _, ok := nodeImageInventory.lookup("a"); ok || score() != unknown {
+		t.Errorf("the removed node is still indexed")
+	}
+}
+
+// nodeWatchServer lists the nodes and streams the events of its watches, a watch ending with 410 Gone
+// once expire is closed
+type nodeWatchServer struct {
+	nodes  []v1.Node
+	events []string
+	expire chan struct{}
+
+	lock    sync.Mutex
+	lists   int
+	watches []string
+}
+
+func (s *nodeWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	if r.URL.Query().Get("watch") == "" {
+		s.lists++
+		s.lock.Unlock()
+		json.NewEncoder(w).Encode(v1.NodeList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}, Items: s.nodes})
+		return
+	}
+	s.watches = append(s.watches, r.URL.Query().Get("resourceVersion"))
+	first := len(s.watches) == 1
+	s.lock.Unlock()
+	if !first {
+		<-r.Context().Done()
+		return
+	}
+	for _, event := range s.events {
+		fmt.Fprintln(w, event)
+	}
+	w.(http.Flusher).Flush()
+	select {
+	case <-s.expire:
+		fmt.Fprintln(w, `{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired"}}`)
+	case <-r.Context().Done():
+	}
+}
+
+// watchEvent encodes a watch event of the node
+func watchEvent(t *testing.T, eventType string, node v1.Node, version string) string {
+	node.ResourceVersion = version
+	object, err := json.Marshal(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf(`{"type":%q,"object":%s}`, eventType, object)
+}
+
+// eventually retries the condition for up to 5 seconds
+func eventually(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", description)
+		}
+	}
+}
+
+func TestNodeInformerWatch(t *testing.T) {
+	const nginx, redis = "docker.io/library/nginx:1.19", "docker.io/library/redis:6"
+	server := &nodeWatchServer{
+		nodes: []v1.Node{imageNode("a", map[string]int64{nginx: 100 * mb}), imageNode("c", nil)},
+		events: []string{
+			// the node pulled an image, a node joined and another left
+			watchEvent(t, "MODIFIED", imageNode("a", map[string]int64{nginx: 100 * mb, redis: 100 * mb}), "11"),
+			watchEvent(t, "ADDED", imageNode("b", map[string]int64{redis: 100 * mb}), "12"),
+			watchEvent(t, "DELETED", imageNode("c", nil), "13"),
+		},
+		expire: make(chan struct{}),
+	}
+	api := httptest.NewServer(server)
+	defer api.Close()
+	nodeImageInventory.flush()
+	defer nodeImageInventory.flush()
+	store := &nodeStore{}
+	informer := newNodeInformer(&apiClient{server: api.URL, client: api.Client()}, nodeImageInventory, store)
+	router := newTestRouter(t, ImagePriority)
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		informer.run(stop)
+		close(stopped)
+	}()
+
+	eventually(t, "the watch events", func() bool {
+		return reflect.DeepEqual(nodeNames(store.List()), []string{"a", "b"})
+	})
+	if images := inventoryImages(nodeImageInventory, "a"); !reflect.DeepEqual(images, []string{nginx, redis}) {
+		t.Errorf("indexed %v on a, expected the pulled image", images)
+	}
+	if _, ok := nodeImageInventory.lookup("c"); ok {
+		t.Errorf("the deleted node is still indexed")
+	}
+	// the scheduler sends the nodes without their images, the scores follow the watched inventory
+	checkScores(t, prioritize(t, router, ImagePriority.Name, imagePod("nginx:1.19", "redis:6"), testNodes("a", "b")), map[string]int{"a": 10, "b": 5})
+
+	// the expired watch makes the informer list the nodes again and watch from the new listing
+	close(server.expire)
+	eventually(t, "the listing after the expired watch", func() bool {
+		server.lock.Lock()
+		defer server.lock.Unlock()
+		return server.lists == 2 && len(server.watches) == 2
+	})
+	server.lock.Lock()
+	if !reflect.DeepEqual(server.watches, []string{"10", "10"}) {
+		t.Errorf("watched from the versions %v, expected the listed one", server.watches)
+	}
+	server.lock.Unlock()
+	if !reflect.DeepEqual(nodeNames(store.List()), []string{"a", "c"}) {
+		t.Errorf("stored %v after listing again", nodeNames(store.List()))
+	}
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Error("the informer did not stop")
+	}
+}
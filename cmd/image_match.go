@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ImageMatcher reports whether a node image name refers to the image of a pod container
+type ImageMatcher func(nodeImage, containerImage string) bool
+
+// imageMatchers are the matching strategies selectable with -image-match-mode
+var imageMatchers = map[string]ImageMatcher{
+	// exact-repo requires the same repository and the same tag (latest when missing) or digest
+	"exact-repo": exactRepoMatch,
+	// repo-prefix requires the same repository whatever the tag, the repository being the image name
+	// without its tag or digest
+	"repo-prefix": repoPrefixMatch,
+	// substring is the legacy heuristic, the container image only has to be a substring of the node
+	// image, so `nginx` also matches `my-nginx-proxy`
+	"substring": substringMatch,
+}
+
+var imageMatchMode string
+
+func init() {
+	flag.StringVar(&imageMatchMode, "image-match-mode", "exact-repo", "How node images are matched against the pod's container images, one of: exact-repo, repo-prefix, substring")
+}
+
+// validateImageMatchMode makes sure the -image-match-mode flag holds a known mode
+func validateImageMatchMode() error {
+	if _, ok := imageMatchers[imageMatchMode]; !ok {
+		return fmt.Errorf("unknown -image-match-mode %q, expecting one of: exact-repo, repo-prefix, substring", imageMatchMode)
+	}
+	return nil
+}
+
+// imageRef is an image name split in its normalized repository, tag and digest
+type imageRef struct {
+	repo   string
+	tag    string
+	digest string
+}
+
+// parseImageRef splits the image name, the repository is qualified the way the container runtime does
+// it: docker.io is the default registry and library the default namespace of single name images
+func parseImageRef(name string) imageRef {
+	var ref imageRef
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.tag = name[:i], name[i+1:]
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		if len(parts) == 1 {
+			name = "library/" + name
+		}
+		name = "docker.io/" + name
+	}
+	ref.repo = name
+	return ref
+}
+
+// exactRepoMatch matches images of the same repository and tag, or digest when the container pins one
+func exactRepoMatch(nodeImage, containerImage string) bool {
+	node, ctnr := parseImageRef(nodeImage), parseImageRef(containerImage)
+	if node.repo != ctnr.repo {
+		return false
+	}
+	if ctnr.digest != "" {
+		return node.digest == ctnr.digest
+	}
+	if ctnr.tag == "" {
+		ctnr.tag = "latest"
+	}
+	return node.tag == ctnr.tag
+}
+
+// repoPrefixMatch matches images of the same repository, whatever their tag or digest
+func repoPrefixMatch(nodeImage, containerImage string) bool {
+	return parseImageRef(nodeImage).repo == parseImageRef(containerImage).repo
+}
+
+// substringMatch is the original heuristic, it was using `strings.Contains` since the missing tag
+// `latest` in the pod's container may be added in the node image
+func substringMatch(nodeImage, containerImage string) bool {
+	return strings.Contains(nodeImage, containerImage)
+}
@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+// withImageMatchMode sets -image-match-mode until the end of the test
+func withImageMatchMode(t *testing.T, mode string) {
+	saved := imageMatchMode
+	t.Cleanup(func() { imageMatchMode = saved })
+	imageMatchMode = mode
+}
+
+func TestValidateImageMatchMode(t *testing.T) {
+	for mode, valid := range map[string]bool{"exact-repo": true, "repo-prefix": true, "substring": true, "": false, "exact": false} {
+		withImageMatchMode(t, mode)
+		if err := validateImageMatchMode(); (err == nil) != valid {
+			t.Errorf("validateImageMatchMode(%q) returned %v", mode, err)
+		}
+	}
+}
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected imageRef
+	}{
+		{"nginx", imageRef{repo: "docker.io/library/nginx"}},
+		{"nginx:1.19", imageRef{repo: "docker.io/library/nginx", tag: "1.19"}},
+		{"bitnami/redis:6", imageRef{repo: "docker.io/bitnami/redis", tag: "6"}},
+		{"docker.io/library/nginx:latest", imageRef{repo: "docker.io/library/nginx", tag: "latest"}},
+		{"gcr.io/project/app@sha256:abc", imageRef{repo: "gcr.io/project/app", digest: "sha256:abc"}},
+		{"gcr.io/project/app:v1@sha256:abc", imageRef{repo: "gcr.io/project/app", tag: "v1", digest: "sha256:abc"}},
+		{"registry:5000/app", imageRef{repo: "registry:5000/app"}},
+		{"registry:5000/app:v2", imageRef{repo: "registry:5000/app", tag: "v2"}},
+		{"localhost/app:dev", imageRef{repo: "localhost/app", tag: "dev"}},
+	}
+	for _, test := range tests {
+		if ref := parseImageRef(test.name); ref != test.expected {
+			t.Errorf("parsed %q as %+v, expected %+v", test.name, ref, test.expected)
+		}
+	}
+}
+
+func TestImageMatchModes(t *testing.T) {
+	tests := []struct {
+		nodeImage, containerImage string
+		// the expected matches of the exact-repo, repo-prefix and substring modes
+		exact, prefix, substring bool
+	}{
+		{"docker.io/library/nginx:latest", "nginx", true, true, true},
+		{"docker.io/library/nginx:1.19", "nginx:1.19", true, true, true},
+		{"docker.io/library/nginx:1.19", "nginx:1.20", false, true, false},
+		{"docker.io/library/nginx:1.19", "nginx", false, true, true},
+		{"docker.io/library/my-nginx-proxy:latest", "nginx", false, false, true},
+		{"docker.io/library/nginx-exporter:1.0", "nginx", false, false, true},
+		{"docker.io/bitnami/nginx:latest", "nginx", false, false, true},
+		{"gcr.io/project/app@sha256:abc", "gcr.io/project/app@sha256:abc", true, true, true},
+		{"gcr.io/project/app@sha256:abc", "gcr.io/project/app@sha256:def", false, true, false},
+		{"gcr.io/project/app:v1", "gcr.io/project/app@sha256:abc", false, true, false},
+		{"gcr.io/other/app:v1", "gcr.io/project/app:v1", false, false, false},
+		{"registry:5000/app:v2", "registry:5000/app:v2", true, true, true},
+	}
+	for _, test := range tests {
+		for mode, expected := range map[string]bool{"exact-repo": test.exact, "repo-prefix": test.prefix, "substring": test.substring} {
+			withImageMatchMode(t, mode)
+			if matches := imageMatches(test.nodeImage, test.containerImage); matches != expected {
+				t.Errorf("%v: matching %q against %q returned %v", mode, test.nodeImage, test.containerImage, matches)
+			}
+		}
+	}
+}
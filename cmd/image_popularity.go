@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	imagePopularityConsolidate = "consolidate"
+	imagePopularitySpread      = "spread"
+)
+
+var imagePopularityMode string
+var imagePopularityWeight int
+
+func init() {
+	flag.StringVar(&imagePopularityMode, "image-popularity-mode", imagePopularityConsolidate, "Whether image_popularity favors the nodes already holding the popular images of the pod, consolidate, or the other nodes, spread")
+	flag.IntVar(&imagePopularityWeight, "image-popularity-weight", 2, "The most image_popularity moves a node away from the neutral score, for an image every pod of the cluster runs. 0 disables image_popularity")
+}
+
+// validateImagePopularity makes sure the image popularity flags hold valid values
+func validateImagePopularity() error {
+	switch imagePopularityMode {
+	case imagePopularityConsolidate, imagePopularitySpread:
+	default:
+		return fmt.Errorf("unknown -image-popularity-mode %q, expecting one of: %v, %v", imagePopularityMode, imagePopularityConsolidate, imagePopularitySpread)
+	}
+	if imagePopularityWeight < 0 || imagePopularityWeight > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -image-popularity-weight flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, imagePopularityWeight)
+	}
+	return nil
+}
+
+// ImagePopularityPriority refines the image locality by how many pods of the cluster run the images of the
+// pod: a node holding an image run by a fraction p of the pods moves by p * -image-popularity-weight from
+// the neutral score, up to consolidate the popular images, down to spread them. The offsets of the images
+// found on the node are averaged over the containers of the pod, the nodes holding none get the neutral score
+var ImagePopularityPriority = PrioritizeMethod{
+	Name:              "image_popularity",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		popularity := imagePopularity(pod, podLister.List())
+		sign := 1.0
+		if imagePopularityMode == imagePopularitySpread {
+			sign = -1
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if imagePopularityWeight == 0 || len(pod.Spec.Containers) == 0 {
+				return neutralScore, nil
+			}
+			images := nodeImages(node)
+			var offset float64
+			for _, ctnr := range pod.Spec.Containers {
+				if _, found := findNodeImage(ctnr.Image, images); found {
+					offset += popularity[ctnr.Image]
+				}
+			}
+			offset = sign * float64(imagePopularityWeight) * offset / float64(len(pod.Spec.Containers))
+			return clampScore(neutralScore + int(math.Round(offset))), nil
+		}
+	},
+}
+
+// imagePopularity returns, for each container image of the pod, the fraction of the scheduled pods running it
+func imagePopularity(pod v1.Pod, pods []v1.Pod) map[string]float64 {
+	popularity := make(map[string]float64, len(pod.Spec.Containers))
+	var scheduled int
+	for _, other := range pods {
+		if other.Spec.NodeName == "" || (other.UID == pod.UID && pod.UID != "") {
+			continue
+		}
+		scheduled++
+		for _, ctnr := range pod.Spec.Containers {
+			for _, otherCtnr := range other.Spec.Containers {
+				if containerImagesMatch(otherCtnr.Image, ctnr.Image) {
+					popularity[ctnr.Image]++
+					break
+				}
+			}
+		}
+	}
+	for image := range popularity {
+		popularity[image] /= float64(scheduled)
+	}
+	return popularity
+}
+
+// containerImagesMatch reports whether two container images refer to the same image according to
+// -image-match-mode, the missing tag of the first one defaults to latest as a node would report it
+func containerImagesMatch(image, other string) bool {
+	if ref := parseImageRef(image); ref.tag == "" && ref.digest == "" {
+		image += ":latest"
+	}
+	return imageMatches(image, other)
+}
@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// withImagePopularity sets -image-popularity-mode and -image-popularity-weight for the test
+func withImagePopularity(t *testing.T, mode string, weight int) {
+	savedMode, savedWeight := imagePopularityMode, imagePopularityWeight
+	t.Cleanup(func() { imagePopularityMode, imagePopularityWeight = savedMode, savedWeight })
+	imagePopularityMode, imagePopularityWeight = mode, weight
+}
+
+// runningPod returns a pod bound to the node running the images
+func runningPod(uid, node string, images ...string) v1.Pod {
+	pod := imagePod(images...)
+	pod.UID = k8stypes.UID(uid)
+	pod.Spec.NodeName = node
+	return pod
+}
+
+func TestValidateImagePopularity(t *testing.T) {
+	tests := []struct {
+		mode   string
+		weight int
+		valid  bool
+	}{
+		{imagePopularityConsolidate, 2, true},
+		{imagePopularitySpread, 0, true},
+		{imagePopularitySpread, 10, true},
+		{"cluster", 2, false},
+		{imagePopularityConsolidate, -1, false},
+		{imagePopularityConsolidate, 11, false},
+	}
+	for _, test := range tests {
+		withImagePopularity(t, test.mode, test.weight)
+		if err := validateImagePopularity(); (err == nil) != test.valid {
+			t.Errorf("validateImagePopularity(%q, %v) returned %v", test.mode, test.weight, err)
+		}
+	}
+}
+
+func TestImagePopularity(t *testing.T) {
+	pods := []v1.Pod{
+		runningPod("1", "a", "nginx:1.19"),
+		runningPod("2", "a", "nginx:1.19", "redis:6"),
+		runningPod("3", "b", "nginx"),
+		runningPod("4", "b", "busybox"),
+		runningPod("pending", "", "nginx:1.19"),
+		runningPod("self", "c", "nginx:1.19"),
+	}
+	pod := runningPod("self", "", "nginx:1.19", "redis:6", "nginx:latest")
+	expected := map[string]float64{"nginx:1.19": 0.5, "redis:6": 0.25, "nginx:latest": 0.25}
+	popularity := imagePopularity(pod, pods)
+	if len(popularity) != len(expected) {
+		t.Errorf("computed the popularity %v, expected %v", popularity, expected)
+	}
+	for image, fraction := range expected {
+		if popularity[image] != fraction {
+			t.Errorf("computed the popularity %v, expected %v", popularity, expected)
+			break
+		}
+	}
+}
+
+func TestImagePopularityPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	withPods(t,
+		runningPod("1", "x", "nginx:1.19"),
+		runningPod("2", "x", "nginx:1.19"),
+		runningPod("3", "y", "nginx:1.19"),
+		runningPod("4", "y", "redis:6"),
+	)
+	nodes := []v1.Node{
+		imageNode("both", map[string]int64{"docker.io/library/nginx:1.19": 100 * mb, "docker.io/library/redis:6": 50 * mb}),
+		imageNode("nginx", map[string]int64{"docker.io/library/nginx:1.19": 100 * mb}),
+		imageNode("redis", map[string]int64{"docker.io/library/redis:6": 50 * mb}),
+		imageNode("none", nil),
+	}
+	tests := []struct {
+		name     string
+		mode     string
+		weight   int
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"consolidate", imagePopularityConsolidate, 8, imagePod("nginx:1.19", "redis:6"), map[string]int{"both": 9, "nginx": 8, "redis": 6, "none": 5}},
+		{"spread", imagePopularitySpread, 8, imagePod("nginx:1.19", "redis:6"), map[string]int{"both": 1, "nginx": 2, "redis": 4, "none": 5}},
+		{"clamped", imagePopularityConsolidate, 10, imagePod("nginx:1.19"), map[string]int{"both": 10, "nginx": 10, "redis": 5, "none": 5}},
+		{"disabled", imagePopularityConsolidate, 0, imagePod("nginx:1.19", "redis:6"), map[string]int{"both": 5, "nginx": 5, "redis": 5, "none": 5}},
+		{"no container", imagePopularityConsolidate, 8, imagePod(), map[string]int{"both": 5, "nginx": 5, "redis": 5, "none": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withImagePopularity(t, test.mode, test.weight)
+			checkScores(t, scoreMethod(t, ImagePopularityPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
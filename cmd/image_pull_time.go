@@ -0,0 +1,101 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var bandwidthAnnotation string
+var defaultBandwidthMbps, defaultImageSizeMB float64
+
+func init() {
+	flag.StringVar(&bandwidthAnnotation, "bandwidth-annotation", "node.example.com/net-mbps", "The node annotation reporting the approximate network bandwidth of the node in Mbps")
+	flag.Float64Var(&defaultBandwidthMbps, "default-bandwidth-mbps", 100, "The bandwidth assumed for nodes missing the bandwidth annotation")
+	flag.Float64Var(&defaultImageSizeMB, "default-image-size-mb", 100, "The size assumed for an image not found on any of the candidate nodes")
+}
+
+// ImagePullTimePriority estimates how long each node would take to pull the pod images it is missing and
+// favors the nodes that would be done first. Nodes without the bandwidth annotation are assumed to have the
+// default bandwidth, so when no node reports it the priority falls back to scoring by missing bytes
+var ImagePullTimePriority = PrioritizeMethod{
+	Name: "image_pull_time",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		imageSizes := knownImageSizes(pod, nodes)
+		pullSeconds := make(map[string]float64, len(nodes))
+		var maxPullSeconds float64
+		for _, node := range nodes {
+			missingBytes := missingImageBytes(pod, node, imageSizes)
+			pullSeconds[node.Name] = missingBytes * 8 / (nodeBandwidthMbps(node) * 1e6)
+			if pullSeconds[node.Name] > maxPullSeconds {
+				maxPullSeconds = pullSeconds[node.Name]
+			}
+			glog.V(6).Infof("node %v would need %.1fs to pull %.0f missing bytes for pod %v\n", node.Name, pullSeconds[node.Name], missingBytes, pod.Name)
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if maxPullSeconds == 0 {
+				return schedulingapi.MaxPriority, nil
+			}
+			return int(float64(schedulingapi.MaxPriority) * (1 - pullSeconds[node.Name]/maxPullSeconds)), nil
+		}
+	},
+}
+
+// knownImageSizes returns, for each container image of the pod, the largest size reported by a candidate node holding it
+func knownImageSizes(pod v1.Pod, nodes []v1.Node) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, ctnr := range pod.Spec.Containers {
+		for _, node := range nodes {
+			if img, found := findNodeImage(ctnr.Image, nodeImages(node)); found && img.SizeBytes > sizes[ctnr.Image] {
+				sizes[ctnr.Image] = img.SizeBytes
+			}
+		}
+	}
+	return sizes
+}
+
+// missingImageBytes sums the size of the pod images the node does not hold yet
+func missingImageBytes(pod v1.Pod, node v1.Node, imageSizes map[string]int64) float64 {
+	var missing float64
+	for _, ctnr := range pod.Spec.Containers {
+		if _, found := findNodeImage(ctnr.Image, nodeImages(node)); found {
+			continue
+		}
+		if size, known := imageSizes[ctnr.Image]; known {
+			missing += float64(size)
+		} else {
+			missing += defaultImageSizeMB * 1024 * 1024
+		}
+	}
+	return missing
+}
+
+// nodeBandwidthMbps returns the bandwidth reported by the node annotation or the default one
+func nodeBandwidthMbps(node v1.Node) float64 {
+	value, ok := node.Annotations[bandwidthAnnotation]
+	if !ok {
+		return defaultBandwidthMbps
+	}
+	mbps, err := strconv.ParseFloat(value, 64)
+	if err != nil || mbps <= 0 {
+		glog.Warningf("ignoring invalid %v annotation %q on node %v", bandwidthAnnotation, value, node.Name)
+		return defaultBandwidthMbps
+	}
+	return mbps
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+const mb = 1024 * 1024
+
+func TestImagePullTimePriority(t *testing.T) {
+	pod := imagePod("app:1", "sidecar:1")
+	slow := imageNode("slow", map[string]int64{"app:1": 100 * mb})
+	slow.Annotations = map[string]string{bandwidthAnnotation: "40"}
+	invalid := imageNode("invalid", map[string]int64{"app:1": 100 * mb})
+	invalid.Annotations = map[string]string{bandwidthAnnotation: "0"}
+	tests := []struct {
+		name     string
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{
+			// pulling the 50MB sidecar takes a third of the time of pulling both images, 2.5 times longer at 40Mbps
+			name: "missing bytes over the bandwidth",
+			nodes: []v1.Node{
+				imageNode("cached", map[string]int64{"app:1": 100 * mb, "sidecar:1": 50 * mb}),
+				imageNode("half", map[string]int64{"app:1": 100 * mb}),
+				slow,
+				imageNode("empty", nil),
+			},
+			expected: map[string]int{"cached": 10, "half": 6, "slow": 1, "empty": 0},
+		},
+		{
+			name: "invalid bandwidth annotation",
+			nodes: []v1.Node{
+				imageNode("cached", map[string]int64{"app:1": 100 * mb, "sidecar:1": 50 * mb}),
+				imageNode("half", map[string]int64{"app:1": 100 * mb}),
+				invalid,
+				imageNode("empty", nil),
+			},
+			expected: map[string]int{"cached": 10, "half": 6, "invalid": 6, "empty": 0},
+		},
+		{
+			name: "every image cached",
+			nodes: []v1.Node{
+				imageNode("a", map[string]int64{"app:1": 100 * mb, "sidecar:1": 50 * mb}),
+				imageNode("b", map[string]int64{"app:1": 100 * mb, "sidecar:1": 50 * mb}),
+			},
+			expected: map[string]int{"a": 10, "b": 10},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, ImagePullTimePriority, pod, test.nodes), test.expected)
+		})
+	}
+}
+
+func TestMissingImageBytes(t *testing.T) {
+	pod := imagePod("app:1", "unknown:1")
+	sizes := knownImageSizes(pod, []v1.Node{
+		imageNode("small", map[string]int64{"app:1": 10 * mb}),
+		imageNode("large", map[string]int64{"app:1": 30 * mb}),
+	})
+	if sizes["app:1"] != 30*mb {
+		t.Errorf("app:1 size %v, expected the largest reported size", sizes["app:1"])
+	}
+	if _, known := sizes["unknown:1"]; known {
+		t.Errorf("unknown:1 has a known size %v", sizes["unknown:1"])
+	}
+	// an image no candidate holds weighs -default-image-size-mb
+	missing := missingImageBytes(pod, imageNode("empty", nil), sizes)
+	if expected := 30*mb + defaultImageSizeMB*mb; missing != expected {
+		t.Errorf("missing bytes %v, expected %v", missing, expected)
+	}
+}
+
+func TestNodeBandwidthMbps(t *testing.T) {
+	tests := []struct {
+		value    string
+		set      bool
+		expected float64
+	}{
+		{"", false, defaultBandwidthMbps},
+		{"1000", true, 1000},
+		{"2.5", true, 2.5},
+		{"0", true, defaultBandwidthMbps},
+		{"-10", true, defaultBandwidthMbps},
+		{"fast", true, defaultBandwidthMbps},
+	}
+	for _, test := range tests {
+		node := testNodes("n")[0]
+		if test.set {
+			node.Annotations = map[string]string{bandwidthAnnotation: test.value}
+		}
+		if mbps := nodeBandwidthMbps(node); mbps != test.expected {
+			t.Errorf("nodeBandwidthMbps(%q, set=%v) = %v, expected %v", test.value, test.set, mbps, test.expected)
+		}
+	}
+}
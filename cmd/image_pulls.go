@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var imagePullBytesWeight float64
+
+func init() {
+	flag.Float64Var(&imagePullBytesWeight, "image-pull-bytes-weight", 0.5, "How much image_score weighs the bytes left to pull against the number of pulls left, from 0 (pull count only) to 1 (pull bytes only)")
+}
+
+// validateImagePullBytesWeight makes sure the bytes weight is a fraction
+func validateImagePullBytesWeight() error {
+	if imagePullBytesWeight < 0 || imagePullBytesWeight > 1 {
+		return fmt.Errorf("the -image-pull-bytes-weight flag must be between 0 and 1, got %v", imagePullBytesWeight)
+	}
+	return nil
+}
+
+// imagePulls estimates the image pulls a pod needs on a node: each distinct image is pulled once no matter
+// how many containers use it, images not found on any candidate node are assumed to be -default-image-size-mb
+type imagePulls struct {
+	count, missingCount int
+	bytes, missingBytes float64
+}
+
+// remainingImagePulls returns the pulls the node still has to do before the pod containers can start
+func remainingImagePulls(pod v1.Pod, node v1.Node, imageSizes map[string]int64) imagePulls {
+	var pulls imagePulls
+	images := nodeImages(node)
+	seen := make(map[string]bool)
+	for _, ctnr := range pod.Spec.Containers {
+		if seen[ctnr.Image] {
+			continue
+		}
+		seen[ctnr.Image] = true
+		size := defaultImageSizeMB * 1024 * 1024
+		if known, ok := imageSizes[ctnr.Image]; ok {
+			size = float64(known)
+		}
+		pulls.count++
+		pulls.bytes += size
+		if _, found := findNodeImage(ctnr.Image, images); !found {
+			pulls.missingCount++
+			pulls.missingBytes += size
+		}
+	}
+	return pulls
+}
+
+// score gives MaxPriority to a node holding every image and 0 to a node holding none, in between the
+// share of pulls left and the share of bytes left are blended by -image-pull-bytes-weight
+func (p imagePulls) score() int {
+	if p.count == 0 {
+		return 0
+	}
+	left := (1 - imagePullBytesWeight) * float64(p.missingCount) / float64(p.count)
+	if p.bytes > 0 {
+		left += imagePullBytesWeight * p.missingBytes / p.bytes
+	}
+	return clampScore(int(float64(schedulingapi.MaxPriority)*(1-left) + 0.5))
+}
+
+// imagePullScore scores the node by the image pulls it has left for the pod
+func imagePullScore(pod v1.Pod, node v1.Node, imageSizes map[string]int64) int {
+	pulls := remainingImagePulls(pod, node, imageSizes)
+	score := pulls.score()
+	glog.V(6).Infof("node %v has %v of %v image pulls left (%.0f of %.0f bytes) for pod %v, score %v\n", node.Name, pulls.missingCount, pulls.count, pulls.missingBytes, pulls.bytes, pod.Name, score)
+	return score
+}
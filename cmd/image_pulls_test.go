@@ -0,0 +1,85 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withImagePulls sets -image-pull-bytes-weight and -default-image-size-mb until the end of the test
+func withImagePulls(t *testing.T, bytesWeight, defaultSizeMB float64) {
+	savedWeight, savedSize := imagePullBytesWeight, defaultImageSizeMB
+	t.Cleanup(func() { imagePullBytesWeight, defaultImageSizeMB = savedWeight, savedSize })
+	imagePullBytesWeight, defaultImageSizeMB = bytesWeight, defaultSizeMB
+}
+
+func TestValidateImagePullBytesWeight(t *testing.T) {
+	for _, test := range []struct {
+		weight float64
+		valid  bool
+	}{
+		{0, true},
+		{0.5, true},
+		{1, true},
+		{-0.1, false},
+		{1.5, false},
+	} {
+		withImagePulls(t, test.weight, 100)
+		if err := validateImagePullBytesWeight(); (err == nil) != test.valid {
+			t.Errorf("-image-pull-bytes-weight=%v: got %v, expected valid %v", test.weight, err, test.valid)
+		}
+	}
+}
+
+func TestRemainingImagePulls(t *testing.T) {
+	withImagePulls(t, 0.5, 300)
+	node := imageNode("n", map[string]int64{"docker.io/library/small:1": 100 * mb})
+	sizes := map[string]int64{"docker.io/library/small:1": 100 * mb}
+	// the repeated image is pulled once, the image of unknown size is assumed -default-image-size-mb
+	pulls := remainingImagePulls(imagePod("docker.io/library/small:1", "docker.io/library/small:1", "docker.io/library/unknown:1"), node, sizes)
+	expected := imagePulls{count: 2, missingCount: 1, bytes: 400 * mb, missingBytes: 300 * mb}
+	if pulls != expected {
+		t.Errorf("estimated %+v, expected %+v", pulls, expected)
+	}
+}
+
+func TestImagePriorityPulls(t *testing.T) {
+	const small, large = "docker.io/library/small:1", "docker.io/library/large:1"
+	nodes := []v1.Node{
+		imageNode("both", map[string]int64{small: 100 * mb, large: 300 * mb}),
+		imageNode("small", map[string]int64{small: 100 * mb}),
+		imageNode("large", map[string]int64{large: 300 * mb}),
+		imageNode("none", nil),
+	}
+	for _, test := range []struct {
+		name     string
+		weight   float64
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"count and bytes", 0.5, imagePod(small, large), map[string]int{"both": 10, "small": 4, "large": 6, "none": 0}},
+		{"count only", 0, imagePod(small, large), map[string]int{"both": 10, "small": 5, "large": 5, "none": 0}},
+		{"bytes only", 1, imagePod(small, large), map[string]int{"both": 10, "small": 3, "large": 8, "none": 0}},
+		{"repeated image", 0.5, imagePod(small, small, large), map[string]int{"both": 10, "small": 4, "large": 6, "none": 0}},
+		// the unknown image is assumed as large as the large one
+		{"unknown image", 1, imagePod(small, "docker.io/library/unknown:1"), map[string]int{"both": 3, "small": 3, "large": 0, "none": 0}},
+		{"no container", 0.5, testPod("default", "p", nil), map[string]int{"both": 0, "small": 0, "large": 0, "none": 0}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withImagePulls(t, test.weight, 300)
+			checkScores(t, scoreMethod(t, ImagePriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	imageStoreMeasureCount = "count"
+	imageStoreMeasureBytes = "bytes"
+
+	imageStorePreferFewer = "fewer"
+	imageStorePreferMore  = "more"
+)
+
+var imageStoreMeasure, imageStorePreference string
+
+func init() {
+	flag.StringVar(&imageStoreMeasure, "image-store-measure", imageStoreMeasureCount, "How image_store sizes the image store of a node, one of: count, bytes")
+	flag.StringVar(&imageStorePreference, "image-store-preference", imageStorePreferFewer, "Whether image_store favors the nodes with the leanest image store, fewer, keeping the image GC predictable, or the fullest one, more, maximizing the cache reuse")
+}
+
+// validateImageStore makes sure the image store flags hold known values
+func validateImageStore() error {
+	switch imageStoreMeasure {
+	case imageStoreMeasureCount, imageStoreMeasureBytes:
+	default:
+		return fmt.Errorf("unknown -image-store-measure %q, expecting one of: %v, %v", imageStoreMeasure, imageStoreMeasureCount, imageStoreMeasureBytes)
+	}
+	switch imageStorePreference {
+	case imageStorePreferFewer, imageStorePreferMore:
+	default:
+		return fmt.Errorf("unknown -image-store-preference %q, expecting one of: %v, %v", imageStorePreference, imageStorePreferFewer, imageStorePreferMore)
+	}
+	return nil
+}
+
+// imageStoreSize returns the number of images of the node, or their total bytes, per -image-store-measure
+func imageStoreSize(node v1.Node) int64 {
+	images := nodeImages(node)
+	if imageStoreMeasure == imageStoreMeasureCount {
+		return int64(len(images))
+	}
+	var bytes int64
+	for _, image := range images {
+		bytes += image.SizeBytes
+	}
+	return bytes
+}
+
+// ImageStorePriority scores the nodes by the size of their image store, whatever the images of the pod:
+// the sizes are spread linearly between the smallest and the largest store of the candidates, the
+// preferred end scoring the max. Every node gets the neutral score when the stores are all the same size
+var ImageStorePriority = PrioritizeMethod{
+	Name: "image_store",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		sizes := make(map[string]int64, len(nodes))
+		var minSize, maxSize int64
+		for i, node := range nodes {
+			size := imageStoreSize(node)
+			sizes[node.Name] = size
+			if i == 0 || size < minSize {
+				minSize = size
+			}
+			if i == 0 || size > maxSize {
+				maxSize = size
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if maxSize == minSize {
+				return neutralScore, nil
+			}
+			share := float64(sizes[node.Name]-minSize) / float64(maxSize-minSize)
+			if imageStorePreference == imageStorePreferFewer {
+				share = 1 - share
+			}
+			return int(float64(schedulingapi.MaxPriority)*share + 0.5), nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		return fmt.Sprintf("image store of %v %v, %v preferred", imageStoreSize(node), imageStoreMeasure, imageStorePreference)
+	},
+}
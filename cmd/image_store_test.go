@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withImageStore sets -image-store-measure and -image-store-preference until the end of the test
+func withImageStore(t *testing.T, measure, preference string) {
+	savedMeasure, savedPreference := imageStoreMeasure, imageStorePreference
+	t.Cleanup(func() { imageStoreMeasure, imageStorePreference = savedMeasure, savedPreference })
+	imageStoreMeasure, imageStorePreference = measure, preference
+}
+
+// storeNode returns a node holding the count of images, each of the size
+func storeNode(name string, count int, size int64) v1.Node {
+	images := make(map[string]int64, count)
+	for i := 0; i < count; i++ {
+		images[fmt.Sprintf("%v-image-%v", name, i)] = size
+	}
+	return imageNode(name, images)
+}
+
+func TestValidateImageStore(t *testing.T) {
+	for _, test := range []struct {
+		measure, preference string
+		valid               bool
+	}{
+		{imageStoreMeasureCount, imageStorePreferFewer, true},
+		{imageStoreMeasureBytes, imageStorePreferMore, true},
+		{"size", imageStorePreferFewer, false},
+		{imageStoreMeasureCount, "less", false},
+		{"", "", false},
+	} {
+		withImageStore(t, test.measure, test.preference)
+		if err := validateImageStore(); (err == nil) != test.valid {
+			t.Errorf("-image-store-measure=%q -image-store-preference=%q: got %v, expected valid %v", test.measure, test.preference, err, test.valid)
+		}
+	}
+}
+
+func TestImageStorePriority(t *testing.T) {
+	// small holds 1 large image, medium 2 and large 5 small ones
+	nodes := []v1.Node{storeNode("small", 1, 900*mb), storeNode("medium", 2, 100*mb), storeNode("large", 5, 100*mb)}
+	for _, test := range []struct {
+		name                string
+		measure, preference string
+		nodes               []v1.Node
+		expected            map[string]int
+	}{
+		{"fewer images", imageStoreMeasureCount, imageStorePreferFewer, nodes, map[string]int{"small": 10, "medium": 8, "large": 0}},
+		{"more images", imageStoreMeasureCount, imageStorePreferMore, nodes, map[string]int{"small": 0, "medium": 3, "large": 10}},
+		{"fewer bytes", imageStoreMeasureBytes, imageStorePreferFewer, nodes, map[string]int{"small": 0, "medium": 10, "large": 6}},
+		{"same size", imageStoreMeasureCount, imageStorePreferFewer, []v1.Node{storeNode("a", 2, mb), storeNode("b", 2, 2*mb)}, map[string]int{"a": 5, "b": 5}},
+		{"no images", imageStoreMeasureBytes, imageStorePreferMore, testNodes("a", "b"), map[string]int{"a": 5, "b": 5}},
+		{"single node", imageStoreMeasureCount, imageStorePreferFewer, nodes[2:], map[string]int{"large": 5}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withImageStore(t, test.measure, test.preference)
+			checkScores(t, scoreMethod(t, ImageStorePriority, testPod("default", "p", nil), test.nodes), test.expected)
+		})
+	}
+}
+
+func TestImageStoreFromInventory(t *testing.T) {
+	withImageStore(t, imageStoreMeasureCount, imageStorePreferFewer)
+	nodeImageInventory.flush()
+	defer nodeImageInventory.flush()
+	nodeImageInventory.onAdd(storeNode("a", 4, mb))
+	nodeImageInventory.onAdd(storeNode("b", 1, mb))
+
+	// the scheduler sends the nodes without their images, the sizes come from the inventory
+	checkScores(t, scoreMethod(t, ImageStorePriority, testPod("default", "p", nil), testNodes("a", "b")), map[string]int{"a": 0, "b": 10})
+}
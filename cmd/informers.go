@@ -0,0 +1,337 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var enableInformers bool
+var kubeAPIServer string
+var informerResync time.Duration
+
+func init() {
+	flag.BoolVar(&enableInformers, "enable-informers", false, "Keep a view of the cluster pods, needed by the priorities relying on more than the ExtenderArgs")
+	flag.StringVar(&kubeAPIServer, "kube-api-server", "", "The api-server URL, e.g. http://localhost:8001 for kubectl proxy, defaults to the in-cluster address and service account")
+	flag.DurationVar(&informerResync, "informer-resync", 30*time.Second, "How often the cluster view is refreshed from the api-server")
+}
+
+// PodLister gives access to the pods of the cluster, terminated pods excluded
+type PodLister interface {
+	List() []v1.Pod
+	// HasSynced reports whether the pods have been listed at least once
+	HasSynced() bool
+}
+
+// podLister is the cluster view used by the priorities, nil when -enable-informers is not set
+var podLister PodLister
+
+// NodeLister gives access to the nodes of the cluster
+type NodeLister interface {
+	List() []v1.Node
+}
+
+// nodeStore is a NodeLister over the last node list, replaced as a whole on each listing and updated
+// node by node by the watch events in between
+type nodeStore struct {
+	lock  sync.RWMutex
+	nodes []v1.Node
+}
+
+// nodeLister is the view of the cluster nodes, empty when -enable-informers is not set
+var nodeLister = &nodeStore{}
+
+func (s *nodeStore) List() []v1.Node {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.nodes
+}
+
+// replace swaps the nodes of the store
+func (s *nodeStore) replace(nodes []v1.Node) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.nodes = nodes
+}
+
+// upsert adds the node, or replaces the stored node of the same name. The list is copied since the
+// callers of List may still be reading the previous one
+func (s *nodeStore) upsert(node v1.Node) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	nodes := make([]v1.Node, 0, len(s.nodes)+1)
+	var replaced bool
+	for _, stored := range s.nodes {
+		if stored.Name == node.Name {
+			stored, replaced = node, true
+		}
+		nodes = append(nodes, stored)
+	}
+	if !replaced {
+		nodes = append(nodes, node)
+	}
+	s.nodes = nodes
+}
+
+// remove drops the node of the name, copying the list as upsert does
+func (s *nodeStore) remove(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	nodes := make([]v1.Node, 0, len(s.nodes))
+	for _, stored := range s.nodes {
+		if stored.Name != name {
+			nodes = append(nodes, stored)
+		}
+	}
+	s.nodes = nodes
+}
+
+// apiClient is a minimal client of the api-server
+type apiClient struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// newAPIClient creates a client for the -kube-api-server, or for the in-cluster api-server using the pod service account
+func newAPIClient() (*apiClient, error) {
+	if kubeAPIServer != "" {
+		return &apiClient{server: strings.TrimSuffix(kubeAPIServer, "/"), client: &http.Client{Timeout: 30 * time.Second}}, nil
+	}
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a cluster and -kube-api-server is not set")
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %v/ca.crt", serviceAccountDir)
+	}
+	return &apiClient{
+		server: "https://" + net.JoinHostPort(host, port),
+		token:  strings.TrimSpace(string(token)),
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// get decodes the JSON object found at the api-server path
+func (c *apiClient) get(path string, into interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v returned %v", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+// errResourceExpired is returned by stream when the api-server no longer has the resource version to
+// watch from, 410 Gone, the resources must be listed again
+var errResourceExpired = errors.New("the resource version is too old")
+
+// stream opens the api-server path, a watch, and returns the body until ctx is done. The client timeout
+// does not apply, the api-server ends the watch at its timeoutSeconds
+func (c *apiClient) stream(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	client := &http.Client{Transport: c.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusGone:
+		resp.Body.Close()
+		return nil, errResourceExpired
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("GET %v returned %v", path, resp.Status)
+}
+
+// patch applies the JSON merge patch to the object at the api-server path
+func (c *apiClient) patch(path string, patch []byte) error {
+	req, err := http.NewRequest(http.MethodPatch, c.server+path, bytes.NewReader(patch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PATCH %v returned %v", path, resp.Status)
+	}
+	return nil
+}
+
+// podInformer keeps a copy of the non terminated pods of the cluster, refreshed every -informer-resync
+type podInformer struct {
+	client   *apiClient
+	lock     sync.RWMutex
+	pods     []v1.Pod
+	lastSync time.Time
+}
+
+func newPodInformer(client *apiClient) *podInformer {
+	return &podInformer{client: client}
+}
+
+// List returns the pods seen at the last refresh
+func (p *podInformer) List() []v1.Pod {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.pods
+}
+
+// HasSynced reports whether the pods have been listed at least once
+func (p *podInformer) HasSynced() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return !p.lastSync.IsZero()
+}
+
+// LastSync returns when the pods were last listed, zero before the first listing
+func (p *podInformer) LastSync() time.Time {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.lastSync
+}
+
+// refresh lists the pods from the api-server, the previous view is kept when it fails
+func (p *podInformer) refresh() error {
+	var list v1.PodList
+	if err := p.client.get("/api/v1/pods?fieldSelector=status.phase!=Succeeded,status.phase!=Failed", &list); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pods = list.Items
+	p.lastSync = time.Now()
+	return nil
+}
+
+// run refreshes the view until the stop channel is closed
+func (p *podInformer) run(stop <-chan struct{}) {
+	poll("pods", p.refresh, stop)
+}
+
+// poll calls refresh every -informer-resync until the stop channel is closed, a failed refresh is retried
+// at the next tick
+func poll(name string, refresh func() error, stop <-chan struct{}) {
+	ticker := time.NewTicker(informerResync)
+	defer ticker.Stop()
+	for {
+		if err := refresh(); err != nil {
+			glog.Warningf("failed to refresh the %v view: %v", name, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startInformers creates the cluster view when -enable-informers is set
+func startInformers(stop <-chan struct{}) {
+	if !enableInformers {
+		return
+	}
+	client, err := newAPIClient()
+	if err != nil {
+		fatalf("failed to create the api-server client: %v", err)
+	}
+	informer := newPodInformer(client)
+	go informer.run(stop)
+	podLister = informer
+	evictionHistory = newEvictionInformer(client)
+	go evictionHistory.run(stop)
+	go newNodeInformer(client, nodeImageInventory, nodeLister).run(stop)
+	if scoreTableConfigMap != "" {
+		go newScoreTableInformer(client, nodeScoreTable).run(stop)
+	}
+	glog.V(0).Infof("informers started, resyncing every %v\n", informerResync)
+}
+
+// nodePods are pods grouped by the normalized name of the node they are bound to
+type nodePods map[string][]v1.Pod
+
+// on returns the pods bound to the node
+func (n nodePods) on(nodeName string) []v1.Pod {
+	return n[normalizeNodeName(nodeName)]
+}
+
+// podsByNode groups the pods of the lister by the node they are bound to
+func podsByNode(lister PodLister) nodePods {
+	byNode := make(nodePods)
+	for _, pod := range lister.List() {
+		if pod.Spec.NodeName != "" {
+			name := normalizeNodeName(pod.Spec.NodeName)
+			byNode[name] = append(byNode[name], pod)
+		}
+	}
+	return byNode
+}
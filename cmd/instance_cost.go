@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	// costModeCost favors the cheapest nodes, for cost tolerant workloads
+	costModeCost = "cost"
+	// costModePerformance favors the most expensive, assumed most capable, nodes
+	costModePerformance = "performance"
+)
+
+var costModeAnnotation, instanceTypeLabel string
+
+func init() {
+	flag.StringVar(&costModeAnnotation, "cost-mode-annotation", "scheduler.extender/cost-mode", "The pod annotation selecting how instance_cost scores the nodes, one of: cost, performance")
+	flag.StringVar(&instanceTypeLabel, "instance-type-label", "node.kubernetes.io/instance-type", "The node label holding the instance type of the node")
+}
+
+// InstanceCostPriority maps the instance type of each node to its price, from the instanceTypePrices of
+// the -config file, and scores the nodes between the cheapest and the most expensive candidate. Pods
+// annotated with the cost mode favor the cheap nodes, the performance mode the expensive ones, the other
+// pods get the neutral score
+var InstanceCostPriority = PrioritizeMethod{
+	Name: "instance_cost",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		mode := pod.Annotations[costModeAnnotation]
+		config := currentConfig()
+		minPrice, maxPrice := -1.0, 0.0
+		for _, node := range nodes {
+			price := config.instancePrice(node.Labels[instanceTypeLabel])
+			if minPrice < 0 || price < minPrice {
+				minPrice = price
+			}
+			if price > maxPrice {
+				maxPrice = price
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if maxPrice <= minPrice || (mode != costModeCost && mode != costModePerformance) {
+				return neutralScore, nil
+			}
+			price := config.instancePrice(node.Labels[instanceTypeLabel])
+			score := int(schedulingapi.MaxPriority * (maxPrice - price) / (maxPrice - minPrice))
+			if mode == costModePerformance {
+				score = schedulingapi.MaxPriority - score
+			}
+			return score, nil
+		}
+	},
+}
+
+// instancePrice returns the price of the instance type, or the default price when it is unknown
+func (c *extenderConfig) instancePrice(instanceType string) float64 {
+	if c == nil {
+		return 0
+	}
+	if price, ok := c.InstanceTypePrices[instanceType]; ok {
+		return price
+	}
+	return c.DefaultInstancePrice
+}
@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestInstanceCostPriority(t *testing.T) {
+	prices := &extenderConfig{InstanceTypePrices: map[string]float64{"m5.large": 0.1, "m5.xlarge": 0.2}, DefaultInstancePrice: 0.15}
+	typed := func(name, instanceType string) v1.Node {
+		if instanceType == "" {
+			return labeledNode(name, nil)
+		}
+		return labeledNode(name, map[string]string{instanceTypeLabel: instanceType})
+	}
+	twoTypes := []v1.Node{typed("large", "m5.large"), typed("xlarge", "m5.xlarge")}
+	withUnknown := append(twoTypes[:2:2], typed("unknown", "c5.large"), typed("unlabeled", ""))
+	costPod := annotatedPod(map[string]string{costModeAnnotation: costModeCost})
+	performancePod := annotatedPod(map[string]string{costModeAnnotation: costModePerformance})
+	tests := []struct {
+		name     string
+		config   *extenderConfig
+		pod      v1.Pod
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{"cost", prices, costPod, twoTypes, map[string]int{"large": 10, "xlarge": 0}},
+		{"performance", prices, performancePod, twoTypes, map[string]int{"large": 0, "xlarge": 10}},
+		{"default price", prices, costPod, withUnknown, map[string]int{"large": 10, "xlarge": 0, "unknown": 5, "unlabeled": 5}},
+		{"no mode", prices, annotatedPod(nil), twoTypes, map[string]int{"large": neutralScore, "xlarge": neutralScore}},
+		{"unknown mode", prices, annotatedPod(map[string]string{costModeAnnotation: "cheap"}), twoTypes, map[string]int{"large": neutralScore, "xlarge": neutralScore}},
+		{"same price", prices, costPod, []v1.Node{typed("a", "m5.large"), typed("b", "m5.large")}, map[string]int{"a": neutralScore, "b": neutralScore}},
+		{"no price table", &extenderConfig{}, costPod, twoTypes, map[string]int{"large": neutralScore, "xlarge": neutralScore}},
+		{"no config", nil, costPod, twoTypes, map[string]int{"large": neutralScore, "xlarge": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withConfig(t, test.config)
+			checkScores(t, scoreMethod(t, InstanceCostPriority, test.pod, test.nodes), test.expected)
+		})
+	}
+}
+
+func TestInstancePrice(t *testing.T) {
+	config := &extenderConfig{InstanceTypePrices: map[string]float64{"m5.large": 0.1, "free": 0}, DefaultInstancePrice: 0.3}
+	tests := []struct {
+		config       *extenderConfig
+		instanceType string
+		expected     float64
+	}{
+		{config, "m5.large", 0.1},
+		{config, "free", 0},
+		{config, "c5.large", 0.3},
+		{config, "", 0.3},
+		{nil, "m5.large", 0},
+	}
+	for _, test := range tests {
+		if price := test.config.instancePrice(test.instanceType); price != test.expected {
+			t.Errorf("priced %q at %v, expected %v", test.instanceType, price, test.expected)
+		}
+	}
+}
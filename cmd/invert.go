@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// invertScores returns the complement of the scores of a method with Invert set, so a priority can be
+// reused with the opposite preference. Only normalized scores can be inverted, a score out of the 0 to
+// MaxPriority range is an error. Vetoes are kept as they are
+func invertScores(priorityMethod PrioritizeMethod, list schedulingapi.HostPriorityList) (schedulingapi.HostPriorityList, error) {
+	if !priorityMethod.Invert {
+		return list, nil
+	}
+	inverted := make(schedulingapi.HostPriorityList, len(list))
+	for i, hp := range list {
+		var err error
+		if inverted[i], err = invertScore(priorityMethod, hp); err != nil {
+			return nil, err
+		}
+	}
+	return inverted, nil
+}
+
+// invertScore inverts a single score as invertScores does
+func invertScore(priorityMethod PrioritizeMethod, hp schedulingapi.HostPriority) (schedulingapi.HostPriority, error) {
+	if !priorityMethod.Invert || hp.Score == UnfitScore {
+		return hp, nil
+	}
+	if hp.Score < 0 || hp.Score > schedulingapi.MaxPriority {
+		return hp, newError(ErrInternal, "priority method %v scored node %v %v, out of the 0-%v range it cannot be inverted", priorityMethod.Name, hp.Host, hp.Score, schedulingapi.MaxPriority)
+	}
+	hp.Score = schedulingapi.MaxPriority - hp.Score
+	return hp, nil
+}
@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+func TestInvertScores(t *testing.T) {
+	scores := schedulingapi.HostPriorityList{{Host: "a", Score: 0}, {Host: "b", Score: 3}, {Host: "c", Score: 10}, {Host: "d", Score: UnfitScore}}
+	tests := []struct {
+		name     string
+		invert   bool
+		list     schedulingapi.HostPriorityList
+		expected schedulingapi.HostPriorityList
+		valid    bool
+	}{
+		{"not inverted", false, scores, scores, true},
+		{"inverted", true, scores, schedulingapi.HostPriorityList{{Host: "a", Score: 10}, {Host: "b", Score: 7}, {Host: "c", Score: 0}, {Host: "d", Score: UnfitScore}}, true},
+		{"not normalized", true, schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 42}}, nil, false},
+		{"negative", true, schedulingapi.HostPriorityList{{Host: "a", Score: -2}}, nil, false},
+		{"out of range but not inverted", false, schedulingapi.HostPriorityList{{Host: "a", Score: 42}}, schedulingapi.HostPriorityList{{Host: "a", Score: 42}}, true},
+	}
+	for _, test := range tests {
+		list, err := invertScores(PrioritizeMethod{Name: "m", Invert: test.invert}, test.list)
+		if (err == nil) != test.valid {
+			t.Errorf("%v: returned %v", test.name, err)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(list, test.expected) {
+			t.Errorf("%v: inverted %v, expected %v", test.name, list, test.expected)
+		}
+	}
+	if scores[0].Score != 0 {
+		t.Errorf("inverting changed the scores of the caller")
+	}
+}
+
+func TestInvertedMethodOrdering(t *testing.T) {
+	nodes := testNodes("node-1", "node-3", "node-7")
+	pod := testPod("default", "p", nil)
+	tests := []struct {
+		name     string
+		config   *extenderConfig
+		expected map[string]int
+	}{
+		{"as registered", nil, map[string]int{"node-1": 1, "node-3": 3, "node-7": 7}},
+		{"inverted by the config", &extenderConfig{Priorities: []priorityConfig{{Name: digitPriority.Name, Invert: true}}}, map[string]int{"node-1": 9, "node-3": 7, "node-7": 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, digitPriority)
+			withConfig(t, test.config)
+			checkScores(t, prioritize(t, router, digitPriority.Name, pod, nodes), test.expected)
+		})
+	}
+}
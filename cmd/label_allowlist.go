@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultNodeLabelAllowlist holds the well-known topology labels
+var defaultNodeLabelAllowlist = []string{
+	"kubernetes.io/hostname",
+	"kubernetes.io/arch",
+	"kubernetes.io/os",
+	"topology.kubernetes.io/region",
+	"topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/zone",
+	"node.kubernetes.io/instance-type",
+	"beta.kubernetes.io/instance-type",
+}
+
+var nodeLabelAllowlist string
+
+// allowedNodeLabelKeys is the parsed -node-label-allowlist, nil when every key is allowed
+var allowedNodeLabelKeys map[string]bool
+
+func init() {
+	flag.StringVar(&nodeLabelAllowlist, "node-label-allowlist", strings.Join(defaultNodeLabelAllowlist, ","), "The comma separated node label keys the preferred node affinity terms of the pods and the score table selectors may consider, the well-known topology labels by default. The terms on other keys are ignored, * opts out and allows every key")
+}
+
+// parseNodeLabelAllowlist parses the -node-label-allowlist flag into allowedNodeLabelKeys
+func parseNodeLabelAllowlist() error {
+	allowedNodeLabelKeys = nil
+	if strings.TrimSpace(nodeLabelAllowlist) == "*" {
+		return nil
+	}
+	allowedNodeLabelKeys = make(map[string]bool)
+	for _, key := range strings.Split(nodeLabelAllowlist, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q in -node-label-allowlist: %v", key, strings.Join(errs, ", "))
+		}
+		allowedNodeLabelKeys[key] = true
+	}
+	return nil
+}
+
+// nodeLabelAllowed reports whether the label key is in the -node-label-allowlist
+func nodeLabelAllowed(key string) bool {
+	return allowedNodeLabelKeys == nil || allowedNodeLabelKeys[key]
+}
+
+// labelSelectorAllowed reports whether every requirement of the selector is on an allowed label key, a
+// selector on another key is ignored as a whole like a node selector term
+func labelSelectorAllowed(selector labels.Selector) bool {
+	requirements, _ := selector.Requirements()
+	for _, req := range requirements {
+		if !nodeLabelAllowed(req.Key()) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorTermAllowed reports whether every requirement of the term is on an allowed label key. A term
+// is dropped as a whole: leaving out one of its requirements would widen it to nodes the pod never asked for
+func nodeSelectorTermAllowed(term v1.NodeSelectorTerm) bool {
+	for _, req := range term.MatchExpressions {
+		if !nodeLabelAllowed(req.Key) {
+			glog.V(5).Infof("ignoring the node selector term requiring label %v, it is not in -node-label-allowlist\n", req.Key)
+			return false
+		}
+	}
+	return len(term.MatchExpressions) > 0 || len(term.MatchFields) > 0
+}
@@ -0,0 +1,163 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// withNodeLabelAllowlist sets -node-label-allowlist until the end of the test
+func withNodeLabelAllowlist(t *testing.T, allowlist string) {
+	saved, savedKeys := nodeLabelAllowlist, allowedNodeLabelKeys
+	t.Cleanup(func() { nodeLabelAllowlist, allowedNodeLabelKeys = saved, savedKeys })
+	nodeLabelAllowlist = allowlist
+	if err := parseNodeLabelAllowlist(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// labelTerm returns a node selector term requiring each label to be one of the values
+func labelTerm(requirements map[string]string) v1.NodeSelectorTerm {
+	var term v1.NodeSelectorTerm
+	for key, value := range requirements {
+		term.MatchExpressions = append(term.MatchExpressions, v1.NodeSelectorRequirement{Key: key, Operator: v1.NodeSelectorOpIn, Values: []string{value}})
+	}
+	return term
+}
+
+func TestParseNodeLabelAllowlist(t *testing.T) {
+	defer func(saved string, savedKeys map[string]bool) {
+		nodeLabelAllowlist, allowedNodeLabelKeys = saved, savedKeys
+	}(nodeLabelAllowlist, allowedNodeLabelKeys)
+	tests := []struct {
+		allowlist string
+		valid     bool
+		allowed   []string
+		denied    []string
+	}{
+		{"*", true, []string{"disktype", "topology.kubernetes.io/zone"}, nil},
+		{" * ", true, []string{"disktype"}, nil},
+		{"topology.kubernetes.io/zone, disktype,", true, []string{"disktype", "topology.kubernetes.io/zone"}, []string{"gpu"}},
+		{"", true, nil, []string{"disktype"}},
+		{"not a key", false, nil, nil},
+	}
+	for _, test := range tests {
+		nodeLabelAllowlist = test.allowlist
+		if err := parseNodeLabelAllowlist(); (err == nil) != test.valid {
+			t.Errorf("parseNodeLabelAllowlist(%q) returned %v", test.allowlist, err)
+			continue
+		}
+		for _, key := range test.allowed {
+			if !nodeLabelAllowed(key) {
+				t.Errorf("-node-label-allowlist=%q denies %v", test.allowlist, key)
+			}
+		}
+		for _, key := range test.denied {
+			if nodeLabelAllowed(key) {
+				t.Errorf("-node-label-allowlist=%q allows %v", test.allowlist, key)
+			}
+		}
+	}
+}
+
+func TestNodeSelectorTermAllowed(t *testing.T) {
+	withNodeLabelAllowlist(t, "zone")
+	tests := []struct {
+		name    string
+		term    v1.NodeSelectorTerm
+		allowed bool
+	}{
+		{"allowed key", labelTerm(map[string]string{"zone": "a"}), true},
+		{"a disallowed key drops the whole term", labelTerm(map[string]string{"zone": "a", "gpu": "true"}), false},
+		{"disallowed key", labelTerm(map[string]string{"gpu": "true"}), false},
+		{"fields only", v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}}}, true},
+		{"empty term", v1.NodeSelectorTerm{}, false},
+	}
+	for _, test := range tests {
+		if allowed := nodeSelectorTermAllowed(test.term); allowed != test.allowed {
+			t.Errorf("%v: nodeSelectorTermAllowed returned %v", test.name, allowed)
+		}
+	}
+}
+
+func TestLabelSelectorAllowed(t *testing.T) {
+	withNodeLabelAllowlist(t, "zone")
+	tests := []struct {
+		selector string
+		allowed  bool
+	}{
+		{"zone=a", true},
+		{"zone=a,disktype!=hdd", false},
+		{"!gpu", false},
+		{"", true},
+	}
+	for _, test := range tests {
+		selector, err := labels.Parse(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed := labelSelectorAllowed(selector); allowed != test.allowed {
+			t.Errorf("labelSelectorAllowed(%q) = %v", test.selector, allowed)
+		}
+	}
+}
+
+func TestNodeAffinityAllowlist(t *testing.T) {
+	nodes := testNodes("zone-a-gpu", "zone-a", "zone-b")
+	nodes[0].Labels = map[string]string{"zone": "a", "gpu": "true"}
+	nodes[1].Labels = map[string]string{"zone": "a"}
+	nodes[2].Labels = map[string]string{"zone": "b", "gpu": "true"}
+	pod := testPod("default", "p", nil)
+	pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+			{Weight: 1, Preference: labelTerm(map[string]string{"zone": "a", "gpu": "true"})},
+		},
+	}}
+
+	tests := []struct {
+		allowlist string
+		expected  map[string]int
+	}{
+		// the default honors every label
+		{"*", map[string]int{"zone-a-gpu": 10, "zone-a": 0, "zone-b": 0}},
+		// the term is not widened to zone=a, it is ignored and every node is neutral
+		{"zone", map[string]int{"zone-a-gpu": neutralScore, "zone-a": neutralScore, "zone-b": neutralScore}},
+		{"zone,gpu", map[string]int{"zone-a-gpu": 10, "zone-a": 0, "zone-b": 0}},
+	}
+	for _, test := range tests {
+		withNodeLabelAllowlist(t, test.allowlist)
+		checkScores(t, scoreMethod(t, NodeAffinityPriority, pod, nodes), test.expected)
+	}
+}
+
+func TestNodeLabelAllowlistDefault(t *testing.T) {
+	withNodeLabelAllowlist(t, strings.Join(defaultNodeLabelAllowlist, ","))
+	for _, key := range []string{"topology.kubernetes.io/zone", "kubernetes.io/hostname", "node.kubernetes.io/instance-type"} {
+		if !nodeLabelAllowed(key) {
+			t.Errorf("the default -node-label-allowlist denies %v", key)
+		}
+	}
+	for _, key := range []string{"disktype", "team", "example.com/rack"} {
+		if nodeLabelAllowed(key) {
+			t.Errorf("the default -node-label-allowlist allows %v", key)
+		}
+	}
+	if defaultValue := flag.Lookup("node-label-allowlist").DefValue; defaultValue != strings.Join(defaultNodeLabelAllowlist, ",") {
+		t.Errorf("-node-label-allowlist defaults to %q, expected the well-known topology labels", defaultValue)
+	}
+}
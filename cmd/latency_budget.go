@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var latencyBudgetAnnotation, networkTierLabel string
+var tightLatencyBudget, looseLatencyBudget time.Duration
+
+func init() {
+	flag.StringVar(&latencyBudgetAnnotation, "latency-budget-annotation", "scheduler.extender/latency-budget", "The pod annotation holding its network latency budget, e.g. 20ms")
+	flag.StringVar(&networkTierLabel, "network-tier-label", "node.example.com/network-tier", "The node label holding its network distance tier, 0 for the nearest nodes")
+	flag.DurationVar(&tightLatencyBudget, "tight-latency-budget", 10*time.Millisecond, "The latency budget at or below which latency_budget fully prefers the nearest tiers")
+	flag.DurationVar(&looseLatencyBudget, "loose-latency-budget", 100*time.Millisecond, "The latency budget at or above which latency_budget has no tier preference")
+}
+
+// validateLatencyBudgets makes sure the tight budget is below the loose one
+func validateLatencyBudgets() error {
+	if tightLatencyBudget < 0 || tightLatencyBudget >= looseLatencyBudget {
+		return fmt.Errorf("the latency budgets must satisfy 0 <= -tight-latency-budget < -loose-latency-budget, got %v and %v", tightLatencyBudget, looseLatencyBudget)
+	}
+	return nil
+}
+
+// LatencyBudgetPriority places the pods with a tight latency budget on the nearest network tiers. The
+// nearest candidate tier scores the max and the farthest 0 for a budget at or below -tight-latency-budget,
+// the preference then relaxes linearly to the neutral score for every node at -loose-latency-budget.
+// Pods without a budget, or nodes without a tier, get the neutral score
+var LatencyBudgetPriority = PrioritizeMethod{
+	Name: "latency_budget",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		strength := latencyBudgetStrength(pod)
+		tiers := make(map[string]int, len(nodes))
+		minTier, maxTier := -1, -1
+		for _, node := range nodes {
+			tier := nodeNetworkTier(node)
+			tiers[node.Name] = tier
+			if tier < 0 {
+				continue
+			}
+			if minTier < 0 || tier < minTier {
+				minTier = tier
+			}
+			if tier > maxTier {
+				maxTier = tier
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			tier := tiers[node.Name]
+			if strength <= 0 || tier < 0 || maxTier <= minTier {
+				return neutralScore, nil
+			}
+			proximity := float64(schedulingapi.MaxPriority) * float64(maxTier-tier) / float64(maxTier-minTier)
+			return clampScore(int(math.Round(float64(neutralScore) + strength*(proximity-float64(neutralScore))))), nil
+		}
+	},
+}
+
+// latencyBudgetStrength returns how strongly the pod budget asks for near nodes, from 0 to 1
+func latencyBudgetStrength(pod v1.Pod) float64 {
+	value, ok := pod.Annotations[latencyBudgetAnnotation]
+	if !ok {
+		return 0
+	}
+	budget, err := time.ParseDuration(value)
+	if err != nil {
+		glog.Warningf("ignoring invalid %v annotation %q on pod %v", latencyBudgetAnnotation, value, pod.Name)
+		return 0
+	}
+	switch {
+	case budget <= tightLatencyBudget:
+		return 1
+	case budget >= looseLatencyBudget:
+		return 0
+	}
+	return float64(looseLatencyBudget-budget) / float64(looseLatencyBudget-tightLatencyBudget)
+}
+
+// nodeNetworkTier returns the network tier of the node, -1 when missing or malformed
+func nodeNetworkTier(node v1.Node) int {
+	value, ok := node.Labels[networkTierLabel]
+	if !ok {
+		return -1
+	}
+	tier, err := strconv.Atoi(value)
+	if err != nil || tier < 0 {
+		glog.Warningf("ignoring invalid %v label %q on node %v", networkTierLabel, value, node.Name)
+		return -1
+	}
+	return tier
+}
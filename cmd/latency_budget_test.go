@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// withLatencyBudgets sets -tight-latency-budget and -loose-latency-budget for the test
+func withLatencyBudgets(t *testing.T, tight, loose time.Duration) {
+	savedTight, savedLoose := tightLatencyBudget, looseLatencyBudget
+	t.Cleanup(func() { tightLatencyBudget, looseLatencyBudget = savedTight, savedLoose })
+	tightLatencyBudget, looseLatencyBudget = tight, loose
+}
+
+// tierNode returns a node labeled with the network tier, not labeled when empty
+func tierNode(name, tier string) v1.Node {
+	if tier == "" {
+		return testNodes(name)[0]
+	}
+	return labeledNode(name, map[string]string{networkTierLabel: tier})
+}
+
+func TestValidateLatencyBudgets(t *testing.T) {
+	tests := []struct {
+		tight, loose time.Duration
+		valid        bool
+	}{
+		{10 * time.Millisecond, 100 * time.Millisecond, true},
+		{0, time.Millisecond, true},
+		{100 * time.Millisecond, 100 * time.Millisecond, false},
+		{100 * time.Millisecond, 10 * time.Millisecond, false},
+		{-time.Millisecond, 10 * time.Millisecond, false},
+	}
+	for _, test := range tests {
+		withLatencyBudgets(t, test.tight, test.loose)
+		if err := validateLatencyBudgets(); (err == nil) != test.valid {
+			t.Errorf("validateLatencyBudgets(%v, %v) returned %v", test.tight, test.loose, err)
+		}
+	}
+}
+
+func TestLatencyBudgetStrength(t *testing.T) {
+	withLatencyBudgets(t, 10*time.Millisecond, 100*time.Millisecond)
+	tests := []struct {
+		budget   string
+		expected float64
+	}{
+		{"", 0},
+		{"5ms", 1},
+		{"10ms", 1},
+		{"55ms", 0.5},
+		{"100ms", 0},
+		{"1s", 0},
+		{"fast", 0},
+	}
+	for _, test := range tests {
+		pod := testPod("default", "p", nil)
+		if test.budget != "" {
+			pod = annotatedPod(map[string]string{latencyBudgetAnnotation: test.budget})
+		}
+		if strength := latencyBudgetStrength(pod); strength != test.expected {
+			t.Errorf("the budget %q has the strength %v, expected %v", test.budget, strength, test.expected)
+		}
+	}
+}
+
+func TestNodeNetworkTier(t *testing.T) {
+	for tier, expected := range map[string]int{"": -1, "0": 0, "3": 3, "-1": -1, "near": -1} {
+		if result := nodeNetworkTier(tierNode("n", tier)); result != expected {
+			t.Errorf("the tier label %q gave the tier %v, expected %v", tier, result, expected)
+		}
+	}
+}
+
+func TestLatencyBudgetPriority(t *testing.T) {
+	withLatencyBudgets(t, 10*time.Millisecond, 100*time.Millisecond)
+	withNeutralScore(t, 5)
+	nodes := []v1.Node{tierNode("near", "0"), tierNode("mid", "1"), tierNode("far", "2"), tierNode("untiered", ""), tierNode("malformed", "x")}
+	tests := []struct {
+		name     string
+		budget   string
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{"tight", "5ms", nodes, map[string]int{"near": 10, "mid": 5, "far": 0, "untiered": 5, "malformed": 5}},
+		{"halfway", "55ms", nodes, map[string]int{"near": 8, "mid": 5, "far": 3, "untiered": 5, "malformed": 5}},
+		{"loose", "1s", nodes, map[string]int{"near": 5, "mid": 5, "far": 5, "untiered": 5, "malformed": 5}},
+		{"no budget", "", nodes, map[string]int{"near": 5, "mid": 5, "far": 5, "untiered": 5, "malformed": 5}},
+		{"malformed budget", "soon", nodes, map[string]int{"near": 5, "mid": 5, "far": 5, "untiered": 5, "malformed": 5}},
+		{"single tier", "5ms", []v1.Node{tierNode("a", "1"), tierNode("b", "1")}, map[string]int{"a": 5, "b": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := testPod("default", "p", nil)
+			if test.budget != "" {
+				pod = annotatedPod(map[string]string{latencyBudgetAnnotation: test.budget})
+			}
+			checkScores(t, scoreMethod(t, LatencyBudgetPriority, pod, test.nodes), test.expected)
+		})
+	}
+}
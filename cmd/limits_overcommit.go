@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// overcommitTolerance is the fraction of the allocatable the summed limits may exceed on a node
+var overcommitTolerance float64
+
+func init() {
+	flag.Float64Var(&overcommitTolerance, "overcommit-tolerance", 0, "The fraction of the node allocatable the summed limits may exceed before limits_overcommit considers the node overcommitted, e.g. 0.2")
+}
+
+// validateOvercommitTolerance checks -overcommit-tolerance is not negative
+func validateOvercommitTolerance() error {
+	if overcommitTolerance < 0 {
+		return fmt.Errorf("the -overcommit-tolerance flag value must not be negative, got %v", overcommitTolerance)
+	}
+	return nil
+}
+
+// overcommitResources are the resources limits_overcommit checks the limits of
+var overcommitResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+
+// LimitsOvercommitPriority prefers, for pods setting cpu or memory limits, the nodes where the summed
+// limits of the pods, the incoming one included, still fit the allocatable plus -overcommit-tolerance.
+// The fitting nodes score 1-10 by the headroom left on their tightest resource, the overcommitted ones 0.
+// Pods without limits, and nodes not reporting the allocatable of the limited resources, get the neutral score
+var LimitsOvercommitPriority = PrioritizeMethod{
+	Name:              "limits_overcommit",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		limits := make(map[v1.ResourceName]int64)
+		for _, name := range overcommitResources {
+			if limit := podLimit(pod, name); limit > 0 {
+				limits[name] = limit
+			}
+		}
+		byNode := podsByNode(podLister)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if len(limits) == 0 {
+				return neutralScore, nil
+			}
+			headroom, checked := 1.0, false
+			for name, limit := range limits {
+				capacity := float64(nodeAllocatable(node, name)) * (1 + overcommitTolerance)
+				if capacity <= 0 {
+					continue
+				}
+				checked = true
+				free := (capacity - float64(sumLimits(byNode.on(node.Name), name)+limit)) / capacity
+				if free < 0 {
+					return 0, nil
+				}
+				if free < headroom {
+					headroom = free
+				}
+			}
+			if !checked {
+				return neutralScore, nil
+			}
+			return 1 + int(float64(schedulingapi.MaxPriority-1)*headroom), nil
+		}
+	},
+}
@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withOvercommitTolerance sets -overcommit-tolerance for the test
+func withOvercommitTolerance(t *testing.T, tolerance float64) {
+	saved := overcommitTolerance
+	t.Cleanup(func() { overcommitTolerance = saved })
+	overcommitTolerance = tolerance
+}
+
+func TestValidateOvercommitTolerance(t *testing.T) {
+	for tolerance, valid := range map[float64]bool{0: true, 0.2: true, 2: true, -0.1: false} {
+		withOvercommitTolerance(t, tolerance)
+		if err := validateOvercommitTolerance(); (err == nil) != valid {
+			t.Errorf("validateOvercommitTolerance(%v) returned %v", tolerance, err)
+		}
+	}
+}
+
+func TestLimitsOvercommitPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	withPods(t,
+		resourcePod("limited", "big", nil, resourceList("1", "2Gi")),
+		resourcePod("full", "small", nil, resourceList("2", "")),
+		resourcePod("requested", "requested", resourceList("2", ""), nil),
+	)
+	memoryOnly := testNodes("memory-only")[0]
+	memoryOnly.Status.Allocatable = resourceList("", "8Gi")
+	nodes := []v1.Node{
+		allocatableNode("big", "4", "8Gi"),
+		allocatableNode("small", "2", "4Gi"),
+		allocatableNode("empty", "4", "8Gi"),
+		allocatableNode("requested", "4", "8Gi"),
+		memoryOnly,
+		testNodes("bare")[0],
+	}
+	tests := []struct {
+		name      string
+		tolerance float64
+		limits    v1.ResourceList
+		expected  map[string]int
+	}{
+		{"cpu and memory limits", 0, resourceList("1", "2Gi"), map[string]int{"big": 5, "small": 0, "empty": 7, "requested": 3, "memory-only": 7, "bare": 5}},
+		{"cpu limit", 0, resourceList("1", ""), map[string]int{"big": 5, "small": 0, "empty": 7, "requested": 3, "memory-only": 5, "bare": 5}},
+		{"tolerance", 0.5, resourceList("1", ""), map[string]int{"big": 7, "small": 1, "empty": 8, "requested": 5, "memory-only": 5, "bare": 5}},
+		{"no limit", 0, nil, map[string]int{"big": 5, "small": 5, "empty": 5, "requested": 5, "memory-only": 5, "bare": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withOvercommitTolerance(t, test.tolerance)
+			pod := resourcePod("p", "", nil, test.limits)
+			checkScores(t, scoreMethod(t, LimitsOvercommitPriority, pod, nodes), test.expected)
+		})
+	}
+}
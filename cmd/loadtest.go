@@ -0,0 +1,204 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// loadTestImages are the images the synthetic pods and nodes are built from
+var loadTestImages = []string{"nginx:1.7.9", "redis:5", "busybox:1.31", "postgres:11", "memcached:1.5", "httpd:2.4"}
+
+// loadTestResult is the outcome of a single request fired by the load test
+type loadTestResult struct {
+	latency time.Duration
+	err     error
+}
+
+// runLoadTest is the `loadtest` subcommand: it fires synthetic ExtenderArgs at a running extender at a
+// fixed rate and reports the latency percentiles and the error rate
+func runLoadTest(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	url := fs.String("url", "http://localhost:80/my_scheduler_extension/my_new_priorities/image_score", "The prioritize URL to load")
+	rps := fs.Int("rps", 50, "The number of requests per second")
+	nodes := fs.Int("nodes", 100, "The number of nodes in each request")
+	duration := fs.Duration("duration", 10*time.Second, "How long the load lasts")
+	token := fs.String("token", "", "The bearer token sent in the Authorization header, if any")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rps <= 0 || *nodes <= 0 || *duration <= 0 {
+		return fmt.Errorf("-rps, -nodes and -duration must be positive")
+	}
+	// the ticker needs a non zero interval
+	if int64(*rps) > int64(time.Second) {
+		return fmt.Errorf("-rps must be at most %v, got %v", int64(time.Second), *rps)
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	nodeList := syntheticNodes(random, *nodes)
+	client := &http.Client{Timeout: 30 * time.Second}
+	results := make(chan loadTestResult, *rps)
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(time.Second / time.Duration(*rps))
+	defer ticker.Stop()
+	deadline := time.After(*duration)
+	start := time.Now()
+	var collected []loadTestResult
+	done := make(chan struct{})
+	go func() {
+		for result := range results {
+			collected = append(collected, result)
+		}
+		close(done)
+	}()
+fire:
+	for i := 0; ; i++ {
+		select {
+		case <-deadline:
+			break fire
+		case <-ticker.C:
+		}
+		body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: syntheticPod(random, i), Nodes: nodeList})
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- fireLoadTestRequest(client, *url, *token, body)
+		}()
+	}
+	wg.Wait()
+	close(results)
+	<-done
+	reportLoadTest(out, collected, time.Since(start))
+	return nil
+}
+
+// fireLoadTestRequest posts the body and measures the latency until the response is fully read
+func fireLoadTestRequest(client *http.Client, url, token string, body []byte) loadTestResult {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return loadTestResult{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return loadTestResult{latency: time.Since(start), err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return loadTestResult{latency: time.Since(start), err: err}
+}
+
+// reportLoadTest prints the achieved rate, the error rate and the latency percentiles
+func reportLoadTest(out io.Writer, results []loadTestResult, elapsed time.Duration) {
+	var latencies []time.Duration
+	var errors int
+	for _, result := range results {
+		if result.err != nil {
+			errors++
+			continue
+		}
+		latencies = append(latencies, result.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	fmt.Fprintf(out, "requests: %v in %v (%.1f/s)\n", len(results), elapsed.Round(time.Millisecond), float64(len(results))/elapsed.Seconds())
+	if len(results) > 0 {
+		fmt.Fprintf(out, "errors: %v (%.2f%%)\n", errors, 100*float64(errors)/float64(len(results)))
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	for _, p := range []float64{50, 90, 99} {
+		fmt.Fprintf(out, "p%v: %v\n", p, latencies[int(float64(len(latencies)-1)*p/100)])
+	}
+	fmt.Fprintf(out, "max: %v\n", latencies[len(latencies)-1])
+}
+
+// syntheticNodes builds nodes holding a random subset of the load test images
+func syntheticNodes(random *rand.Rand, count int) *v1.NodeList {
+	list := &v1.NodeList{Items: make([]v1.Node, count)}
+	for i := range list.Items {
+		var images []v1.ContainerImage
+		for _, image := range loadTestImages {
+			if random.Intn(2) == 0 {
+				images = append(images, v1.ContainerImage{Names: []string{"docker.io/library/" + image}, SizeBytes: int64(random.Intn(500)+10) << 20})
+			}
+		}
+		list.Items[i] = v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("loadtest-node-%v", i),
+				Labels: map[string]string{"kubernetes.io/hostname": fmt.Sprintf("loadtest-node-%v", i)},
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%v", 2*(random.Intn(16)+1))),
+					v1.ResourceMemory: resource.MustParse(fmt.Sprintf("%vGi", 4*(random.Intn(16)+1))),
+					v1.ResourcePods:   resource.MustParse("110"),
+				},
+				Images: images,
+			},
+		}
+	}
+	return list
+}
+
+// syntheticPod builds a pod running one to three of the load test images
+func syntheticPod(random *rand.Rand, i int) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("loadtest-pod-%v", i),
+			Namespace: "default",
+			UID:       types.UID(fmt.Sprintf("loadtest-%v-%v", os.Getpid(), i)),
+		},
+	}
+	containers := random.Intn(3) + 1
+	for c := 0; c < containers; c++ {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+			Name:  fmt.Sprintf("ctnr-%v", c),
+			Image: loadTestImages[random.Intn(len(loadTestImages))],
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("128Mi"),
+			}},
+		})
+	}
+	return pod
+}
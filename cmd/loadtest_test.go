@@ -0,0 +1,110 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTestArguments(t *testing.T) {
+	tests := [][]string{
+		{"-rps", "0"},
+		{"-rps", "2000000000"},
+		{"-nodes", "-1"},
+		{"-duration", "0s"},
+		{"-unknown"},
+	}
+	for _, args := range tests {
+		var out bytes.Buffer
+		if err := runLoadTest(args, &out); err == nil {
+			t.Errorf("loadtest %v was accepted", args)
+		}
+	}
+}
+
+func TestLoadTest(t *testing.T) {
+	router := newTestRouter(t, ImagePriority)
+	tests := []struct {
+		name    string
+		handler http.Handler
+		errors  string
+	}{
+		{"extender", router, "errors: 0 (0.00%)"},
+		{"failing extender", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusInternalServerError)
+		}), "(100.00%)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+			var out bytes.Buffer
+			args := []string{"-url", server.URL + apiPrefixes[0] + prioritiesPrefix + "/" + ImagePriority.Name, "-rps", "20", "-nodes", "20", "-duration", "250ms"}
+			if err := runLoadTest(args, &out); err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(out.String(), "requests: 0 ") || !strings.Contains(out.String(), test.errors) {
+				t.Errorf("reported %q, expected requests with %v", out.String(), test.errors)
+			}
+		})
+	}
+}
+
+func TestReportLoadTest(t *testing.T) {
+	var results []loadTestResult
+	for i := 1; i <= 10; i++ {
+		results = append(results, loadTestResult{latency: time.Duration(11-i) * time.Millisecond})
+	}
+	results = append(results, loadTestResult{err: errors.New("timeout")}, loadTestResult{err: errors.New("timeout")})
+	tests := []struct {
+		name     string
+		results  []loadTestResult
+		expected string
+	}{
+		{"latencies", results, "requests: 12 in 2s (6.0/s)\nerrors: 2 (16.67%)\np50: 5ms\np90: 9ms\np99: 9ms\nmax: 10ms\n"},
+		{"no request", nil, "requests: 0 in 2s (0.0/s)\n"},
+		{"only errors", results[10:], "requests: 2 in 2s (1.0/s)\nerrors: 2 (100.00%)\n"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		reportLoadTest(&out, test.results, 2*time.Second)
+		if out.String() != test.expected {
+			t.Errorf("%v: reported %q, expected %q", test.name, out.String(), test.expected)
+		}
+	}
+}
+
+func TestSyntheticPodContainers(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	counts := make(map[int]int)
+	const pods = 3000
+	for i := 0; i < pods; i++ {
+		counts[len(syntheticPod(random, i).Spec.Containers)]++
+	}
+	// one to three containers, as likely as each other
+	for containers := 1; containers <= 3; containers++ {
+		if share := float64(counts[containers]) / pods; share < 0.28 || share > 0.39 {
+			t.Errorf("%v of the pods have %v containers, expected about a third", share, containers)
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("built pods with %v containers, expected one to three", counts)
+	}
+}
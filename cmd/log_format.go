@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+)
+
+var logFormat string
+
+// logConverter is the json converter stderr is rerouted through, nil in the text format
+var logConverter *jsonConverter
+
+// jsonConverter converts the lines glog writes to the pipe, done is closed once they are all written out
+type jsonConverter struct {
+	pipe *os.File
+	out  io.Writer
+	done chan struct{}
+}
+
+func init() {
+	flag.StringVar(&logFormat, "log-format", "text", "The format of the logs written to stderr, one of: text, json. json needs -logtostderr or -alsologtostderr, the log files stay in the text format")
+}
+
+// glogHeader matches the header glog prefixes each line with, Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg
+var glogHeader = regexp.MustCompile(`^([IWEF])(\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+(\d+) ([^:\]]+):(\d+)\] (.*)$`)
+
+var glogLevels = map[string]string{"I": "info", "W": "warning", "E": "error", "F": "fatal"}
+
+// jsonLogLine is a log line in the json format
+type jsonLogLine struct {
+	Level   string `json:"level"`
+	Time    string `json:"time"`
+	Message string `json:"msg"`
+	Caller  string `json:"caller,omitempty"`
+	Thread  string `json:"thread,omitempty"`
+	// Fields holds the key/value pairs logFields appended to the message
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// logFieldsSuffix matches the key="value" pairs logFields appends to a message, logField one of them
+var logFieldsSuffix = regexp.MustCompile(`((?: [A-Za-z_][A-Za-z0-9_]*="(?:[^"\\]|\\.)*")+)$`)
+var logField = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)=("(?:[^"\\]|\\.)*")`)
+
+// logFields renders the key/value pairs as a key="value" suffix of the log message, in the json format
+// the converter moves them to the fields of the record, e.g.
+// glog.Infof("scored %v nodes%v", n, logFields("pod", "default/p", "method", "image_score"))
+func logFields(keysAndValues ...interface{}) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fmt.Fprintf(&b, " %v=%q", keysAndValues[i], fmt.Sprint(keysAndValues[i+1]))
+	}
+	return b.String()
+}
+
+// podLogName is the pod field of logFields, namespace/name
+func podLogName(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// splitLogFields splits the logFields suffix from the message
+func splitLogFields(message string) (string, map[string]string) {
+	loc := logFieldsSuffix.FindStringIndex(message)
+	if loc == nil {
+		return message, nil
+	}
+	fields := make(map[string]string)
+	for _, match := range logField.FindAllStringSubmatch(message[loc[0]:], -1) {
+		value, err := strconv.Unquote(match[2])
+		if err != nil {
+			return message, nil
+		}
+		fields[match[1]] = value
+	}
+	return message[:loc[0]], fields
+}
+
+// setupLogFormat validates -log-format and, for json, reroutes stderr through the json converter. glog
+// keeps doing the V-level filtering, only the lines it writes are converted
+func setupLogFormat() error {
+	switch logFormat {
+	case "text":
+		return nil
+	case "json":
+	default:
+		return fmt.Errorf("unknown -log-format %q, expecting one of: text, json", logFormat)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create the log pipe: %v", err)
+	}
+	logConverter = &jsonConverter{pipe: w, out: os.Stderr, done: make(chan struct{})}
+	os.Stderr = w
+	go func() {
+		convertLogs(r, logConverter.out)
+		close(logConverter.done)
+	}()
+	return nil
+}
+
+// fatal logs the arguments like glog.Fatal and exits, fatalf like glog.Fatalf. glog exits as soon as it
+// wrote a fatal line, before the json converter could write it out, so the converter is drained and the
+// fatal line written synchronously first
+func fatal(args ...interface{}) {
+	logFatal(fmt.Sprint(args...))
+}
+
+func fatalf(format string, args ...interface{}) {
+	logFatal(fmt.Sprintf(format, args...))
+}
+
+func logFatal(message string) {
+	if logConverter != nil {
+		logConverter.writeFatal(message, 3, time.Now())
+	}
+	glog.FatalDepth(2, message)
+}
+
+// writeFatal stops the conversion once the lines already logged are written out, and writes the fatal
+// line of the caller at depth. glog still writes it to the log files, its write to the closed pipe is lost
+func (c *jsonConverter) writeFatal(message string, depth int, now time.Time) {
+	c.pipe.Close()
+	select {
+	case <-c.done:
+	case <-time.After(5 * time.Second):
+	}
+	line := jsonLogLine{Level: "fatal", Time: now.Format(time.RFC3339Nano), Message: message, Thread: strconv.Itoa(os.Getpid())}
+	if _, file, lineNumber, ok := runtime.Caller(depth); ok {
+		line.Caller = filepath.Base(file) + ":" + strconv.Itoa(lineNumber)
+	}
+	json.NewEncoder(c.out).Encode(line)
+}
+
+// convertLogs writes each glog line read from r as a json object to w, the lines continuing a multi-line
+// message are written as messages of their own with the level of the line they continue
+func convertLogs(r io.Reader, w io.Writer) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(w)
+	level := "info"
+	for scanner.Scan() {
+		encoder.Encode(parseLogLine(scanner.Text(), &level, time.Now()))
+	}
+}
+
+// parseLogLine turns a glog line into its json form, level holds the level of the previous line
+func parseLogLine(line string, level *string, now time.Time) jsonLogLine {
+	match := glogHeader.FindStringSubmatch(line)
+	if match == nil {
+		return jsonLogLine{Level: *level, Time: now.Format(time.RFC3339Nano), Message: strings.TrimSpace(line)}
+	}
+	*level = glogLevels[match[1]]
+	logged := now
+	// glog leaves the year out of its timestamps
+	if t, err := time.ParseInLocation("0102 15:04:05.000000", match[2], time.Local); err == nil {
+		logged = t.AddDate(now.Year(), 0, 0)
+	}
+	message, fields := splitLogFields(match[6])
+	return jsonLogLine{
+		Level:   *level,
+		Time:    logged.Format(time.RFC3339Nano),
+		Message: message,
+		Caller:  match[4] + ":" + match[5],
+		Thread:  match[3],
+		Fields:  fields,
+	}
+}
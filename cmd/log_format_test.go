@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		line          string
+		previousLevel string
+		expected      jsonLogLine
+	}{
+		{"I0501 10:11:12.123456    42 main.go:88] added priority method", "info",
+			jsonLogLine{Level: "info", Message: "added priority method", Caller: "main.go:88", Thread: "42"}},
+		{"W0501 10:11:12.123456 7 node_health.go:12] slow endpoint", "info",
+			jsonLogLine{Level: "warning", Message: "slow endpoint", Caller: "node_health.go:12", Thread: "7"}},
+		{"F0501 10:11:12.123456 7 main.go:1] bad flag", "info",
+			jsonLogLine{Level: "fatal", Message: "bad flag", Caller: "main.go:1", Thread: "7"}},
+		{`I0501 10:11:12.123456 7 main.go:591] scored pod="default/p" node="n \"1\"" method="image_score"`, "info",
+			jsonLogLine{Level: "info", Message: "scored", Caller: "main.go:591", Thread: "7", Fields: map[string]string{"pod": "default/p", "node": `n "1"`, "method": "image_score"}}},
+		{`I0501 10:11:12.123456 7 main.go:591] a quoted "word" stays in the message`, "info",
+			jsonLogLine{Level: "info", Message: `a quoted "word" stays in the message`, Caller: "main.go:591", Thread: "7"}},
+		// the continuation of a multi-line message keeps the level of the line it continues
+		{"  second line of the message ", "error",
+			jsonLogLine{Level: "error", Message: "second line of the message"}},
+	}
+	for _, test := range tests {
+		level := test.previousLevel
+		got := parseLogLine(test.line, &level, now)
+		got.Time = ""
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("parseLogLine(%q) = %+v, expected %+v", test.line, got, test.expected)
+		}
+		if level != test.expected.Level {
+			t.Errorf("parseLogLine(%q) left the level %v", test.line, level)
+		}
+	}
+	level := "info"
+	if got := parseLogLine("I0501 10:11:12.123456 42 main.go:88] x", &level, now); !strings.HasPrefix(got.Time, "2026-05-01T10:11:12.123456") {
+		t.Errorf("expected the glog timestamp with the current year, got %v", got.Time)
+	}
+}
+
+// TestLogFields checks the logFields of a message logged by glog end up in the fields of the json record
+func TestLogFields(t *testing.T) {
+	pod := testPod("default", "p", nil)
+	message := fmt.Sprintf("priorityMethod %v failed%v", "image_score", logFields("pod", podLogName(&pod), "node", "n1", "method", "image_score"))
+	var out bytes.Buffer
+	convertLogs(strings.NewReader("E0501 10:11:12.123456 42 main.go:548] "+message+"\n"), &out)
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("the record %q is not valid json: %v", out.String(), err)
+	}
+	expected := map[string]interface{}{"pod": "default/p", "node": "n1", "method": "image_score"}
+	if record["msg"] != "priorityMethod image_score failed" || !reflect.DeepEqual(record["fields"], expected) {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+func TestSetupLogFormat(t *testing.T) {
+	defer func(saved string) { logFormat = saved }(logFormat)
+	for format, valid := range map[string]bool{"text": true, "xml": false} {
+		logFormat = format
+		if err := setupLogFormat(); (err == nil) != valid {
+			t.Errorf("setupLogFormat with %q returned %v", format, err)
+		}
+	}
+}
+
+// TestWriteFatal checks the lines logged before the fatal one are written out first, and the fatal line
+// last, before the process would exit
+func TestWriteFatal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	converter := &jsonConverter{pipe: w, out: &out, done: make(chan struct{})}
+	go func() {
+		convertLogs(r, converter.out)
+		close(converter.done)
+	}()
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("I0501 10:11:12.123456 42 main.go:88] starting\n"))
+	}
+	converter.writeFatal("the api-server is unreachable", 1, time.Now())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 101 {
+		t.Fatalf("expected 100 converted lines and the fatal one, got %v lines", len(lines))
+	}
+	var fatalLine jsonLogLine
+	if err := json.Unmarshal([]byte(lines[100]), &fatalLine); err != nil {
+		t.Fatal(err)
+	}
+	if fatalLine.Level != "fatal" || fatalLine.Message != "the api-server is unreachable" || !strings.HasPrefix(fatalLine.Caller, "log_format_test.go:") {
+		t.Errorf("unexpected fatal line %+v", fatalLine)
+	}
+}
@@ -14,11 +14,15 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 
@@ -29,56 +33,243 @@ import (
 
 var httpAddr, apiPrefix, prioritiesPrefix string
 
+// apiPrefixes holds the normalized values of the comma separated -api-prefix flag, every route is
+// served under each of them so the extender can be migrated to a new prefix without downtime
+var apiPrefixes []string
+
+// neutralScore is the score given when a priority has no opinion about a node, set by -neutral-score.
+// The offsets of node_bias, owner_stickiness, pool_density and qos_headroom apply around it, clamped to
+// 0-10, while invert maps a score to 10 - score whatever the neutral score
+var neutralScore = schedulingapi.MaxPriority / 2
+
 func init() {
-	flag.StringVar(&apiPrefix, "api-prefix", "/my_scheduler_extension", "The api prefix path, e.g. /scheduler_extension")
+	flag.IntVar(&neutralScore, "neutral-score", schedulingapi.MaxPriority/2, "The score given when the extender has no opinion about a node, e.g. while warming up or for sampled out nodes")
+	flag.StringVar(&apiPrefix, "api-prefix", "/my_scheduler_extension", "The api prefix path, a comma separated list serves the routes under each prefix, e.g. /scheduler_extension,/v2/scheduler_extension")
 	flag.StringVar(&prioritiesPrefix, "priorities-prefix", "/my_new_priorities", "The priorities prefix path, e.g. /a_new_priorities")
 	flag.StringVar(&httpAddr, "http-addr", ":80", "The ip:port address the extender endpoint binds to, if <ip> is missing it bings to localhost")
 	flag.Set("logtostderr", "true")
 	flag.Set("stderrthreshold", "WARNING")
+}
+
+// validateNeutralScore makes sure the -neutral-score flag is a valid score
+func validateNeutralScore() error {
+	if neutralScore < 0 || neutralScore > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -neutral-score flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, neutralScore)
+	}
+	return nil
+}
+
+// parseFlags parses the command line and normalizes the flag values, flags are registered
+// in the init functions of each file so parsing has to wait until main is called
+func parseFlags() {
 	flag.Parse()
+	if err := setupLogFormat(); err != nil {
+		fatal(err)
+	}
 	if !strings.Contains(httpAddr, ":") {
 		httpAddr = ":" + httpAddr
 		glog.Warningf("the -http-addr flag value was missing a `:`, it was automatically added -> %v", httpAddr)
 	}
-	if !strings.HasPrefix(apiPrefix, "/") {
-		apiPrefix = "/" + apiPrefix
-		glog.Warningf("the -api-prefix flag value was missing a `/`, it was automatically added -> %v", apiPrefix)
+	if err := parseAPIPrefixes(); err != nil {
+		fatal(err)
 	}
 	if !strings.HasPrefix(prioritiesPrefix, "/") {
 		prioritiesPrefix = "/" + prioritiesPrefix
 		glog.Warningf("the -priorities-prefix flag value was missing a `/`, it was automatically added -> %v", prioritiesPrefix)
 	}
-	prioritiesPrefix = apiPrefix + prioritiesPrefix
+	normalizeFiltersPrefix()
+	loadAuthToken()
+	if err := validateExternalScorer(); err != nil {
+		fatal(err)
+	}
+	if err := validateVetoMode(); err != nil {
+		fatal(err)
+	}
+	if err := validateImageMatchMode(); err != nil {
+		fatal(err)
+	}
+	if err := validateNodeScoringConcurrency(); err != nil {
+		fatal(err)
+	}
+	if err := validateZoneFalloff(); err != nil {
+		fatal(err)
+	}
+	if err := validateImageStore(); err != nil {
+		fatal(err)
+	}
+	if err := validateScoreFloor(); err != nil {
+		fatal(err)
+	}
+	if err := validateGPUBalance(); err != nil {
+		fatal(err)
+	}
+	if err := validateScoreTable(); err != nil {
+		fatal(err)
+	}
+	if err := validateImagePullBytesWeight(); err != nil {
+		fatal(err)
+	}
+	if err := validateImageGCWatermarks(); err != nil {
+		fatal(err)
+	}
+	if err := validateRecencyDecay(); err != nil {
+		fatal(err)
+	}
+	if err := validateBaseScoreWeight(); err != nil {
+		fatal(err)
+	}
+	if err := validateTopK(); err != nil {
+		fatal(err)
+	}
+	if err := validateLatencyBudgets(); err != nil {
+		fatal(err)
+	}
+	if err := validateSharedVolumeBonus(); err != nil {
+		fatal(err)
+	}
+	if err := validateOvercommitTolerance(); err != nil {
+		fatal(err)
+	}
+	if err := validateCircuit(); err != nil {
+		fatal(err)
+	}
+	if err := validateImagePopularity(); err != nil {
+		fatal(err)
+	}
+	if err := validateExtenderAPIVersion(); err != nil {
+		fatal(err)
+	}
+	if err := validateRuntimeVersionMode(); err != nil {
+		fatal(err)
+	}
+	if err := validateSecurityProfileMode(); err != nil {
+		fatal(err)
+	}
+	if err := validateAllPoor(); err != nil {
+		fatal(err)
+	}
+	if err := validateNodeAgent(); err != nil {
+		fatal(err)
+	}
+	if err := validateScoreAnnotationQPS(); err != nil {
+		fatal(err)
+	}
+	if err := validateNeutralScore(); err != nil {
+		fatal(err)
+	}
+	if err := parseNodeLabelAllowlist(); err != nil {
+		fatal(err)
+	}
+	if err := parseDaemonSelector(); err != nil {
+		fatal(err)
+	}
+	if err := parsePodAllowlist(); err != nil {
+		fatal(err)
+	}
+	if err := validateSampling(); err != nil {
+		fatal(err)
+	}
+	if err := validateAllFailedStatus(); err != nil {
+		fatal(err)
+	}
+	if err := validateQOSBiases(); err != nil {
+		fatal(err)
+	}
+	if err := compileNodeNameRegex(); err != nil {
+		fatal(err)
+	}
+	if err := validateContention(); err != nil {
+		fatal(err)
+	}
+	if err := validateWarmup(); err != nil {
+		fatal(err)
+	}
+	if err := validateEvictionPenalty(); err != nil {
+		fatal(err)
+	}
+	if spreadMaxSkew < 0 {
+		fatalf("the -spread-max-skew flag value must not be negative, got %v", spreadMaxSkew)
+	}
+	if hypervisorMaxSkew < 0 {
+		fatalf("the -hypervisor-max-skew flag value must not be negative, got %v", hypervisorMaxSkew)
+	}
+	if stabilityWindow <= 0 {
+		fatalf("the -stability-window flag value must be positive, got %v", stabilityWindow)
+	}
+	if defaultBandwidthMbps <= 0 {
+		fatalf("the -default-bandwidth-mbps flag value must be positive, got %v", defaultBandwidthMbps)
+	}
+	if err := validateFlags(); err != nil {
+		fatal(err)
+	}
 }
 
 // PrioritizeMethod defines the name of the priority. this name should much the one specified in the
-// scheduler config file, since it is part of the URL to be called by the scheduler
+// scheduler config file, since it is part of the URL to be called by the scheduler.
+// Func is called concurrently by the http server, any state shared between calls must be synchronized
+// and the returned scores must only depend on the arguments and that state.
+// Scorer replaces Func for the methods holding dependencies, its name must be the method name.
+// Prepare replaces Func for the methods scoring each node on its own: it computes what the scores of the
+// request share and returns the scorer of a node, the nodes are then scored through scoreNodes and their
+// scores can be streamed to the scheduler as they are produced
 type PrioritizeMethod struct {
-	Name string
-	Func func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error)
+	Name    string
+	Func    func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error)
+	Scorer  Scorer
+	Prepare func(pod v1.Pod, nodes []v1.Node) NodeScorer
+	// Weight is the weight suggested for the method in the scheduler policy, 0 means 1
+	Weight int
+	// RequiresInformers is set for methods that need cluster state beyond the ExtenderArgs
+	RequiresInformers bool
+	// SchedulerNames restricts the method to the pods of these schedulers, the other pods get the neutral
+	// score. Empty applies the method to every pod
+	SchedulerNames []string
+	// Invert returns the complement of the scores, MaxPriority - score, reversing the preference of the method
+	Invert bool
+	// Timeout bounds how long the method may score a request, 0 falls back on -priority-timeout
+	Timeout time.Duration
+	// Explain optionally returns the reason behind the score of a node, it is logged and kept for /debug/explain
+	Explain func(pod v1.Pod, node v1.Node) string
+	// Version is the version of the scoring logic, bumped whenever the method scores the same request
+	// differently, so the consumers of the explain and audit outputs can tell the results apart. 0 means 1
+	Version int
+	// NodeNamesOnly is set for methods scoring the nodes by their name, their metadata at most: the spec
+	// and status of the nodes, but the allocatable resources the node sampling reads, are not decoded for
+	// the requests of the method alone
+	NodeNamesOnly bool
 }
 
 // Handler takes as input the pod and a list of nodes and returns a hostPriority list
-func (p PrioritizeMethod) Handler(args schedulingapi.ExtenderArgs) (*schedulingapi.HostPriorityList, error) {
-	return p.Func(*args.Pod, args.Nodes.Items)
+func (p PrioritizeMethod) Handler(ctx context.Context, args schedulingapi.ExtenderArgs) (*schedulingapi.HostPriorityList, error) {
+	list, err := p.scorer().Score(ctx, *args.Pod, args.Nodes.Items)
+	if err != nil {
+		return nil, err
+	}
+	// only the scores of the method are inverted, not the neutral scores the extender fills in
+	list, err = invertScores(p, list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
 }
 
 // ImagePriority defines the name and method for a priotity
 // for each priority we should add a PrioritizeMethod
 var ImagePriority = PrioritizeMethod{
 	Name: "image_score",
-	Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
-		var priorityList schedulingapi.HostPriorityList
-		priorityList = make([]schedulingapi.HostPriority, len(nodes))
-		for i, node := range nodes {
-			score := nodeHasImage(pod, node.Status.Images, node.Name)
-			priorityList[i] = schedulingapi.HostPriority{
-				Host:  node.Name,
-				Score: int(score),
-			}
-			glog.V(6).Infof("node %v has priority score of %v for pod %v\n", node.Name, score, pod.Name)
+	// 2: the images are matched according to -image-match-mode instead of by substring
+	// 3: the nodes are scored by the image pulls and bytes left instead of the count of images found
+	Version: 3,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		imageSizes := knownImageSizes(pod, nodes)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			return imagePullScore(pod, node, imageSizes), nil
 		}
-		return &priorityList, nil
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		// the image sizes known to the other candidate nodes are not at hand here, so only the pulls are reported
+		pulls := remainingImagePulls(pod, node, nil)
+		return fmt.Sprintf("%v of the %v image pulls left on the node", pulls.missingCount, pulls.count)
 	},
 }
 
@@ -95,8 +286,7 @@ func nodeHasImage(pod v1.Pod, nodeImages []v1.ContainerImage, nodeName string) u
 				break
 			}
 			for _, imgName := range img.Names {
-				if strings.Contains(imgName, ctnr.Image) {
-					// we use the heuristic approach of `strings.Contains` since the missing tag `latest` in the pod's container may be added in the node image
+				if imageMatches(imgName, ctnr.Image) {
 					count++
 					glog.V(6).Infof("nodeImage %v matches container Image %v on node %v\n", imgName, ctnr.Image, nodeName)
 					shouldBreak = true
@@ -108,6 +298,23 @@ func nodeHasImage(pod v1.Pod, nodeImages []v1.ContainerImage, nodeName string) u
 	return count
 }
 
+// imageMatches reports whether the node image name refers to the container image, according to -image-match-mode
+func imageMatches(nodeImage, containerImage string) bool {
+	return imageMatchers[imageMatchMode](nodeImage, containerImage)
+}
+
+// findNodeImage returns the node image matching the container image, if any
+func findNodeImage(containerImage string, nodeImages []v1.ContainerImage) (v1.ContainerImage, bool) {
+	for _, img := range nodeImages {
+		for _, imgName := range img.Names {
+			if imageMatches(imgName, containerImage) {
+				return img, true
+			}
+		}
+	}
+	return v1.ContainerImage{}, false
+}
+
 // making sure the request has a body
 func checkRequestBody(w http.ResponseWriter, r *http.Request) bool {
 	if r.Body == nil {
@@ -117,59 +324,373 @@ func checkRequestBody(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// decodeExtenderArgs decodes the arguments sent by the scheduler with the -extender-api-version decoder,
+// making sure they hold a pod and each node once
+func decodeExtenderArgs(body io.Reader) (schedulingapi.ExtenderArgs, error) {
+	return decodeExtenderArgsWith(argsDecoders[extenderAPIVersion], body)
+}
+
+// decodeExtenderArgsWith decodes the arguments sent by the scheduler with the decoder, making sure they
+// hold a pod and each node once
+func decodeExtenderArgsWith(decode argsDecoder, body io.Reader) (schedulingapi.ExtenderArgs, error) {
+	extenderArgs, err := decode(body)
+	if err != nil {
+		return extenderArgs, err
+	}
+	if extenderArgs.Pod == nil {
+		return extenderArgs, newError(ErrBadRequest, "the ExtenderArgs have no pod")
+	}
+	if extenderArgs.Nodes != nil {
+		extenderArgs.Nodes.Items = dedupNodes(extenderArgs.Pod.Name, extenderArgs.Nodes.Items)
+	}
+	return extenderArgs, nil
+}
+
+// dedupNodes drops the nodes listed more than once, keeping their first entry, so each node gets a
+// single score
+func dedupNodes(podName string, nodes []v1.Node) []v1.Node {
+	seen := make(map[string]bool, len(nodes))
+	deduped := nodes[:0]
+	var duplicates []string
+	for _, node := range nodes {
+		if seen[node.Name] {
+			duplicates = append(duplicates, node.Name)
+			continue
+		}
+		seen[node.Name] = true
+		deduped = append(deduped, node)
+	}
+	if len(duplicates) > 0 {
+		glog.Warningf("the ExtenderArgs of pod %v list some nodes more than once, their first entry is kept: %v", podName, duplicates)
+	}
+	return deduped
+}
+
+// runPriority scores the nodes of the request with the priority method, the nodes left out by the
+// sampling get the neutral score and the UnfitScore sentinels are kept for the caller to translate. The
+// degraded but valid scores are noted in the request warnings. When the stream accepts the method, the
+// scores are also written to it as they are produced, the caller then finishes the stream
+func runPriority(ctx context.Context, priorityMethod PrioritizeMethod, extenderArgs schedulingapi.ExtenderArgs, warnings *requestWarnings, stream *scoreStream) (schedulingapi.HostPriorityList, error) {
+	if warmingUp(priorityMethod) {
+		if warmupMode == warmupModeUnavailable {
+			return nil, newError(ErrUnavailable, "priority method %v is warming up, the informers are not synced", priorityMethod.Name)
+		}
+		warnings.add(priorityMethod.Name, warningWarmingUp, "the informers are not synced, neutral scores")
+		if extenderArgs.Nodes == nil {
+			return nil, nil
+		}
+		return neutralScores(extenderArgs.Nodes.Items), nil
+	}
+	if !appliesToScheduler(priorityMethod, *extenderArgs.Pod) {
+		glog.V(4).Infof("priorityMethod %v does not apply to pod %v of scheduler %v%v\n", priorityMethod.Name, extenderArgs.Pod.Name, extenderArgs.Pod.Spec.SchedulerName, logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+		if extenderArgs.Nodes == nil {
+			return nil, nil
+		}
+		return neutralScores(extenderArgs.Nodes.Items), nil
+	}
+	if !podScored(*extenderArgs.Pod) {
+		glog.V(2).Infof("priorityMethod %v skips pod %v/%v, it is not matched by -pod-selector and -pod-namespaces%v\n", priorityMethod.Name, extenderArgs.Pod.Namespace, extenderArgs.Pod.Name, logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+		if extenderArgs.Nodes == nil {
+			return nil, nil
+		}
+		return neutralScores(extenderArgs.Nodes.Items), nil
+	}
+	circuit := circuitFor(priorityMethod.Name)
+	if !circuit.allow(time.Now()) {
+		glog.V(4).Infof("priorityMethod %v is skipped, its circuit is open%v\n", priorityMethod.Name, logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+		warnings.add(priorityMethod.Name, warningCircuitOpen, "the circuit is open, neutral scores")
+		if extenderArgs.Nodes == nil {
+			return nil, nil
+		}
+		return neutralScores(extenderArgs.Nodes.Items), nil
+	}
+	if priorityMethod.RequiresInformers {
+		if age, stale := staleCache(time.Now()); stale {
+			warnings.add(priorityMethod.Name, warningStaleCache, "scored on a cluster view synced %v ago", age.Round(time.Second))
+		}
+	}
+	var skipped, all []v1.Node
+	if extenderArgs.Nodes != nil {
+		all = extenderArgs.Nodes.Items
+		// copying the node list so the sampling does not affect the other methods scoring the same request
+		nodes := *extenderArgs.Nodes
+		nodes.Items, skipped = sampleNodes(*extenderArgs.Pod, nodes.Items)
+		extenderArgs.Nodes = &nodes
+	}
+	cycle := podCycles.get(*extenderArgs.Pod, time.Now())
+	var scores schedulingapi.HostPriorityList
+	var err error
+	if extenderArgs.Nodes != nil && stream.accepts(ctx, priorityMethod) {
+		stream.active = true
+		scores, err = streamPriority(priorityMethod, *extenderArgs.Pod, extenderArgs.Nodes.Items, skipped, cycle, stream)
+	} else {
+		scores, err = scorePriority(ctx, priorityMethod, extenderArgs, skipped, all, cycle, warnings)
+	}
+	if circuit.record(err, time.Now()) {
+		glog.Warningf("priorityMethod %v failed %v times within %v, it is skipped for %v", priorityMethod.Name, circuitErrorThreshold, circuitWindow, circuitCooldown)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if extenderArgs.Nodes != nil {
+		explainScores(priorityMethod, *extenderArgs.Pod, extenderArgs.Nodes.Items, scores)
+	}
+	return scores, nil
+}
+
+// scorePriority scores the sampled nodes with the method, within its timeout, and completes the list
+// with the neutral scores of the skipped nodes
+func scorePriority(ctx context.Context, priorityMethod PrioritizeMethod, extenderArgs schedulingapi.ExtenderArgs, skipped, all []v1.Node, cycle *podCycle, warnings *requestWarnings) (schedulingapi.HostPriorityList, error) {
+	list, err := handleWithTimeout(ctx, priorityMethod, extenderArgs, warnings)
+	if err != nil {
+		return nil, err
+	}
+	scores := blendBaseScores(append(*list, neutralScores(skipped)...), all)
+	for i, hp := range scores {
+		scores[i] = vetoRejected(priorityMethod.Name, *extenderArgs.Pod, cycle, hp)
+	}
+	return scores, nil
+}
+
+// streamPriority scores the sampled nodes with the Prepare of the method and writes each score to the
+// stream as it is produced, followed by the neutral scores of the skipped nodes. The scores go through
+// the same steps as in scorePriority, one at a time
+func streamPriority(priorityMethod PrioritizeMethod, pod v1.Pod, nodes, skipped []v1.Node, cycle *podCycle, stream *scoreStream) (scores schedulingapi.HostPriorityList, err error) {
+	defer func() {
+		// the fail-open recovery of the route would write its scores after the ones already sent
+		if r := recover(); r != nil {
+			if !stream.started() || r == http.ErrAbortHandler {
+				panic(r)
+			}
+			recordPanic(priorityMethod.Name, r)
+			stream.abort(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	emit := func(hp schedulingapi.HostPriority, node v1.Node, methodScore bool) error {
+		if methodScore {
+			var err error
+			if hp, err = invertScore(priorityMethod, hp); err != nil {
+				return err
+			}
+		}
+		hp = vetoRejected(priorityMethod.Name, pod, cycle, blendBaseScore(hp, node))
+		scores = append(scores, hp)
+		return stream.write(hp)
+	}
+	scorer := priorityMethod.Prepare(pod, nodes)
+	err = scoreNodesInOrder(priorityMethod.Name, pod, nodes, scorer, func(i int, hp schedulingapi.HostPriority) error {
+		return emit(hp, nodes[i], true)
+	})
+	for _, node := range skipped {
+		if err != nil {
+			break
+		}
+		err = emit(schedulingapi.HostPriority{Host: node.Name, Score: neutralScore}, node, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// vetoRejected turns the score of a node one of the extender filters rejected in this cycle into a veto:
+// the node stays unfit, whatever the method thinks of it
+func vetoRejected(methodName string, pod v1.Pod, cycle *podCycle, hp schedulingapi.HostPriority) schedulingapi.HostPriority {
+	if reason, rejected := cycle.rejected(hp.Host); rejected && hp.Score != UnfitScore {
+		glog.V(4).Infof("priorityMethod %v: node %v was rejected by a filter for pod %v: %v%v\n", methodName, hp.Host, pod.Name, reason, logFields("pod", podLogName(&pod), "node", hp.Host, "method", methodName))
+		return Unfit(hp.Host)
+	}
+	return hp
+}
+
 // PrioritizeRoute returns an http handle
 func PrioritizeRoute(priorityMethod PrioritizeMethod) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		snapshot := currentSnapshot()
+		if !snapshot.serves(priorityMethod.Name) {
+			http.NotFound(w, r)
+			return
+		}
 		if !checkRequestBody(w, r) {
 			glog.Warning("received empty request!")
 			return
 		}
+		timing := newServerTiming()
 		var buf bytes.Buffer
 		body := io.TeeReader(r.Body, &buf)
 		glog.V(8).Infof("detailed info: %v  ExtenderArgs = %v\n", priorityMethod.Name, buf.String())
 
-		var extenderArgs schedulingapi.ExtenderArgs
-		var hostPriorityList *schedulingapi.HostPriorityList
-
-		if err := json.NewDecoder(body).Decode(&extenderArgs); err != nil {
-			panic(err)
+		extenderArgs, err := decodeExtenderArgsWith(argsDecoderFor(priorityMethod), body)
+		if err != nil {
+			glog.Warningf("priorityMethod %v received an invalid request: %v", priorityMethod.Name, err)
+			writeError(w, err)
+			return
+		}
+		timing.phase("decode", "")
+		defer recoverFailOpenScores(w, priorityMethod.Name, extenderArgs)
+		// the method may be left out, or have other options, for the namespace of the pod
+		priorityMethod, active := snapshot.method(extenderArgs.Pod.Namespace, priorityMethod.Name)
+		if !active {
+			priorityMethod = inactiveMethod(priorityMethod.Name)
 		}
 
-		if list, err := priorityMethod.Handler(extenderArgs); err != nil {
-			panic(err)
+		warnings := newRequestWarnings()
+		ctx, cancel := requestContext(r)
+		defer cancel()
+		stream := newScoreStream(w, priorityMethod.Name, extenderArgs.Pod.Name, func() {
+			timing.write(w)
+			warnings.emit(w, priorityMethod.Name, extenderArgs.Pod.Name)
+		})
+		list, err := runPriority(ctx, priorityMethod, extenderArgs, warnings, stream)
+		timing.phase("compute", priorityMethod.Name)
+		if err != nil {
+			glog.Errorf("priorityMethod %v failed for pod %v: %v%v", priorityMethod.Name, extenderArgs.Pod.Name, err, logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+			auditLog.record(priorityMethod.Name, extenderArgs, nil, nil, []MethodError{{Method: priorityMethod.Name, Error: err.Error()}})
+			if stream.started() {
+				stream.abort(err)
+			}
+			timing.write(w)
+			if failOpen {
+				warnings.add(priorityMethod.Name, warningFailOpen, "the method failed, neutral scores")
+				warnings.emit(w, priorityMethod.Name, extenderArgs.Pod.Name)
+				writeFailOpenScores(w, priorityMethod.Name, extenderArgs, err)
+				return
+			}
+			writeError(w, err)
+			return
+		}
+		var hostPriorityList schedulingapi.HostPriorityList
+		if stream.streamed() {
+			hostPriorityList = stream.finish()
 		} else {
-			hostPriorityList = list
+			hostPriorityList = topK(breakTies(translateVetoes(priorityMethod.Name, extenderArgs.Pod.Name, applyScoreFloor(flattenPoorScores(priorityMethod.Name, extenderArgs.Pod.Name, list)))))
 		}
+		recordScores(priorityMethod.Name, extenderArgs.Pod, hostPriorityList)
+		decisionFeed.publish(priorityMethod.Name, extenderArgs.Pod, hostPriorityList)
+		scoreAnnotations.observe(priorityMethod.Name, extenderArgs.Pod, hostPriorityList, time.Now())
+		auditLog.record(priorityMethod.Name, extenderArgs, map[string]schedulingapi.HostPriorityList{priorityMethod.Name: list}, hostPriorityList, nil)
 
+		if stream.streamed() {
+			glog.V(4).Infof("priorityMethod %v, streamed the scores of %v hosts as they were produced%v\n", priorityMethod.Name, len(hostPriorityList), logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+			return
+		}
+		if streamResponses {
+			timing.write(w)
+			warnings.emit(w, priorityMethod.Name, extenderArgs.Pod.Name)
+			glog.V(4).Infof("priorityMethod %v, streaming the scores of %v hosts%v\n", priorityMethod.Name, len(hostPriorityList), logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+			streamScores(w, hostPriorityList)
+			return
+		}
 		if resultBody, err := json.Marshal(hostPriorityList); err != nil {
 			panic(err)
 		} else {
-			glog.V(4).Infof("priorityMethod %v, hostPriorityList = %v\n ", priorityMethod.Name, string(resultBody))
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			w.Write(resultBody)
+			timing.phase("encode", "")
+			timing.write(w)
+			warnings.emit(w, priorityMethod.Name, extenderArgs.Pod.Name)
+			glog.V(4).Infof("priorityMethod %v, hostPriorityList = %v%v\n ", priorityMethod.Name, string(resultBody), logFields("pod", podLogName(extenderArgs.Pod), "method", priorityMethod.Name))
+			writeScores(w, r, extenderArgs.Pod, resultBody)
 		}
 	}
 }
 
 // AddPrioritizeFunc adding the route path to the router
 func AddPrioritizeFunc(router *httprouter.Router, priorityMethod PrioritizeMethod) {
-	path := prioritiesPrefix + "/" + priorityMethod.Name
-	router.POST(path, PrioritizeRoute(priorityMethod))
-	glog.V(2).Infof("added priority method: %v at path: %v\n", priorityMethod.Name, path)
+	if priorityMethod.Scorer != nil && priorityMethod.Scorer.Name() != priorityMethod.Name {
+		fatalf("priority method %v has a scorer named %v", priorityMethod.Name, priorityMethod.Scorer.Name())
+	}
+	paths := prefixedPaths(prioritiesPrefix + "/" + priorityMethod.Name)
+	handle := requireAuth(countPanics(priorityMethod.Name, PrioritizeRoute(priorityMethod)))
+	for _, path := range paths {
+		router.POST(path, handle)
+		glog.V(2).Infof("added priority method: %v at path: %v\n", priorityMethod.Name, path)
+	}
+	registerPriority(priorityMethod, paths)
+}
+
+// parseAPIPrefixes splits the -api-prefix flag on commas into apiPrefixes, adding the missing
+// leading `/` and rejecting empty or repeated prefixes
+func parseAPIPrefixes() error {
+	apiPrefixes = nil
+	seen := make(map[string]bool)
+	for _, prefix := range strings.Split(apiPrefix, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			return fmt.Errorf("the -api-prefix flag value %q has an empty prefix", apiPrefix)
+		}
+		if !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+			glog.Warningf("the -api-prefix flag value was missing a `/`, it was automatically added -> %v", prefix)
+		}
+		if seen[prefix] {
+			return fmt.Errorf("the -api-prefix flag value %q repeats the prefix %v", apiPrefix, prefix)
+		}
+		seen[prefix] = true
+		apiPrefixes = append(apiPrefixes, prefix)
+	}
+	return nil
+}
+
+// prefixedPaths returns the path under each of the api prefixes, the first prefix comes first
+func prefixedPaths(path string) []string {
+	paths := make([]string, len(apiPrefixes))
+	for i, prefix := range apiPrefixes {
+		paths[i] = prefix + path
+	}
+	return paths
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		if err := runLoadTest(os.Args[2:], os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	parseFlags()
+	scoreRecorder = newScoreRecorder()
 
 	router := httprouter.New()
 
-	priorities := []PrioritizeMethod{ImagePriority}
-	for _, p := range priorities {
-		AddPrioritizeFunc(router, p)
+	startInformers(make(chan struct{}))
+	startNodeHealth()
+	startScoreAnnotations()
+	startAuditLog()
+
+	priorities := []PrioritizeMethod{ImagePriority, NodeBiasPriority, ImagePullTimePriority, SpotPriority, PoolDensityPriority, QOSPriority, OwnerStickinessPriority, NodeStabilityPriority, TopologySpreadPriority, NodeAffinityPriority, ResourceContentionPriority, WarmPoolPriority, InstanceCostPriority, HypervisorSpreadPriority, NodeCapabilitiesPriority, PlacementOutcomePriority, EphemeralStoragePriority, ImageAntiAffinityPriority, ImageGCRiskPriority, NodeHealthPriority, RecencyDecayPriority, NUMAPriority, EvictionRatePriority, LatencyBudgetPriority, SharedVolumesPriority, LimitsOvercommitPriority, ImagePopularityPriority, GangLocalityPriority, NodeAgentPriority, PoolScaleDownPriority, ScoreTablePriority, GPUBalancePriority, ImageStorePriority, PreferredZonePriority}
+	if daemonDependencyMode == daemonDependencyModePriority {
+		priorities = append(priorities, DaemonDependencyPriority)
+	}
+	if securityProfileMode == securityProfileModePriority {
+		priorities = append(priorities, SecurityProfilePriority)
+	}
+	if runtimeVersionMode == runtimeVersionModePriority {
+		priorities = append(priorities, RuntimeVersionPriority)
+	}
+	if externalScorerAddr != "" {
+		ExternalPriority.Scorer = &externalScorer{client: newExternalScorerClient(externalScorerAddr)}
+		priorities = append(priorities, ExternalPriority)
+	}
+
+	filters := []FilterMethod{GPUModelFilter, EntitlementFilter, HostPortsFilter, PodCountCapFilter, RequiredNodeAffinityFilter}
+	if daemonDependencyMode == daemonDependencyModeFilter {
+		filters = append(filters, DaemonDependencyFilter)
+	}
+	if securityProfileMode == securityProfileModeFilter {
+		filters = append(filters, SecurityProfileFilter)
+	}
+	if runtimeVersionMode == runtimeVersionModeFilter {
+		filters = append(filters, RuntimeVersionFilter)
 	}
+	addVerbRoutes(router, priorities, filters)
+	startConfig()
+	glog.V(0).Infof("active verbs: %v\n", activeVerbs())
+	glog.V(0).Infof("active api prefixes: %v\n", strings.Join(apiPrefixes, ", "))
+	router.GET("/priorities", informational(PrioritiesRoute))
+	router.GET("/metrics", informational(gzipped(MetricsRoute)))
+	AddDebugRoutes(router)
 
 	glog.V(0).Infof("scheduler extender http server started on the address %v\n", httpAddr)
 	if err := http.ListenAndServe(httpAddr, router); err != nil {
-		glog.Fatal(err)
+		fatal(err)
 	}
 }
@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withAPIPrefix sets -api-prefix for the test, the prefixes are parsed again at its end
+func withAPIPrefix(t *testing.T, prefix string) {
+	saved := apiPrefix
+	t.Cleanup(func() {
+		apiPrefix = saved
+		parseAPIPrefixes()
+	})
+	apiPrefix = prefix
+}
+
+func TestParseAPIPrefixes(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected []string
+		valid    bool
+	}{
+		{"/scheduler", []string{"/scheduler"}, true},
+		{"scheduler", []string{"/scheduler"}, true},
+		{"/v1,/v2", []string{"/v1", "/v2"}, true},
+		{" /v1 , v2 ", []string{"/v1", "/v2"}, true},
+		{"", nil, false},
+		{"/v1,,/v2", nil, false},
+		{"/v1,", nil, false},
+		{"/v1,v1", nil, false},
+	}
+	for _, test := range tests {
+		withAPIPrefix(t, test.prefix)
+		err := parseAPIPrefixes()
+		if (err == nil) != test.valid {
+			t.Errorf("parseAPIPrefixes(%q) returned %v", test.prefix, err)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(apiPrefixes, test.expected) {
+			t.Errorf("parseAPIPrefixes(%q) parsed %v, expected %v", test.prefix, apiPrefixes, test.expected)
+		}
+	}
+}
+
+func TestRoutesUnderEachPrefix(t *testing.T) {
+	withAPIPrefix(t, "/v1,/v2")
+	router := newTestRouter(t, digitPriority)
+	AddCombinedRoute(router)
+	AddFilterFunc(router, FilterMethod{Name: "any", Func: func(v1.Pod, v1.Node) (bool, string, error) { return true, "", nil }})
+	body, err := json.Marshal(extenderArgsOf(testPod("default", "p", nil), testNodes("n1", "n2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{prioritiesPrefix + "/" + digitPriority.Name, prioritiesPrefix, filtersPrefix + "/any"} {
+		var answers []string
+		for _, prefix := range []string{"/v1", "/v2"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, prefix+path, bytes.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Errorf("%v answered %v: %v", prefix+path, w.Code, w.Body.String())
+			}
+			answers = append(answers, w.Body.String())
+		}
+		if answers[0] != answers[1] {
+			t.Errorf("the prefixes answered %v differently: %q and %q", path, answers[0], answers[1])
+		}
+	}
+	if paths := prefixedPaths("/x"); !reflect.DeepEqual(paths, []string{"/v1/x", "/v2/x"}) {
+		t.Errorf("prefixedPaths returned %v", paths)
+	}
+}
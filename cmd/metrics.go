@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// cacheStats counts the lookups answered, or not, by a cache
+type cacheStats struct {
+	hits   int64
+	misses int64
+}
+
+// record counts a lookup
+func (s *cacheStats) record(hit bool) {
+	if hit {
+		atomic.AddInt64(&s.hits, 1)
+	} else {
+		atomic.AddInt64(&s.misses, 1)
+	}
+}
+
+var cacheStatsLock sync.Mutex
+var cacheStatsByName = make(map[string]*cacheStats)
+
+// registerCacheStats returns the lookup counters of the named cache, exposed on /metrics
+func registerCacheStats(name string) *cacheStats {
+	cacheStatsLock.Lock()
+	defer cacheStatsLock.Unlock()
+	stats, ok := cacheStatsByName[name]
+	if !ok {
+		stats = &cacheStats{}
+		cacheStatsByName[name] = stats
+	}
+	return stats
+}
+
+// metricSample is a value of a metric with its labels, e.g. {`cache="owner_placements"`, 3}
+type metricSample struct {
+	labels string
+	value  float64
+}
+
+// writeMetric writes a metric in the Prometheus text exposition format
+func writeMetric(w io.Writer, name, kind, help string, samples ...metricSample) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, sample := range samples {
+		if sample.labels == "" {
+			fmt.Fprintf(w, "%s %v\n", name, sample.value)
+		} else {
+			fmt.Fprintf(w, "%s{%s} %v\n", name, sample.labels, sample.value)
+		}
+	}
+}
+
+// writeCacheMetrics writes the lookup counters of the registered caches
+func writeCacheMetrics(w io.Writer) {
+	cacheStatsLock.Lock()
+	names := make([]string, 0, len(cacheStatsByName))
+	for name := range cacheStatsByName {
+		names = append(names, name)
+	}
+	cacheStatsLock.Unlock()
+	sort.Strings(names)
+
+	var hits, misses []metricSample
+	for _, name := range names {
+		stats := registerCacheStats(name)
+		labels := fmt.Sprintf("cache=%q", name)
+		hits = append(hits, metricSample{labels, float64(atomic.LoadInt64(&stats.hits))})
+		misses = append(misses, metricSample{labels, float64(atomic.LoadInt64(&stats.misses))})
+	}
+	writeMetric(w, "extender_cache_hits_total", "counter", "Lookups answered by the cache.", hits...)
+	writeMetric(w, "extender_cache_misses_total", "counter", "Lookups the cache could not answer.", misses...)
+}
+
+// writeInformerMetrics writes the age of the cluster view and the number of objects it holds, the
+// informer metrics are left out when -enable-informers is not set
+func writeInformerMetrics(w io.Writer) {
+	informer, ok := podLister.(*podInformer)
+	if !ok {
+		return
+	}
+	labels := `informer="pods"`
+	if lastSync := informer.LastSync(); !lastSync.IsZero() {
+		writeMetric(w, "extender_informer_last_sync_age_seconds", "gauge", "Seconds since the informer last listed its objects.",
+			metricSample{labels, time.Since(lastSync).Seconds()})
+	}
+	writeMetric(w, "extender_informer_synced", "gauge", "Whether the informer listed its objects at least once.",
+		metricSample{labels, boolValue(informer.HasSynced())})
+	writeMetric(w, "extender_lister_objects", "gauge", "Number of objects held by the lister.",
+		metricSample{`lister="pods"`, float64(len(informer.List()))})
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// MetricsRoute exposes the extender metrics in the Prometheus text format
+func MetricsRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeCacheMetrics(w)
+	writeInformerMetrics(w)
+	writeCircuitMetrics(w)
+	writeAllPoorMetrics(w)
+	writeFailOpenMetrics(w)
+	writeWarningMetrics(w)
+	writePanicMetrics(w)
+}
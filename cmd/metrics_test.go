@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// testCacheStats registers the lookup counters of a cache until the end of the test
+func testCacheStats(t *testing.T, name string) *cacheStats {
+	t.Cleanup(func() {
+		cacheStatsLock.Lock()
+		delete(cacheStatsByName, name)
+		cacheStatsLock.Unlock()
+	})
+	return registerCacheStats(name)
+}
+
+func TestCacheStats(t *testing.T) {
+	stats := testCacheStats(t, "test_cache")
+	if registerCacheStats("test_cache") != stats {
+		t.Errorf("registering a cache twice returned other counters")
+	}
+	stats.record(true)
+	stats.record(false)
+	stats.record(false)
+	if stats.hits != 1 || stats.misses != 2 {
+		t.Errorf("counted %v hits and %v misses, expected 1 and 2", stats.hits, stats.misses)
+	}
+}
+
+func TestPlacementCacheStats(t *testing.T) {
+	cache := &placementCache{entries: make(map[placementKey]time.Time), stats: testCacheStats(t, "test_placements")}
+	now := time.Now()
+	cache.observe([]v1.Pod{ownedPod("p", "rs", "a", now, time.Time{}, 0)}, now)
+	tests := []struct {
+		node         string
+		hits, misses int64
+	}{
+		{"a", 1, 0},
+		{"b", 1, 1},
+		{"a", 2, 1},
+	}
+	for _, test := range tests {
+		cache.recent("rs", test.node, now)
+		if cache.stats.hits != test.hits || cache.stats.misses != test.misses {
+			t.Errorf("after looking %v up, counted %v hits and %v misses, expected %v and %v", test.node, cache.stats.hits, cache.stats.misses, test.hits, test.misses)
+		}
+	}
+}
+
+func TestWriteMetric(t *testing.T) {
+	var out bytes.Buffer
+	writeMetric(&out, "extender_test", "gauge", "A test metric.", metricSample{"", 1.5}, metricSample{`cache="a"`, 2})
+	expected := "# HELP extender_test A test metric.\n# TYPE extender_test gauge\nextender_test 1.5\nextender_test{cache=\"a\"} 2\n"
+	if out.String() != expected {
+		t.Errorf("wrote %q, expected %q", out.String(), expected)
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	stats := testCacheStats(t, "test_cache")
+	stats.record(true)
+	stats.record(false)
+	stats.record(false)
+	tests := []struct {
+		name     string
+		lister   PodLister
+		expected []string
+		missing  []string
+	}{
+		{
+			name:   "informer",
+			lister: &podInformer{pods: []v1.Pod{testPod("default", "a", nil), testPod("default", "b", nil)}, lastSync: time.Now().Add(-time.Minute)},
+			expected: []string{
+				`extender_cache_hits_total{cache="test_cache"} 1`,
+				`extender_cache_misses_total{cache="test_cache"} 2`,
+				`extender_informer_synced{informer="pods"} 1`,
+				`extender_lister_objects{lister="pods"} 2`,
+				`extender_informer_last_sync_age_seconds{informer="pods"} 60.`,
+			},
+		},
+		{
+			name:     "informer not synced",
+			lister:   &podInformer{},
+			expected: []string{`extender_informer_synced{informer="pods"} 0`, `extender_lister_objects{lister="pods"} 0`},
+			missing:  []string{"extender_informer_last_sync_age_seconds"},
+		},
+		{
+			name:     "informers disabled",
+			lister:   nil,
+			expected: []string{`extender_cache_hits_total{cache="test_cache"} 1`},
+			missing:  []string{"extender_informer_synced", "extender_lister_objects"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withPodLister(t, test.lister)
+			w := httptest.NewRecorder()
+			MetricsRoute(w, httptest.NewRequest(http.MethodGet, "/metrics", nil), nil)
+			for _, line := range test.expected {
+				if !strings.Contains(w.Body.String(), line) {
+					t.Errorf("the metrics miss %q:\n%v", line, w.Body.String())
+				}
+			}
+			for _, metric := range test.missing {
+				if strings.Contains(w.Body.String(), metric) {
+					t.Errorf("the metrics hold %v:\n%v", metric, w.Body.String())
+				}
+			}
+		})
+	}
+}
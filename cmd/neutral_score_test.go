@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withNeutralScore sets -neutral-score for the test
+func withNeutralScore(t *testing.T, score int) {
+	saved := neutralScore
+	t.Cleanup(func() { neutralScore = saved })
+	neutralScore = score
+}
+
+func TestValidateNeutralScore(t *testing.T) {
+	for score, valid := range map[int]bool{0: true, 5: true, 10: true, -1: false, 11: false} {
+		withNeutralScore(t, score)
+		if err := validateNeutralScore(); (err == nil) != valid {
+			t.Errorf("validateNeutralScore(%v) returned %v", score, err)
+		}
+	}
+}
+
+func TestNeutralScoreWarmup(t *testing.T) {
+	withNeutralScore(t, 3)
+	withWarmup(t, warmupModeNeutral, 0)
+	withPodLister(t, &testPodLister{unsynced: true})
+	informed := constantPriority("informed", 1, 9)
+	informed.RequiresInformers = true
+	router := newTestRouter(t, informed)
+	checkScores(t, prioritize(t, router, "informed", testPod("default", "p", nil), testNodes("a", "b")), map[string]int{"a": 3, "b": 3})
+}
+
+func TestNeutralScoreNotInverted(t *testing.T) {
+	withNeutralScore(t, 3)
+	withSampling(t, 1, "first")
+	inverted := constantPriority("inverted", 1, 8)
+	inverted.Invert = true
+	router := newTestRouter(t, inverted)
+	// the sampled node is inverted, the sampled out node keeps the neutral score
+	checkScores(t, prioritize(t, router, "inverted", testPod("default", "p", nil), testNodes("a", "b")), map[string]int{"a": 2, "b": 3})
+}
+
+func TestNeutralScoreOffsets(t *testing.T) {
+	withNeutralScore(t, 3)
+	nodes := []v1.Node{
+		annotatedNode("up", map[string]string{biasAnnotation: "+2"}),
+		annotatedNode("down", map[string]string{biasAnnotation: "-5"}),
+		annotatedNode("top", map[string]string{biasAnnotation: "+9"}),
+		annotatedNode("malformed", map[string]string{biasAnnotation: "high"}),
+		annotatedNode("none", nil),
+	}
+	checkScores(t, scoreMethod(t, NodeBiasPriority, testPod("default", "p", nil), nodes), map[string]int{"up": 5, "down": 0, "top": 10, "malformed": 3, "none": 3})
+}
@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strconv"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// NodeAffinityPriority replicates the preferredDuringSchedulingIgnoredDuringExecution node affinity of the
+// pod: the weights of the terms a node matches are summed and normalized by the total weight of the terms.
+// The terms requiring a label key outside -node-label-allowlist are ignored along with their weight. Pods
+// without preferred terms get the neutral score
+var NodeAffinityPriority = PrioritizeMethod{
+	Name: "preferred_node_affinity",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		var terms []v1.PreferredSchedulingTerm
+		if pod.Spec.Affinity != nil && pod.Spec.Affinity.NodeAffinity != nil {
+			for _, term := range pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				if nodeSelectorTermAllowed(term.Preference) {
+					terms = append(terms, term)
+				}
+			}
+		}
+		var totalWeight int
+		for _, term := range terms {
+			totalWeight += int(term.Weight)
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if totalWeight == 0 {
+				return neutralScore, nil
+			}
+			var matched int
+			for _, term := range terms {
+				if nodeSelectorTermMatches(term.Preference, node) {
+					matched += int(term.Weight)
+				}
+			}
+			return schedulingapi.MaxPriority * matched / totalWeight, nil
+		}
+	},
+}
+
+// nodeSelectorTermMatches reports whether the node satisfies all the requirements of the term, a term
+// without any requirement matches no node, as in the scheduler
+func nodeSelectorTermMatches(term v1.NodeSelectorTerm, node v1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, req := range term.MatchExpressions {
+		value, exists := node.Labels[req.Key]
+		if !requirementMatches(req, value, exists) {
+			return false
+		}
+	}
+	for _, req := range term.MatchFields {
+		// metadata.name is the only field supported by the scheduler
+		if req.Key != "metadata.name" || !requirementMatches(req, node.Name, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// requirementMatches evaluates a node selector requirement against the value of its key
+func requirementMatches(req v1.NodeSelectorRequirement, value string, exists bool) bool {
+	switch req.Operator {
+	case v1.NodeSelectorOpIn:
+		return exists && containsString(req.Values, value)
+	case v1.NodeSelectorOpNotIn:
+		return !exists || !containsString(req.Values, value)
+	case v1.NodeSelectorOpExists:
+		return exists
+	case v1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+		if !exists || len(req.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(req.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if req.Operator == v1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+// containsString reports whether the value is one of the values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// affinityPod returns a pod preferring the terms
+func affinityPod(terms ...v1.PreferredSchedulingTerm) v1.Pod {
+	pod := testPod("default", "p", nil)
+	pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{PreferredDuringSchedulingIgnoredDuringExecution: terms}}
+	return pod
+}
+
+func TestNodeAffinityPriority(t *testing.T) {
+	withNodeLabelAllowlist(t, "*")
+	nodes := []v1.Node{
+		labeledNode("ssd-a", map[string]string{"disktype": "ssd", "zone": "a"}),
+		labeledNode("ssd-b", map[string]string{"disktype": "ssd", "zone": "b"}),
+		labeledNode("hdd-a", map[string]string{"disktype": "hdd", "zone": "a"}),
+		labeledNode("none", nil),
+	}
+	ssd := v1.PreferredSchedulingTerm{Weight: 80, Preference: labelTerm(map[string]string{"disktype": "ssd"})}
+	zoneA := v1.PreferredSchedulingTerm{Weight: 20, Preference: labelTerm(map[string]string{"zone": "a"})}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"two weighted terms", affinityPod(ssd, zoneA), map[string]int{"ssd-a": 10, "ssd-b": 8, "hdd-a": 2, "none": 0}},
+		{"one term", affinityPod(zoneA), map[string]int{"ssd-a": 10, "ssd-b": 0, "hdd-a": 10, "none": 0}},
+		{"empty term", affinityPod(v1.PreferredSchedulingTerm{Weight: 50}, zoneA), map[string]int{"ssd-a": 10, "ssd-b": 0, "hdd-a": 10, "none": 0}},
+		{"no preferred term", affinityPod(), map[string]int{"ssd-a": neutralScore, "ssd-b": neutralScore, "hdd-a": neutralScore, "none": neutralScore}},
+		{"no affinity", testPod("default", "p", nil), map[string]int{"ssd-a": neutralScore, "ssd-b": neutralScore, "hdd-a": neutralScore, "none": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, NodeAffinityPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
+
+func TestRequirementMatches(t *testing.T) {
+	tests := []struct {
+		operator v1.NodeSelectorOperator
+		values   []string
+		value    string
+		exists   bool
+		matches  bool
+	}{
+		{v1.NodeSelectorOpIn, []string{"a", "b"}, "b", true, true},
+		{v1.NodeSelectorOpIn, []string{"a", "b"}, "c", true, false},
+		{v1.NodeSelectorOpIn, []string{""}, "", false, false},
+		{v1.NodeSelectorOpNotIn, []string{"a"}, "b", true, true},
+		{v1.NodeSelectorOpNotIn, []string{"a"}, "a", true, false},
+		{v1.NodeSelectorOpNotIn, []string{"a"}, "", false, true},
+		{v1.NodeSelectorOpExists, nil, "", true, true},
+		{v1.NodeSelectorOpExists, nil, "", false, false},
+		{v1.NodeSelectorOpDoesNotExist, nil, "", false, true},
+		{v1.NodeSelectorOpDoesNotExist, nil, "x", true, false},
+		{v1.NodeSelectorOpGt, []string{"4"}, "8", true, true},
+		{v1.NodeSelectorOpGt, []string{"8"}, "8", true, false},
+		{v1.NodeSelectorOpLt, []string{"8"}, "4", true, true},
+		{v1.NodeSelectorOpLt, []string{"4"}, "8", true, false},
+		{v1.NodeSelectorOpGt, []string{"4"}, "eight", true, false},
+		{v1.NodeSelectorOpGt, []string{"four"}, "8", true, false},
+		{v1.NodeSelectorOpGt, []string{"4", "5"}, "8", true, false},
+		{v1.NodeSelectorOpGt, []string{"4"}, "", false, false},
+		{"Unknown", []string{"a"}, "a", true, false},
+	}
+	for _, test := range tests {
+		req := v1.NodeSelectorRequirement{Key: "k", Operator: test.operator, Values: test.values}
+		if matches := requirementMatches(req, test.value, test.exists); matches != test.matches {
+			t.Errorf("%v %v against %q (exists %v) returned %v", test.operator, test.values, test.value, test.exists, matches)
+		}
+	}
+}
+
+func TestNodeSelectorTermMatches(t *testing.T) {
+	node := labeledNode("node-1", map[string]string{"zone": "a"})
+	byName := func(key, name string) v1.NodeSelectorTerm {
+		return v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{{Key: key, Operator: v1.NodeSelectorOpIn, Values: []string{name}}}}
+	}
+	tests := []struct {
+		name    string
+		term    v1.NodeSelectorTerm
+		matches bool
+	}{
+		{"labels", labelTerm(map[string]string{"zone": "a"}), true},
+		{"one label mismatching", labelTerm(map[string]string{"zone": "a", "gpu": "true"}), false},
+		{"node name", byName("metadata.name", "node-1"), true},
+		{"other node name", byName("metadata.name", "node-2"), false},
+		{"unsupported field", byName("spec.podCIDR", "node-1"), false},
+		{"no requirement", v1.NodeSelectorTerm{}, false},
+	}
+	for _, test := range tests {
+		if matches := nodeSelectorTermMatches(test.term, node); matches != test.matches {
+			t.Errorf("%v: nodeSelectorTermMatches returned %v", test.name, matches)
+		}
+	}
+}
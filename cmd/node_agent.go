@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var nodeAgentPort int
+var nodeAgentPath string
+var nodeAgentTimeout, nodeAgentTTL time.Duration
+var nodeAgentWeight float64
+
+func init() {
+	flag.IntVar(&nodeAgentPort, "node-agent-port", 0, "The port of the agent running on each node and reporting its score at the node InternalIP, 0 disables node_agent")
+	flag.StringVar(&nodeAgentPath, "node-agent-path", "/score", "The path of the node agent score endpoint")
+	flag.DurationVar(&nodeAgentTimeout, "node-agent-timeout", 500*time.Millisecond, "How long node_agent waits for a node agent")
+	flag.DurationVar(&nodeAgentTTL, "node-agent-ttl", 15*time.Second, "How long the score of a node agent, or its failure, is cached")
+	flag.Float64Var(&nodeAgentWeight, "node-agent-weight", 1, "How much of the agent score node_agent blends into the neutral score, from 0 to 1")
+}
+
+// validateNodeAgent makes sure the node agent flags hold valid values
+func validateNodeAgent() error {
+	if nodeAgentPort < 0 || nodeAgentPort > 65535 {
+		return fmt.Errorf("the -node-agent-port flag value must be a port number, got %v", nodeAgentPort)
+	}
+	if nodeAgentTimeout <= 0 || nodeAgentTTL <= 0 {
+		return fmt.Errorf("the -node-agent-timeout and -node-agent-ttl flag values must be positive, got %v and %v", nodeAgentTimeout, nodeAgentTTL)
+	}
+	if nodeAgentWeight < 0 || nodeAgentWeight > 1 {
+		return fmt.Errorf("the -node-agent-weight flag value must be between 0 and 1, got %v", nodeAgentWeight)
+	}
+	return nil
+}
+
+// nodeAgentReport is the answer of a node agent to GET http://<node InternalIP>:<port><path>, with a 200
+// status, e.g. {"score": 7.5}. The score ranges from 0 to 10, higher is better
+type nodeAgentReport struct {
+	Score *float64 `json:"score"`
+}
+
+// agentScore is a cached answer of a node agent, ok is false when the agent failed
+type agentScore struct {
+	score     float64
+	ok        bool
+	attempted time.Time
+}
+
+// nodeAgentCache queries the node agents, caching their answers and failures for -node-agent-ttl
+type nodeAgentCache struct {
+	stats *cacheStats
+
+	lock   sync.Mutex
+	scores map[string]agentScore
+}
+
+// nodeAgents is the node agent cache shared by the requests
+var nodeAgents = &nodeAgentCache{
+	scores: make(map[string]agentScore),
+	stats:  registerCacheStats("node_agent"),
+}
+
+// score returns the score reported by the agent of the node, false when the agent is unreachable or the
+// node has no InternalIP
+func (c *nodeAgentCache) score(node v1.Node, now time.Time) (float64, bool) {
+	name := normalizeNodeName(node.Name)
+	c.lock.Lock()
+	cached, found := c.scores[name]
+	c.lock.Unlock()
+	fresh := found && now.Sub(cached.attempted) < nodeAgentTTL
+	c.stats.record(fresh)
+	if fresh {
+		return cached.score, cached.ok
+	}
+	cached = agentScore{attempted: now}
+	score, err := c.fetch(node)
+	if err != nil {
+		glog.V(2).Infof("node agent of node %v unavailable, scoring neutral: %v\n", node.Name, err)
+	} else {
+		cached.score, cached.ok = score, true
+	}
+	c.lock.Lock()
+	c.scores[name] = cached
+	c.lock.Unlock()
+	return cached.score, cached.ok
+}
+
+// fetch queries the agent of the node
+func (c *nodeAgentCache) fetch(node v1.Node) (float64, error) {
+	var address string
+	for _, a := range node.Status.Addresses {
+		if a.Type == v1.NodeInternalIP {
+			address = a.Address
+			break
+		}
+	}
+	if address == "" {
+		return 0, fmt.Errorf("the node has no InternalIP")
+	}
+	url := "http://" + net.JoinHostPort(address, strconv.Itoa(nodeAgentPort)) + nodeAgentPath
+	client := &http.Client{Timeout: nodeAgentTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %v returned %v", url, resp.Status)
+	}
+	var report nodeAgentReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return 0, fmt.Errorf("invalid report from %v: %v", url, err)
+	}
+	if report.Score == nil || math.IsNaN(*report.Score) || *report.Score < 0 || *report.Score > schedulingapi.MaxPriority {
+		return 0, fmt.Errorf("the report of %v has no score between 0 and %v", url, schedulingapi.MaxPriority)
+	}
+	return *report.Score, nil
+}
+
+// flush drops the cached scores, returning the number of nodes dropped
+func (c *nodeAgentCache) flush() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	count := len(c.scores)
+	c.scores = make(map[string]agentScore)
+	return count
+}
+
+// NodeAgentPriority blends the score reported by the agent running on each node, e.g. from its thermal
+// state or local disk IOPS headroom, into the neutral score by -node-agent-weight. The agents are queried
+// concurrently, bounded by -node-scoring-concurrency. The nodes whose agent is unreachable, or every node
+// when -node-agent-port is not set, get the neutral score
+var NodeAgentPriority = PrioritizeMethod{
+	Name: "node_agent",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		now := time.Now()
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if nodeAgentPort == 0 {
+				return neutralScore, nil
+			}
+			score, ok := nodeAgents.score(node, now)
+			if !ok {
+				return neutralScore, nil
+			}
+			blended := float64(neutralScore) + nodeAgentWeight*(score-float64(neutralScore))
+			return clampScore(int(math.Round(blended))), nil
+		}
+	},
+}
@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// withNodeAgent sets the node agent flags for the test, the cached agent scores are dropped at its end
+func withNodeAgent(t *testing.T, port int, weight float64, timeout, ttl time.Duration) {
+	savedPort, savedWeight, savedTimeout, savedTTL := nodeAgentPort, nodeAgentWeight, nodeAgentTimeout, nodeAgentTTL
+	t.Cleanup(func() {
+		nodeAgentPort, nodeAgentWeight, nodeAgentTimeout, nodeAgentTTL = savedPort, savedWeight, savedTimeout, savedTTL
+		nodeAgents.flush()
+	})
+	nodeAgentPort, nodeAgentWeight, nodeAgentTimeout, nodeAgentTTL = port, weight, timeout, ttl
+}
+
+// testNodeAgent serves the report with the status as the agent of every node at 127.0.0.1, counting the requests
+type testNodeAgent struct {
+	status   int
+	report   string
+	requests int32
+}
+
+func (a *testNodeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&a.requests, 1)
+	if r.URL.Path != nodeAgentPath {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(a.status)
+	fmt.Fprint(w, a.report)
+}
+
+// startNodeAgent starts the agent and points the node agent flags at it
+func startNodeAgent(t *testing.T, agent *testNodeAgent, weight float64) {
+	server := httptest.NewServer(agent)
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(serverURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	withNodeAgent(t, port, weight, time.Second, time.Minute)
+}
+
+// agentNode returns a node with the InternalIP, without address when empty
+func agentNode(name, ip string) v1.Node {
+	node := testNodes(name)[0]
+	if ip != "" {
+		node.Status.Addresses = []v1.NodeAddress{{Type: v1.NodeHostName, Address: name}, {Type: v1.NodeInternalIP, Address: ip}}
+	}
+	return node
+}
+
+func TestValidateNodeAgent(t *testing.T) {
+	tests := []struct {
+		port         int
+		weight       float64
+		timeout, ttl time.Duration
+		valid        bool
+	}{
+		{0, 1, time.Second, time.Minute, true},
+		{9100, 0.5, time.Second, time.Minute, true},
+		{-1, 1, time.Second, time.Minute, false},
+		{70000, 1, time.Second, time.Minute, false},
+		{9100, 1, 0, time.Minute, false},
+		{9100, 1, time.Second, 0, false},
+		{9100, 1.5, time.Second, time.Minute, false},
+		{9100, -0.5, time.Second, time.Minute, false},
+	}
+	for _, test := range tests {
+		withNodeAgent(t, test.port, test.weight, test.timeout, test.ttl)
+		if err := validateNodeAgent(); (err == nil) != test.valid {
+			t.Errorf("validateNodeAgent(%v, %v, %v, %v) returned %v", test.port, test.weight, test.timeout, test.ttl, err)
+		}
+	}
+}
+
+func TestNodeAgentFetch(t *testing.T) {
+	agent := &testNodeAgent{}
+	startNodeAgent(t, agent, 1)
+	tests := []struct {
+		name   string
+		status int
+		report string
+		node   v1.Node
+		score  float64
+		ok     bool
+	}{
+		{"score", http.StatusOK, `{"score": 7.5}`, agentNode("n", "127.0.0.1"), 7.5, true},
+		{"no score", http.StatusOK, `{}`, agentNode("n", "127.0.0.1"), 0, false},
+		{"score too high", http.StatusOK, `{"score": 11}`, agentNode("n", "127.0.0.1"), 0, false},
+		{"negative score", http.StatusOK, `{"score": -1}`, agentNode("n", "127.0.0.1"), 0, false},
+		{"malformed report", http.StatusOK, `score: 7`, agentNode("n", "127.0.0.1"), 0, false},
+		{"agent error", http.StatusInternalServerError, `{"score": 7}`, agentNode("n", "127.0.0.1"), 0, false},
+		{"no InternalIP", http.StatusOK, `{"score": 7}`, agentNode("n", ""), 0, false},
+	}
+	for _, test := range tests {
+		agent.status, agent.report = test.status, test.report
+		score, err := nodeAgents.fetch(test.node)
+		if (err == nil) != test.ok || score != test.score {
+			t.Errorf("%v: fetched %v, %v", test.name, score, err)
+		}
+	}
+}
+
+func TestNodeAgentCache(t *testing.T) {
+	agent := &testNodeAgent{status: http.StatusOK, report: `{"score": 8}`}
+	startNodeAgent(t, agent, 1)
+	node := agentNode("n", "127.0.0.1")
+	now := time.Now()
+	steps := []struct {
+		at       time.Duration
+		status   int
+		score    float64
+		ok       bool
+		requests int32
+	}{
+		{0, http.StatusOK, 8, true, 1},
+		{30 * time.Second, http.StatusInternalServerError, 8, true, 1},
+		// the ttl expired, the failure is cached in turn
+		{61 * time.Second, http.StatusInternalServerError, 0, false, 2},
+		{90 * time.Second, http.StatusOK, 0, false, 2},
+		{122 * time.Second, http.StatusOK, 8, true, 3},
+	}
+	for i, step := range steps {
+		agent.status = step.status
+		score, ok := nodeAgents.score(node, now.Add(step.at))
+		if score != step.score || ok != step.ok || atomic.LoadInt32(&agent.requests) != step.requests {
+			t.Errorf("step %v: scored %v, %v after %v agent requests", i, score, ok, atomic.LoadInt32(&agent.requests))
+		}
+	}
+	if flushed := nodeAgents.flush(); flushed != 1 {
+		t.Errorf("flushed %v nodes, expected 1", flushed)
+	}
+}
+
+func TestNodeAgentPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	nodes := []v1.Node{agentNode("agent", "127.0.0.1"), agentNode("unreachable", "")}
+	tests := []struct {
+		name     string
+		disabled bool
+		weight   float64
+		expected map[string]int
+	}{
+		{"full weight", false, 1, map[string]int{"agent": 8, "unreachable": 5}},
+		{"half weight", false, 0.5, map[string]int{"agent": 7, "unreachable": 5}},
+		{"no weight", false, 0, map[string]int{"agent": 5, "unreachable": 5}},
+		{"disabled", true, 1, map[string]int{"agent": 5, "unreachable": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := &testNodeAgent{status: http.StatusOK, report: `{"score": 8}`}
+			startNodeAgent(t, agent, test.weight)
+			if test.disabled {
+				nodeAgentPort = 0
+			}
+			checkScores(t, scoreMethod(t, NodeAgentPriority, testPod("default", "p", nil), nodes), test.expected)
+			if requests := atomic.LoadInt32(&agent.requests); test.disabled && requests != 0 {
+				t.Errorf("queried the agent %v times without -node-agent-port", requests)
+			}
+		})
+	}
+}
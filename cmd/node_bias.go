@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var biasAnnotation string
+
+func init() {
+	flag.StringVar(&biasAnnotation, "bias-annotation", "scheduler.extender/bias", "The node annotation holding a manual score offset, e.g. +5 or -3")
+}
+
+// NodeBiasPriority lets operators manually push pods toward or away from a node by annotating it,
+// every node starts from the neutral score and the annotation value is added as an offset
+var NodeBiasPriority = PrioritizeMethod{
+	Name: "node_bias",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			return clampScore(neutralScore + nodeBias(node)), nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		return fmt.Sprintf("neutral score offset by a bias of %v", nodeBias(node))
+	},
+}
+
+// nodeBias returns the offset found in the bias annotation of the node, a missing or malformed value means no bias
+func nodeBias(node v1.Node) int {
+	value, ok := node.Annotations[biasAnnotation]
+	if !ok {
+		return 0
+	}
+	bias, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		glog.Warningf("ignoring malformed %v annotation %q on node %v: %v", biasAnnotation, value, node.Name, err)
+		return 0
+	}
+	return bias
+}
+
+// clampScore keeps a score within the range accepted by the scheduler
+func clampScore(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if score > schedulingapi.MaxPriority {
+		return schedulingapi.MaxPriority
+	}
+	return score
+}
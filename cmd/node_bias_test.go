@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// annotatedNode returns a node with the annotations
+func annotatedNode(name string, annotations map[string]string) v1.Node {
+	node := testNodes(name)[0]
+	node.Annotations = annotations
+	return node
+}
+
+func TestNodeBiasPriority(t *testing.T) {
+	nodes := []v1.Node{
+		annotatedNode("plain", nil),
+		annotatedNode("preferred", map[string]string{biasAnnotation: "+5"}),
+		annotatedNode("avoided", map[string]string{biasAnnotation: " -2 "}),
+		annotatedNode("over", map[string]string{biasAnnotation: "20"}),
+		annotatedNode("under", map[string]string{biasAnnotation: "-20"}),
+		annotatedNode("malformed", map[string]string{biasAnnotation: "high"}),
+		annotatedNode("other", map[string]string{"scheduler.extender/other": "5"}),
+	}
+	list := scoreMethod(t, NodeBiasPriority, testPod("default", "p", nil), nodes)
+	checkScores(t, list, map[string]int{
+		"plain":     neutralScore,
+		"preferred": neutralScore + 5,
+		"avoided":   neutralScore - 2,
+		"over":      10,
+		"under":     0,
+		"malformed": neutralScore,
+		"other":     neutralScore,
+	})
+}
+
+// TestNodeBiasOutscores checks a node annotated +5 outscores an otherwise equal node
+func TestNodeBiasOutscores(t *testing.T) {
+	nodes := []v1.Node{annotatedNode("plain", nil), annotatedNode("biased", map[string]string{biasAnnotation: "+5"})}
+	scores := scoresByHost(scoreMethod(t, NodeBiasPriority, testPod("default", "p", nil), nodes))
+	if scores["biased"] <= scores["plain"] {
+		t.Errorf("the +5 node scored %v, not above the plain node %v", scores["biased"], scores["plain"])
+	}
+}
+
+func TestNodeBias(t *testing.T) {
+	tests := []struct {
+		value    string
+		set      bool
+		expected int
+	}{
+		{"", false, 0},
+		{"5", true, 5},
+		{"+5", true, 5},
+		{"-5", true, -5},
+		{" 4\n", true, 4},
+		{"", true, 0},
+		{"1.5", true, 0},
+		{"five", true, 0},
+	}
+	for _, test := range tests {
+		node := annotatedNode("n", nil)
+		if test.set {
+			node.Annotations = map[string]string{biasAnnotation: test.value}
+		}
+		if bias := nodeBias(node); bias != test.expected {
+			t.Errorf("nodeBias(%q, set=%v) = %v, expected %v", test.value, test.set, bias, test.expected)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var nodeHealthURL string
+var nodeHealthTTL time.Duration
+
+func init() {
+	flag.StringVar(&nodeHealthURL, "node-health-url", "", "The URL returning a JSON map of node names to their health, from 0 (degraded) to 1 (healthy), empty disables node_health")
+	flag.DurationVar(&nodeHealthTTL, "node-health-ttl", 30*time.Second, "How long the node health fetched from -node-health-url is cached")
+}
+
+// NodeHealthProvider reports the health of the nodes as seen by the monitoring, before they get cordoned
+type NodeHealthProvider interface {
+	// NodeHealth returns the health of the nodes by normalized name, from 0 (degraded) to 1 (healthy),
+	// the nodes missing from the map have an unknown health
+	NodeHealth() (map[string]float64, error)
+}
+
+// nodeHealthProvider is the provider consulted by node_health, nil when -node-health-url is not set
+var nodeHealthProvider NodeHealthProvider
+
+// startNodeHealth creates the http provider when -node-health-url is set
+func startNodeHealth() {
+	if nodeHealthURL == "" {
+		return
+	}
+	provider := newHTTPHealthProvider(nodeHealthURL, nodeHealthTTL)
+	nodeHealthProvider = provider
+	cacheFlushers["node_health"] = provider.flush
+}
+
+// httpHealthProvider polls the health map from a URL, at most once per TTL. When the URL fails the
+// last fetched map keeps being used, however old, so the monitoring being down does not make every
+// node look the same. A single request fetches at a time, without holding the lock: once a map was
+// fetched the expired one keeps being served while the next is fetched in the background
+type httpHealthProvider struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+	stats  *cacheStats
+
+	lock      sync.Mutex
+	health    map[string]float64
+	fetched   time.Time
+	attempted time.Time
+}
+
+func newHTTPHealthProvider(url string, ttl time.Duration) *httpHealthProvider {
+	return &httpHealthProvider{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		stats:  registerCacheStats("node_health"),
+	}
+}
+
+func (p *httpHealthProvider) NodeHealth() (map[string]float64, error) {
+	p.lock.Lock()
+	// a failed fetch is not retried before the TTL either, the requests would otherwise all wait on a down
+	// URL. The fetch in flight counts as attempted, the other requests do not start one of their own
+	fresh := time.Since(p.attempted) < p.ttl
+	p.stats.record(fresh)
+	health := p.health
+	if !fresh {
+		attempted := time.Now()
+		p.attempted = attempted
+		if health != nil {
+			p.lock.Unlock()
+			go p.refresh(attempted)
+			return health, nil
+		}
+		p.lock.Unlock()
+		p.refresh(attempted)
+		p.lock.Lock()
+		health = p.health
+	}
+	p.lock.Unlock()
+	if health == nil {
+		return nil, fmt.Errorf("the node health could not be fetched from %v yet", p.url)
+	}
+	return health, nil
+}
+
+// refresh fetches the health map and swaps it in, the previous map is kept when the fetch fails
+func (p *httpHealthProvider) refresh(attempted time.Time) {
+	health, err := p.fetch()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		glog.Warningf("failed to fetch the node health, using the health fetched at %v: %v", p.fetched, err)
+		return
+	}
+	p.health, p.fetched = health, attempted
+}
+
+// fetch gets the health map from the URL, keyed by normalized node name
+func (p *httpHealthProvider) fetch() (map[string]float64, error) {
+	resp, err := p.client.Get(p.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %v returned %v", p.url, resp.Status)
+	}
+	var reported map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&reported); err != nil {
+		return nil, fmt.Errorf("invalid node health from %v: %v", p.url, err)
+	}
+	health := make(map[string]float64, len(reported))
+	for name, h := range reported {
+		health[normalizeNodeName(name)] = h
+	}
+	return health, nil
+}
+
+// flush drops the cached health, returning the number of nodes dropped
+func (p *httpHealthProvider) flush() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	count := len(p.health)
+	p.health, p.fetched, p.attempted = nil, time.Time{}, time.Time{}
+	return count
+}
+
+// NodeHealthPriority scores the nodes by the health the monitoring reports, steering the pods away from
+// degraded nodes before they get cordoned. The nodes of unknown health, or every node when the provider
+// is not configured or unreachable, get the neutral score
+var NodeHealthPriority = PrioritizeMethod{
+	Name: "node_health",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		var health map[string]float64
+		if nodeHealthProvider != nil {
+			var err error
+			if health, err = nodeHealthProvider.NodeHealth(); err != nil {
+				glog.Warningf("node health unavailable, scoring neutral: %v", err)
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			h, ok := health[normalizeNodeName(node.Name)]
+			if !ok {
+				return neutralScore, nil
+			}
+			return clampScore(int(h * schedulingapi.MaxPriority)), nil
+		}
+	},
+}
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// healthServer serves the health map, counting the calls. Once slow is set, the calls wait for release
+type healthServer struct {
+	calls   int32
+	failing int32
+	slow    int32
+	release chan struct{}
+}
+
+func (s *healthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.calls, 1)
+	if atomic.LoadInt32(&s.slow) > 0 {
+		<-s.release
+	}
+	if atomic.LoadInt32(&s.failing) > 0 {
+		http.Error(w, "down", http.StatusBadGateway)
+		return
+	}
+	w.Write([]byte(`{"node-a": 1, "node-b": 0.3, "node-c": 0}`))
+}
+
+func TestNodeHealthPriority(t *testing.T) {
+	server := httptest.NewServer(&healthServer{})
+	defer server.Close()
+	defer func(saved NodeHealthProvider) { nodeHealthProvider = saved }(nodeHealthProvider)
+
+	nodes := testNodes("node-a", "node-b", "node-c", "node-d")
+	tests := []struct {
+		name     string
+		provider NodeHealthProvider
+		expected map[string]int
+	}{
+		{"health of the monitoring", newHTTPHealthProvider(server.URL, time.Minute), map[string]int{"node-a": 10, "node-b": 3, "node-c": 0, "node-d": neutralScore}},
+		{"unreachable monitoring", newHTTPHealthProvider("http://127.0.0.1:1/health", time.Minute), map[string]int{"node-a": neutralScore, "node-b": neutralScore, "node-c": neutralScore, "node-d": neutralScore}},
+		{"no provider", nil, map[string]int{"node-a": neutralScore, "node-b": neutralScore, "node-c": neutralScore, "node-d": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodeHealthProvider = test.provider
+			checkScores(t, scoreMethod(t, NodeHealthPriority, testPod("default", "p", nil), nodes), test.expected)
+		})
+	}
+}
+
+// TestNodeHealthSlowEndpoint checks a slow endpoint holds no lock: once a map was fetched, the requests are
+// served the expired one while a single fetch refreshes it
+func TestNodeHealthSlowEndpoint(t *testing.T) {
+	health := &healthServer{release: make(chan struct{})}
+	server := httptest.NewServer(health)
+	defer server.Close()
+	provider := newHTTPHealthProvider(server.URL, time.Millisecond)
+	if _, err := provider.NodeHealth(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	atomic.StoreInt32(&health.slow, 1)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h, err := provider.NodeHealth(); err != nil || h["node-a"] != 1 {
+				t.Errorf("expected the expired health map, got %v, %v", h, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the requests waited %v on the slow endpoint", elapsed)
+	}
+	close(health.release)
+	if calls := atomic.LoadInt32(&health.calls); calls > 20 {
+		t.Errorf("expected the refreshes to be bounded by the TTL, got %v calls", calls)
+	}
+}
+
+func TestNodeHealthKeepsLastMap(t *testing.T) {
+	health := &healthServer{}
+	server := httptest.NewServer(health)
+	defer server.Close()
+	provider := newHTTPHealthProvider(server.URL, time.Millisecond)
+	if _, err := provider.NodeHealth(); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&health.failing, 1)
+	for i := 0; i < 5; i++ {
+		time.Sleep(2 * time.Millisecond)
+		if h, err := provider.NodeHealth(); err != nil || h["node-b"] != 0.3 {
+			t.Fatalf("expected the last fetched map while the endpoint fails, got %v, %v", h, err)
+		}
+	}
+	if provider.flush() != 3 {
+		t.Error("expected the flush to drop the 3 nodes")
+	}
+	if _, err := provider.NodeHealth(); err == nil {
+		t.Error("expected an error once flushed while the endpoint fails")
+	}
+}
+
+func TestNodeHealthSingleFlight(t *testing.T) {
+	health := &healthServer{release: make(chan struct{}), slow: 1}
+	server := httptest.NewServer(health)
+	defer server.Close()
+	provider := newHTTPHealthProvider(server.URL, time.Minute)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		provider.NodeHealth()
+	}()
+	for atomic.LoadInt32(&health.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the first fetch is in flight, the other requests neither wait on it nor start their own
+	if _, err := provider.NodeHealth(); err == nil {
+		t.Error("expected no health before the first fetch completes")
+	}
+	close(health.release)
+	wg.Wait()
+	if calls := atomic.LoadInt32(&health.calls); calls != 1 {
+		t.Errorf("expected a single fetch, got %v", calls)
+	}
+	if h, err := provider.NodeHealth(); err != nil || h["node-a"] != 1 {
+		t.Errorf("expected the fetched map, got %v, %v", h, err)
+	}
+}
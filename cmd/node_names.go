@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var nodeNameSuffix, nodeNameRegex string
+var nodeNameLowercase bool
+
+// nodeNamePattern is the compiled -node-name-regex, nil when unset
+var nodeNamePattern *regexp.Regexp
+
+func init() {
+	flag.StringVar(&nodeNameSuffix, "node-name-strip-suffix", "", "A domain suffix stripped from node names before looking up node data, e.g. .cluster.local")
+	flag.BoolVar(&nodeNameLowercase, "node-name-lowercase", false, "Lowercase node names before looking up node data")
+	flag.StringVar(&nodeNameRegex, "node-name-regex", "", "A regular expression whose first capture group is used as the node name when looking up node data, e.g. ^([^.]+)")
+}
+
+// compileNodeNameRegex validates the -node-name-regex flag
+func compileNodeNameRegex() error {
+	if nodeNameRegex == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(nodeNameRegex)
+	if err != nil {
+		return fmt.Errorf("invalid -node-name-regex: %v", err)
+	}
+	if pattern.NumSubexp() < 1 {
+		return fmt.Errorf("the -node-name-regex %q has no capture group", nodeNameRegex)
+	}
+	nodeNamePattern = pattern
+	return nil
+}
+
+// normalizeNodeName turns a node name into the key used by the caches and external data sources,
+// the responses sent to the scheduler keep using the original node names
+func normalizeNodeName(name string) string {
+	if nodeNamePattern != nil {
+		if match := nodeNamePattern.FindStringSubmatch(name); match != nil {
+			name = match[1]
+		}
+	}
+	if nodeNameSuffix != "" {
+		name = strings.TrimSuffix(name, nodeNameSuffix)
+	}
+	if nodeNameLowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+// withNodeNameNormalization sets the node name flags until the end of the test
+func withNodeNameNormalization(t *testing.T, suffix, regex string, lowercase bool) {
+	savedSuffix, savedRegex, savedLowercase, savedPattern := nodeNameSuffix, nodeNameRegex, nodeNameLowercase, nodeNamePattern
+	t.Cleanup(func() {
+		nodeNameSuffix, nodeNameRegex, nodeNameLowercase, nodeNamePattern = savedSuffix, savedRegex, savedLowercase, savedPattern
+	})
+	nodeNameSuffix, nodeNameRegex, nodeNameLowercase, nodeNamePattern = suffix, regex, lowercase, nil
+	if err := compileNodeNameRegex(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompileNodeNameRegex(t *testing.T) {
+	tests := []struct {
+		regex string
+		valid bool
+	}{
+		{"", true},
+		{"^([^.]+)", true},
+		{"^[^.]+", false},
+		{"^([^.]+", false},
+	}
+	for _, test := range tests {
+		saved, savedPattern := nodeNameRegex, nodeNamePattern
+		nodeNameRegex = test.regex
+		if err := compileNodeNameRegex(); (err == nil) != test.valid {
+			t.Errorf("compileNodeNameRegex(%q) returned %v", test.regex, err)
+		}
+		nodeNameRegex, nodeNamePattern = saved, savedPattern
+	}
+}
+
+func TestNormalizeNodeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		suffix    string
+		regex     string
+		lowercase bool
+		node      string
+		expected  string
+	}{
+		{"unchanged", "", "", false, "Node-A.cluster.local", "Node-A.cluster.local"},
+		{"suffix", ".cluster.local", "", false, "node-a.cluster.local", "node-a"},
+		{"missing suffix", ".cluster.local", "", false, "node-a", "node-a"},
+		{"lowercase", "", "", true, "Node-A", "node-a"},
+		{"regex", "", `^([^.]+)`, false, "node-a.us-east-1.compute.internal", "node-a"},
+		{"regex not matching", "", `^ip-(\d+)`, false, "node-a", "node-a"},
+		// the suffix is stripped before lowercasing, it has to match the case of the name
+		{"suffix then lowercase", ".Cluster.Local", "", true, "Node-A.Cluster.Local", "node-a"},
+		{"regex then suffix", "-spot", `^([^.]+)`, false, "node-a-spot.internal", "node-a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withNodeNameNormalization(t, test.suffix, test.regex, test.lowercase)
+			if name := normalizeNodeName(test.node); name != test.expected {
+				t.Errorf("normalizeNodeName(%q) = %q, expected %q", test.node, name, test.expected)
+			}
+		})
+	}
+}
+
+func TestPodsByNormalizedNode(t *testing.T) {
+	withNodeNameNormalization(t, ".cluster.local", "", true)
+	byNode := podsByNode(&testPodLister{pods: podsOn("Node-A.cluster.local", 2)})
+	if pods := byNode.on("node-a"); len(pods) != 2 {
+		t.Errorf("found %v pods on node-a, expected the 2 pods bound to Node-A.cluster.local", len(pods))
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var nodeScoringConcurrency int
+
+func init() {
+	flag.IntVar(&nodeScoringConcurrency, "node-scoring-concurrency", 1, "How many nodes of a request the per-node priorities score in parallel, 1 scores them sequentially")
+}
+
+// validateNodeScoringConcurrency makes sure the -node-scoring-concurrency flag is positive
+func validateNodeScoringConcurrency() error {
+	if nodeScoringConcurrency < 1 {
+		return fmt.Errorf("the -node-scoring-concurrency flag value must be positive, got %v", nodeScoringConcurrency)
+	}
+	return nil
+}
+
+// NodeScorer scores a single node, independently of the other candidates
+type NodeScorer func(pod v1.Pod, node v1.Node) (int, error)
+
+// scoreNodes scores each node with the scorer, up to -node-scoring-concurrency nodes at a time. The
+// scores are stored by index so the list follows the order of the nodes whatever the concurrency, the
+// first error met is returned
+func scoreNodes(method string, pod v1.Pod, nodes []v1.Node, scorer NodeScorer) (*schedulingapi.HostPriorityList, error) {
+	priorityList := make(schedulingapi.HostPriorityList, 0, len(nodes))
+	err := scoreNodesInOrder(method, pod, nodes, scorer, func(i int, hp schedulingapi.HostPriority) error {
+		priorityList = append(priorityList, hp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &priorityList, nil
+}
+
+// scoreNodesInOrder scores the nodes as scoreNodes does and hands each score to emit as soon as it and
+// the scores of the nodes before it are known, so the scores are emitted in the order of the nodes. emit
+// is never called concurrently. The first error met, of the scorer or of emit, stops the emission. A
+// panic of the scorer in a worker goroutine is recorded and becomes the error of the node, it would
+// otherwise be out of reach of the recoveries of the routes and kill the process
+func scoreNodesInOrder(method string, pod v1.Pod, nodes []v1.Node, scorer NodeScorer, emit func(i int, hp schedulingapi.HostPriority) error) error {
+	scores := make([]int, len(nodes))
+	errs := make([]error, len(nodes))
+	done := make([]bool, len(nodes))
+	var lock sync.Mutex
+	var next int
+	var emitErr error
+	score := func(i int) {
+		score, err := scorer(pod, nodes[i])
+		if err != nil {
+			err = annotateError(err, "failed to score node %v", nodes[i].Name)
+		} else {
+			glog.V(6).Infof("node %v has priority score of %v for pod %v\n", nodes[i].Name, score, pod.Name)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		scores[i], errs[i], done[i] = score, err, true
+		for emitErr == nil && next < len(nodes) && done[next] {
+			if emitErr = errs[next]; emitErr == nil {
+				emitErr = emit(next, schedulingapi.HostPriority{Host: nodes[next].Name, Score: scores[next]})
+			}
+			next++
+		}
+	}
+
+	if nodeScoringConcurrency <= 1 || len(nodes) <= 1 {
+		for i := range nodes {
+			score(i)
+			if emitErr != nil {
+				break
+			}
+		}
+	} else {
+		scorer = recoveringScorer(method, scorer)
+		indexes := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < nodeScoringConcurrency && w < len(nodes); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					score(i)
+				}
+			}()
+		}
+		for i := range nodes {
+			indexes <- i
+		}
+		close(indexes)
+		wg.Wait()
+	}
+	return emitErr
+}
+
+// recoveringScorer returns the scorer turning its panics into errors, for the worker goroutines
+func recoveringScorer(method string, scorer NodeScorer) NodeScorer {
+	return func(pod v1.Pod, node v1.Node) (score int, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				recordPanic(method, r)
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return scorer(pod, node)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withNodeScoringConcurrency sets -node-scoring-concurrency until the end of the test
+func withNodeScoringConcurrency(tb testing.TB, concurrency int) {
+	saved := nodeScoringConcurrency
+	tb.Cleanup(func() { nodeScoringConcurrency = saved })
+	nodeScoringConcurrency = concurrency
+}
+
+// numberedNodes returns n nodes named node-0 to node-n-1
+func numberedNodes(n int) []v1.Node {
+	nodes := make([]v1.Node, n)
+	for i := range nodes {
+		nodes[i].Name = fmt.Sprintf("node-%v", i)
+	}
+	return nodes
+}
+
+func TestScoreNodesConcurrency(t *testing.T) {
+	nodes := numberedNodes(50)
+	byLength := func(pod v1.Pod, node v1.Node) (int, error) {
+		return len(node.Name) % schedulingapi.MaxPriority, nil
+	}
+	failing := func(pod v1.Pod, node v1.Node) (int, error) {
+		if node.Name == "node-7" || node.Name == "node-30" {
+			return 0, errors.New("unreachable")
+		}
+		return neutralScore, nil
+	}
+	tests := []struct {
+		name   string
+		scorer NodeScorer
+		nodes  []v1.Node
+		err    string
+	}{
+		{"scores", byLength, nodes, ""},
+		{"single node", byLength, nodes[:1], ""},
+		{"no node", byLength, nil, ""},
+		{"first error", failing, nodes, "failed to score node node-7: unreachable"},
+	}
+	for _, test := range tests {
+		withNodeScoringConcurrency(t, 1)
+		sequential, sequentialErr := scoreNodes("test", v1.Pod{}, test.nodes, test.scorer)
+		for _, concurrency := range []int{2, 8, 100} {
+			withNodeScoringConcurrency(t, concurrency)
+			parallel, err := scoreNodes("test", v1.Pod{}, test.nodes, test.scorer)
+			if fmt.Sprint(err) != fmt.Sprint(sequentialErr) || (test.err != "" && fmt.Sprint(err) != test.err) {
+				t.Errorf("%v: concurrency %v returned the error %v, sequentially %v", test.name, concurrency, err, sequentialErr)
+			}
+			if !reflect.DeepEqual(parallel, sequential) {
+				t.Errorf("%v: concurrency %v scored %v, sequentially %v", test.name, concurrency, parallel, sequential)
+			}
+		}
+	}
+}
+
+func TestScoreNodesErrorKind(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		text   string
+	}{
+		{newError(ErrUnavailable, "backend down"), http.StatusServiceUnavailable, "unavailable: failed to score node a: backend down"},
+		{ErrBadRequest, http.StatusBadRequest, "bad request: failed to score node a"},
+		{errors.New("bug"), http.StatusInternalServerError, "failed to score node a: bug"},
+	}
+	for _, test := range tests {
+		_, err := scoreNodes("test", v1.Pod{}, testNodes("a"), func(pod v1.Pod, node v1.Node) (int, error) {
+			return 0, test.err
+		})
+		if status := statusForError(err); status != test.status || fmt.Sprint(err) != test.text {
+			t.Errorf("scoring failed with %q, status %v, expected %q, status %v", err, status, test.text, test.status)
+		}
+	}
+}
+
+func TestScoreNodesPanic(t *testing.T) {
+	withPanicWebhook(t, "", time.Minute)
+	withNodeScoringConcurrency(t, 4)
+	panicking := func(pod v1.Pod, node v1.Node) (int, error) {
+		if node.Name == "node-3" {
+			panic("boom")
+		}
+		return neutralScore, nil
+	}
+	if _, err := scoreNodes("panicking", v1.Pod{}, numberedNodes(10), panicking); fmt.Sprint(err) != "failed to score node node-3: panic: boom" {
+		t.Errorf("returned the error %v, expected the panic of node-3", err)
+	}
+	if count := panicCount("panicking"); count != 1 {
+		t.Errorf("counted %v panics, expected 1", count)
+	}
+}
+
+func TestPanickingScorerRoutes(t *testing.T) {
+	withPanicWebhook(t, "", time.Minute)
+	withNodeScoringConcurrency(t, 4)
+	withFilterFailOpen(t, false, true)
+	withFailOpenCounts(t)
+	panicking := PrioritizeMethod{
+		Name: "panicking",
+		Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+			return func(pod v1.Pod, node v1.Node) (int, error) {
+				panic("boom")
+			}
+		},
+	}
+	router := newTestRouter(t, panicking, constantPriority("constant", 1, 9))
+	AddCombinedRoute(router)
+	nodes := numberedNodes(8)
+	neutral := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		neutral[node.Name] = neutralScore
+	}
+
+	// with -fail-open the method route answers neutral scores, the combined route drops the method
+	checkScores(t, prioritize(t, router, panicking.Name, testPod("default", "p", nil), nodes), neutral)
+	w := combine(t, router, "", nodes)
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("combined answered %v: %v", w.Code, w.Body.String())
+	}
+	for _, hp := range list {
+		if hp.Score != 9 {
+			t.Errorf("combined scored %v, expected the constant method only", list)
+			break
+		}
+	}
+	if count := panicCount(panicking.Name); count < 2 {
+		t.Errorf("counted %v panics, expected the panics of both requests", count)
+	}
+}
+
+// TestPrioritiesConcurrency checks the per-node priorities score the same whatever the concurrency
+func TestPrioritiesConcurrency(t *testing.T) {
+	nodes := numberedNodes(30)
+	for i := range nodes {
+		nodes[i].Labels = map[string]string{
+			networkTierLabel:                 fmt.Sprint(i % 4),
+			capabilityLabelPrefix + "sgx":    fmt.Sprint(i%2 == 0),
+			capabilityLabelPrefix + "avx512": fmt.Sprint(i%3 == 0),
+			instanceTypeLabel:                fmt.Sprint(i % 5),
+			capacityTypeLabel:                []string{spotCapacityType, "on-demand"}[i%2],
+		}
+		nodes[i].Annotations = map[string]string{warmPoolAnnotation: fmt.Sprint(i % 7)}
+	}
+	pod := testPod("default", "p", map[string]string{latencySensitiveLabel: "true", workloadClassKey: tolerantWorkloadClass})
+	pod.Annotations = map[string]string{
+		latencyBudgetAnnotation: "20ms",
+		capabilitiesAnnotation:  "sgx,avx512",
+		costModeAnnotation:      costModeCost,
+	}
+	withConfig(t, &extenderConfig{InstanceTypePrices: map[string]float64{"0": 1, "1": 2, "2": 3, "3": 4, "4": 5}})
+	for _, method := range []PrioritizeMethod{SpotPriority, WarmPoolPriority, InstanceCostPriority, NodeCapabilitiesPriority, NodeAffinityPriority, LatencyBudgetPriority, ImagePullTimePriority} {
+		withNodeScoringConcurrency(t, 1)
+		sequential := scoreMethod(t, method, pod, nodes)
+		withNodeScoringConcurrency(t, 8)
+		if parallel := scoreMethod(t, method, pod, nodes); !reflect.DeepEqual(parallel, sequential) {
+			t.Errorf("%v: scored %v in parallel, %v sequentially", method.Name, parallel, sequential)
+		}
+	}
+}
+
+func BenchmarkScoreNodes(b *testing.B) {
+	nodes := numberedNodes(100)
+	// slow stands for a per-node lookup of an external service
+	slow := func(pod v1.Pod, node v1.Node) (int, error) {
+		time.Sleep(100 * time.Microsecond)
+		return neutralScore, nil
+	}
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency-%v", concurrency), func(b *testing.B) {
+			withNodeScoringConcurrency(b, concurrency)
+			for i := 0; i < b.N; i++ {
+				if _, err := scoreNodes("test", v1.Pod{}, nodes, slow); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
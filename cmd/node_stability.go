@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var stabilityWindow time.Duration
+var preferFreshNodes bool
+
+func init() {
+	flag.DurationVar(&stabilityWindow, "stability-window", time.Hour, "How long a node must have been Ready to get the full node_stability score")
+	flag.BoolVar(&preferFreshNodes, "prefer-fresh-nodes", false, "Invert node_stability to favor the nodes that became Ready recently")
+}
+
+// NodeStabilityPriority favors the nodes that have been Ready for long, a node that recently rebooted or
+// had its kubelet restarted scores proportionally to its time Ready within the stability window.
+// Nodes without a Ready condition get the neutral score
+var NodeStabilityPriority = PrioritizeMethod{
+	Name: "node_stability",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		now := time.Now()
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			return nodeStabilityScore(node, now), nil
+		}
+	},
+}
+
+// nodeStabilityScore scores the time elapsed since the node became Ready against the stability window,
+// a node that is not Ready scores 0 whatever the direction. A transition in the future, from a skewed
+// clock, counts as freshly Ready
+func nodeStabilityScore(node v1.Node, now time.Time) int {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != v1.NodeReady {
+			continue
+		}
+		if condition.Status != v1.ConditionTrue {
+			return 0
+		}
+		ready := now.Sub(condition.LastTransitionTime.Time)
+		if ready < 0 {
+			ready = 0
+		} else if ready > stabilityWindow {
+			ready = stabilityWindow
+		}
+		score := int(int64(schedulingapi.MaxPriority) * int64(ready) / int64(stabilityWindow))
+		if preferFreshNodes {
+			return schedulingapi.MaxPriority - score
+		}
+		return score
+	}
+	return neutralScore
+}
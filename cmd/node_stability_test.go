@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func withStability(t *testing.T, window time.Duration, fresh bool) {
+	savedWindow, savedFresh := stabilityWindow, preferFreshNodes
+	stabilityWindow, preferFreshNodes = window, fresh
+	t.Cleanup(func() { stabilityWindow, preferFreshNodes = savedWindow, savedFresh })
+}
+
+func readyNode(status v1.ConditionStatus, since time.Time) v1.Node {
+	var node v1.Node
+	node.Status.Conditions = []v1.NodeCondition{
+		{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse},
+		{Type: v1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(since)},
+	}
+	return node
+}
+
+func TestNodeStabilityScore(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		node     v1.Node
+		fresh    bool
+		expected int
+	}{
+		{"stable", readyNode(v1.ConditionTrue, now.Add(-2*time.Hour)), false, 10},
+		{"half the window", readyNode(v1.ConditionTrue, now.Add(-30*time.Minute)), false, 5},
+		{"freshly ready", readyNode(v1.ConditionTrue, now), false, 0},
+		{"clock skew", readyNode(v1.ConditionTrue, now.Add(30*time.Minute)), false, 0},
+		{"not ready", readyNode(v1.ConditionFalse, now.Add(-2*time.Hour)), false, 0},
+		{"unknown", readyNode(v1.ConditionUnknown, now.Add(-2*time.Hour)), false, 0},
+		{"no ready condition", v1.Node{}, false, neutralScore},
+		{"stable preferring fresh", readyNode(v1.ConditionTrue, now.Add(-2*time.Hour)), true, 0},
+		{"freshly ready preferring fresh", readyNode(v1.ConditionTrue, now), true, 10},
+		{"not ready preferring fresh", readyNode(v1.ConditionFalse, now), true, 0},
+		{"no ready condition preferring fresh", v1.Node{}, true, neutralScore},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withStability(t, time.Hour, test.fresh)
+			if score := nodeStabilityScore(test.node, now); score != test.expected {
+				t.Errorf("scored %v, expected %v", score, test.expected)
+			}
+		})
+	}
+}
+
+func TestNodeStabilityPriority(t *testing.T) {
+	withStability(t, time.Hour, false)
+	fresh, stable := readyNode(v1.ConditionTrue, time.Now()), readyNode(v1.ConditionTrue, time.Now().Add(-2*time.Hour))
+	fresh.Name, stable.Name = "fresh", "stable"
+	checkScores(t, scoreMethod(t, NodeStabilityPriority, testPod("default", "web", nil), []v1.Node{fresh, stable}), map[string]int{"fresh": 0, "stable": 10})
+}
@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	// numaPreferenceSingle asks for a node able to fit the pod CPUs in a single NUMA node
+	numaPreferenceSingle = "single-numa-node"
+	// numaPreferenceRestricted favors the nodes spreading the pod CPUs over the fewest NUMA nodes
+	numaPreferenceRestricted = "restricted"
+)
+
+var numaPreferenceAnnotation, numaCPUsAnnotation string
+
+func init() {
+	flag.StringVar(&numaPreferenceAnnotation, "numa-preference-annotation", "scheduler.extender/numa-preference", "The pod annotation holding its NUMA preference, one of: single-numa-node, restricted")
+	flag.StringVar(&numaCPUsAnnotation, "numa-cpus-annotation", "node.example.com/numa-cpus", "The node annotation advertising how many CPUs each NUMA node of the node holds")
+}
+
+// NUMAPriority favors the nodes whose NUMA layout best aligns the CPUs the pod requests. The node status
+// carries no NUMA state, so the priority relies on the NUMA node size the nodes advertise: a pod fitting
+// in one NUMA node scores the max, a pod spread over n NUMA nodes scores max/n with the restricted
+// preference and 0 with the single-numa-node one. Pods without a preference, or nodes not advertising
+// their layout, get the neutral score
+var NUMAPriority = PrioritizeMethod{
+	Name: "numa_alignment",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		preference := pod.Annotations[numaPreferenceAnnotation]
+		milliCPUs := podCycles.get(pod, time.Now()).request(v1.ResourceCPU)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			cpusPerNUMANode := nodeNUMACPUs(node)
+			if cpusPerNUMANode <= 0 || milliCPUs <= 0 || (preference != numaPreferenceSingle && preference != numaPreferenceRestricted) {
+				return neutralScore, nil
+			}
+			spanned := int((milliCPUs + cpusPerNUMANode*1000 - 1) / (cpusPerNUMANode * 1000))
+			switch {
+			case spanned <= 1:
+				return schedulingapi.MaxPriority, nil
+			case preference == numaPreferenceSingle:
+				return 0, nil
+			}
+			return schedulingapi.MaxPriority / spanned, nil
+		}
+	},
+}
+
+// nodeNUMACPUs returns the CPUs of each NUMA node of the node, 0 when not advertised or malformed
+func nodeNUMACPUs(node v1.Node) int64 {
+	value, ok := node.Annotations[numaCPUsAnnotation]
+	if !ok {
+		return 0
+	}
+	cpus, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cpus < 0 {
+		glog.Warningf("ignoring invalid %v annotation %q on node %v", numaCPUsAnnotation, value, node.Name)
+		return 0
+	}
+	return cpus
+}
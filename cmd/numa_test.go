@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestNodeNUMACPUs(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    int64
+	}{
+		{map[string]string{numaCPUsAnnotation: "16"}, 16},
+		{map[string]string{numaCPUsAnnotation: "0"}, 0},
+		{map[string]string{numaCPUsAnnotation: "-8"}, 0},
+		{map[string]string{numaCPUsAnnotation: "sixteen"}, 0},
+		{nil, 0},
+	}
+	for _, test := range tests {
+		if cpus := nodeNUMACPUs(annotatedNode("n", test.annotations)); cpus != test.expected {
+			t.Errorf("read %v as %v CPUs, expected %v", test.annotations, cpus, test.expected)
+		}
+	}
+}
+
+func TestNUMAPriority(t *testing.T) {
+	numaNode := func(name, cpus string) v1.Node {
+		return annotatedNode(name, map[string]string{numaCPUsAnnotation: cpus})
+	}
+	nodes := []v1.Node{numaNode("wide", "8"), numaNode("exact", "6"), numaNode("half", "4"), numaNode("narrow", "2"), numaNode("malformed", "many"), annotatedNode("unadvertised", nil)}
+	numaPod := func(preference, cpu string) v1.Pod {
+		pod := resourcePod("p", "", resourceList(cpu, ""), nil)
+		if preference != "" {
+			pod.Annotations = map[string]string{numaPreferenceAnnotation: preference}
+		}
+		return pod
+	}
+	neutral := map[string]int{"wide": neutralScore, "exact": neutralScore, "half": neutralScore, "narrow": neutralScore, "malformed": neutralScore, "unadvertised": neutralScore}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"restricted", numaPod(numaPreferenceRestricted, "6"), map[string]int{"wide": 10, "exact": 10, "half": 5, "narrow": 3, "malformed": neutralScore, "unadvertised": neutralScore}},
+		{"single NUMA node", numaPod(numaPreferenceSingle, "6"), map[string]int{"wide": 10, "exact": 10, "half": 0, "narrow": 0, "malformed": neutralScore, "unadvertised": neutralScore}},
+		{"fractional CPUs", numaPod(numaPreferenceSingle, "4100m"), map[string]int{"wide": 10, "exact": 10, "half": 0, "narrow": 0, "malformed": neutralScore, "unadvertised": neutralScore}},
+		{"no preference", numaPod("", "6"), neutral},
+		{"unknown preference", numaPod("best-effort", "6"), neutral},
+		{"no CPU request", numaPod(numaPreferenceRestricted, ""), neutral},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, NUMAPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var ownerStickiness int
+var ownerPlacementWindow time.Duration
+
+func init() {
+	flag.IntVar(&ownerStickiness, "owner-stickiness", 2, "The score offset given to nodes where the pod owner recently placed replicas, negative values spread the replicas")
+	flag.DurationVar(&ownerPlacementWindow, "owner-placement-window", 10*time.Minute, "How long the placements of an owner's replicas are remembered")
+}
+
+// placementKey identifies the node a replica of an owner was placed on
+type placementKey struct {
+	owner types.UID
+	node  string
+}
+
+// placementCache remembers when the replicas of an owner were last seen on a node, so the placements
+// outlive the replicas deleted during a rollout
+type placementCache struct {
+	lock    sync.Mutex
+	entries map[placementKey]time.Time
+	stats   *cacheStats
+}
+
+// ownerPlacements is the placement cache shared by the requests
+var ownerPlacements = &placementCache{entries: make(map[placementKey]time.Time), stats: registerCacheStats("owner_placements")}
+
+// observe records the placements of the controlled pods and drops the expired ones
+func (c *placementCache) observe(pods []v1.Pod, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, seen := range c.entries {
+		if now.Sub(seen) > ownerPlacementWindow {
+			delete(c.entries, key)
+		}
+	}
+	for i := range pods {
+		owner := metav1.GetControllerOf(&pods[i])
+		if owner == nil || pods[i].Spec.NodeName == "" {
+			continue
+		}
+		c.entries[placementKey{owner: owner.UID, node: normalizeNodeName(pods[i].Spec.NodeName)}] = now
+	}
+}
+
+// recent reports whether a replica of the owner was placed on the node within the window
+func (c *placementCache) recent(owner types.UID, node string, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	seen, ok := c.entries[placementKey{owner: owner, node: normalizeNodeName(node)}]
+	hit := ok && now.Sub(seen) <= ownerPlacementWindow
+	c.stats.record(hit)
+	return hit
+}
+
+// flush empties the cache and returns the number of entries dropped
+func (c *placementCache) flush() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	count := len(c.entries)
+	c.entries = make(map[placementKey]time.Time)
+	return count
+}
+
+// OwnerStickinessPriority offsets the score of the nodes where the owner of the pod recently placed
+// replicas, smoothing the churn of rollouts. Pods without a controller get the neutral score
+var OwnerStickinessPriority = PrioritizeMethod{
+	Name:              "owner_stickiness",
+	RequiresInformers: true,
+	NodeNamesOnly:     true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		now := time.Now()
+		ownerPlacements.observe(podLister.List(), now)
+		owner := metav1.GetControllerOf(&pod)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if owner != nil && ownerPlacements.recent(owner.UID, node.Name, now) {
+				return clampScore(neutralScore + ownerStickiness), nil
+			}
+			return neutralScore, nil
+		}
+	},
+}
@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestPlacementCache(t *testing.T) {
+	now := time.Now()
+	cache := &placementCache{entries: make(map[placementKey]time.Time), stats: ownerPlacements.stats}
+	cache.observe([]v1.Pod{
+		ownedPod("r1", "rs", "a", now, time.Time{}, 0),
+		ownedPod("r2", "rs", "", now, time.Time{}, 0),
+		ownedPod("single", "", "c", now, time.Time{}, 0),
+	}, now)
+	// the replica on a is deleted by the rollout, its placement is remembered
+	cache.observe([]v1.Pod{ownedPod("r3", "rs", "b", now, time.Time{}, 0)}, now.Add(time.Minute))
+	tests := []struct {
+		name     string
+		node     string
+		at       time.Time
+		expected bool
+	}{
+		{"placed", "a", now, true},
+		{"deleted replica within the window", "a", now.Add(ownerPlacementWindow), true},
+		{"deleted replica past the window", "a", now.Add(ownerPlacementWindow + time.Second), false},
+		{"later replica", "b", now.Add(ownerPlacementWindow), true},
+		{"pod without controller", "c", now, false},
+		{"no replica", "d", now, false},
+	}
+	for _, test := range tests {
+		if recent := cache.recent("rs", test.node, test.at); recent != test.expected {
+			t.Errorf("%v: recent on %v = %v, expected %v", test.name, test.node, recent, test.expected)
+		}
+	}
+	cache.observe(nil, now.Add(ownerPlacementWindow+2*time.Minute))
+	if count := cache.flush(); count != 0 {
+		t.Errorf("%v expired placements were kept", count)
+	}
+}
+
+func TestOwnerStickinessPriority(t *testing.T) {
+	withPods(t, ownedPod("r1", "rs", "a", time.Now(), time.Time{}, 0), ownedPod("o1", "other", "b", time.Now(), time.Time{}, 0))
+	tests := []struct {
+		name       string
+		pod        v1.Pod
+		stickiness int
+		expected   map[string]int
+	}{
+		{"replica", ownedPod("r2", "rs", "", time.Now(), time.Time{}, 0), 2, map[string]int{"a": 7, "b": neutralScore, "c": neutralScore}},
+		{"spread replicas", ownedPod("r2", "rs", "", time.Now(), time.Time{}, 0), -2, map[string]int{"a": 3, "b": neutralScore, "c": neutralScore}},
+		{"clamped stickiness", ownedPod("r2", "rs", "", time.Now(), time.Time{}, 0), 20, map[string]int{"a": 10, "b": neutralScore, "c": neutralScore}},
+		{"pod without controller", ownedPod("single", "", "", time.Now(), time.Time{}, 0), 2, map[string]int{"a": neutralScore, "b": neutralScore, "c": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer ownerPlacements.flush()
+			saved := ownerStickiness
+			defer func() { ownerStickiness = saved }()
+			ownerStickiness = test.stickiness
+			checkScores(t, scoreMethod(t, OwnerStickinessPriority, test.pod, testNodes("a", "b", "c")), test.expected)
+		})
+	}
+}
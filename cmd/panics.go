@@ -0,0 +1,140 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+)
+
+// panicStackBytes bounds the stack sent to the panic webhook
+const panicStackBytes = 8 * 1024
+
+var panicWebhookURL string
+var panicWebhookInterval time.Duration
+
+func init() {
+	flag.StringVar(&panicWebhookURL, "panic-webhook-url", "", "A URL the details of a handler panic are POSTed to as JSON, for alerting")
+	flag.DurationVar(&panicWebhookInterval, "panic-webhook-interval", time.Minute, "The minimum interval between two panic webhook calls, the panics in between are counted in the next call")
+}
+
+// PanicReport is the body POSTed to the -panic-webhook-url
+type PanicReport struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Method  string    `json:"method"`
+	Message string    `json:"message"`
+	Stack   string    `json:"stack"`
+	// Suppressed counts the panics left unreported since the previous call, by the rate limit
+	Suppressed int `json:"suppressed"`
+}
+
+var panicsLock sync.Mutex
+
+// panicCounts counts the recovered handler panics per method, exposed on /metrics
+var panicCounts = make(map[string]int)
+
+// panicWebhookState rate limits the webhook calls
+var panicWebhookState struct {
+	last       time.Time
+	suppressed int
+}
+
+// recordPanic counts a panic recovered while serving the method and reports it to the webhook, it must
+// be called from the deferred function that recovered the panic so the stack is the panicking one
+func recordPanic(method string, r interface{}) {
+	stack := debug.Stack()
+	glog.Errorf("method %v panicked: %v\n%s", method, r, stack)
+	panicsLock.Lock()
+	defer panicsLock.Unlock()
+	panicCounts[method]++
+	if panicWebhookURL == "" {
+		return
+	}
+	now := time.Now()
+	if !panicWebhookState.last.IsZero() && now.Sub(panicWebhookState.last) < panicWebhookInterval {
+		panicWebhookState.suppressed++
+		return
+	}
+	if len(stack) > panicStackBytes {
+		stack = stack[:panicStackBytes]
+	}
+	host, _ := os.Hostname()
+	report := PanicReport{Time: now, Host: host, Method: method, Message: fmt.Sprint(r), Stack: string(stack), Suppressed: panicWebhookState.suppressed}
+	panicWebhookState.last, panicWebhookState.suppressed = now, 0
+	go postPanicReport(report)
+}
+
+// postPanicReport POSTs the report to the webhook, a failure is only logged
+func postPanicReport(report PanicReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		glog.Warningf("failed to encode the panic report: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(panicWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		glog.Warningf("failed to call the panic webhook: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		glog.Warningf("the panic webhook returned %v", resp.Status)
+	}
+}
+
+// countPanics wraps the handle of a method route so the panics nothing recovered below are recorded
+// before going on to the http server, which aborts the response
+func countPanics(method string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		defer func() {
+			if p := recover(); p != nil {
+				// an aborted response is not a bug of the method
+				if p != http.ErrAbortHandler {
+					recordPanic(method, p)
+				}
+				panic(p)
+			}
+		}()
+		handle(w, r, ps)
+	}
+}
+
+// writePanicMetrics writes the number of recovered handler panics per method
+func writePanicMetrics(w io.Writer) {
+	panicsLock.Lock()
+	defer panicsLock.Unlock()
+	methods := make([]string, 0, len(panicCounts))
+	for method := range panicCounts {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	samples := make([]metricSample, len(methods))
+	for i, method := range methods {
+		samples[i] = metricSample{fmt.Sprintf("method=%q", method), float64(panicCounts[method])}
+	}
+	writeMetric(w, "extender_handler_panics_total", "counter", "Number of panics recovered while serving a method.", samples...)
+}
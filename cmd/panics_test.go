@@ -0,0 +1,181 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// withPanicWebhook sets -panic-webhook-url and -panic-webhook-interval and clears the panic counts and
+// the webhook rate limit until the end of the test
+func withPanicWebhook(t *testing.T, url string, interval time.Duration) {
+	savedURL, savedInterval := panicWebhookURL, panicWebhookInterval
+	reset := func() {
+		panicsLock.Lock()
+		panicCounts = make(map[string]int)
+		panicWebhookState.last, panicWebhookState.suppressed = time.Time{}, 0
+		panicsLock.Unlock()
+	}
+	t.Cleanup(func() {
+		panicWebhookURL, panicWebhookInterval = savedURL, savedInterval
+		reset()
+	})
+	panicWebhookURL, panicWebhookInterval = url, interval
+	reset()
+}
+
+// panicCount returns the number of panics recorded for the method
+func panicCount(method string) int {
+	panicsLock.Lock()
+	defer panicsLock.Unlock()
+	return panicCounts[method]
+}
+
+// startPanicWebhook serves a webhook sending the reports it receives on the returned channel
+func startPanicWebhook(t *testing.T) (*httptest.Server, <-chan PanicReport) {
+	reports := make(chan PanicReport, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report PanicReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("the webhook received an invalid report: %v", err)
+		}
+		reports <- report
+	}))
+	t.Cleanup(server.Close)
+	return server, reports
+}
+
+// receiveReport waits for the next report of the webhook
+func receiveReport(t *testing.T, reports <-chan PanicReport) PanicReport {
+	t.Helper()
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook received no report")
+	}
+	return PanicReport{}
+}
+
+func TestRecordPanicWebhook(t *testing.T) {
+	server, reports := startPanicWebhook(t)
+	withPanicWebhook(t, server.URL, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		recordPanic("m", "boom")
+	}
+	if count := panicCount("m"); count != 3 {
+		t.Errorf("counted %v panics, expected 3", count)
+	}
+	report := receiveReport(t, reports)
+	if report.Method != "m" || report.Message != "boom" || report.Suppressed != 0 || report.Stack == "" || len(report.Stack) > panicStackBytes {
+		t.Errorf("got the report %+v", report)
+	}
+	select {
+	case report := <-reports:
+		t.Errorf("the rate limit let another report through: %+v", report)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// past the interval, the next report counts the panics left unreported
+	panicsLock.Lock()
+	panicWebhookState.last = time.Now().Add(-2 * time.Hour)
+	panicsLock.Unlock()
+	recordPanic("n", "again")
+	if report := receiveReport(t, reports); report.Method != "n" || report.Suppressed != 2 {
+		t.Errorf("got the report %+v, expected 2 suppressed panics", report)
+	}
+}
+
+func TestRecordPanicWithoutWebhook(t *testing.T) {
+	withPanicWebhook(t, "", time.Minute)
+	recordPanic("m", "boom")
+	if count := panicCount("m"); count != 1 {
+		t.Errorf("counted %v panics, expected 1", count)
+	}
+	panicsLock.Lock()
+	defer panicsLock.Unlock()
+	if !panicWebhookState.last.IsZero() {
+		t.Error("reported a panic without -panic-webhook-url")
+	}
+}
+
+func TestCountPanics(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		handle  httprouter.Handle
+		panics  bool
+		counted int
+	}{
+		{"no panic", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {}, false, 0},
+		{"panic", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) { panic("boom") }, true, 1},
+		{"aborted response", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) { panic(http.ErrAbortHandler) }, true, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withPanicWebhook(t, "", time.Minute)
+			var recovered interface{}
+			func() {
+				defer func() { recovered = recover() }()
+				countPanics("m", test.handle)(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), nil)
+			}()
+			if (recovered != nil) != test.panics {
+				t.Errorf("panic %v going on to the http server, expected %v", recovered, test.panics)
+			}
+			if count := panicCount("m"); count != test.counted {
+				t.Errorf("counted %v panics, expected %v", count, test.counted)
+			}
+		})
+	}
+}
+
+func TestPanicRoutes(t *testing.T) {
+	withPanicWebhook(t, "", time.Minute)
+	withFilterFailOpen(t, false, true)
+	withFailOpenCounts(t)
+	router := newTestRouter(t, panickingPriority("panicking"), constantPriority("constant", 1, 7))
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b")
+
+	// with -fail-open the route answers neutral scores, each recovered panic is counted once
+	checkScores(t, prioritize(t, router, "panicking", testPod("default", "p", nil), nodes), map[string]int{"a": 5, "b": 5})
+	if w := combine(t, router, "", nodes); w.Code != http.StatusOK {
+		t.Fatalf("combined answered %v: %v", w.Code, w.Body.String())
+	}
+	if count := panicCount("panicking"); count != 2 {
+		t.Errorf("counted %v panics, expected 2", count)
+	}
+
+	var metrics bytes.Buffer
+	writePanicMetrics(&metrics)
+	if expected := `extender_handler_panics_total{method="panicking"} 2`; !strings.Contains(metrics.String(), expected) {
+		t.Errorf("the metrics are missing %v:\n%v", expected, metrics.String())
+	}
+	panicsLock.Lock()
+	methods := make([]string, 0, len(panicCounts))
+	for method := range panicCounts {
+		methods = append(methods, method)
+	}
+	panicsLock.Unlock()
+	if !reflect.DeepEqual(methods, []string{"panicking"}) {
+		t.Errorf("counted panics for %v, expected only the panicking method", methods)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var outcomeReadyThreshold, outcomeWindow time.Duration
+
+func init() {
+	flag.DurationVar(&outcomeReadyThreshold, "outcome-ready-threshold", 2*time.Minute, "How quickly a pod must become ready for its placement to count as a success in placement_outcome")
+	flag.DurationVar(&outcomeWindow, "outcome-window", time.Hour, "How long the placement outcomes are remembered by the in-memory outcome store")
+}
+
+// OutcomeStore records whether past placements went well. Outcomes are grouped by a similarity key,
+// the owner of the pods, so a placement only informs the scoring of pods of the same workload
+type OutcomeStore interface {
+	// Record stores the outcome of the pod placed on the node, replacing a previous outcome of the pod
+	Record(key, node string, pod types.UID, success bool, now time.Time)
+	// Outcomes returns the number of successful and failed placements on the node
+	Outcomes(key, node string, now time.Time) (successes, failures int)
+}
+
+// outcomeStore is the store placement_outcome is created with
+var outcomeStore OutcomeStore = newMemoryOutcomeStore()
+
+type outcomeKey struct {
+	key  string
+	node string
+}
+
+type outcome struct {
+	success bool
+	seen    time.Time
+}
+
+// outcomePruneInterval is how often the in-memory outcome store drops the outcomes past -outcome-window
+const outcomePruneInterval = time.Minute
+
+// memoryOutcomeStore keeps the outcomes of the last -outcome-window in memory, they are lost on restart.
+// The expired outcomes are pruned as new ones are recorded, at most every outcomePruneInterval
+type memoryOutcomeStore struct {
+	lock     sync.Mutex
+	outcomes map[outcomeKey]map[types.UID]outcome
+	pruned   time.Time
+}
+
+func newMemoryOutcomeStore() *memoryOutcomeStore {
+	return &memoryOutcomeStore{outcomes: make(map[outcomeKey]map[types.UID]outcome)}
+}
+
+func (s *memoryOutcomeStore) Record(key, node string, pod types.UID, success bool, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if now.Sub(s.pruned) >= outcomePruneInterval {
+		s.prune(now)
+	}
+	k := outcomeKey{key: key, node: normalizeNodeName(node)}
+	if s.outcomes[k] == nil {
+		s.outcomes[k] = make(map[types.UID]outcome)
+	}
+	s.outcomes[k][pod] = outcome{success: success, seen: now}
+}
+
+func (s *memoryOutcomeStore) Outcomes(key, node string, now time.Time) (successes, failures int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	k := outcomeKey{key: key, node: normalizeNodeName(node)}
+	for _, o := range s.outcomes[k] {
+		if now.Sub(o.seen) > outcomeWindow {
+			continue
+		}
+		if o.success {
+			successes++
+		} else {
+			failures++
+		}
+	}
+	return successes, failures
+}
+
+// prune drops the outcomes past the window, and the keys left without outcomes. The lock must be held
+func (s *memoryOutcomeStore) prune(now time.Time) {
+	for k, pods := range s.outcomes {
+		for pod, o := range pods {
+			if now.Sub(o.seen) > outcomeWindow {
+				delete(pods, pod)
+			}
+		}
+		if len(pods) == 0 {
+			delete(s.outcomes, k)
+		}
+	}
+	s.pruned = now
+}
+
+// len returns the number of outcomes held, expired or not
+func (s *memoryOutcomeStore) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for _, pods := range s.outcomes {
+		count += len(pods)
+	}
+	return count
+}
+
+// PlacementOutcomePriority biases the scores toward the nodes where the pods of the same owner became
+// ready quickly, a lightweight bandit: the score is the smoothed success rate of the placements, so a
+// node without history gets the neutral score and each outcome moves it a bit further from it
+var PlacementOutcomePriority = PrioritizeMethod{
+	Name:              "placement_outcome",
+	RequiresInformers: true,
+	NodeNamesOnly:     true,
+	Scorer:            &placementOutcomeScorer{store: outcomeStore},
+}
+
+// placementOutcomeScorer scores the nodes from the outcomes of its store. The pods are observed once per
+// refresh of the pod view, the requests in between read the store only
+type placementOutcomeScorer struct {
+	store OutcomeStore
+
+	observeLock sync.Mutex
+	observed    time.Time
+}
+
+func (s *placementOutcomeScorer) Name() string {
+	return "placement_outcome"
+}
+
+func (s *placementOutcomeScorer) Score(ctx context.Context, pod v1.Pod, nodes []v1.Node) (schedulingapi.HostPriorityList, error) {
+	now := time.Now()
+	s.observeChanges(now)
+	key := outcomeSimilarityKey(pod)
+	list, err := scoreNodes(s.Name(), pod, nodes, func(pod v1.Pod, node v1.Node) (int, error) {
+		if key == "" {
+			return neutralScore, nil
+		}
+		successes, failures := s.store.Outcomes(key, node.Name, now)
+		if successes+failures == 0 {
+			return neutralScore, nil
+		}
+		return schedulingapi.MaxPriority * (successes + 1) / (successes + failures + 2), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *list, nil
+}
+
+// outcomeSimilarityKey groups the pods sharing outcomes, empty for pods without a controller
+func outcomeSimilarityKey(pod v1.Pod) string {
+	if owner := metav1.GetControllerOf(&pod); owner != nil {
+		return string(owner.UID)
+	}
+	return ""
+}
+
+// observeChanges observes the pods when the pod view changed since the last observation. The listers not
+// telling when they last synced are observed on every request
+func (s *placementOutcomeScorer) observeChanges(now time.Time) {
+	s.observeLock.Lock()
+	defer s.observeLock.Unlock()
+	if informer, ok := podLister.(interface {
+		LastSync() time.Time
+	}); ok {
+		lastSync := informer.LastSync()
+		if !lastSync.IsZero() && lastSync.Equal(s.observed) {
+			return
+		}
+		s.observed = lastSync
+	}
+	s.observe(podLister.List(), now)
+}
+
+// observe records the outcome of the placed pods: a pod ready within -outcome-ready-threshold of
+// its creation is a success, a pod ready later, not ready past the threshold or with restarted containers
+// is a failure. Pods still starting within the threshold are not recorded yet
+func (s *placementOutcomeScorer) observe(pods []v1.Pod, now time.Time) {
+	for _, pod := range pods {
+		key := outcomeSimilarityKey(pod)
+		if key == "" || pod.Spec.NodeName == "" {
+			continue
+		}
+		created := pod.CreationTimestamp.Time
+		var restarts int32
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += status.RestartCount
+		}
+		ready, readySince := podReadySince(pod)
+		switch {
+		case restarts > 0:
+			s.store.Record(key, pod.Spec.NodeName, pod.UID, false, now)
+		case ready:
+			s.store.Record(key, pod.Spec.NodeName, pod.UID, readySince.Sub(created) <= outcomeReadyThreshold, now)
+		case now.Sub(created) > outcomeReadyThreshold:
+			s.store.Record(key, pod.Spec.NodeName, pod.UID, false, now)
+		}
+	}
+}
+
+// podReadySince reports whether the pod is ready and since when
+func podReadySince(pod v1.Pod) (bool, time.Time) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue, condition.LastTransitionTime.Time
+		}
+	}
+	return false, time.Time{}
+}
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ownedPod returns a pod of the owner, none when empty, bound to the node, created at created and ready at
+// ready unless zero
+func ownedPod(uid types.UID, owner types.UID, node string, created, ready time.Time, restarts int32) v1.Pod {
+	controller := true
+	pod := testPod("default", string(uid), nil)
+	pod.UID = uid
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{UID: owner, Controller: &controller}}
+	}
+	pod.Spec.NodeName = node
+	pod.CreationTimestamp = metav1.NewTime(created)
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{RestartCount: restarts}}
+	if !ready.IsZero() {
+		pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(ready)}}
+	}
+	return pod
+}
+
+func TestPlacementOutcomeObserve(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-time.Hour)
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		recorded bool
+		success  bool
+	}{
+		{"ready quickly", ownedPod("p", "rs", "a", created, created.Add(time.Minute), 0), true, true},
+		{"ready late", ownedPod("p", "rs", "a", created, created.Add(time.Hour), 0), true, false},
+		{"restarted", ownedPod("p", "rs", "a", created, created.Add(time.Minute), 1), true, false},
+		{"never ready", ownedPod("p", "rs", "a", created, time.Time{}, 0), true, false},
+		{"still starting", ownedPod("p", "rs", "a", now.Add(-time.Second), time.Time{}, 0), false, false},
+		{"no owner", ownedPod("p", "", "a", created, created, 0), false, false},
+		{"not bound", ownedPod("p", "rs", "", created, created, 0), false, false},
+	}
+	for _, test := range tests {
+		store := newMemoryOutcomeStore()
+		scorer := &placementOutcomeScorer{store: store}
+		scorer.observe([]v1.Pod{test.pod}, now)
+		successes, failures := store.Outcomes("rs", "a", now)
+		if recorded := successes+failures > 0; recorded != test.recorded || (recorded && (successes > 0) != test.success) {
+			t.Errorf("%v: expected recorded=%v success=%v, got %v successes and %v failures", test.name, test.recorded, test.success, successes, failures)
+		}
+	}
+}
+
+func TestPlacementOutcomeScores(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-time.Hour)
+	withPods(t,
+		ownedPod("good-1", "rs", "good", created, created.Add(time.Second), 0),
+		ownedPod("good-2", "rs", "good", created, created.Add(time.Second), 0),
+		ownedPod("bad-1", "rs", "bad", created, time.Time{}, 0),
+		ownedPod("bad-2", "rs", "bad", created, created.Add(time.Second), 3),
+		ownedPod("other", "other-rs", "fresh", created, time.Time{}, 0),
+	)
+	scorer := &placementOutcomeScorer{store: newMemoryOutcomeStore()}
+	controller := true
+	pod := testPod("default", "new", nil)
+	pod.OwnerReferences = []metav1.OwnerReference{{UID: "rs", Controller: &controller}}
+	list, err := scorer.Score(context.Background(), pod, testNodes("good", "bad", "fresh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScores(t, list, map[string]int{"good": 7, "bad": 2, "fresh": neutralScore})
+	// a pod without a controller has no similar pods
+	list, err = scorer.Score(context.Background(), testPod("default", "bare", nil), testNodes("good", "bad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScores(t, list, map[string]int{"good": neutralScore, "bad": neutralScore})
+}
+
+func TestMemoryOutcomeStorePrune(t *testing.T) {
+	store := newMemoryOutcomeStore()
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		store.Record("rs", "a", types.UID(fmt.Sprint(i)), true, start)
+	}
+	if store.len() != 100 {
+		t.Fatalf("expected 100 outcomes, got %v", store.len())
+	}
+	later := start.Add(outcomeWindow + outcomePruneInterval)
+	if successes, failures := store.Outcomes("rs", "a", later); successes+failures != 0 {
+		t.Errorf("expected the expired outcomes to be ignored, got %v successes and %v failures", successes, failures)
+	}
+	store.Record("rs", "b", "fresh", true, later)
+	if store.len() != 1 {
+		t.Errorf("expected the expired outcomes to be pruned, %v left", store.len())
+	}
+}
+
+// syncedPodLister is a pod lister telling when it last synced and counting its listings
+type syncedPodLister struct {
+	testPodLister
+	lastSync time.Time
+	listed   int
+}
+
+func (l *syncedPodLister) List() []v1.Pod {
+	l.listed++
+	return l.pods
+}
+
+func (l *syncedPodLister) LastSync() time.Time {
+	return l.lastSync
+}
+
+func TestPlacementOutcomeObservesChanges(t *testing.T) {
+	saved := podLister
+	defer func() { podLister = saved }()
+	lister := &syncedPodLister{lastSync: time.Now()}
+	podLister = lister
+	scorer := &placementOutcomeScorer{store: newMemoryOutcomeStore()}
+	for i := 0; i < 3; i++ {
+		scorer.observeChanges(time.Now())
+	}
+	if lister.listed != 1 {
+		t.Errorf("expected the pods to be listed once per sync, listed %v times", lister.listed)
+	}
+	lister.lastSync = lister.lastSync.Add(time.Second)
+	scorer.observeChanges(time.Now())
+	if lister.listed != 2 {
+		t.Errorf("expected the pods to be listed again after a sync, listed %v times", lister.listed)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var podSelector string
+var podNamespaces string
+
+// scoredPodSelector is the parsed -pod-selector, nil when the pods of any labels are scored
+var scoredPodSelector labels.Selector
+
+// scoredPodNamespaces is the parsed -pod-namespaces, nil when the pods of any namespace are scored
+var scoredPodNamespaces map[string]bool
+
+func init() {
+	flag.StringVar(&podSelector, "pod-selector", "", "The label selector of the pods the extender scores, e.g. team=batch, the other pods get the neutral score. Empty scores every pod")
+	flag.StringVar(&podNamespaces, "pod-namespaces", "", "The comma separated namespaces of the pods the extender scores, the pods of the other namespaces get the neutral score. Empty scores every namespace")
+}
+
+// parsePodAllowlist parses the -pod-selector and -pod-namespaces flags
+func parsePodAllowlist() error {
+	scoredPodSelector = nil
+	if strings.TrimSpace(podSelector) != "" {
+		selector, err := labels.Parse(podSelector)
+		if err != nil {
+			return fmt.Errorf("invalid -pod-selector %q: %v", podSelector, err)
+		}
+		scoredPodSelector = selector
+	}
+	scoredPodNamespaces = nil
+	for _, namespace := range strings.Split(podNamespaces, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" {
+			continue
+		}
+		if scoredPodNamespaces == nil {
+			scoredPodNamespaces = make(map[string]bool)
+		}
+		scoredPodNamespaces[namespace] = true
+	}
+	return nil
+}
+
+// podScored reports whether the pod is in the allowlist of -pod-selector and -pod-namespaces, a pod
+// without a namespace belongs to the default namespace
+func podScored(pod v1.Pod) bool {
+	if scoredPodNamespaces != nil {
+		namespace := pod.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		if !scoredPodNamespaces[namespace] {
+			return false
+		}
+	}
+	return scoredPodSelector == nil || scoredPodSelector.Matches(labels.Set(pod.Labels))
+}
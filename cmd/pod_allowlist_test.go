@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withPodAllowlist sets -pod-selector and -pod-namespaces until the end of the test
+func withPodAllowlist(t *testing.T, selector, namespaces string) {
+	savedSelector, savedNamespaces := podSelector, podNamespaces
+	t.Cleanup(func() {
+		podSelector, podNamespaces = savedSelector, savedNamespaces
+		parsePodAllowlist()
+	})
+	podSelector, podNamespaces = selector, namespaces
+	if err := parsePodAllowlist(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParsePodAllowlist(t *testing.T) {
+	defer func(selector, namespaces string) {
+		podSelector, podNamespaces = selector, namespaces
+		parsePodAllowlist()
+	}(podSelector, podNamespaces)
+	for _, test := range []struct {
+		selector, namespaces string
+		valid                bool
+		anySelector          bool
+		namespaceCount       int
+	}{
+		{"", "", true, true, 0},
+		{"  ", " , ,", true, true, 0},
+		{"team=batch,tier!=web", "batch, default,", true, false, 2},
+		{"team in (batch, ml)", "batch", true, false, 1},
+		{"team in (batch", "", false, true, 0},
+		{"=batch", "", false, true, 0},
+	} {
+		podSelector, podNamespaces = test.selector, test.namespaces
+		err := parsePodAllowlist()
+		if (err == nil) != test.valid {
+			t.Errorf("-pod-selector=%q: got %v, expected valid %v", test.selector, err, test.valid)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+		if (scoredPodSelector == nil) != test.anySelector || len(scoredPodNamespaces) != test.namespaceCount {
+			t.Errorf("-pod-selector=%q -pod-namespaces=%q parsed as %v and %v", test.selector, test.namespaces, scoredPodSelector, scoredPodNamespaces)
+		}
+	}
+}
+
+func TestPodScored(t *testing.T) {
+	batch := map[string]string{"team": "batch"}
+	withoutNamespace := testPod("", "p", batch)
+	for _, test := range []struct {
+		name                 string
+		selector, namespaces string
+		pod                  v1.Pod
+		scored               bool
+	}{
+		{"no allowlist", "", "", testPod("web", "p", nil), true},
+		{"matching labels", "team=batch", "", testPod("web", "p", batch), true},
+		{"other labels", "team=batch", "", testPod("web", "p", map[string]string{"team": "ml"}), false},
+		{"no labels", "team=batch", "", testPod("web", "p", nil), false},
+		{"listed namespace", "", "batch,ml", testPod("ml", "p", nil), true},
+		{"other namespace", "", "batch,ml", testPod("web", "p", batch), false},
+		{"no namespace is default", "", "default", withoutNamespace, true},
+		{"both matching", "team=batch", "batch", testPod("batch", "p", batch), true},
+		{"namespace only matching", "team=batch", "batch", testPod("batch", "p", nil), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withPodAllowlist(t, test.selector, test.namespaces)
+			if scored := podScored(test.pod); scored != test.scored {
+				t.Errorf("scored %v, expected %v", scored, test.scored)
+			}
+		})
+	}
+}
+
+func TestPodAllowlistRoutes(t *testing.T) {
+	withPodAllowlist(t, "team=batch", "batch")
+	router := newTestRouter(t, digitPriority)
+	AddCombinedRoute(router)
+	nodes := testNodes("n1", "n7")
+	scored, skipped := map[string]int{"n1": 1, "n7": 7}, map[string]int{"n1": 5, "n7": 5}
+
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("batch", "p", map[string]string{"team": "batch"}), nodes), scored)
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("batch", "p", map[string]string{"team": "ml"}), nodes), skipped)
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("web", "p", map[string]string{"team": "batch"}), nodes), skipped)
+
+	// the combined route posts a pod of the default namespace without labels
+	w := combine(t, router, "", nodes)
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+	}
+	checkScores(t, list, skipped)
+
+	for _, node := range simulate(testPod("web", "p", nil), nodes).Nodes {
+		if node.Methods[digitPriority.Name] != 5 {
+			t.Errorf("simulated %v for a skipped pod on %v, expected the neutral score", node.Methods, node.Host)
+		}
+	}
+}
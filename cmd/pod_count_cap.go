@@ -0,0 +1,52 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// PodCountCapFilter rejects the nodes already running as many pods as the cap the config file sets for the
+// workload class of the pod, whatever the pods capacity of the node. Pods without a workload class, or of
+// a class without a cap, pass everywhere
+var PodCountCapFilter = FilterMethod{
+	Name:              "pod_count_cap",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod) NodeFilter {
+		class, classified := podWorkloadClass(pod)
+		limit, capped := currentConfig().podCountCap(class)
+		if !classified || !capped {
+			return func(pod v1.Pod, node v1.Node) (bool, string, error) {
+				return true, "", nil
+			}
+		}
+		byNode := podsByNode(podLister)
+		return func(pod v1.Pod, node v1.Node) (bool, string, error) {
+			if count := len(byNode.on(node.Name)); count >= limit {
+				return false, fmt.Sprintf("node runs %v pods, the cap of the %v workload class is %v", count, class, limit), nil
+			}
+			return true, "", nil
+		}
+	},
+}
+
+// podCountCap returns the pod count cap of the workload class, false when the class has none
+func (c *extenderConfig) podCountCap(class string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+	limit, ok := c.PodCountCaps[class]
+	return limit, ok
+}
@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// podsOn returns count running pods bound to the node
+func podsOn(node string, count int) []v1.Pod {
+	pods := make([]v1.Pod, count)
+	for i := range pods {
+		pods[i] = testPod("default", fmt.Sprintf("%v-%v", node, i), nil)
+		pods[i].Spec.NodeName = node
+	}
+	return pods
+}
+
+func TestPodCountCapFilter(t *testing.T) {
+	var pods []v1.Pod
+	pods = append(pods, podsOn("busy", 5)...)
+	pods = append(pods, podsOn("half", 3)...)
+	withPods(t, pods...)
+	withConfig(t, &extenderConfig{PodCountCaps: map[string]int{"batch": 4, "web": 10}})
+	nodes := testNodes("busy", "half", "empty")
+
+	annotated := testPod("default", "p", nil)
+	annotated.Annotations = map[string]string{workloadClassKey: "batch"}
+	tests := []struct {
+		name   string
+		pod    v1.Pod
+		passed []string
+	}{
+		{"capped class", testPod("default", "p", map[string]string{workloadClassKey: "batch"}), []string{"half", "empty"}},
+		{"class from the annotation", annotated, []string{"half", "empty"}},
+		{"class under its cap", testPod("default", "p", map[string]string{workloadClassKey: "web"}), []string{"busy", "half", "empty"}},
+		{"class without cap", testPod("default", "p", map[string]string{workloadClassKey: "infra"}), []string{"busy", "half", "empty"}},
+		{"pod without class", testPod("default", "p", nil), []string{"busy", "half", "empty"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, result := filterNodes(t, PodCountCapFilter, test.pod, nodes)
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+				t.Errorf("expected %v to pass, got %v, rejected %v", test.passed, passed, result.FailedNodes)
+			}
+		})
+	}
+}
+
+func TestPodCountCapWithoutConfig(t *testing.T) {
+	withPods(t, podsOn("busy", 50)...)
+	withConfig(t, nil)
+	_, result := filterNodes(t, PodCountCapFilter, testPod("default", "p", map[string]string{workloadClassKey: "batch"}), testNodes("busy"))
+	if passed := passedNodes(result); !reflect.DeepEqual(passed, []string{"busy"}) {
+		t.Errorf("expected every node to pass without a config, got %v", passed)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"math"
+
+	"k8s.io/api/core/v1"
+)
+
+var nodeGroupLabel string
+
+func init() {
+	flag.StringVar(&nodeGroupLabel, "nodegroup-label", "cloud.google.com/gke-nodepool", "The node label identifying the node group (pool) of a node")
+}
+
+// PoolDensityPriority spreads pods within each node group: a node hosting fewer pods than the average
+// of the candidate nodes of its group scores higher. Nodes alone in their group, or without the group
+// label, get the neutral score
+var PoolDensityPriority = PrioritizeMethod{
+	Name:              "pool_density",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		byNode := podsByNode(podLister)
+		groupPods := make(map[string]int)
+		groupNodes := make(map[string]int)
+		for _, node := range nodes {
+			if group, ok := node.Labels[nodeGroupLabel]; ok {
+				groupPods[group] += len(byNode.on(node.Name))
+				groupNodes[group]++
+			}
+		}
+
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			group, ok := node.Labels[nodeGroupLabel]
+			if !ok || groupNodes[group] <= 1 {
+				return neutralScore, nil
+			}
+			average := float64(groupPods[group]) / float64(groupNodes[group])
+			deviation := (average - float64(len(byNode.on(node.Name)))) / math.Max(average, 1)
+			return clampScore(neutralScore + int(math.Round(deviation*float64(neutralScore)))), nil
+		}
+	},
+}
@@ -0,0 +1,48 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestPoolDensityPriority(t *testing.T) {
+	var pods []v1.Pod
+	for node, count := range map[string]int{"a2": 4, "a3": 2, "b1": 3, "d1": 1, "plain": 6} {
+		pods = append(pods, podsOn(node, count)...)
+	}
+	withPods(t, pods...)
+	pool := func(name, group string) v1.Node {
+		return labeledNode(name, map[string]string{nodeGroupLabel: group})
+	}
+	nodes := []v1.Node{
+		pool("a1", "a"), pool("a2", "a"), pool("a3", "a"),
+		pool("b1", "b"),
+		pool("c1", "c"), pool("c2", "c"),
+		pool("d1", "d"), pool("d2", "d"), pool("d3", "d"),
+		labeledNode("plain", nil),
+	}
+	checkScores(t, scoreMethod(t, PoolDensityPriority, testPod("default", "p", nil), nodes), map[string]int{
+		// 2 pods on average, the deviation is relative to the average
+		"a1": 10, "a2": 0, "a3": neutralScore,
+		// alone in its group
+		"b1": neutralScore,
+		// no pod in the group
+		"c1": neutralScore, "c2": neutralScore,
+		// under one pod on average the deviation is relative to a single pod
+		"d1": 2, "d2": 7, "d3": 7,
+		"plain": neutralScore,
+	})
+}
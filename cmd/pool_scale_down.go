@@ -0,0 +1,51 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"k8s.io/api/core/v1"
+)
+
+var scaleDownKey string
+
+func init() {
+	flag.StringVar(&scaleDownKey, "scale-down-key", "autoscaler.example.com/scale-down", "The node label or annotation set to true on the nodes of a pool being scaled down, pool_scale_down steers the pods away from the whole pool")
+}
+
+// PoolScaleDownPriority steers the pods away from the node pools being scaled down so their nodes can
+// drain: a node carrying -scale-down-key set to true, or in the same -nodegroup-label pool as a candidate
+// carrying it, scores 0. The other nodes, and every node when no candidate carries the key, get the neutral score
+var PoolScaleDownPriority = PrioritizeMethod{
+	Name: "pool_scale_down",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		drainingPools := make(map[string]bool)
+		for _, node := range nodes {
+			if pool, ok := node.Labels[nodeGroupLabel]; ok && scalingDown(node) {
+				drainingPools[pool] = true
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if pool, ok := node.Labels[nodeGroupLabel]; scalingDown(node) || (ok && drainingPools[pool]) {
+				return 0, nil
+			}
+			return neutralScore, nil
+		}
+	},
+}
+
+// scalingDown reports whether the node carries -scale-down-key set to true, as a label or an annotation
+func scalingDown(node v1.Node) bool {
+	return node.Labels[scaleDownKey] == "true" || node.Annotations[scaleDownKey] == "true"
+}
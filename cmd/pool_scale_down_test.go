@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// poolNode returns a node of the pool, outside any pool when empty, with the scale down key set to the
+// value as a label, and as an annotation when annotated
+func poolNode(name, pool, value string, annotated bool) v1.Node {
+	labels := make(map[string]string)
+	if pool != "" {
+		labels[nodeGroupLabel] = pool
+	}
+	node := labeledNode(name, labels)
+	switch {
+	case value == "":
+	case annotated:
+		node.Annotations = map[string]string{scaleDownKey: value}
+	default:
+		node.Labels[scaleDownKey] = value
+	}
+	return node
+}
+
+func TestScalingDown(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     v1.Node
+		expected bool
+	}{
+		{"label", poolNode("n", "", "true", false), true},
+		{"annotation", poolNode("n", "", "true", true), true},
+		{"false", poolNode("n", "", "false", false), false},
+		{"malformed", poolNode("n", "", "yes", true), false},
+		{"missing", poolNode("n", "", "", false), false},
+	}
+	for _, test := range tests {
+		if scaling := scalingDown(test.node); scaling != test.expected {
+			t.Errorf("%v: scalingDown returned %v", test.name, scaling)
+		}
+	}
+}
+
+func TestPoolScaleDownPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	tests := []struct {
+		name     string
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{"draining pool", []v1.Node{poolNode("a1", "a", "true", false), poolNode("a2", "a", "", false), poolNode("b1", "b", "", false)}, map[string]int{"a1": 0, "a2": 0, "b1": 5}},
+		{"annotated pool", []v1.Node{poolNode("a1", "a", "true", true), poolNode("a2", "a", "", false), poolNode("b1", "b", "false", false)}, map[string]int{"a1": 0, "a2": 0, "b1": 5}},
+		{"node outside a pool", []v1.Node{poolNode("lone", "", "true", false), poolNode("other", "", "", false), poolNode("b1", "b", "", false)}, map[string]int{"lone": 0, "other": 5, "b1": 5}},
+		{"nothing draining", []v1.Node{poolNode("a1", "a", "", false), poolNode("b1", "b", "no", false)}, map[string]int{"a1": 5, "b1": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, PoolScaleDownPriority, testPod("default", "p", nil), test.nodes), test.expected)
+		})
+	}
+}
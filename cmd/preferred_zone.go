@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var preferredZoneAnnotation, zoneLabel, regionLabel string
+var zoneFalloff int
+
+func init() {
+	flag.StringVar(&preferredZoneAnnotation, "preferred-zone-annotation", "scheduler.extender/preferred-zone", "The pod annotation naming the zone preferred_zone favors")
+	flag.StringVar(&zoneLabel, "zone-label", "topology.kubernetes.io/zone", "The node label holding the zone of the node")
+	flag.StringVar(&regionLabel, "region-label", "topology.kubernetes.io/region", "The node label holding the region of the node, the zones of the preferred zone's region are its adjacent zones")
+	flag.IntVar(&zoneFalloff, "zone-falloff", 5, "The points preferred_zone takes off per step away from the preferred zone: once for the adjacent zones, twice for the other zones")
+}
+
+// validateZoneFalloff makes sure the falloff is a score
+func validateZoneFalloff() error {
+	if zoneFalloff < 0 || zoneFalloff > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -zone-falloff flag must be between 0 and %v, got %v", schedulingapi.MaxPriority, zoneFalloff)
+	}
+	return nil
+}
+
+// preferredZoneRegions returns the regions of the candidate nodes in the zone, ok is false when no
+// candidate is in the zone
+func preferredZoneRegions(zone string, nodes []v1.Node) (regions map[string]bool, ok bool) {
+	regions = make(map[string]bool)
+	for _, node := range nodes {
+		if node.Labels[zoneLabel] != zone {
+			continue
+		}
+		ok = true
+		if region, found := node.Labels[regionLabel]; found {
+			regions[region] = true
+		}
+	}
+	return regions, ok
+}
+
+// PreferredZonePriority is a simpler interface than the node affinity for the pods preferring a zone: the
+// nodes of the zone in the pod annotation score the max, the nodes of the adjacent zones, in the same
+// region, -zone-falloff less and the nodes of the other zones twice that less. Nodes without a zone get
+// the neutral score, as do all the nodes when the pod has no annotation or no candidate is in the zone
+var PreferredZonePriority = PrioritizeMethod{
+	Name: "preferred_zone",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		zone := pod.Annotations[preferredZoneAnnotation]
+		regions, found := preferredZoneRegions(zone, nodes)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			nodeZone, zoned := node.Labels[zoneLabel]
+			switch {
+			case zone == "" || !found || !zoned:
+				return neutralScore, nil
+			case nodeZone == zone:
+				return schedulingapi.MaxPriority, nil
+			case regions[node.Labels[regionLabel]]:
+				return clampScore(schedulingapi.MaxPriority - zoneFalloff), nil
+			}
+			return clampScore(schedulingapi.MaxPriority - 2*zoneFalloff), nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		zone, ok := pod.Annotations[preferredZoneAnnotation]
+		if !ok {
+			return "pod prefers no zone"
+		}
+		return fmt.Sprintf("pod prefers zone %v, node is in zone %q of region %q", zone, node.Labels[zoneLabel], node.Labels[regionLabel])
+	},
+}
@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withZoneFalloff sets -zone-falloff until the end of the test
+func withZoneFalloff(t *testing.T, falloff int) {
+	saved := zoneFalloff
+	t.Cleanup(func() { zoneFalloff = saved })
+	zoneFalloff = falloff
+}
+
+// zoneNode returns a node in the zone and region, the labels being left out when empty
+func zoneNode(name, zone, region string) v1.Node {
+	labels := make(map[string]string)
+	if zone != "" {
+		labels[zoneLabel] = zone
+	}
+	if region != "" {
+		labels[regionLabel] = region
+	}
+	return labeledNode(name, labels)
+}
+
+// zonePod returns a pod preferring the zone, without annotation when nil
+func zonePod(zone *string) v1.Pod {
+	if zone == nil {
+		return testPod("default", "p", nil)
+	}
+	return annotatedPod(map[string]string{preferredZoneAnnotation: *zone})
+}
+
+func TestValidateZoneFalloff(t *testing.T) {
+	for _, test := range []struct {
+		falloff int
+		valid   bool
+	}{
+		{0, true},
+		{5, true},
+		{10, true},
+		{-1, false},
+		{11, false},
+	} {
+		withZoneFalloff(t, test.falloff)
+		if err := validateZoneFalloff(); (err == nil) != test.valid {
+			t.Errorf("-zone-falloff=%v: got %v, expected valid %v", test.falloff, err, test.valid)
+		}
+	}
+}
+
+func TestPreferredZonePriority(t *testing.T) {
+	nodes := []v1.Node{
+		zoneNode("eu-1a", "eu-1a", "eu-1"),
+		zoneNode("eu-1b", "eu-1b", "eu-1"),
+		zoneNode("us-1a", "us-1a", "us-1"),
+		zoneNode("regionless", "us-1b", ""),
+		zoneNode("zoneless", "", "eu-1"),
+	}
+	zone := func(name string) *string { return &name }
+	allNeutral := map[string]int{"eu-1a": 5, "eu-1b": 5, "us-1a": 5, "regionless": 5, "zoneless": 5}
+	for _, test := range []struct {
+		name     string
+		falloff  int
+		zone     *string
+		expected map[string]int
+	}{
+		{"falloff 3", 3, zone("eu-1a"), map[string]int{"eu-1a": 10, "eu-1b": 7, "us-1a": 4, "regionless": 4, "zoneless": 5}},
+		{"default falloff", 5, zone("eu-1a"), map[string]int{"eu-1a": 10, "eu-1b": 5, "us-1a": 0, "regionless": 0, "zoneless": 5}},
+		{"clamped falloff", 8, zone("us-1a"), map[string]int{"eu-1a": 0, "eu-1b": 0, "us-1a": 10, "regionless": 0, "zoneless": 5}},
+		{"no falloff", 0, zone("eu-1b"), map[string]int{"eu-1a": 10, "eu-1b": 10, "us-1a": 10, "regionless": 10, "zoneless": 5}},
+		{"regionless preferred zone", 3, zone("us-1b"), map[string]int{"eu-1a": 4, "eu-1b": 4, "us-1a": 4, "regionless": 10, "zoneless": 5}},
+		{"no annotation", 3, nil, allNeutral},
+		{"empty annotation", 3, zone(""), allNeutral},
+		{"zone without candidates", 3, zone("ap-1a"), allNeutral},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withZoneFalloff(t, test.falloff)
+			checkScores(t, scoreMethod(t, PreferredZonePriority, zonePod(test.zone), nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var qosBiases = map[v1.PodQOSClass]*float64{
+	v1.PodQOSGuaranteed: new(float64),
+	v1.PodQOSBurstable:  new(float64),
+	v1.PodQOSBestEffort: new(float64),
+}
+
+func init() {
+	flag.Float64Var(qosBiases[v1.PodQOSGuaranteed], "qos-bias-guaranteed", 0, "How much Guaranteed pods favor nodes with headroom, from -1 (fullest nodes) to 1 (emptiest nodes), 0 is neutral")
+	flag.Float64Var(qosBiases[v1.PodQOSBurstable], "qos-bias-burstable", 0, "How much Burstable pods favor nodes with headroom, from -1 (fullest nodes) to 1 (emptiest nodes), 0 is neutral")
+	flag.Float64Var(qosBiases[v1.PodQOSBestEffort], "qos-bias-besteffort", 0, "How much BestEffort pods favor nodes with headroom, from -1 (fullest nodes) to 1 (emptiest nodes), 0 is neutral")
+}
+
+// validateQOSBiases makes sure the biases are within [-1, 1]
+func validateQOSBiases() error {
+	for class, bias := range qosBiases {
+		if *bias < -1 || *bias > 1 {
+			return fmt.Errorf("the %v QoS bias must be within [-1, 1], got %v", class, *bias)
+		}
+	}
+	return nil
+}
+
+// QOSPriority scores the nodes by their cpu and memory headroom, weighted by the bias configured for the
+// QoS class of the pod, e.g. Guaranteed pods on the emptiest nodes while BestEffort pods fill the gaps.
+// The requests of the pods already on the nodes are only accounted for when the informers are enabled
+var QOSPriority = PrioritizeMethod{
+	Name: "qos_headroom",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		class := podCycles.get(pod, time.Now()).qosClass
+		bias := *qosBiases[class]
+		var byNode nodePods
+		if podLister != nil {
+			byNode = podsByNode(podLister)
+		}
+		glog.V(6).Infof("scoring the headroom of the nodes for %v pod %v\n", class, pod.Name)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			headroom := nodeHeadroom(node, byNode.on(node.Name)) * schedulingapi.MaxPriority
+			return clampScore(neutralScore + int(math.Round(bias*(headroom-float64(neutralScore))))), nil
+		}
+	},
+}
+
+// nodeHeadroom returns the average free fraction of cpu and memory of the node, given the pods it hosts
+func nodeHeadroom(node v1.Node, pods []v1.Pod) float64 {
+	var free float64
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		allocatable := nodeAllocatable(node, name)
+		if allocatable <= 0 {
+			continue
+		}
+		free += math.Max(0, float64(allocatable-sumRequests(pods, name))/float64(allocatable))
+	}
+	return free / 2
+}
+
+// podQOSClass follows the QoS rules of the kubelet: Guaranteed when every container sets equal cpu and memory
+// requests and limits, BestEffort when no container sets any request or limit, Burstable otherwise
+func podQOSClass(pod v1.Pod) v1.PodQOSClass {
+	containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	guaranteed, bestEffort := true, true
+	for _, ctnr := range containers {
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			request, hasRequest := ctnr.Resources.Requests[name]
+			limit, hasLimit := ctnr.Resources.Limits[name]
+			if (hasRequest && !request.IsZero()) || (hasLimit && !limit.IsZero()) {
+				bestEffort = false
+			}
+			if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case bestEffort:
+		return v1.PodQOSBestEffort
+	case guaranteed:
+		return v1.PodQOSGuaranteed
+	}
+	return v1.PodQOSBurstable
+}
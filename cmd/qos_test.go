@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withQOSBias sets the bias of the QoS class until the end of the test
+func withQOSBias(t *testing.T, class v1.PodQOSClass, bias float64) {
+	saved := *qosBiases[class]
+	t.Cleanup(func() { *qosBiases[class] = saved })
+	*qosBiases[class] = bias
+}
+
+func TestPodQOSClass(t *testing.T) {
+	withInit := resourcePod("p", "", resourceList("1", "1Gi"), resourceList("1", "1Gi"))
+	withInit.Spec.InitContainers = []v1.Container{{Name: "init", Resources: v1.ResourceRequirements{Requests: resourceList("100m", "")}}}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected v1.PodQOSClass
+	}{
+		{"no resources", resourcePod("p", "", nil, nil), v1.PodQOSBestEffort},
+		{"zero requests", resourcePod("p", "", resourceList("0", "0"), nil), v1.PodQOSBestEffort},
+		{"equal requests and limits", resourcePod("p", "", resourceList("1", "1Gi"), resourceList("1", "1Gi")), v1.PodQOSGuaranteed},
+		{"limits only", resourcePod("p", "", nil, resourceList("1", "1Gi")), v1.PodQOSGuaranteed},
+		{"requests under the limits", resourcePod("p", "", resourceList("500m", "1Gi"), resourceList("1", "1Gi")), v1.PodQOSBurstable},
+		{"requests only", resourcePod("p", "", resourceList("1", "1Gi"), nil), v1.PodQOSBurstable},
+		{"no memory limit", resourcePod("p", "", nil, resourceList("1", "")), v1.PodQOSBurstable},
+		{"burstable init container", withInit, v1.PodQOSBurstable},
+	}
+	for _, test := range tests {
+		if class := podQOSClass(test.pod); class != test.expected {
+			t.Errorf("%v: class %v, expected %v", test.name, class, test.expected)
+		}
+	}
+}
+
+func TestValidateQOSBiases(t *testing.T) {
+	tests := []struct {
+		bias  float64
+		valid bool
+	}{
+		{0, true},
+		{-1, true},
+		{1, true},
+		{1.5, false},
+		{-2, false},
+	}
+	for _, test := range tests {
+		withQOSBias(t, v1.PodQOSBurstable, test.bias)
+		if err := validateQOSBiases(); (err == nil) != test.valid {
+			t.Errorf("validateQOSBiases with a bias of %v returned %v", test.bias, err)
+		}
+	}
+}
+
+func TestQOSPriority(t *testing.T) {
+	nodes := []v1.Node{
+		allocatableNode("empty", "4", "8Gi"),
+		allocatableNode("half", "4", "8Gi"),
+		allocatableNode("full", "4", "8Gi"),
+		testNodes("unknown")[0],
+	}
+	existing := []v1.Pod{
+		resourcePod("half-0", "half", resourceList("2", "4Gi"), nil),
+		resourcePod("full-0", "full", resourceList("3", "6Gi"), nil),
+		resourcePod("full-1", "full", resourceList("3", "6Gi"), nil),
+	}
+	guaranteed := resourcePod("p", "", resourceList("1", "1Gi"), resourceList("1", "1Gi"))
+	tests := []struct {
+		name     string
+		pods     []v1.Pod
+		bias     float64
+		expected map[string]int
+	}{
+		{"emptiest nodes", existing, 1, map[string]int{"empty": 10, "half": 5, "full": 0, "unknown": 0}},
+		{"fullest nodes", existing, -1, map[string]int{"empty": 0, "half": 5, "full": 10, "unknown": 10}},
+		{"half bias", existing, 0.5, map[string]int{"empty": 8, "half": 5, "full": 2, "unknown": 2}},
+		{"neutral bias", existing, 0, map[string]int{"empty": 5, "half": 5, "full": 5, "unknown": 5}},
+		{"without informers", nil, 1, map[string]int{"empty": 10, "half": 10, "full": 10, "unknown": 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withQOSBias(t, v1.PodQOSGuaranteed, test.bias)
+			saved := podLister
+			defer func() { podLister = saved }()
+			podLister = nil
+			if test.pods != nil {
+				podLister = &testPodLister{pods: test.pods}
+			}
+			checkScores(t, scoreMethod(t, QOSPriority, guaranteed, nodes), test.expected)
+		})
+	}
+}
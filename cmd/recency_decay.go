@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var recencyDecayPenalty int
+var recencyDecayWindow time.Duration
+
+func init() {
+	flag.IntVar(&recencyDecayPenalty, "recency-decay-penalty", 5, "The score a node loses in recency_decay right after the extender recommended it")
+	flag.DurationVar(&recencyDecayWindow, "recency-decay-window", 30*time.Second, "How long the recency_decay penalty of a recommended node takes to fade out")
+}
+
+// validateRecencyDecay makes sure the decay flags hold valid values
+func validateRecencyDecay() error {
+	if recencyDecayPenalty < 0 || recencyDecayPenalty > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -recency-decay-penalty flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, recencyDecayPenalty)
+	}
+	if recencyDecayWindow <= 0 {
+		return fmt.Errorf("the -recency-decay-window flag value must be positive, got %v", recencyDecayWindow)
+	}
+	return nil
+}
+
+// recommendationCache remembers when the extender last recommended each node, i.e. scored it the highest
+// in the combined route. The method routes are not the final decision, each one would record its own
+// top node and several nodes would be penalized for a single pod
+type recommendationCache struct {
+	lock   sync.Mutex
+	chosen map[string]time.Time
+}
+
+// recentRecommendations is the recommendation cache shared by the requests
+var recentRecommendations = &recommendationCache{chosen: make(map[string]time.Time)}
+
+// recommendedHost returns the node with the highest score of the list, the first one on ties. A list
+// scoring every node the same recommends none
+func recommendedHost(list schedulingapi.HostPriorityList) (schedulingapi.HostPriority, bool) {
+	best, tied := -1, true
+	for i, hp := range list {
+		if best >= 0 && hp.Score != list[best].Score {
+			tied = false
+		}
+		if best < 0 || hp.Score > list[best].Score {
+			best = i
+		}
+	}
+	if tied {
+		return schedulingapi.HostPriority{}, false
+	}
+	return list[best], true
+}
+
+// observe records the recommended node of the list and drops the recommendations older than the window
+func (c *recommendationCache) observe(list schedulingapi.HostPriorityList, now time.Time) {
+	best, recommended := recommendedHost(list)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for node, chosen := range c.chosen {
+		if now.Sub(chosen) > recencyDecayWindow {
+			delete(c.chosen, node)
+		}
+	}
+	if recommended {
+		c.chosen[normalizeNodeName(best.Host)] = now
+	}
+}
+
+// penalty returns the decayed penalty of the node, fading out linearly over the window
+func (c *recommendationCache) penalty(node string, now time.Time) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	chosen, ok := c.chosen[normalizeNodeName(node)]
+	if !ok || now.Sub(chosen) >= recencyDecayWindow {
+		return 0
+	}
+	remaining := float64(recencyDecayWindow-now.Sub(chosen)) / float64(recencyDecayWindow)
+	return int(float64(recencyDecayPenalty)*remaining + 0.5)
+}
+
+// flush empties the cache and returns the number of entries dropped
+func (c *recommendationCache) flush() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	count := len(c.chosen)
+	c.chosen = make(map[string]time.Time)
+	return count
+}
+
+// RecencyDecayPriority lowers the score of the nodes the extender recently recommended, so a burst of
+// pods spreads over the good nodes instead of piling on the single best one. The recommendations are
+// the top nodes of the combined route. The penalty fades out over -recency-decay-window, nothing is kept
+// across restarts
+var RecencyDecayPriority = PrioritizeMethod{
+	Name:          "recency_decay",
+	NodeNamesOnly: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		now := time.Now()
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			return schedulingapi.MaxPriority - recentRecommendations.penalty(node.Name, now), nil
+		}
+	},
+}
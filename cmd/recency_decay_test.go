@@ -0,0 +1,163 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+func TestRecommendedHost(t *testing.T) {
+	tests := []struct {
+		name        string
+		list        schedulingapi.HostPriorityList
+		host        string
+		recommended bool
+	}{
+		{"highest score", schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 9}, {Host: "c", Score: 5}}, "b", true},
+		{"first of the best", schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 9}, {Host: "c", Score: 9}}, "b", true},
+		{"every node the same", schedulingapi.HostPriorityList{{Host: "a", Score: 5}, {Host: "b", Score: 5}}, "", false},
+		{"empty list", nil, "", false},
+	}
+	for _, test := range tests {
+		best, recommended := recommendedHost(test.list)
+		if recommended != test.recommended || best.Host != test.host {
+			t.Errorf("%v: recommendedHost returned %v %v", test.name, best, recommended)
+		}
+	}
+}
+
+func TestRecencyPenalty(t *testing.T) {
+	cache := &recommendationCache{chosen: make(map[string]time.Time)}
+	now := time.Now()
+	cache.observe(schedulingapi.HostPriorityList{{Host: "a", Score: 9}, {Host: "b", Score: 1}}, now)
+	tests := []struct {
+		node    string
+		after   time.Duration
+		penalty int
+	}{
+		{"a", 0, recencyDecayPenalty},
+		{"a", recencyDecayWindow / 2, (recencyDecayPenalty + 1) / 2},
+		{"a", recencyDecayWindow, 0},
+		{"b", 0, 0},
+	}
+	for _, test := range tests {
+		if penalty := cache.penalty(test.node, now.Add(test.after)); penalty != test.penalty {
+			t.Errorf("penalty of %v after %v = %v, expected %v", test.node, test.after, penalty, test.penalty)
+		}
+	}
+	cache.observe(nil, now.Add(2*recencyDecayWindow))
+	if cache.flush() != 0 {
+		t.Error("expected the expired recommendations to be dropped")
+	}
+}
+
+func TestValidateRecencyDecay(t *testing.T) {
+	defer func(penalty int, window time.Duration) {
+		recencyDecayPenalty, recencyDecayWindow = penalty, window
+	}(recencyDecayPenalty, recencyDecayWindow)
+	tests := []struct {
+		penalty int
+		window  time.Duration
+		valid   bool
+	}{
+		{5, time.Second, true},
+		{0, time.Second, true},
+		{11, time.Second, false},
+		{-1, time.Second, false},
+		{5, 0, false},
+	}
+	for _, test := range tests {
+		recencyDecayPenalty, recencyDecayWindow = test.penalty, test.window
+		if err := validateRecencyDecay(); (err == nil) != test.valid {
+			t.Errorf("validateRecencyDecay(%v, %v) returned %v", test.penalty, test.window, err)
+		}
+	}
+}
+
+// combinedScores posts a pod to the combined route and decodes the scores
+func combinedScores(t *testing.T, router http.Handler, nodes []v1.Node) schedulingapi.HostPriorityList {
+	t.Helper()
+	w := combine(t, router, "", nodes)
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("combined answered %v: %v", w.Code, w.Body.String())
+	}
+	return list
+}
+
+// TestRecencyDecayCombined checks recency_decay spreads a burst of pods over the nodes the combined route
+// recommends
+func TestRecencyDecayCombined(t *testing.T) {
+	recentRecommendations.flush()
+	defer recentRecommendations.flush()
+	router := newTestRouter(t, RecencyDecayPriority)
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b", "c")
+
+	// a tie recommends no node, the scheduler picks one
+	checkScores(t, combinedScores(t, router, nodes), map[string]int{"a": 10, "b": 10, "c": 10})
+	recentRecommendations.observe(schedulingapi.HostPriorityList{{Host: "a", Score: 10}, {Host: "b", Score: 0}}, time.Now())
+	second := combinedScores(t, router, nodes)
+	if scores := scoresByHost(second); scores["a"] >= scores["b"] {
+		t.Fatalf("expected a to be penalized, got %v", second)
+	}
+	third := combinedScores(t, router, nodes)
+	if scores := scoresByHost(third); scores["b"] >= scores["c"] {
+		t.Fatalf("expected b, recommended by the previous combined answer, to be penalized, got %v", third)
+	}
+}
+
+// TestRecencyDecayFinalDecision checks only the combined decision is recorded when the methods disagree
+// on the top node
+func TestRecencyDecayFinalDecision(t *testing.T) {
+	recentRecommendations.flush()
+	defer recentRecommendations.flush()
+	preferring := func(name, host string, weight int) PrioritizeMethod {
+		return PrioritizeMethod{
+			Name:   name,
+			Weight: weight,
+			Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+				return func(pod v1.Pod, node v1.Node) (int, error) {
+					if node.Name == host {
+						return 10, nil
+					}
+					return 0, nil
+				}
+			},
+		}
+	}
+	router := newTestRouter(t, preferring("prefer_a", "a", 1), preferring("prefer_b", "b", 3))
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b", "c")
+
+	// the method routes are asked too, each recommends its own node
+	prioritize(t, router, "prefer_a", testPod("default", "p", nil), nodes)
+	prioritize(t, router, "prefer_b", testPod("default", "p", nil), nodes)
+	if count := recentRecommendations.flush(); count != 0 {
+		t.Errorf("the method routes recorded %v recommendations", count)
+	}
+
+	combinedScores(t, router, nodes)
+	now := time.Now()
+	for node, penalized := range map[string]bool{"a": false, "b": true, "c": false} {
+		if penalty := recentRecommendations.penalty(node, now); (penalty > 0) != penalized {
+			t.Errorf("node %v has a penalty of %v, expected penalized %v", node, penalty, penalized)
+		}
+	}
+}
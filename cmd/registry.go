@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+
+	"k8s.io/api/core/v1"
+)
+
+// priorityInfo describes an active priority method, it is what /priorities returns for each method
+type priorityInfo struct {
+	Name              string   `json:"name"`
+	Version           int      `json:"version"`
+	Path              string   `json:"path"`
+	Aliases           []string `json:"aliases,omitempty"`
+	Weight            int      `json:"weight"`
+	RequiresInformers bool     `json:"requiresInformers"`
+	Timeout           string   `json:"timeout,omitempty"`
+	Invert            bool     `json:"invert,omitempty"`
+	NodeNamesOnly     bool     `json:"nodeNamesOnly,omitempty"`
+	SchedulerNames    []string `json:"schedulerNames,omitempty"`
+}
+
+// registeredMethods keeps track of the priority methods added to the router and their paths, the routes
+// are registered once at startup while the -config file decides which of them are active
+var registeredMethods []PrioritizeMethod
+var registeredPaths = make(map[string][]string)
+var registryLock sync.RWMutex
+
+// appliesToScheduler reports whether the method scores the pods of the pod scheduler, a pod without a
+// scheduler name belongs to the default scheduler
+func appliesToScheduler(method PrioritizeMethod, pod v1.Pod) bool {
+	if len(method.SchedulerNames) == 0 {
+		return true
+	}
+	schedulerName := pod.Spec.SchedulerName
+	if schedulerName == "" {
+		schedulerName = v1.DefaultSchedulerName
+	}
+	return containsString(method.SchedulerNames, schedulerName)
+}
+
+// methodWeight returns the weight of the priority method, 0 meaning 1
+func methodWeight(priorityMethod PrioritizeMethod) int {
+	if priorityMethod.Weight == 0 {
+		return 1
+	}
+	return priorityMethod.Weight
+}
+
+// methodVersion returns the version of the scoring logic of the priority method, 0 meaning 1
+func methodVersion(priorityMethod PrioritizeMethod) int {
+	if priorityMethod.Version == 0 {
+		return 1
+	}
+	return priorityMethod.Version
+}
+
+// registerPriority records a priority method served at the given paths, one per api prefix
+func registerPriority(priorityMethod PrioritizeMethod, paths []string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registeredMethods = append(registeredMethods, priorityMethod)
+	registeredPaths[priorityMethod.Name] = paths
+}
+
+// registeredMethod returns the registered priority method with the given name
+func registeredMethod(name string) (PrioritizeMethod, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, method := range registeredMethods {
+		if method.Name == name {
+			return method, true
+		}
+	}
+	return PrioritizeMethod{}, false
+}
+
+// listMethods returns the active priority methods of the snapshot in use
+func listMethods() []PrioritizeMethod {
+	return currentSnapshot().methods
+}
+
+// listPriorities describes the active priority methods
+func listPriorities() []priorityInfo {
+	methods := listMethods()
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	priorities := make([]priorityInfo, len(methods))
+	for i, method := range methods {
+		priorities[i] = priorityInfo{
+			Name:              method.Name,
+			Version:           methodVersion(method),
+			Weight:            methodWeight(method),
+			RequiresInformers: method.RequiresInformers,
+			Invert:            method.Invert,
+			NodeNamesOnly:     method.NodeNamesOnly,
+			SchedulerNames:    method.SchedulerNames,
+		}
+		if paths := registeredPaths[method.Name]; len(paths) > 0 {
+			priorities[i].Path, priorities[i].Aliases = paths[0], paths[1:]
+		}
+		if timeout := methodTimeout(method); timeout > 0 {
+			priorities[i].Timeout = timeout.String()
+		}
+	}
+	return priorities
+}
+
+// PrioritiesRoute returns the list of the active priority methods as JSON
+func PrioritiesRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	resultBody, err := json.Marshal(listPriorities())
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
@@ -0,0 +1,201 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// TestConcurrentRegisterServe fires identical requests while methods are registered and the active
+// snapshot rebuilt, run it with -race: the answers must not change and nothing must race
+func TestConcurrentRegisterServe(t *testing.T) {
+	router := newTestRouter(t, SpotPriority, NodeAffinityPriority)
+	nodes := testNodes("a", "b", "c", "d")
+	for i := range nodes {
+		nodes[i].Labels = map[string]string{capacityTypeLabel: []string{spotCapacityType, "on-demand"}[i%2]}
+	}
+	pod := testPod("default", "p", map[string]string{workloadClassKey: tolerantWorkloadClass})
+	body, err := json.Marshal(extenderArgsOf(pod, nodes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// serve answers the request of the method, prioritize can not fail the test out of its goroutine
+	serve := func(name string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+name, bytes.NewReader(body)))
+		return fmt.Sprintf("%v %v", w.Code, w.Body.String())
+	}
+	expected := map[string]string{
+		"spot_preference":         serve("spot_preference"),
+		"preferred_node_affinity": serve("preferred_node_affinity"),
+	}
+	checkScores(t, prioritize(t, router, "spot_preference", pod, nodes), map[string]int{"a": 10, "b": 0, "c": 10, "d": 0})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			method := PrioritizeMethod{Name: fmt.Sprintf("extra-%v", i), Prepare: SpotPriority.Prepare}
+			registerPriority(method, []string{"/extra/" + method.Name})
+			activeSnapshot.Store(newSnapshot(nil))
+			if _, ok := registeredMethod(method.Name); !ok {
+				errs <- fmt.Errorf("%v is not registered", method.Name)
+			}
+			w := httptest.NewRecorder()
+			PrioritiesRoute(w, httptest.NewRequest(http.MethodGet, "/priorities", nil), nil)
+			if w.Code != http.StatusOK {
+				errs <- fmt.Errorf("/priorities answered %v", w.Code)
+			}
+		}(i)
+	}
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if answer := serve(name); answer != expected[name] {
+				errs <- fmt.Errorf("%v answered %v, expected %v", name, answer, expected[name])
+			}
+		}([]string{"spot_preference", "preferred_node_affinity"}[i%2])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if priorities := listPriorities(); len(priorities) != 12 {
+		t.Errorf("expected the 12 registered methods to be active, got %v", len(priorities))
+	}
+}
+
+func TestMethodVersion(t *testing.T) {
+	tests := []struct {
+		method   PrioritizeMethod
+		expected int
+	}{
+		{PrioritizeMethod{Name: "unversioned"}, 1},
+		{PrioritizeMethod{Name: "first", Version: 1}, 1},
+		{PrioritizeMethod{Name: "third", Version: 3}, 3},
+	}
+	for _, test := range tests {
+		if version := methodVersion(test.method); version != test.expected {
+			t.Errorf("%v has the version %v, expected %v", test.method.Name, version, test.expected)
+		}
+	}
+}
+
+func TestAppliesToScheduler(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedulers []string
+		scheduler  string
+		applies    bool
+	}{
+		{"every scheduler", nil, "custom", true},
+		{"listed", []string{"custom"}, "custom", true},
+		{"not listed", []string{"custom"}, "other", false},
+		{"default scheduler", []string{v1.DefaultSchedulerName}, "", true},
+	}
+	for _, test := range tests {
+		pod := testPod("default", "p", nil)
+		pod.Spec.SchedulerName = test.scheduler
+		if applies := appliesToScheduler(PrioritizeMethod{SchedulerNames: test.schedulers}, pod); applies != test.applies {
+			t.Errorf("%v: appliesToScheduler returned %v", test.name, applies)
+		}
+	}
+}
+
+func TestPrioritiesRoute(t *testing.T) {
+	saved := apiPrefix
+	defer func() {
+		apiPrefix = saved
+		parseAPIPrefixes()
+	}()
+	apiPrefix = "/v1,/v2"
+	timed := PrioritizeMethod{Name: "timed", Prepare: SpotPriority.Prepare, Timeout: time.Second, Version: 2}
+	tests := []struct {
+		name     string
+		config   *extenderConfig
+		expected []priorityInfo
+	}{
+		{
+			name: "every registered method",
+			expected: []priorityInfo{
+				{Name: "spot_preference", Version: 1, Path: "/v1" + prioritiesPrefix + "/spot_preference", Aliases: []string{"/v2" + prioritiesPrefix + "/spot_preference"}, Weight: 1},
+				{Name: "timed", Version: 2, Path: "/v1" + prioritiesPrefix + "/timed", Aliases: []string{"/v2" + prioritiesPrefix + "/timed"}, Weight: 1, Timeout: "1s"},
+			},
+		},
+		{
+			name:   "configured methods",
+			config: &extenderConfig{Priorities: []priorityConfig{{Name: "timed", Weight: 3, Invert: true, SchedulerNames: []string{"batch"}}, {Name: "unknown"}}},
+			expected: []priorityInfo{
+				{Name: "timed", Version: 2, Path: "/v1" + prioritiesPrefix + "/timed", Aliases: []string{"/v2" + prioritiesPrefix + "/timed"}, Weight: 3, Timeout: "1s", Invert: true, SchedulerNames: []string{"batch"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, SpotPriority, timed)
+			router.GET("/priorities", informational(PrioritiesRoute))
+			withConfig(t, test.config)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/priorities", nil))
+			var priorities []priorityInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &priorities); w.Code != http.StatusOK || err != nil {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if !reflect.DeepEqual(priorities, test.expected) {
+				t.Errorf("listed %+v, expected %+v", priorities, test.expected)
+			}
+		})
+	}
+}
+
+func TestSchedulerScopedMethod(t *testing.T) {
+	scoped := constantPriority("scoped", 1, 9)
+	scoped.SchedulerNames = []string{"scheduler-a"}
+	nodes := testNodes("a", "b")
+	tests := []struct {
+		name      string
+		method    PrioritizeMethod
+		config    *extenderConfig
+		scheduler string
+		expected  int
+	}{
+		{"registered scope", scoped, nil, "scheduler-a", 9},
+		{"other scheduler", scoped, nil, "scheduler-b", neutralScore},
+		{"default scheduler", scoped, nil, "", neutralScore},
+		{"configured scope", constantPriority("scoped", 1, 9), &extenderConfig{Priorities: []priorityConfig{{Name: "scoped", SchedulerNames: []string{"scheduler-a"}}}}, "scheduler-b", neutralScore},
+		{"unscoped", constantPriority("scoped", 1, 9), nil, "scheduler-b", 9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, test.method)
+			withConfig(t, test.config)
+			pod := testPod("default", "p", nil)
+			pod.Spec.SchedulerName = test.scheduler
+			checkScores(t, prioritize(t, router, "scoped", pod, nodes), map[string]int{"a": test.expected, "b": test.expected})
+		})
+	}
+}
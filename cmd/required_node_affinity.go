@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// RequiredNodeAffinityFilter rejects the nodes matching none of the requiredDuringSchedulingIgnoredDuringExecution
+// node selector terms of the pod. It duplicates the scheduler's own node affinity predicate so a node whose
+// labels changed since the scheduler cache was last updated is not picked. The terms are evaluated on every
+// label of the node, -node-label-allowlist only limits the scoring
+var RequiredNodeAffinityFilter = FilterMethod{
+	Name: "required_node_affinity",
+	Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+		if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+			return true, "", nil
+		}
+		terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		if len(terms) == 0 {
+			return true, "", nil
+		}
+		// the terms are ORed, the requirements of a term ANDed
+		for _, term := range terms {
+			if nodeSelectorTermMatches(term, node) {
+				return true, "", nil
+			}
+		}
+		return false, fmt.Sprintf("node matches none of the %v required node affinity terms of the pod", len(terms)), nil
+	},
+}
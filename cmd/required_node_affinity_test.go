@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// requiredAffinityPod returns a pod requiring one of the node selector terms, no affinity without terms
+func requiredAffinityPod(terms ...v1.NodeSelectorTerm) v1.Pod {
+	pod := testPod("default", "p", nil)
+	if terms != nil {
+		pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: terms},
+		}}
+	}
+	return pod
+}
+
+func TestRequiredNodeAffinityFilter(t *testing.T) {
+	// the filter sees every label, -node-label-allowlist only limits the scoring
+	withNodeLabelAllowlist(t, "disktype")
+	nodes := []v1.Node{
+		labeledNode("n1", map[string]string{"zone": "a", "gen": "4"}),
+		labeledNode("n2", map[string]string{"zone": "b", "gen": "3"}),
+		labeledNode("n3", map[string]string{"zone": "c", "special": "true"}),
+		labeledNode("n4", map[string]string{"zone": "d", "special": "true"}),
+		labeledNode("n5", nil),
+	}
+	zoneAndGen := v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+		{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+		{Key: "gen", Operator: v1.NodeSelectorOpGt, Values: []string{"3"}},
+	}}
+	specialOutsideC := v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+		{Key: "special", Operator: v1.NodeSelectorOpExists},
+		{Key: "zone", Operator: v1.NodeSelectorOpNotIn, Values: []string{"c"}},
+	}}
+	byName := v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{
+		{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"n5"}},
+	}}
+	for _, test := range []struct {
+		name   string
+		pod    v1.Pod
+		passed []string
+	}{
+		{"no affinity", requiredAffinityPod(), []string{"n1", "n2", "n3", "n4", "n5"}},
+		{"no term", requiredAffinityPod([]v1.NodeSelectorTerm{}...), []string{"n1", "n2", "n3", "n4", "n5"}},
+		{"requirements anded", requiredAffinityPod(zoneAndGen), []string{"n1"}},
+		{"terms ored", requiredAffinityPod(zoneAndGen, specialOutsideC), []string{"n1", "n4"}},
+		{"node name field", requiredAffinityPod(byName), []string{"n5"}},
+		{"label missing", requiredAffinityPod(labelTerm(map[string]string{"disktype": "ssd"})), nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w, result := filterNodes(t, RequiredNodeAffinityFilter, test.pod, nodes)
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+				t.Errorf("passed %v, expected %v", passed, test.passed)
+			}
+			var failed []string
+			for name, reason := range result.FailedNodes {
+				if reason == "" {
+					t.Errorf("node %v failed without a reason", name)
+				}
+				failed = append(failed, name)
+			}
+			if len(failed)+len(test.passed) != len(nodes) {
+				sort.Strings(failed)
+				t.Errorf("failed %v along with passed %v, expected every node in either", failed, test.passed)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// resourceBias tells which resource dominates the requests of a pod
+type resourceBias int
+
+const (
+	balancedBias resourceBias = iota
+	cpuBias
+	memoryBias
+)
+
+var memoryPerCore, resourceBiasThreshold, contentionPenalty float64
+
+func init() {
+	flag.Float64Var(&memoryPerCore, "memory-per-core-gib", 4, "The GiB of memory per cpu core of a balanced pod, used to classify pods as cpu or memory heavy")
+	flag.Float64Var(&resourceBiasThreshold, "resource-bias-threshold", 2, "How many times a resource must outweigh the other, relative to -memory-per-core-gib, for a pod to be cpu or memory heavy")
+	flag.Float64Var(&contentionPenalty, "contention-penalty", 1, "The fraction of the max score removed from a node hosting only pods of the same bias as the incoming pod, within [0, 1]")
+}
+
+// validateContention makes sure the contention flags hold valid values
+func validateContention() error {
+	if memoryPerCore <= 0 || resourceBiasThreshold < 1 {
+		return fmt.Errorf("-memory-per-core-gib must be positive and -resource-bias-threshold at least 1")
+	}
+	if contentionPenalty < 0 || contentionPenalty > 1 {
+		return fmt.Errorf("the -contention-penalty flag value must be within [0, 1], got %v", contentionPenalty)
+	}
+	return nil
+}
+
+// podResourceBias classifies the pod from the ratio of its cpu and memory requests
+func podResourceBias(pod v1.Pod) resourceBias {
+	cores := float64(podRequest(pod, v1.ResourceCPU)) / 1000
+	gib := float64(podRequest(pod, v1.ResourceMemory)) / (1 << 30)
+	if cores == 0 || gib == 0 {
+		switch {
+		case cores > 0:
+			return cpuBias
+		case gib > 0:
+			return memoryBias
+		}
+		return balancedBias
+	}
+	ratio := cores * memoryPerCore / gib
+	switch {
+	case ratio >= resourceBiasThreshold:
+		return cpuBias
+	case ratio <= 1/resourceBiasThreshold:
+		return memoryBias
+	}
+	return balancedBias
+}
+
+// ResourceContentionPriority avoids concentrating pods of the same resource bias: a cpu heavy pod scores
+// lower the nodes whose pods are mostly cpu heavy, and likewise for memory heavy pods. Balanced pods get
+// the neutral score
+var ResourceContentionPriority = PrioritizeMethod{
+	Name:              "resource_contention",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		bias := podResourceBias(pod)
+		byNode := podsByNode(podLister)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if bias == balancedBias {
+				return neutralScore, nil
+			}
+			pods := byNode.on(node.Name)
+			if len(pods) == 0 {
+				return schedulingapi.MaxPriority, nil
+			}
+			var same int
+			for _, other := range pods {
+				if podResourceBias(other) == bias {
+					same++
+				}
+			}
+			concentration := float64(same) / float64(len(pods))
+			return schedulingapi.MaxPriority - int(math.Round(contentionPenalty*concentration*schedulingapi.MaxPriority)), nil
+		}
+	},
+}
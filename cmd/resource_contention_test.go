@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withContention sets the contention flags until the end of the test
+func withContention(t *testing.T, perCore, threshold, penalty float64) {
+	savedPerCore, savedThreshold, savedPenalty := memoryPerCore, resourceBiasThreshold, contentionPenalty
+	t.Cleanup(func() {
+		memoryPerCore, resourceBiasThreshold, contentionPenalty = savedPerCore, savedThreshold, savedPenalty
+	})
+	memoryPerCore, resourceBiasThreshold, contentionPenalty = perCore, threshold, penalty
+}
+
+func TestValidateContention(t *testing.T) {
+	tests := []struct {
+		perCore, threshold, penalty float64
+		valid                       bool
+	}{
+		{4, 2, 1, true},
+		{4, 1, 0, true},
+		{0, 2, 1, false},
+		{-4, 2, 1, false},
+		{4, 0.5, 1, false},
+		{4, 2, -0.1, false},
+		{4, 2, 1.1, false},
+	}
+	for _, test := range tests {
+		withContention(t, test.perCore, test.threshold, test.penalty)
+		if err := validateContention(); (err == nil) != test.valid {
+			t.Errorf("validateContention with %v GiB per core, a threshold of %v and a penalty of %v returned %v", test.perCore, test.threshold, test.penalty, err)
+		}
+	}
+}
+
+func TestPodResourceBias(t *testing.T) {
+	withContention(t, 4, 2, 1)
+	tests := []struct {
+		name     string
+		requests v1.ResourceList
+		expected resourceBias
+	}{
+		{"cpu heavy", resourceList("2", "1Gi"), cpuBias},
+		{"cpu heavy at the threshold", resourceList("1", "2Gi"), cpuBias},
+		{"balanced", resourceList("1", "4Gi"), balancedBias},
+		{"slightly cpu heavy", resourceList("1", "3Gi"), balancedBias},
+		{"memory heavy at the threshold", resourceList("1", "8Gi"), memoryBias},
+		{"memory heavy", resourceList("500m", "16Gi"), memoryBias},
+		{"cpu only", resourceList("1", ""), cpuBias},
+		{"memory only", resourceList("", "1Gi"), memoryBias},
+		{"no request", nil, balancedBias},
+	}
+	for _, test := range tests {
+		if bias := podResourceBias(resourcePod("p", "", test.requests, nil)); bias != test.expected {
+			t.Errorf("%v: classified %v, expected %v", test.name, bias, test.expected)
+		}
+	}
+}
+
+func TestResourceContentionPriority(t *testing.T) {
+	cpuPod, memoryPod, balancedPod := resourceList("2", "1Gi"), resourceList("500m", "16Gi"), resourceList("1", "4Gi")
+	withPods(t,
+		resourcePod("c1", "cpu-heavy", cpuPod, nil),
+		resourcePod("c2", "cpu-heavy", cpuPod, nil),
+		resourcePod("c3", "mixed", cpuPod, nil),
+		resourcePod("m1", "mixed", memoryPod, nil),
+		resourcePod("b1", "mixed", balancedPod, nil),
+		resourcePod("b2", "mixed", balancedPod, nil),
+		resourcePod("m2", "memory-heavy", memoryPod, nil),
+	)
+	nodes := testNodes("cpu-heavy", "mixed", "memory-heavy", "empty")
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		penalty  float64
+		expected map[string]int
+	}{
+		{"cpu heavy pod", resourcePod("p", "", cpuPod, nil), 1, map[string]int{"cpu-heavy": 0, "mixed": 7, "memory-heavy": 10, "empty": 10}},
+		{"memory heavy pod", resourcePod("p", "", memoryPod, nil), 1, map[string]int{"cpu-heavy": 10, "mixed": 7, "memory-heavy": 0, "empty": 10}},
+		{"half penalty", resourcePod("p", "", cpuPod, nil), 0.5, map[string]int{"cpu-heavy": 5, "mixed": 9, "memory-heavy": 10, "empty": 10}},
+		{"no penalty", resourcePod("p", "", cpuPod, nil), 0, map[string]int{"cpu-heavy": 10, "mixed": 10, "memory-heavy": 10, "empty": 10}},
+		{"balanced pod", resourcePod("p", "", balancedPod, nil), 1, map[string]int{"cpu-heavy": neutralScore, "mixed": neutralScore, "memory-heavy": neutralScore, "empty": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withContention(t, 4, 2, test.penalty)
+			checkScores(t, scoreMethod(t, ResourceContentionPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// quantityValue returns the quantity in millicores for cpu and in units (e.g. bytes) for the other resources
+func quantityValue(name v1.ResourceName, q resource.Quantity) int64 {
+	if name == v1.ResourceCPU {
+		return q.MilliValue()
+	}
+	return q.Value()
+}
+
+// podResource sums a resource over the containers of the pod, an init container needing more than
+// the sum sets the value since init containers run one at a time before the others
+func podResource(pod v1.Pod, name v1.ResourceName, of func(v1.ResourceRequirements) v1.ResourceList) int64 {
+	var total int64
+	for _, ctnr := range pod.Spec.Containers {
+		if q, ok := of(ctnr.Resources)[name]; ok {
+			total += quantityValue(name, q)
+		}
+	}
+	for _, ctnr := range pod.Spec.InitContainers {
+		if q, ok := of(ctnr.Resources)[name]; ok && quantityValue(name, q) > total {
+			total = quantityValue(name, q)
+		}
+	}
+	return total
+}
+
+// podRequest returns what the pod requests of the resource
+func podRequest(pod v1.Pod, name v1.ResourceName) int64 {
+	return podResource(pod, name, func(r v1.ResourceRequirements) v1.ResourceList { return r.Requests })
+}
+
+// podLimit returns the limit the pod sets on the resource
+func podLimit(pod v1.Pod, name v1.ResourceName) int64 {
+	return podResource(pod, name, func(r v1.ResourceRequirements) v1.ResourceList { return r.Limits })
+}
+
+// nodeAllocatable returns the allocatable amount of the resource on the node
+func nodeAllocatable(node v1.Node, name v1.ResourceName) int64 {
+	q, ok := node.Status.Allocatable[name]
+	if !ok {
+		return 0
+	}
+	return quantityValue(name, q)
+}
+
+// sumRequests returns what the pods request of the resource altogether
+func sumRequests(pods []v1.Pod, name v1.ResourceName) int64 {
+	var total int64
+	for _, pod := range pods {
+		total += podRequest(pod, name)
+	}
+	return total
+}
+
+// sumLimits returns the limits the pods set on the resource altogether, a pod without a limit counts for
+// its request, the least it may use
+func sumLimits(pods []v1.Pod, name v1.ResourceName) int64 {
+	var total int64
+	for _, pod := range pods {
+		if limit := podLimit(pod, name); limit > 0 {
+			total += limit
+		} else {
+			total += podRequest(pod, name)
+		}
+	}
+	return total
+}
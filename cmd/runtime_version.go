@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	runtimeVersionModeFilter   = "filter"
+	runtimeVersionModePriority = "priority"
+)
+
+var minRuntimeAnnotation, runtimeVersionMode string
+
+func init() {
+	flag.StringVar(&minRuntimeAnnotation, "min-runtime-annotation", "scheduler.extender/min-runtime-version", "The pod annotation holding the minimum container runtime version the pod needs, e.g. 1.6 or containerd://1.6.8 to also require the runtime")
+	flag.StringVar(&runtimeVersionMode, "runtime-version-mode", runtimeVersionModePriority, "How runtime_version treats nodes with a container runtime older than the pod needs, one of: filter, priority")
+}
+
+// validateRuntimeVersionMode makes sure -runtime-version-mode is known
+func validateRuntimeVersionMode() error {
+	switch runtimeVersionMode {
+	case runtimeVersionModeFilter, runtimeVersionModePriority:
+		return nil
+	}
+	return fmt.Errorf("unknown -runtime-version-mode %q, expecting one of: %v, %v", runtimeVersionMode, runtimeVersionModeFilter, runtimeVersionModePriority)
+}
+
+// runtimeVersion is a container runtime version as reported by the kubelet, e.g. containerd://1.6.8
+type runtimeVersion struct {
+	// runtime is the runtime name, empty when the version string has none
+	runtime string
+	// parts are the numeric major, minor and patch parts, the missing ones being 0
+	parts [3]int
+}
+
+// parseRuntimeVersion parses versions like containerd://1.6.8, docker://20.10.7, cri-o://1.24.1-rc.1,
+// v1.7 or 1.6.8+k3s1: the runtime name is optional, a leading v and anything after the numeric parts
+// are ignored
+func parseRuntimeVersion(value string) (runtimeVersion, error) {
+	var version runtimeVersion
+	rest := strings.TrimSpace(value)
+	if i := strings.Index(rest, "://"); i >= 0 {
+		version.runtime, rest = strings.ToLower(rest[:i]), rest[i+3:]
+	}
+	rest = strings.TrimPrefix(rest, "v")
+	if end := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); end >= 0 {
+		rest = rest[:end]
+	}
+	fields := strings.Split(strings.Trim(rest, "."), ".")
+	if len(fields) > len(version.parts) {
+		fields = fields[:len(version.parts)]
+	}
+	for i, field := range fields {
+		part, err := strconv.Atoi(field)
+		if err != nil {
+			return version, fmt.Errorf("invalid container runtime version %q", value)
+		}
+		version.parts[i] = part
+	}
+	return version, nil
+}
+
+// atLeast reports whether the version is the same as or newer than the minimum
+func (v runtimeVersion) atLeast(minimum runtimeVersion) bool {
+	for i := range v.parts {
+		if v.parts[i] != minimum.parts[i] {
+			return v.parts[i] > minimum.parts[i]
+		}
+	}
+	return true
+}
+
+func (v runtimeVersion) String() string {
+	version := fmt.Sprintf("%v.%v.%v", v.parts[0], v.parts[1], v.parts[2])
+	if v.runtime != "" {
+		return v.runtime + "://" + version
+	}
+	return version
+}
+
+// podMinRuntime returns the minimum runtime version of the pod annotation, ok is false when the pod
+// has none or it can't be parsed
+func podMinRuntime(pod v1.Pod) (runtimeVersion, bool) {
+	value, found := pod.Annotations[minRuntimeAnnotation]
+	if !found {
+		return runtimeVersion{}, false
+	}
+	minimum, err := parseRuntimeVersion(value)
+	if err != nil {
+		glog.Warningf("ignoring the %v annotation of pod %v/%v: %v", minRuntimeAnnotation, pod.Namespace, pod.Name, err)
+		return runtimeVersion{}, false
+	}
+	return minimum, true
+}
+
+// runtimeCompatible reports whether the container runtime of the node satisfies the minimum, known is
+// false when the node reports no version or one that can't be parsed. A minimum naming a runtime also
+// requires the node to run that runtime
+func runtimeCompatible(node v1.Node, minimum runtimeVersion) (compatible, known bool, reason string) {
+	reported := node.Status.NodeInfo.ContainerRuntimeVersion
+	if reported == "" {
+		return false, false, "node reports no container runtime version"
+	}
+	version, err := parseRuntimeVersion(reported)
+	if err != nil {
+		return false, false, err.Error()
+	}
+	if minimum.runtime != "" && version.runtime != minimum.runtime {
+		return false, true, fmt.Sprintf("node runs %v, the pod needs %v", reported, minimum)
+	}
+	if !version.atLeast(minimum) {
+		return false, true, fmt.Sprintf("node runs %v, older than the %v the pod needs", reported, minimum)
+	}
+	return true, true, ""
+}
+
+// RuntimeVersionPriority prefers the nodes whose container runtime is at least the version the pod
+// annotation asks for, served when -runtime-version-mode is priority. Compatible nodes score the max and
+// incompatible ones 0, pods without the annotation and nodes of unknown version get the neutral score
+var RuntimeVersionPriority = PrioritizeMethod{
+	Name: "runtime_version",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		minimum, ok := podMinRuntime(pod)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if !ok {
+				return neutralScore, nil
+			}
+			compatible, known, _ := runtimeCompatible(node, minimum)
+			switch {
+			case !known:
+				return neutralScore, nil
+			case compatible:
+				return schedulingapi.MaxPriority, nil
+			}
+			return 0, nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		minimum, ok := podMinRuntime(pod)
+		if !ok {
+			return "pod needs no minimum container runtime version"
+		}
+		if _, _, reason := runtimeCompatible(node, minimum); reason != "" {
+			return reason
+		}
+		return fmt.Sprintf("node runs %v, at least the %v the pod needs", node.Status.NodeInfo.ContainerRuntimeVersion, minimum)
+	},
+}
+
+// RuntimeVersionFilter rejects the nodes whose container runtime is older than the pod annotation asks
+// for, served when -runtime-version-mode is filter. Nodes of unknown version pass
+var RuntimeVersionFilter = FilterMethod{
+	Name: "runtime_version",
+	Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+		minimum, ok := podMinRuntime(pod)
+		if !ok {
+			return true, "", nil
+		}
+		compatible, known, reason := runtimeCompatible(node, minimum)
+		if compatible || !known {
+			return true, "", nil
+		}
+		return false, reason, nil
+	},
+}
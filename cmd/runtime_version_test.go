@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withRuntimeVersionMode sets -runtime-version-mode until the end of the test
+func withRuntimeVersionMode(t *testing.T, mode string) {
+	saved := runtimeVersionMode
+	t.Cleanup(func() { runtimeVersionMode = saved })
+	runtimeVersionMode = mode
+}
+
+// runtimeNode returns a node reporting the container runtime version
+func runtimeNode(name, version string) v1.Node {
+	node := testNodes(name)[0]
+	node.Status.NodeInfo.ContainerRuntimeVersion = version
+	return node
+}
+
+// runtimePod returns a pod needing the minimum runtime version, none when empty
+func runtimePod(minimum string) v1.Pod {
+	if minimum == "" {
+		return testPod("default", "p", nil)
+	}
+	return annotatedPod(map[string]string{minRuntimeAnnotation: minimum})
+}
+
+func TestValidateRuntimeVersionMode(t *testing.T) {
+	for _, test := range []struct {
+		mode  string
+		valid bool
+	}{
+		{runtimeVersionModeFilter, true},
+		{runtimeVersionModePriority, true},
+		{"", false},
+		{"Filter", false},
+	} {
+		withRuntimeVersionMode(t, test.mode)
+		if err := validateRuntimeVersionMode(); (err == nil) != test.valid {
+			t.Errorf("-runtime-version-mode=%q: got %v, expected valid %v", test.mode, err, test.valid)
+		}
+	}
+}
+
+func TestParseRuntimeVersion(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected string
+		valid    bool
+	}{
+		{"containerd://1.6.8", "containerd://1.6.8", true},
+		{"docker://20.10.7", "docker://20.10.7", true},
+		{"CRI-O://1.24.1-rc.1", "cri-o://1.24.1", true},
+		{"v1.7", "1.7.0", true},
+		{"1.6.8-rc.1+k3s1", "1.6.8", true},
+		{" 2 ", "2.0.0", true},
+		{"1.6.8.4", "1.6.8", true},
+		{"containerd://v1.7.0", "containerd://1.7.0", true},
+		{"", "", false},
+		{"v", "", false},
+		{"containerd://", "", false},
+		{"latest", "", false},
+		{"1..6", "", false},
+	} {
+		version, err := parseRuntimeVersion(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: got %v, expected valid %v", test.value, err, test.valid)
+			continue
+		}
+		if test.valid && version.String() != test.expected {
+			t.Errorf("%q parsed as %v, expected %v", test.value, version, test.expected)
+		}
+	}
+}
+
+func TestRuntimeVersionAtLeast(t *testing.T) {
+	for _, test := range []struct {
+		version, minimum string
+		atLeast          bool
+	}{
+		{"1.6.8", "1.6.8", true},
+		{"1.6.9", "1.6.8", true},
+		{"1.7", "1.6.8", true},
+		{"2.0.0", "1.9.9", true},
+		{"1.6.7", "1.6.8", false},
+		{"1.5.10", "1.6", false},
+		{"0.9", "1", false},
+	} {
+		version, _ := parseRuntimeVersion(test.version)
+		minimum, _ := parseRuntimeVersion(test.minimum)
+		if atLeast := version.atLeast(minimum); atLeast != test.atLeast {
+			t.Errorf("%v at least %v: %v, expected %v", test.version, test.minimum, atLeast, test.atLeast)
+		}
+	}
+}
+
+var runtimeNodes = []v1.Node{
+	runtimeNode("newer", "containerd://1.7.2"),
+	runtimeNode("older", "containerd://1.5.9"),
+	runtimeNode("rc", "containerd://1.6.8-rc.1"),
+	runtimeNode("docker", "docker://20.10.7"),
+	runtimeNode("unparseable", "containerd://latest"),
+	runtimeNode("unknown", ""),
+}
+
+func TestRuntimeVersionPriority(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		minimum  string
+		expected map[string]int
+	}{
+		{"no annotation", "", map[string]int{"newer": 5, "older": 5, "rc": 5, "docker": 5, "unparseable": 5, "unknown": 5}},
+		{"malformed annotation", "soon", map[string]int{"newer": 5, "older": 5, "rc": 5, "docker": 5, "unparseable": 5, "unknown": 5}},
+		{"any runtime", "1.6.8", map[string]int{"newer": 10, "older": 0, "rc": 10, "docker": 10, "unparseable": 5, "unknown": 5}},
+		{"containerd only", "containerd://1.6", map[string]int{"newer": 10, "older": 0, "rc": 10, "docker": 0, "unparseable": 5, "unknown": 5}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, RuntimeVersionPriority, runtimePod(test.minimum), runtimeNodes), test.expected)
+		})
+	}
+}
+
+func TestRuntimeVersionFilter(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		minimum string
+		passed  []string
+	}{
+		{"no annotation", "", []string{"newer", "older", "rc", "docker", "unparseable", "unknown"}},
+		{"malformed annotation", "soon", []string{"newer", "older", "rc", "docker", "unparseable", "unknown"}},
+		{"any runtime", "1.6.8", []string{"newer", "rc", "docker", "unparseable", "unknown"}},
+		{"docker only", "docker://19", []string{"docker", "unparseable", "unknown"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w, result := filterNodes(t, RuntimeVersionFilter, runtimePod(test.minimum), runtimeNodes)
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if passed := passedNodes(result); !reflect.DeepEqual(passed, test.passed) {
+				t.Errorf("passed %v, expected %v", passed, test.passed)
+			}
+			for name, reason := range result.FailedNodes {
+				if reason == "" {
+					t.Errorf("node %v failed without a reason", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// NodeSampler picks the n nodes worth scoring out of a request carrying too many nodes.
+// Sampling bounds the latency of large requests at the cost of accuracy: a node left out of the
+// sample gets the neutral score, even if the priority would have scored it the highest, so the
+// heuristic should be cheap and correlate with what the priorities favor
+type NodeSampler func(pod v1.Pod, nodes []v1.Node, n int) []v1.Node
+
+// nodeSamplers are the sampling strategies selectable with -node-sampling-strategy
+var nodeSamplers = map[string]NodeSampler{
+	"first":            sampleFirstNodes,
+	"most-allocatable": sampleMostAllocatableNodes,
+}
+
+var maxNodesScored int
+var nodeSamplingStrategy string
+
+func init() {
+	flag.IntVar(&maxNodesScored, "max-nodes-scored", 0, "The maximum number of nodes scored per request, the other nodes get the neutral score, 0 means no limit")
+	flag.StringVar(&nodeSamplingStrategy, "node-sampling-strategy", "most-allocatable", "How the scored nodes are picked when a request exceeds -max-nodes-scored, one of: first, most-allocatable")
+}
+
+// validateSampling makes sure the sampling flags hold valid values
+func validateSampling() error {
+	if maxNodesScored < 0 {
+		return fmt.Errorf("the -max-nodes-scored flag value must not be negative, got %v", maxNodesScored)
+	}
+	if _, ok := nodeSamplers[nodeSamplingStrategy]; !ok {
+		return fmt.Errorf("unknown -node-sampling-strategy %q", nodeSamplingStrategy)
+	}
+	return nil
+}
+
+// sampleFirstNodes keeps the first n nodes, in the order sent by the scheduler
+func sampleFirstNodes(pod v1.Pod, nodes []v1.Node, n int) []v1.Node {
+	return nodes[:n]
+}
+
+// sampleMostAllocatableNodes keeps the n nodes with the most allocatable cpu, then memory
+func sampleMostAllocatableNodes(pod v1.Pod, nodes []v1.Node, n int) []v1.Node {
+	sorted := make([]v1.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cpuI, cpuJ := sorted[i].Status.Allocatable.Cpu(), sorted[j].Status.Allocatable.Cpu()
+		if c := cpuI.Cmp(*cpuJ); c != 0 {
+			return c > 0
+		}
+		memI, memJ := sorted[i].Status.Allocatable.Memory(), sorted[j].Status.Allocatable.Memory()
+		return memI.Cmp(*memJ) > 0
+	})
+	return sorted[:n]
+}
+
+// sampleNodes splits the nodes of the request into the ones to score and the ones getting the neutral score
+func sampleNodes(pod v1.Pod, nodes []v1.Node) (sampled, skipped []v1.Node) {
+	if maxNodesScored == 0 || len(nodes) <= maxNodesScored {
+		return nodes, nil
+	}
+	sampled = nodeSamplers[nodeSamplingStrategy](pod, nodes, maxNodesScored)
+	kept := make(map[string]bool, len(sampled))
+	for _, node := range sampled {
+		kept[node.Name] = true
+	}
+	for _, node := range nodes {
+		if !kept[node.Name] {
+			skipped = append(skipped, node)
+		}
+	}
+	glog.V(4).Infof("scoring %v out of %v nodes for pod %v (strategy=%v)\n", len(sampled), len(nodes), pod.Name, nodeSamplingStrategy)
+	return sampled, skipped
+}
+
+// neutralScores gives the neutral score to each of the nodes
+func neutralScores(nodes []v1.Node) schedulingapi.HostPriorityList {
+	list := make(schedulingapi.HostPriorityList, len(nodes))
+	for i, node := range nodes {
+		list[i] = schedulingapi.HostPriority{Host: node.Name, Score: neutralScore}
+	}
+	return list
+}
@@ -0,0 +1,129 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// withSampling sets -max-nodes-scored and -node-sampling-strategy until the end of the test
+func withSampling(t *testing.T, max int, strategy string) {
+	savedMax, savedStrategy := maxNodesScored, nodeSamplingStrategy
+	t.Cleanup(func() { maxNodesScored, nodeSamplingStrategy = savedMax, savedStrategy })
+	maxNodesScored, nodeSamplingStrategy = max, strategy
+}
+
+// allocatableNode returns a node with the allocatable cpu and memory
+func allocatableNode(name, cpu, memory string) v1.Node {
+	node := testNodes(name)[0]
+	node.Status.Allocatable = v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+	return node
+}
+
+// nodeNames returns the names of the nodes
+func nodeNames(nodes []v1.Node) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestValidateSampling(t *testing.T) {
+	tests := []struct {
+		max      int
+		strategy string
+		valid    bool
+	}{
+		{0, "first", true},
+		{10, "most-allocatable", true},
+		{-1, "first", false},
+		{10, "random", false},
+	}
+	for _, test := range tests {
+		withSampling(t, test.max, test.strategy)
+		if err := validateSampling(); (err == nil) != test.valid {
+			t.Errorf("validateSampling(%v, %q) returned %v", test.max, test.strategy, err)
+		}
+	}
+}
+
+func TestSampleNodes(t *testing.T) {
+	nodes := []v1.Node{
+		allocatableNode("small", "2", "4Gi"),
+		allocatableNode("large", "16", "64Gi"),
+		allocatableNode("medium-less-memory", "8", "16Gi"),
+		allocatableNode("medium", "8", "32Gi"),
+	}
+	tests := []struct {
+		name             string
+		max              int
+		strategy         string
+		sampled, skipped []string
+	}{
+		{"no limit", 0, "first", []string{"small", "large", "medium-less-memory", "medium"}, nil},
+		{"under the limit", 4, "first", []string{"small", "large", "medium-less-memory", "medium"}, nil},
+		{"first nodes", 2, "first", []string{"small", "large"}, []string{"medium-less-memory", "medium"}},
+		{"most allocatable, memory breaking the cpu ties", 2, "most-allocatable", []string{"large", "medium"}, []string{"small", "medium-less-memory"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withSampling(t, test.max, test.strategy)
+			sampled, skipped := sampleNodes(testPod("default", "p", nil), nodes)
+			if names := nodeNames(sampled); !reflect.DeepEqual(names, test.sampled) {
+				t.Errorf("sampled %v, expected %v", names, test.sampled)
+			}
+			if names := nodeNames(skipped); !reflect.DeepEqual(names, test.skipped) {
+				t.Errorf("skipped %v, expected %v", names, test.skipped)
+			}
+		})
+	}
+	if names := nodeNames(nodes); !reflect.DeepEqual(names, []string{"small", "large", "medium-less-memory", "medium"}) {
+		t.Errorf("sampling reordered the request nodes: %v", names)
+	}
+}
+
+func TestSampledOutNodesNeutral(t *testing.T) {
+	withSampling(t, 2, "first")
+	router := newTestRouter(t, digitPriority)
+	list := prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("node-1", "node-2", "node-3", "node-9"))
+	checkScores(t, list, map[string]int{"node-1": 1, "node-2": 2, "node-3": neutralScore, "node-9": neutralScore})
+}
+
+// TestSampleTenOfHundred checks a request of 100 nodes with -max-nodes-scored 10 scores exactly 10 of
+// them, whatever the strategy
+func TestSampleTenOfHundred(t *testing.T) {
+	for _, strategy := range []string{"first", "most-allocatable"} {
+		t.Run(strategy, func(t *testing.T) {
+			withSampling(t, 10, strategy)
+			router := newTestRouter(t, constantPriority("constant", 1, neutralScore+1))
+			list := prioritize(t, router, "constant", testPod("default", "p", nil), numberedNodes(100))
+			if len(list) != 100 {
+				t.Fatalf("returned %v scores for 100 nodes", len(list))
+			}
+			scored := 0
+			for _, host := range list {
+				if host.Score != neutralScore {
+					scored++
+				}
+			}
+			if scored != 10 {
+				t.Errorf("scored %v nodes out of 100, expected 10", scored)
+			}
+		})
+	}
+}
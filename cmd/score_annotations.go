@@ -0,0 +1,148 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var annotateScores bool
+var scoreAnnotation string
+var scoreAnnotationInterval time.Duration
+var scoreAnnotationQPS float64
+
+func init() {
+	flag.BoolVar(&annotateScores, "annotate-scores", false, "Record on the recommended node of each response its score and method as an annotation, visible with kubectl describe node")
+	flag.StringVar(&scoreAnnotation, "score-annotation", "scheduler.extender/last-score", "The node annotation recording the last score when -annotate-scores is set")
+	flag.DurationVar(&scoreAnnotationInterval, "score-annotation-interval", time.Minute, "The minimum time between two score annotations of the same node")
+	flag.Float64Var(&scoreAnnotationQPS, "score-annotation-qps", 5, "The maximum number of node patches per second sent for the score annotations")
+}
+
+// validateScoreAnnotationQPS makes sure the -score-annotation-qps flag is positive and at most one
+// patch per nanosecond, so the throttle interval does not truncate to 0
+func validateScoreAnnotationQPS() error {
+	if !(scoreAnnotationQPS > 0) {
+		return fmt.Errorf("the -score-annotation-qps flag value must be positive, got %v", scoreAnnotationQPS)
+	}
+	if scoreAnnotationQPS > float64(time.Second) {
+		return fmt.Errorf("the -score-annotation-qps flag value must be at most %v, got %v", float64(time.Second), scoreAnnotationQPS)
+	}
+	return nil
+}
+
+// scoreAnnotationValue is the JSON value of the score annotation
+type scoreAnnotationValue struct {
+	Method string    `json:"method"`
+	Score  int       `json:"score"`
+	Pod    string    `json:"pod"`
+	Time   time.Time `json:"time"`
+}
+
+// scoreAnnotator patches the recommended nodes from a background goroutine. Each node is annotated at
+// most once per interval, the patches are sent at most at the configured rate and the ones that would
+// not fit in the queue are dropped, the annotations are a best effort view for the operators
+type scoreAnnotator struct {
+	client  *apiClient
+	patches chan nodeScorePatch
+
+	lock      sync.Mutex
+	annotated map[string]time.Time
+}
+
+type nodeScorePatch struct {
+	node  string
+	value scoreAnnotationValue
+}
+
+// scoreAnnotations is the annotator fed by the routes, nil when -annotate-scores is not set
+var scoreAnnotations *scoreAnnotator
+
+// startScoreAnnotations creates the annotator when -annotate-scores is set
+func startScoreAnnotations() {
+	if !annotateScores {
+		return
+	}
+	client, err := newAPIClient()
+	if err != nil {
+		fatalf("failed to create the api-server client for -annotate-scores: %v", err)
+	}
+	scoreAnnotations = &scoreAnnotator{
+		client:    client,
+		patches:   make(chan nodeScorePatch, 100),
+		annotated: make(map[string]time.Time),
+	}
+	go scoreAnnotations.run()
+}
+
+// observe queues the annotation of the recommended node of the list, unless it was annotated recently
+func (a *scoreAnnotator) observe(methodName string, pod *v1.Pod, list schedulingapi.HostPriorityList, now time.Time) {
+	if a == nil {
+		return
+	}
+	best, recommended := recommendedHost(list)
+	if !recommended {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if last, ok := a.annotated[best.Host]; ok && now.Sub(last) < scoreAnnotationInterval {
+		return
+	}
+	for node, last := range a.annotated {
+		if now.Sub(last) >= scoreAnnotationInterval {
+			delete(a.annotated, node)
+		}
+	}
+	select {
+	case a.patches <- nodeScorePatch{node: best.Host, value: scoreAnnotationValue{Method: methodName, Score: best.Score, Pod: pod.Namespace + "/" + pod.Name, Time: now}}:
+		a.annotated[best.Host] = now
+	default:
+		glog.V(4).Infof("score annotation queue full, dropping the annotation of node %v\n", best.Host)
+	}
+}
+
+// run sends the queued patches at most at -score-annotation-qps
+func (a *scoreAnnotator) run() {
+	throttle := time.NewTicker(time.Duration(float64(time.Second) / scoreAnnotationQPS))
+	defer throttle.Stop()
+	for p := range a.patches {
+		<-throttle.C
+		value, err := json.Marshal(p.value)
+		if err != nil {
+			glog.Errorf("failed to marshal the score annotation of node %v: %v", p.node, err)
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{scoreAnnotation: string(value)},
+			},
+		})
+		if err != nil {
+			glog.Errorf("failed to marshal the score annotation patch of node %v: %v", p.node, err)
+			continue
+		}
+		if err := a.client.patch("/api/v1/nodes/"+url.PathEscape(p.node), patch); err != nil {
+			glog.Warningf("failed to annotate node %v with its score: %v", p.node, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withScoreAnnotationRate sets -score-annotation-interval and -score-annotation-qps for the test
+func withScoreAnnotationRate(t *testing.T, interval time.Duration, qps float64) {
+	savedInterval, savedQPS := scoreAnnotationInterval, scoreAnnotationQPS
+	t.Cleanup(func() { scoreAnnotationInterval, scoreAnnotationQPS = savedInterval, savedQPS })
+	scoreAnnotationInterval, scoreAnnotationQPS = interval, qps
+}
+
+// newTestAnnotator returns an annotator queuing up to size patches, sent to the client
+func newTestAnnotator(client *apiClient, size int) *scoreAnnotator {
+	return &scoreAnnotator{client: client, patches: make(chan nodeScorePatch, size), annotated: make(map[string]time.Time)}
+}
+
+func TestValidateScoreAnnotationQPS(t *testing.T) {
+	for qps, valid := range map[float64]bool{5: true, 0.5: true, 1e9: true, 0: false, -1: false, 2e9: false, math.NaN(): false} {
+		withScoreAnnotationRate(t, time.Minute, qps)
+		if err := validateScoreAnnotationQPS(); (err == nil) != valid {
+			t.Errorf("validateScoreAnnotationQPS(%v) returned %v", qps, err)
+		}
+	}
+}
+
+func TestScoreAnnotatorObserve(t *testing.T) {
+	withScoreAnnotationRate(t, time.Minute, 5)
+	now := time.Now()
+	pod := testPod("default", "p", nil)
+	best := func(host string) schedulingapi.HostPriorityList {
+		return schedulingapi.HostPriorityList{{Host: host, Score: 9}, {Host: "other", Score: 2}}
+	}
+	tests := []struct {
+		name     string
+		list     schedulingapi.HostPriorityList
+		at       time.Duration
+		expected string
+	}{
+		{"recommended node", best("a"), 0, "a"},
+		{"within the interval", best("a"), 30 * time.Second, ""},
+		{"another node", best("b"), 30 * time.Second, "b"},
+		{"tied scores", schedulingapi.HostPriorityList{{Host: "c", Score: 5}, {Host: "d", Score: 5}}, 30 * time.Second, ""},
+		{"empty list", nil, 30 * time.Second, ""},
+		{"after the interval", best("a"), 2 * time.Minute, "a"},
+	}
+	annotator := newTestAnnotator(nil, 10)
+	for _, test := range tests {
+		annotator.observe("method", &pod, test.list, now.Add(test.at))
+		select {
+		case p := <-annotator.patches:
+			if p.node != test.expected || p.value.Score != 9 || p.value.Method != "method" || p.value.Pod != "default/p" {
+				t.Errorf("%v: queued %+v, expected node %q", test.name, p, test.expected)
+			}
+		default:
+			if test.expected != "" {
+				t.Errorf("%v: nothing queued, expected node %q", test.name, test.expected)
+			}
+		}
+	}
+}
+
+func TestScoreAnnotatorQueueFull(t *testing.T) {
+	withScoreAnnotationRate(t, time.Minute, 5)
+	pod := testPod("default", "p", nil)
+	annotator := newTestAnnotator(nil, 1)
+	annotator.observe("method", &pod, schedulingapi.HostPriorityList{{Host: "a", Score: 9}, {Host: "b", Score: 1}}, time.Now())
+	annotator.observe("method", &pod, schedulingapi.HostPriorityList{{Host: "b", Score: 9}, {Host: "a", Score: 1}}, time.Now())
+	if len(annotator.patches) != 1 {
+		t.Fatalf("queued %v patches, expected 1", len(annotator.patches))
+	}
+	// the dropped node is not remembered as annotated, its next recommendation is queued
+	<-annotator.patches
+	annotator.observe("method", &pod, schedulingapi.HostPriorityList{{Host: "b", Score: 9}, {Host: "a", Score: 1}}, time.Now())
+	if p := <-annotator.patches; p.node != "b" {
+		t.Errorf("queued node %v, expected b", p.node)
+	}
+}
+
+func TestScoreAnnotatorDisabled(t *testing.T) {
+	// without -annotate-scores the routes observe through a nil annotator
+	var annotator *scoreAnnotator
+	pod := testPod("default", "p", nil)
+	annotator.observe("method", &pod, schedulingapi.HostPriorityList{{Host: "a", Score: 9}, {Host: "b", Score: 1}}, time.Now())
+}
+
+func TestScoreAnnotatorRun(t *testing.T) {
+	withScoreAnnotationRate(t, time.Minute, 1000)
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	annotator := newTestAnnotator(&apiClient{server: server.URL, client: server.Client()}, 10)
+	pod := testPod("default", "p", nil)
+	annotator.observe("combined", &pod, schedulingapi.HostPriorityList{{Host: "a", Score: 8}, {Host: "b", Score: 3}}, time.Now())
+	close(annotator.patches)
+	annotator.run()
+
+	var patch struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(cluster.patched("a"), &patch); err != nil {
+		t.Fatalf("the node patch %q is not valid: %v", cluster.patched("a"), err)
+	}
+	var value scoreAnnotationValue
+	if err := json.Unmarshal([]byte(patch.Metadata.Annotations[scoreAnnotation]), &value); err != nil {
+		t.Fatalf("the score annotation %q is not valid: %v", patch.Metadata.Annotations[scoreAnnotation], err)
+	}
+	if value.Method != "combined" || value.Score != 8 || value.Pod != "default/p" {
+		t.Errorf("annotated %+v", value)
+	}
+	if cluster.patched("b") != nil {
+		t.Errorf("node b was annotated")
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var scoreFloor int
+
+func init() {
+	flag.IntVar(&scoreFloor, "score-floor", 0, "The lowest score a node gets, the scores are rescaled from 0-10 to floor-10 so their order is kept, tempering the extender's weight in the scheduler. Vetoed nodes are not raised")
+}
+
+// validateScoreFloor makes sure the floor leaves room for the scores to differ
+func validateScoreFloor() error {
+	if scoreFloor < 0 || scoreFloor >= schedulingapi.MaxPriority {
+		return fmt.Errorf("the -score-floor flag must be between 0 and %v, got %v", schedulingapi.MaxPriority-1, scoreFloor)
+	}
+	return nil
+}
+
+// applyScoreFloor rescales the scores linearly from 0-MaxPriority to -score-floor-MaxPriority, rounding to
+// the nearest point, the UnfitScore sentinels are left for translateVetoes
+func applyScoreFloor(list schedulingapi.HostPriorityList) schedulingapi.HostPriorityList {
+	if scoreFloor == 0 {
+		return list
+	}
+	floored := make(schedulingapi.HostPriorityList, len(list))
+	for i, hp := range list {
+		floored[i] = floorScore(hp)
+	}
+	return floored
+}
+
+// floorScore rescales a single score as applyScoreFloor does
+func floorScore(hp schedulingapi.HostPriority) schedulingapi.HostPriority {
+	if scoreFloor == 0 || hp.Score == UnfitScore {
+		return hp
+	}
+	span := schedulingapi.MaxPriority - scoreFloor
+	hp.Score = scoreFloor + (clampScore(hp.Score)*span+schedulingapi.MaxPriority/2)/schedulingapi.MaxPriority
+	return hp
+}
@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withScoreFloor sets -score-floor until the end of the test
+func withScoreFloor(t *testing.T, floor int) {
+	saved := scoreFloor
+	t.Cleanup(func() { scoreFloor = saved })
+	scoreFloor = floor
+}
+
+func TestValidateScoreFloor(t *testing.T) {
+	for _, test := range []struct {
+		floor int
+		valid bool
+	}{
+		{0, true},
+		{2, true},
+		{schedulingapi.MaxPriority - 1, true},
+		{schedulingapi.MaxPriority, false},
+		{-1, false},
+	} {
+		withScoreFloor(t, test.floor)
+		if err := validateScoreFloor(); (err == nil) != test.valid {
+			t.Errorf("-score-floor=%v: got %v, expected valid %v", test.floor, err, test.valid)
+		}
+	}
+}
+
+func TestFloorScore(t *testing.T) {
+	for _, test := range []struct {
+		floor    int
+		score    int
+		expected int
+	}{
+		{0, 3, 3},
+		{2, 0, 2},
+		{2, 3, 4},
+		{2, 5, 6},
+		{2, 10, 10},
+		{9, 0, 9},
+		{9, 4, 9},
+		{9, 5, 10},
+		{2, -3, 2},
+		{2, 14, 10},
+		{2, UnfitScore, UnfitScore},
+	} {
+		withScoreFloor(t, test.floor)
+		if floored := floorScore(schedulingapi.HostPriority{Host: "n", Score: test.score}); floored.Score != test.expected {
+			t.Errorf("floor %v raised %v to %v, expected %v", test.floor, test.score, floored.Score, test.expected)
+		}
+	}
+}
+
+func TestScoreFloorRoutes(t *testing.T) {
+	withScoreFloor(t, 2)
+	withVetoMode(t, vetoModeZero)
+	router := newTestRouter(t, digitPriority)
+	AddCombinedRoute(router)
+	nodes := testNodes("n0", "n5", "n8", "n9")
+
+	// the order is kept and the vetoed node is not raised
+	expected := map[string]int{"n0": 2, "n5": 6, "n8": 8, "n9": 0}
+	checkScores(t, prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), nodes), expected)
+
+	w := combine(t, router, "", nodes)
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+	}
+	checkScores(t, list, expected)
+}
@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// ScoreRecord is a scoring decision as recorded for offline analysis
+type ScoreRecord struct {
+	Time         time.Time                      `json:"time"`
+	Method       string                         `json:"method"`
+	PodNamespace string                         `json:"podNamespace"`
+	PodName      string                         `json:"podName"`
+	PodUID       string                         `json:"podUID"`
+	PodLabels    map[string]string              `json:"podLabels,omitempty"`
+	Scores       schedulingapi.HostPriorityList `json:"scores"`
+}
+
+// ScoreRecorder collects the scoring decisions, Record must never block the request
+type ScoreRecorder interface {
+	Record(record ScoreRecord)
+}
+
+var scoreLogFile string
+var scoreLogMaxBytes int64
+var scoreLogBuffer int
+
+// scoreRecorder is the configured recorder, nil when recording is disabled
+var scoreRecorder ScoreRecorder
+
+func init() {
+	flag.StringVar(&scoreLogFile, "score-log-file", "", "Record every scoring decision as a JSON line in this file, - means stdout, disabled when empty")
+	flag.Int64Var(&scoreLogMaxBytes, "score-log-max-bytes", 100*1024*1024, "The size after which the score log file is rotated, 0 disables the rotation")
+	flag.IntVar(&scoreLogBuffer, "score-log-buffer", 1000, "The number of records buffered before new ones are dropped")
+}
+
+// newScoreRecorder creates the recorder selected by -score-log-file
+func newScoreRecorder() ScoreRecorder {
+	switch scoreLogFile {
+	case "":
+		return nil
+	case "-":
+		return newAsyncRecorder(nopCloser{os.Stdout}, scoreLogBuffer)
+	}
+	file, err := newRotatingFile(scoreLogFile, scoreLogMaxBytes)
+	if err != nil {
+		fatalf("failed to open the -score-log-file: %v", err)
+	}
+	return newAsyncRecorder(file, scoreLogBuffer)
+}
+
+// recordScores hands the decision to the configured recorder, if any
+func recordScores(method string, pod *v1.Pod, scores schedulingapi.HostPriorityList) {
+	if scoreRecorder == nil {
+		return
+	}
+	scoreRecorder.Record(ScoreRecord{
+		Time:         time.Now(),
+		Method:       method,
+		PodNamespace: pod.Namespace,
+		PodName:      pod.Name,
+		PodUID:       string(pod.UID),
+		PodLabels:    pod.Labels,
+		Scores:       scores,
+	})
+}
+
+// asyncRecorder writes the records as JSON lines from a background goroutine
+type asyncRecorder struct {
+	records chan ScoreRecord
+}
+
+func newAsyncRecorder(out io.WriteCloser, buffer int) *asyncRecorder {
+	recorder := &asyncRecorder{records: make(chan ScoreRecord, buffer)}
+	go recorder.run(out)
+	return recorder
+}
+
+// Record queues the record, it is dropped when the writer can't keep up
+func (a *asyncRecorder) Record(record ScoreRecord) {
+	select {
+	case a.records <- record:
+	default:
+		glog.V(2).Infof("score recorder buffer is full, dropping the record of pod %v\n", record.PodName)
+	}
+}
+
+// run writes the queued records, flushing whenever the queue is drained
+func (a *asyncRecorder) run(out io.WriteCloser) {
+	defer out.Close()
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	for record := range a.records {
+		if err := encoder.Encode(record); err != nil {
+			glog.Warningf("failed to record scores: %v", err)
+		}
+		if len(a.records) == 0 {
+			if err := writer.Flush(); err != nil {
+				glog.Warningf("failed to flush the score records: %v", err)
+			}
+		}
+	}
+	writer.Flush()
+}
+
+// rotatingFile is a file renamed with a timestamp suffix and reopened once it grows past maxBytes
+type rotatingFile struct {
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(path string, maxBytes int64) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxBytes: maxBytes}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	rotated := fmt.Sprintf("%v.%v", r.path, time.Now().UTC().Format("20060102T150405.000"))
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
+
+// nopCloser keeps stdout open when the recorder stops
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// recordWriter collects the written bytes, Write blocks while blocked is not nil and signals written
+type recordWriter struct {
+	buffer  bytes.Buffer
+	written chan struct{}
+	blocked chan struct{}
+	closed  chan struct{}
+}
+
+func newRecordWriter(blocking bool) *recordWriter {
+	w := &recordWriter{written: make(chan struct{}, 100), closed: make(chan struct{})}
+	if blocking {
+		w.blocked = make(chan struct{})
+	}
+	return w
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.written <- struct{}{}
+	if w.blocked != nil {
+		<-w.blocked
+	}
+	return w.buffer.Write(p)
+}
+
+func (w *recordWriter) Close() error {
+	close(w.closed)
+	return nil
+}
+
+// records decodes the JSON lines written once the recorder is stopped
+func (w *recordWriter) records(t *testing.T, recorder *asyncRecorder) []ScoreRecord {
+	t.Helper()
+	close(recorder.records)
+	<-w.closed
+	var records []ScoreRecord
+	scanner := bufio.NewScanner(&w.buffer)
+	for scanner.Scan() {
+		var record ScoreRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// memoryRecorder keeps the records in memory
+type memoryRecorder struct {
+	records []ScoreRecord
+}
+
+func (m *memoryRecorder) Record(record ScoreRecord) {
+	m.records = append(m.records, record)
+}
+
+func TestRecordScores(t *testing.T) {
+	saved := scoreRecorder
+	defer func() { scoreRecorder = saved }()
+	scoreRecorder = nil
+	pod := testPod("default", "p", map[string]string{"app": "web"})
+	pod.UID = "uid"
+	scores := schedulingapi.HostPriorityList{{Host: "a", Score: 3}}
+	// no recorder configured, nothing to record into
+	recordScores("test", &pod, scores)
+
+	recorder := &memoryRecorder{}
+	scoreRecorder = recorder
+	recordScores("test", &pod, scores)
+	if len(recorder.records) != 1 {
+		t.Fatalf("recorded %v records, expected 1", len(recorder.records))
+	}
+	record := recorder.records[0]
+	if record.Time.IsZero() {
+		t.Errorf("recorded without a time")
+	}
+	record.Time = time.Time{}
+	expected := ScoreRecord{Method: "test", PodNamespace: "default", PodName: "p", PodUID: "uid", PodLabels: map[string]string{"app": "web"}, Scores: scores}
+	if !reflect.DeepEqual(record, expected) {
+		t.Errorf("recorded %+v, expected %+v", record, expected)
+	}
+}
+
+func TestAsyncRecorder(t *testing.T) {
+	out := newRecordWriter(false)
+	recorder := newAsyncRecorder(out, 10)
+	for _, method := range []string{"a", "b", "c"} {
+		recorder.Record(ScoreRecord{Method: method, Scores: schedulingapi.HostPriorityList{{Host: "n", Score: 1}}})
+	}
+	var methods []string
+	for _, record := range out.records(t, recorder) {
+		methods = append(methods, record.Method)
+	}
+	if !reflect.DeepEqual(methods, []string{"a", "b", "c"}) {
+		t.Errorf("recorded %v, expected a, b and c in order", methods)
+	}
+}
+
+func TestAsyncRecorderDropsWhenFull(t *testing.T) {
+	out := newRecordWriter(true)
+	recorder := newAsyncRecorder(out, 1)
+	recorder.Record(ScoreRecord{Method: "written"})
+	// the writer is stuck flushing the first record, the buffer holds one more
+	<-out.written
+	recorder.Record(ScoreRecord{Method: "buffered"})
+	recorder.Record(ScoreRecord{Method: "dropped"})
+	close(out.blocked)
+	var methods []string
+	for _, record := range out.records(t, recorder) {
+		methods = append(methods, record.Method)
+	}
+	if !reflect.DeepEqual(methods, []string{"written", "buffered"}) {
+		t.Errorf("recorded %v, expected the dropped record to be missing", methods)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scores.log")
+	if err := ioutil.WriteFile(path, []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the size of the existing file counts toward the limit
+	file, err := newRotatingFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("abcde\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(path + "*")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("found %v, expected the file and its rotated copy", files)
+	}
+	current, err := ioutil.ReadFile(path)
+	if err != nil || string(current) != "abcde\n" {
+		t.Errorf("the current file holds %q, expected the last write", current)
+	}
+	rotated, err := ioutil.ReadFile(files[1])
+	if files[1] == path {
+		rotated, err = ioutil.ReadFile(files[0])
+	}
+	if err != nil || string(rotated) != "12345\n" {
+		t.Errorf("the rotated file holds %q, expected the previous content", rotated)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+var scoreTableConfigMap, scoreTableKey string
+
+func init() {
+	flag.StringVar(&scoreTableConfigMap, "score-table-configmap", "", "The namespace/name of the ConfigMap holding the score_table node score offsets, refreshed every -informer-resync")
+	flag.StringVar(&scoreTableKey, "score-table-key", "table", "The ConfigMap data key holding the score table")
+}
+
+// validateScoreTable makes sure -score-table-configmap names a namespaced ConfigMap
+func validateScoreTable() error {
+	if scoreTableConfigMap == "" {
+		return nil
+	}
+	if parts := strings.Split(scoreTableConfigMap, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("the -score-table-configmap flag must be namespace/name, got %q", scoreTableConfigMap)
+	}
+	return nil
+}
+
+// scoreTableEntry is a row of the score table, the table being a YAML or JSON list of rows, e.g.
+//
+//	[{node: node-1, offset: 4}, {selector: "topology.kubernetes.io/zone=zone-a,disktype!=hdd", offset: -2}]
+//
+// a row names either a node or a label selector, the offsets of all the rows matching a node add up. The
+// selectors requiring a label key outside -node-label-allowlist match no node
+type scoreTableEntry struct {
+	Node     string `json:"node,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Offset   int    `json:"offset"`
+
+	selector labels.Selector
+}
+
+// matches reports whether the row applies to the node
+func (e scoreTableEntry) matches(node v1.Node) bool {
+	if e.Node != "" {
+		return normalizeNodeName(e.Node) == normalizeNodeName(node.Name)
+	}
+	return labelSelectorAllowed(e.selector) && e.selector.Matches(labels.Set(node.Labels))
+}
+
+// parseScoreTable parses the YAML or JSON rows of the score table
+func parseScoreTable(content string) ([]scoreTableEntry, error) {
+	var entries []scoreTableEntry
+	if err := yaml.Unmarshal([]byte(content), &entries); err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if (entry.Node == "") == (entry.Selector == "") {
+			return nil, fmt.Errorf("row %v must set either node or selector", i)
+		}
+		if entry.Selector != "" {
+			selector, err := labels.Parse(entry.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("row %v has an invalid selector %q: %v", i, entry.Selector, err)
+			}
+			entries[i].selector = selector
+		}
+	}
+	return entries, nil
+}
+
+// scoreTable holds the rows of the last valid version of the ConfigMap, an invalid version is logged and
+// the previous rows are kept
+type scoreTable struct {
+	lock            sync.RWMutex
+	entries         []scoreTableEntry
+	resourceVersion string
+}
+
+// nodeScoreTable is the table used by the score_table priority, empty until the ConfigMap is read
+var nodeScoreTable = &scoreTable{}
+
+// update replaces the rows with the ones of the ConfigMap, unless its version was already seen
+func (t *scoreTable) update(configMap v1.ConfigMap) error {
+	t.lock.RLock()
+	seen := configMap.ResourceVersion != "" && configMap.ResourceVersion == t.resourceVersion
+	t.lock.RUnlock()
+	if seen {
+		return nil
+	}
+	entries, err := parseScoreTable(configMap.Data[scoreTableKey])
+	if err != nil {
+		return fmt.Errorf("invalid score table in the %v key of ConfigMap %v/%v: %v", scoreTableKey, configMap.Namespace, configMap.Name, err)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.entries = entries
+	t.resourceVersion = configMap.ResourceVersion
+	glog.V(2).Infof("loaded %v score table rows from ConfigMap %v/%v version %v\n", len(entries), configMap.Namespace, configMap.Name, configMap.ResourceVersion)
+	return nil
+}
+
+// offset sums the offsets of the rows matching the node
+func (t *scoreTable) offset(node v1.Node) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	var offset int
+	for _, entry := range t.entries {
+		if entry.matches(node) {
+			offset += entry.Offset
+		}
+	}
+	return offset
+}
+
+// scoreTableInformer keeps the score table in sync with the -score-table-configmap
+type scoreTableInformer struct {
+	client *apiClient
+	table  *scoreTable
+}
+
+func newScoreTableInformer(client *apiClient, table *scoreTable) *scoreTableInformer {
+	return &scoreTableInformer{client: client, table: table}
+}
+
+// refresh reads the ConfigMap, the previous rows are kept when it can't be read
+func (s *scoreTableInformer) refresh() error {
+	parts := strings.Split(scoreTableConfigMap, "/")
+	var configMap v1.ConfigMap
+	if err := s.client.get(fmt.Sprintf("/api/v1/namespaces/%v/configmaps/%v", parts[0], parts[1]), &configMap); err != nil {
+		return err
+	}
+	return s.table.update(configMap)
+}
+
+// run refreshes the table until the stop channel is closed
+func (s *scoreTableInformer) run(stop <-chan struct{}) {
+	poll("score table", s.refresh, stop)
+}
+
+// ScoreTablePriority applies the node score offsets operators maintain in the -score-table-configmap, every
+// node starts from the neutral score and the offsets of the rows matching it are added. The ConfigMap is
+// read again at each resync, so an edit takes effect without restarting the extender
+var ScoreTablePriority = PrioritizeMethod{
+	Name:              "score_table",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			return clampScore(neutralScore + nodeScoreTable.offset(node)), nil
+		}
+	},
+	Explain: func(pod v1.Pod, node v1.Node) string {
+		return fmt.Sprintf("neutral score offset by %v from the score table", nodeScoreTable.offset(node))
+	},
+}
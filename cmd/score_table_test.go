@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withScoreTable sets -score-table-configmap and -score-table-key and empties the score table until the
+// end of the test
+func withScoreTable(t *testing.T, configMap, key string) {
+	savedConfigMap, savedKey, savedTable := scoreTableConfigMap, scoreTableKey, nodeScoreTable
+	t.Cleanup(func() { scoreTableConfigMap, scoreTableKey, nodeScoreTable = savedConfigMap, savedKey, savedTable })
+	scoreTableConfigMap, scoreTableKey, nodeScoreTable = configMap, key, &scoreTable{}
+}
+
+// tableConfigMap returns a kube-system/score-table ConfigMap of the version holding the table
+func tableConfigMap(version, table string) v1.ConfigMap {
+	return v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "score-table", ResourceVersion: version},
+		Data:       map[string]string{"table": table},
+	}
+}
+
+func TestValidateScoreTable(t *testing.T) {
+	for _, test := range []struct {
+		configMap string
+		valid     bool
+	}{
+		{"", true},
+		{"kube-system/score-table", true},
+		{"score-table", false},
+		{"/score-table", false},
+		{"kube-system/", false},
+		{"kube-system/score/table", false},
+	} {
+		withScoreTable(t, test.configMap, "table")
+		if err := validateScoreTable(); (err == nil) != test.valid {
+			t.Errorf("-score-table-configmap=%q: got %v, expected valid %v", test.configMap, err, test.valid)
+		}
+	}
+}
+
+func TestParseScoreTable(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		rows    int
+		valid   bool
+	}{
+		{"empty", "", 0, true},
+		{"yaml", "[{node: a, offset: 3}, {selector: \"zone=z1,disk!=hdd\", offset: -2}]", 2, true},
+		{"json", `[{"node": "a", "offset": 1}]`, 1, true},
+		{"neither node nor selector", "[{offset: 3}]", 0, false},
+		{"both node and selector", "[{node: a, selector: zone=z1, offset: 3}]", 0, false},
+		{"invalid selector", "[{selector: \"zone in (z1\", offset: 3}]", 0, false},
+		{"not a list", "node: a", 0, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := parseScoreTable(test.content)
+			if (err == nil) != test.valid {
+				t.Fatalf("got %v, expected valid %v", err, test.valid)
+			}
+			if len(entries) != test.rows {
+				t.Errorf("parsed %v rows, expected %v", len(entries), test.rows)
+			}
+		})
+	}
+}
+
+func TestScoreTablePriority(t *testing.T) {
+	withScoreTable(t, "kube-system/score-table", "table")
+	withNodeLabelAllowlist(t, "*")
+	nodes := []v1.Node{
+		labeledNode("a", map[string]string{"zone": "z1"}),
+		labeledNode("b", map[string]string{"zone": "z1", "disk": "hdd"}),
+		labeledNode("c", map[string]string{"zone": "z2"}),
+		labeledNode("d", nil),
+	}
+	table := "[{node: a, offset: 3}, {selector: \"zone=z1,disk!=hdd\", offset: 4}, {selector: zone=z2, offset: -9}, {node: unknown, offset: 1}]"
+	if err := nodeScoreTable.update(tableConfigMap("1", table)); err != nil {
+		t.Fatal(err)
+	}
+	// the offsets of the matching rows add up and the scores are clamped, the unlisted nodes stay neutral
+	checkScores(t, scoreMethod(t, ScoreTablePriority, testPod("default", "p", nil), nodes), map[string]int{"a": 10, "b": 5, "c": 0, "d": 5})
+
+	// an invalid version is rejected and the previous rows are kept
+	if err := nodeScoreTable.update(tableConfigMap("2", "[{offset: 3}]")); err == nil {
+		t.Error("loaded a table with a row naming neither a node nor a selector")
+	}
+	if offset := nodeScoreTable.offset(nodes[0]); offset != 7 {
+		t.Errorf("offset %v after the invalid version, expected the previous 7", offset)
+	}
+
+	// a version already loaded is not parsed again
+	if err := nodeScoreTable.update(tableConfigMap("1", "[{offset: 3}]")); err != nil {
+		t.Errorf("parsed again the loaded version: %v", err)
+	}
+
+	// the selectors on keys outside -node-label-allowlist match no node
+	withNodeLabelAllowlist(t, "disk")
+	if offset := nodeScoreTable.offset(nodes[0]); offset != 3 {
+		t.Errorf("offset %v with zone outside the allowlist, expected 3", offset)
+	}
+}
+
+func TestScoreTableInformerRefresh(t *testing.T) {
+	withScoreTable(t, "kube-system/score-table", "table")
+	configMap := tableConfigMap("1", "[{node: a, offset: 2}]")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/kube-system/configmaps/score-table" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(configMap)
+	}))
+	defer server.Close()
+	informer := newScoreTableInformer(&apiClient{server: server.URL, client: server.Client()}, nodeScoreTable)
+	node := testNodes("a")[0]
+
+	for _, step := range []struct {
+		name      string
+		configMap v1.ConfigMap
+		failed    bool
+		offset    int
+	}{
+		{"first version", tableConfigMap("1", "[{node: a, offset: 2}]"), false, 2},
+		{"edited", tableConfigMap("2", "[{node: a, offset: -1}]"), false, -1},
+		{"invalid edit", tableConfigMap("3", "[{node: a, selector: x=y, offset: 4}]"), true, -1},
+		{"other key", v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "4"}, Data: map[string]string{"rows": "[{node: a, offset: 4}]"}}, false, 0},
+	} {
+		configMap = step.configMap
+		if err := informer.refresh(); (err != nil) != step.failed {
+			t.Errorf("%v: refresh got %v, expected failure %v", step.name, err, step.failed)
+		}
+		if offset := nodeScoreTable.offset(node); offset != step.offset {
+			t.Errorf("%v: offset %v, expected %v", step.name, offset, step.offset)
+		}
+	}
+
+	// the ConfigMap can't be read, the previous rows are kept
+	configMap = tableConfigMap("5", "[{node: a, offset: 4}]")
+	scoreTableConfigMap = "kube-system/missing"
+	if err := informer.refresh(); err == nil {
+		t.Error("refreshed the table from a missing ConfigMap")
+	}
+	if offset := nodeScoreTable.offset(node); offset != 0 {
+		t.Errorf("the failed refresh changed the offset to %v", offset)
+	}
+}
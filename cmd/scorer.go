@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// Scorer scores the candidate nodes of a pod. Stateful priorities implement it on a struct holding their
+// dependencies, e.g. a store or a lister, simple ones keep a Func adapted by ScorerFunc. The context is
+// done when the method times out, a long running scorer should give up then
+type Scorer interface {
+	Name() string
+	Score(ctx context.Context, pod v1.Pod, nodes []v1.Node) (schedulingapi.HostPriorityList, error)
+}
+
+// scorerFunc is the Scorer of a PrioritizeMethod Func
+type scorerFunc struct {
+	name string
+	fn   func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error)
+}
+
+// ScorerFunc adapts a priority function to the Scorer interface, the function ignores the context
+func ScorerFunc(name string, fn func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error)) Scorer {
+	return scorerFunc{name: name, fn: fn}
+}
+
+func (s scorerFunc) Name() string {
+	return s.name
+}
+
+func (s scorerFunc) Score(ctx context.Context, pod v1.Pod, nodes []v1.Node) (schedulingapi.HostPriorityList, error) {
+	list, err := s.fn(pod, nodes)
+	if err != nil {
+		return nil, err
+	}
+	return *list, nil
+}
+
+// scorer returns the Scorer of the method, its Prepare or Func adapted when it has no Scorer
+func (p PrioritizeMethod) scorer() Scorer {
+	if p.Scorer != nil {
+		return p.Scorer
+	}
+	if p.Prepare != nil {
+		return ScorerFunc(p.Name, func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			return scoreNodes(p.Name, pod, nodes, p.Prepare(pod, nodes))
+		})
+	}
+	return ScorerFunc(p.Name, p.Func)
+}
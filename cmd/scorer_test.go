@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// rankScorer is a stateful Scorer, it scores the nodes from the ranks it holds
+type rankScorer struct {
+	ranks map[string]int
+}
+
+func (s rankScorer) Name() string {
+	return "rank"
+}
+
+func (s rankScorer) Score(ctx context.Context, pod v1.Pod, nodes []v1.Node) (schedulingapi.HostPriorityList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	list := make(schedulingapi.HostPriorityList, len(nodes))
+	for i, node := range nodes {
+		list[i] = schedulingapi.HostPriority{Host: node.Name, Score: s.ranks[node.Name]}
+	}
+	return list, nil
+}
+
+func TestScorer(t *testing.T) {
+	ranks := rankScorer{ranks: map[string]int{"a": 2, "b": 8}}
+	byName := func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+		list := make(schedulingapi.HostPriorityList, len(nodes))
+		for i, node := range nodes {
+			list[i] = schedulingapi.HostPriority{Host: node.Name, Score: int(node.Name[0] - 'a')}
+		}
+		return &list, nil
+	}
+	tests := []struct {
+		name     string
+		method   PrioritizeMethod
+		expected map[string]int
+	}{
+		{"interface", PrioritizeMethod{Name: "rank", Scorer: ranks}, map[string]int{"a": 2, "b": 8}},
+		{"interface before func", PrioritizeMethod{Name: "rank", Scorer: ranks, Func: byName}, map[string]int{"a": 2, "b": 8}},
+		{"func adapter", PrioritizeMethod{Name: "rank", Func: byName}, map[string]int{"a": 0, "b": 1}},
+		{"prepare adapter", constantPriority("rank", 1, 6), map[string]int{"a": 6, "b": 6}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if name := test.method.scorer().Name(); name != "rank" {
+				t.Errorf("the scorer is named %v", name)
+			}
+			router := newTestRouter(t, test.method)
+			checkScores(t, prioritize(t, router, "rank", testPod("default", "p", nil), testNodes("a", "b")), test.expected)
+		})
+	}
+}
+
+func TestScorerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	method := PrioritizeMethod{Name: "rank", Scorer: rankScorer{}}
+	if _, err := method.scorer().Score(ctx, testPod("default", "p", nil), testNodes("a")); err == nil {
+		t.Errorf("the scorer did not get the done context")
+	}
+}
+
+func TestImagePriorityAdapter(t *testing.T) {
+	scorer := ImagePriority.scorer()
+	if scorer.Name() != ImagePriority.Name {
+		t.Errorf("the image priority scorer is named %v", scorer.Name())
+	}
+	nodes := []v1.Node{imageNode("cached", map[string]int64{"docker.io/library/nginx:1.19": 100 * mb}), imageNode("empty", nil)}
+	list, err := scorer.Score(context.Background(), imagePod("nginx:1.19"), nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores := scoresByHost(list); scores["cached"] <= scores["empty"] {
+		t.Errorf("scored %v, expected the node caching the image first", list)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	securityProfileModeFilter   = "filter"
+	securityProfileModePriority = "priority"
+
+	// appArmorAnnotationPrefix prefixes the pod annotations setting the AppArmor profile of a container
+	appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+	// appArmorProfileRuntimeDefault is the default AppArmor profile of the container runtime
+	appArmorProfileRuntimeDefault = "runtime/default"
+	// localhostProfilePrefix prefixes the seccomp and AppArmor profiles loaded on the node
+	localhostProfilePrefix = "localhost/"
+)
+
+var securityLabelPrefix, securityProfileMode string
+
+func init() {
+	flag.StringVar(&securityLabelPrefix, "security-label-prefix", "security.example.com/", "The prefix of the node labels advertising the security features the node supports, e.g. security.example.com/privileged, security.example.com/seccomp.<profile> or security.example.com/apparmor.<profile>, a node supports the feature when the label is set to anything but false")
+	flag.StringVar(&securityProfileMode, "security-profile-mode", securityProfileModePriority, "How security_profile treats nodes missing a security feature the pod needs, one of: filter, priority")
+}
+
+// validateSecurityProfileMode makes sure -security-profile-mode is known
+func validateSecurityProfileMode() error {
+	switch securityProfileMode {
+	case securityProfileModeFilter, securityProfileModePriority:
+		return nil
+	}
+	return fmt.Errorf("unknown -security-profile-mode %q, expecting one of: %v, %v", securityProfileMode, securityProfileModeFilter, securityProfileModePriority)
+}
+
+// SecurityProfilePriority prefers the nodes supporting the security features the pod needs: running
+// privileged, and the localhost seccomp and AppArmor profiles, served when -security-profile-mode is
+// priority. A node scores by the fraction of the features it supports, pods needing none get the neutral score
+var SecurityProfilePriority = PrioritizeMethod{
+	Name: "security_profile",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		features := podSecurityFeatures(pod)
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if len(features) == 0 {
+				return neutralScore, nil
+			}
+			missing := missingSecurityFeatures(node, features)
+			return schedulingapi.MaxPriority * (len(features) - len(missing)) / len(features), nil
+		}
+	},
+}
+
+// SecurityProfileFilter rejects the nodes missing a security feature the pod needs, served when
+// -security-profile-mode is filter
+var SecurityProfileFilter = FilterMethod{
+	Name: "security_profile",
+	Func: func(pod v1.Pod, node v1.Node) (bool, string, error) {
+		missing := missingSecurityFeatures(node, podSecurityFeatures(pod))
+		if len(missing) == 0 {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("node does not advertise the security features %v", strings.Join(missing, ", ")), nil
+	},
+}
+
+// podSecurityFeatures returns, sorted, the security features the pod needs from its node: privileged
+// when a container runs privileged, seccomp.<profile> and apparmor.<profile> for the localhost profiles
+// and apparmor for the runtime AppArmor profile. The unconfined and runtime seccomp profiles need nothing
+func podSecurityFeatures(pod v1.Pod) []string {
+	features := make(map[string]bool)
+	containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, ctnr := range containers {
+		if ctnr.SecurityContext != nil && ctnr.SecurityContext.Privileged != nil && *ctnr.SecurityContext.Privileged {
+			features["privileged"] = true
+		}
+	}
+	for key, value := range pod.Annotations {
+		switch {
+		case key == v1.SeccompPodAnnotationKey || strings.HasPrefix(key, v1.SeccompContainerAnnotationKeyPrefix):
+			if strings.HasPrefix(value, localhostProfilePrefix) {
+				features["seccomp."+profileLabelName(value)] = true
+			}
+		case strings.HasPrefix(key, appArmorAnnotationPrefix):
+			if strings.HasPrefix(value, localhostProfilePrefix) {
+				features["apparmor."+profileLabelName(value)] = true
+			} else if value == appArmorProfileRuntimeDefault {
+				features["apparmor"] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(features))
+	for feature := range features {
+		sorted = append(sorted, feature)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// profileLabelName turns a localhost profile into the name of its node label, the profile path
+// separators become dots, e.g. localhost/audit/strict -> audit.strict
+func profileLabelName(profile string) string {
+	return strings.Replace(strings.TrimPrefix(profile, localhostProfilePrefix), "/", ".", -1)
+}
+
+// missingSecurityFeatures returns the features the node does not advertise
+func missingSecurityFeatures(node v1.Node, features []string) []string {
+	var missing []string
+	for _, feature := range features {
+		if value, ok := node.Labels[securityLabelPrefix+feature]; !ok || value == "false" {
+			missing = append(missing, feature)
+		}
+	}
+	return missing
+}
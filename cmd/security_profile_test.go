@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// withSecurityProfileMode sets -security-profile-mode for the test
+func withSecurityProfileMode(t *testing.T, mode string) {
+	saved := securityProfileMode
+	t.Cleanup(func() { securityProfileMode = saved })
+	securityProfileMode = mode
+}
+
+// privilegedPod returns a pod annotated with the annotations, its container running privileged when set
+func privilegedPod(privileged bool, annotations map[string]string) v1.Pod {
+	pod := annotatedPod(annotations)
+	pod.Spec.Containers = []v1.Container{{Name: "main", SecurityContext: &v1.SecurityContext{Privileged: &privileged}}}
+	return pod
+}
+
+// securityNode returns a node labeled with the security features, set to the value
+func securityNode(name, value string, features ...string) v1.Node {
+	labels := make(map[string]string)
+	for _, feature := range features {
+		labels[securityLabelPrefix+feature] = value
+	}
+	return labeledNode(name, labels)
+}
+
+func TestValidateSecurityProfileMode(t *testing.T) {
+	for mode, valid := range map[string]bool{securityProfileModeFilter: true, securityProfileModePriority: true, "strict": false, "": false} {
+		withSecurityProfileMode(t, mode)
+		if err := validateSecurityProfileMode(); (err == nil) != valid {
+			t.Errorf("validateSecurityProfileMode(%q) returned %v", mode, err)
+		}
+	}
+}
+
+func TestPodSecurityFeatures(t *testing.T) {
+	initPrivileged := testPod("default", "p", nil)
+	privileged := true
+	initPrivileged.Spec.InitContainers = []v1.Container{{Name: "init", SecurityContext: &v1.SecurityContext{Privileged: &privileged}}}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected []string
+	}{
+		{"nothing", testPod("default", "p", nil), []string{}},
+		{"privileged", privilegedPod(true, nil), []string{"privileged"}},
+		{"unprivileged", privilegedPod(false, nil), []string{}},
+		{"privileged init container", initPrivileged, []string{"privileged"}},
+		{"seccomp pod profile", annotatedPod(map[string]string{v1.SeccompPodAnnotationKey: "localhost/audit/strict"}), []string{"seccomp.audit.strict"}},
+		{"seccomp container profile", annotatedPod(map[string]string{v1.SeccompContainerAnnotationKeyPrefix + "main": "localhost/audit"}), []string{"seccomp.audit"}},
+		{"seccomp runtime profile", annotatedPod(map[string]string{v1.SeccompPodAnnotationKey: "runtime/default"}), []string{}},
+		{"seccomp unconfined", annotatedPod(map[string]string{v1.SeccompPodAnnotationKey: "unconfined"}), []string{}},
+		{"apparmor localhost profile", annotatedPod(map[string]string{appArmorAnnotationPrefix + "main": "localhost/k8s-nginx"}), []string{"apparmor.k8s-nginx"}},
+		{"apparmor runtime profile", annotatedPod(map[string]string{appArmorAnnotationPrefix + "main": "runtime/default"}), []string{"apparmor"}},
+		{"apparmor unconfined", annotatedPod(map[string]string{appArmorAnnotationPrefix + "main": "unconfined"}), []string{}},
+		{"unrelated annotation", annotatedPod(map[string]string{"example.com/profile": "localhost/audit"}), []string{}},
+		{"sorted and deduplicated", privilegedPod(true, map[string]string{
+			v1.SeccompPodAnnotationKey:                   "localhost/audit",
+			v1.SeccompContainerAnnotationKeyPrefix + "a": "localhost/audit",
+			appArmorAnnotationPrefix + "main":            "runtime/default",
+		}), []string{"apparmor", "privileged", "seccomp.audit"}},
+	}
+	for _, test := range tests {
+		if features := podSecurityFeatures(test.pod); !reflect.DeepEqual(features, test.expected) {
+			t.Errorf("%v: the pod needs %v, expected %v", test.name, features, test.expected)
+		}
+	}
+}
+
+func TestSecurityProfilePriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	nodes := []v1.Node{
+		securityNode("both", "true", "privileged", "seccomp.audit"),
+		securityNode("privileged", "", "privileged"),
+		securityNode("disabled", "false", "privileged", "seccomp.audit"),
+		testNodes("bare")[0],
+	}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"two features", privilegedPod(true, map[string]string{v1.SeccompPodAnnotationKey: "localhost/audit"}), map[string]int{"both": 10, "privileged": 5, "disabled": 0, "bare": 0}},
+		{"one feature", privilegedPod(true, nil), map[string]int{"both": 10, "privileged": 10, "disabled": 0, "bare": 0}},
+		{"no feature", testPod("default", "p", nil), map[string]int{"both": 5, "privileged": 5, "disabled": 5, "bare": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, SecurityProfilePriority, test.pod, nodes), test.expected)
+		})
+	}
+}
+
+func TestSecurityProfileFilter(t *testing.T) {
+	pod := privilegedPod(true, map[string]string{appArmorAnnotationPrefix + "main": "localhost/strict"})
+	nodes := []v1.Node{
+		securityNode("both", "true", "privileged", "apparmor.strict"),
+		securityNode("privileged", "true", "privileged"),
+		testNodes("bare")[0],
+	}
+	_, result := filterNodes(t, SecurityProfileFilter, pod, nodes)
+	if passed := passedNodes(result); !reflect.DeepEqual(passed, []string{"both"}) {
+		t.Errorf("passed %v, expected both", passed)
+	}
+	expected := map[string]string{
+		"privileged": "node does not advertise the security features apparmor.strict",
+		"bare":       "node does not advertise the security features apparmor.strict, privileged",
+	}
+	if !reflect.DeepEqual(map[string]string(result.FailedNodes), expected) {
+		t.Errorf("failed %v, expected %v", result.FailedNodes, expected)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var enableServerTiming bool
+
+func init() {
+	flag.BoolVar(&enableServerTiming, "server-timing", false, "Add a Server-Timing header to the prioritize responses, breaking the request down into decode, compute per method and encode durations")
+}
+
+// serverTiming measures the phases of a request for the Server-Timing header, e.g.
+//
+//	Server-Timing: decode;dur=0.212, compute;desc="image_score";dur=1.734, encode;dur=0.051
+//
+// a nil *serverTiming, returned when -server-timing is not set, measures nothing
+type serverTiming struct {
+	last    time.Time
+	metrics []string
+}
+
+// newServerTiming starts measuring the first phase of the request
+func newServerTiming() *serverTiming {
+	if !enableServerTiming {
+		return nil
+	}
+	return &serverTiming{last: time.Now()}
+}
+
+// phase ends the current phase, naming it, and starts the next one. desc, when set, describes the phase,
+// e.g. the priority method computed during it
+func (t *serverTiming) phase(name, desc string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	metric := name
+	if desc != "" {
+		metric += fmt.Sprintf(";desc=%q", desc)
+	}
+	t.metrics = append(t.metrics, fmt.Sprintf("%v;dur=%.3f", metric, float64(now.Sub(t.last))/float64(time.Millisecond)))
+	t.last = now
+}
+
+// write sets the Server-Timing header, it must be called before the response status is written
+func (t *serverTiming) write(w http.ResponseWriter) {
+	if t == nil || len(t.metrics) == 0 {
+		return
+	}
+	w.Header().Set("Server-Timing", strings.Join(t.metrics, ", "))
+}
@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// withServerTiming sets -server-timing for the test
+func withServerTiming(t *testing.T, enabled bool) {
+	saved := enableServerTiming
+	t.Cleanup(func() { enableServerTiming = saved })
+	enableServerTiming = enabled
+}
+
+func TestServerTiming(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		phases   [][2]string
+		expected string
+	}{
+		{"disabled", false, [][2]string{{"decode", ""}, {"compute", "a"}}, "^$"},
+		{"no phase", true, nil, "^$"},
+		{"phases", true, [][2]string{{"decode", ""}, {"compute", "a"}, {"encode", ""}}, `^decode;dur=\d+\.\d{3}, compute;desc="a";dur=\d+\.\d{3}, encode;dur=\d+\.\d{3}$`},
+		{"quoted desc", true, [][2]string{{"compute", `a"b`}}, `^compute;desc="a\\"b";dur=\d+\.\d{3}$`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withServerTiming(t, test.enabled)
+			timing := newServerTiming()
+			if (timing != nil) != test.enabled {
+				t.Errorf("newServerTiming returned %v", timing)
+			}
+			for _, phase := range test.phases {
+				timing.phase(phase[0], phase[1])
+			}
+			w := httptest.NewRecorder()
+			timing.write(w)
+			if header := w.Header().Get("Server-Timing"); !regexp.MustCompile(test.expected).MatchString(header) {
+				t.Errorf("Server-Timing %q does not match %v", header, test.expected)
+			}
+		})
+	}
+}
+
+func TestServerTimingRoutes(t *testing.T) {
+	router := newTestRouter(t, constantPriority("a", 1, 3), constantPriority("b", 1, 7))
+	AddCombinedRoute(router)
+	nodes := testNodes("n1", "n2")
+	tests := []struct {
+		name     string
+		enabled  bool
+		query    string
+		expected string
+	}{
+		{"method", true, "/a", `^decode;dur=[\d.]+, compute;desc="a";dur=[\d.]+, encode;dur=[\d.]+$`},
+		{"combined", true, "", `^decode;dur=[\d.]+, compute;desc="a";dur=[\d.]+, compute;desc="b";dur=[\d.]+, combine;dur=[\d.]+, encode;dur=[\d.]+$`},
+		{"disabled", false, "/a", "^$"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withServerTiming(t, test.enabled)
+			w := combine(t, router, test.query, nodes)
+			if header := w.Header().Get("Server-Timing"); !regexp.MustCompile(test.expected).MatchString(header) {
+				t.Errorf("Server-Timing %q does not match %v", header, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// sharedVolumeBonus is added to the neutral score of the nodes already mounting a configmap or secret of the pod
+var sharedVolumeBonus int
+
+func init() {
+	flag.IntVar(&sharedVolumeBonus, "shared-volume-bonus", 0, "The score added to the nodes already running pods that mount the configmaps/secrets of the pod, 0 disables shared_volumes")
+}
+
+// validateSharedVolumeBonus checks -shared-volume-bonus fits in the score range
+func validateSharedVolumeBonus() error {
+	if sharedVolumeBonus < 0 || sharedVolumeBonus > schedulingapi.MaxPriority {
+		return fmt.Errorf("the -shared-volume-bonus flag value must be between 0 and %v, got %v", schedulingapi.MaxPriority, sharedVolumeBonus)
+	}
+	return nil
+}
+
+// SharedVolumesPriority gives a small locality bonus to the nodes already running pods that mount the
+// same configmaps or secrets as the pod, the kubelet there has them cached. The other nodes, and every
+// node when the pod mounts none or -shared-volume-bonus is 0, get the neutral score
+var SharedVolumesPriority = PrioritizeMethod{
+	Name:              "shared_volumes",
+	RequiresInformers: true,
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		wanted := podConfigVolumes(pod)
+		var byNode nodePods
+		if sharedVolumeBonus > 0 && len(wanted) > 0 {
+			byNode = podsByNode(podLister)
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			for _, other := range byNode.on(node.Name) {
+				if other.UID == pod.UID && other.UID != "" {
+					continue
+				}
+				for volume := range podConfigVolumes(other) {
+					if wanted[volume] {
+						if score := neutralScore + sharedVolumeBonus; score < schedulingapi.MaxPriority {
+							return score, nil
+						}
+						return schedulingapi.MaxPriority, nil
+					}
+				}
+			}
+			return neutralScore, nil
+		}
+	},
+}
+
+// podConfigVolumes returns the configmaps and secrets mounted by the pod, keyed by namespace, kind and name
+func podConfigVolumes(pod v1.Pod) map[string]bool {
+	volumes := make(map[string]bool)
+	add := func(kind, name string) {
+		volumes[pod.Namespace+"/"+kind+"/"+name] = true
+	}
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.ConfigMap != nil:
+			add("configmap", volume.ConfigMap.Name)
+		case volume.Secret != nil:
+			add("secret", volume.Secret.SecretName)
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add("configmap", source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					add("secret", source.Secret.Name)
+				}
+			}
+		}
+	}
+	return volumes
+}
@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// withSharedVolumeBonus sets -shared-volume-bonus for the test
+func withSharedVolumeBonus(t *testing.T, bonus int) {
+	saved := sharedVolumeBonus
+	t.Cleanup(func() { sharedVolumeBonus = saved })
+	sharedVolumeBonus = bonus
+}
+
+// volumePod returns a pod of the namespace bound to the node, mounting the volumes
+func volumePod(namespace, name, node string, volumes ...v1.VolumeSource) v1.Pod {
+	pod := testPod(namespace, name, nil)
+	pod.UID = k8stypes.UID("uid-" + name)
+	pod.Spec.NodeName = node
+	for _, source := range volumes {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: name, VolumeSource: source})
+	}
+	return pod
+}
+
+// configMapVolume returns a volume source mounting the configmap
+func configMapVolume(name string) v1.VolumeSource {
+	return v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: name}}}
+}
+
+// secretVolume returns a volume source mounting the secret
+func secretVolume(name string) v1.VolumeSource {
+	return v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: name}}
+}
+
+// projectedVolume returns a volume source projecting the configmap and the secret
+func projectedVolume(configMap, secret string) v1.VolumeSource {
+	return v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{
+		{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: configMap}}},
+		{Secret: &v1.SecretProjection{LocalObjectReference: v1.LocalObjectReference{Name: secret}}},
+	}}}
+}
+
+func TestValidateSharedVolumeBonus(t *testing.T) {
+	for bonus, valid := range map[int]bool{0: true, 2: true, 10: true, -1: false, 11: false} {
+		withSharedVolumeBonus(t, bonus)
+		if err := validateSharedVolumeBonus(); (err == nil) != valid {
+			t.Errorf("validateSharedVolumeBonus(%v) returned %v", bonus, err)
+		}
+	}
+}
+
+func TestPodConfigVolumes(t *testing.T) {
+	pod := volumePod("ns", "p", "", configMapVolume("settings"), secretVolume("token"), projectedVolume("ca", "key"), v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}})
+	volumes := podConfigVolumes(pod)
+	for _, expected := range []string{"ns/configmap/settings", "ns/secret/token", "ns/configmap/ca", "ns/secret/key"} {
+		if !volumes[expected] {
+			t.Errorf("%v is missing from %v", expected, volumes)
+		}
+	}
+	if len(volumes) != 4 {
+		t.Errorf("found the volumes %v, expected 4", volumes)
+	}
+}
+
+func TestSharedVolumesPriority(t *testing.T) {
+	withNeutralScore(t, 5)
+	withPods(t,
+		volumePod("ns", "settings", "a", configMapVolume("settings")),
+		volumePod("ns", "token", "b", projectedVolume("other", "token")),
+		volumePod("other", "foreign", "c", configMapVolume("settings")),
+		volumePod("ns", "p", "d", configMapVolume("settings")),
+	)
+	nodes := testNodes("a", "b", "c", "d")
+	tests := []struct {
+		name     string
+		bonus    int
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"configmap and secret", 2, volumePod("ns", "p", "", configMapVolume("settings"), secretVolume("token")), map[string]int{"a": 7, "b": 7, "c": 5, "d": 5}},
+		{"clamped bonus", 8, volumePod("ns", "p", "", configMapVolume("settings")), map[string]int{"a": 10, "b": 5, "c": 5, "d": 5}},
+		{"disabled", 0, volumePod("ns", "p", "", configMapVolume("settings")), map[string]int{"a": 5, "b": 5, "c": 5, "d": 5}},
+		{"no volume", 2, volumePod("ns", "p", ""), map[string]int{"a": 5, "b": 5, "c": 5, "d": 5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withSharedVolumeBonus(t, test.bonus)
+			checkScores(t, scoreMethod(t, SharedVolumesPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
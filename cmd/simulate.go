@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// simulatedNode is the combined score of a node for the simulated pod, with the score of each method
+type simulatedNode struct {
+	Host    string         `json:"host"`
+	Score   int            `json:"score"`
+	Methods map[string]int `json:"methods"`
+}
+
+// simulation is the answer of /simulate, the nodes ranked by their combined score, highest first
+type simulation struct {
+	Pod   string          `json:"pod"`
+	Nodes []simulatedNode `json:"nodes"`
+	// MethodVersions are the versions of the scoring logic of the methods run
+	MethodVersions map[string]int `json:"methodVersions"`
+	Errors         []MethodError  `json:"errors,omitempty"`
+}
+
+// simulate scores the nodes for the pod with the active methods, like the combined route does but
+// without recording the decision. An unfit node keeps the UnfitScore sentinel
+func simulate(pod v1.Pod, nodes []v1.Node) simulation {
+	// a simulated pod must not reuse, nor fill, the cycle of a pod being scheduled
+	pod.UID = ""
+	extenderArgs := schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}}
+	result := simulation{Pod: pod.Namespace + "/" + pod.Name, MethodVersions: make(map[string]int)}
+	byHost := make(map[string]map[string]int, len(nodes))
+	var lists []schedulingapi.HostPriorityList
+	var weights []int
+	for _, priorityMethod := range currentSnapshot().methodsFor(pod.Namespace) {
+		list, err := safeRunPriority(context.Background(), priorityMethod, extenderArgs, nil)
+		if err != nil {
+			result.Errors = append(result.Errors, MethodError{Method: priorityMethod.Name, Error: err.Error()})
+			continue
+		}
+		for _, hp := range list {
+			if byHost[hp.Host] == nil {
+				byHost[hp.Host] = make(map[string]int)
+			}
+			byHost[hp.Host][priorityMethod.Name] = hp.Score
+		}
+		lists = append(lists, list)
+		weights = append(weights, methodWeight(priorityMethod))
+		result.MethodVersions[priorityMethod.Name] = methodVersion(priorityMethod)
+	}
+	if len(lists) == 0 {
+		return result
+	}
+	for _, hp := range breakTies(combineScores(lists, weights)) {
+		result.Nodes = append(result.Nodes, simulatedNode{Host: hp.Host, Score: hp.Score, Methods: byHost[hp.Host]})
+	}
+	sort.SliceStable(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].Score > result.Nodes[j].Score
+	})
+	return result
+}
+
+// SimulateRoute answers where a hypothetical pod, the request body, would be scored highest right now
+// over the nodes of the cluster, without involving the scheduler
+func SimulateRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !enableInformers {
+		writeError(w, newError(ErrUnavailable, "simulating needs -enable-informers for the cluster nodes"))
+		return
+	}
+	if !checkRequestBody(w, r) {
+		glog.Warning("received empty request!")
+		return
+	}
+	var pod v1.Pod
+	if err := json.NewDecoder(r.Body).Decode(&pod); err != nil {
+		writeError(w, newError(ErrBadRequest, "failed to decode the pod: %v", err))
+		return
+	}
+	resultBody, err := json.Marshal(simulate(pod, nodeLister.List()))
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resultBody)
+}
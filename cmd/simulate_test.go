@@ -0,0 +1,109 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// withClusterNodes serves the nodes from the node informer view, as -enable-informers does, until the end of the test
+func withClusterNodes(t *testing.T, names ...string) {
+	saved := enableInformers
+	t.Cleanup(func() {
+		enableInformers = saved
+		nodeLister.replace(nil)
+	})
+	enableInformers = true
+	nodeLister.replace(testNodes(names...))
+}
+
+func TestSimulate(t *testing.T) {
+	withNeutralScore(t, 5)
+	newTestRouter(t, digitPriority, constantPriority("flat", 1, 5), failingPriority("broken"))
+	pod := testPod("default", "p", nil)
+	pod.UID = "uid-p"
+	result := simulate(pod, testNodes("n1", "n3", "n9"))
+	expected := []simulatedNode{
+		{Host: "n3", Score: 4, Methods: map[string]int{"digit": 3, "flat": 5}},
+		{Host: "n1", Score: 3, Methods: map[string]int{"digit": 1, "flat": 5}},
+		{Host: "n9", Score: UnfitScore, Methods: map[string]int{"digit": UnfitScore, "flat": 5}},
+	}
+	if !reflect.DeepEqual(result.Nodes, expected) {
+		t.Errorf("simulated %+v, expected %+v", result.Nodes, expected)
+	}
+	if result.Pod != "default/p" || len(result.Errors) != 1 || result.Errors[0].Method != "broken" {
+		t.Errorf("simulated the pod %v with the errors %v", result.Pod, result.Errors)
+	}
+	if _, ok := result.MethodVersions["digit"]; !ok || len(result.MethodVersions) != 2 {
+		t.Errorf("simulated with the method versions %v", result.MethodVersions)
+	}
+}
+
+func TestSimulateAllFailed(t *testing.T) {
+	newTestRouter(t, failingPriority("broken"))
+	if result := simulate(testPod("default", "p", nil), testNodes("a")); len(result.Nodes) != 0 || len(result.Errors) != 1 {
+		t.Errorf("simulated %+v, expected only the error", result)
+	}
+}
+
+func TestSimulateRoute(t *testing.T) {
+	withExplainBuffer(t, true, 0)
+	router := newTestRouter(t, digitPriority)
+	AddDebugRoutes(router)
+	pod, err := json.Marshal(testPod("default", "p", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		informed bool
+		body     []byte
+		status   int
+		expected []string
+	}{
+		{"ranked nodes", true, pod, http.StatusOK, []string{"n7", "n2", "n1"}},
+		{"without informers", false, pod, http.StatusServiceUnavailable, nil},
+		{"invalid pod", true, []byte("{"), http.StatusBadRequest, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.informed {
+				withClusterNodes(t, "n1", "n7", "n2")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/simulate", bytes.NewReader(test.body)))
+			if w.Code != test.status {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var result simulation
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			var hosts []string
+			for _, node := range result.Nodes {
+				hosts = append(hosts, node.Host)
+			}
+			if !reflect.DeepEqual(hosts, test.expected) {
+				t.Errorf("ranked %v, expected %v", hosts, test.expected)
+			}
+		})
+	}
+}
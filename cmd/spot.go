@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var workloadClassKey, tolerantWorkloadClass, capacityTypeLabel, spotCapacityType string
+
+func init() {
+	flag.StringVar(&workloadClassKey, "workload-class-key", "workload-class", "The pod label (or annotation) holding the workload class")
+	flag.StringVar(&tolerantWorkloadClass, "tolerant-workload-class", "batch", "The workload class of pods tolerating interruptions")
+	flag.StringVar(&capacityTypeLabel, "capacity-type-label", "node.kubernetes.io/capacity", "The node label holding the capacity type of the node")
+	flag.StringVar(&spotCapacityType, "spot-capacity-type", "spot", "The value of the capacity type label marking spot/preemptible nodes")
+}
+
+// SpotPriority steers interruption tolerant pods toward spot nodes and the other classified pods toward
+// on-demand nodes, pods without a workload class get the neutral score everywhere
+var SpotPriority = PrioritizeMethod{
+	Name: "spot_preference",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		class, classified := podWorkloadClass(pod)
+		tolerant := class == tolerantWorkloadClass
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if !classified {
+				return neutralScore, nil
+			}
+			if spot := node.Labels[capacityTypeLabel] == spotCapacityType; spot == tolerant {
+				return schedulingapi.MaxPriority, nil
+			}
+			return 0, nil
+		}
+	},
+}
+
+// podWorkloadClass returns the workload class of the pod, looking at its labels first and then its annotations
+func podWorkloadClass(pod v1.Pod) (string, bool) {
+	if class, ok := pod.Labels[workloadClassKey]; ok {
+		return class, true
+	}
+	class, ok := pod.Annotations[workloadClassKey]
+	return class, ok
+}
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+// labeledNode returns a node with the labels
+func labeledNode(name string, labels map[string]string) v1.Node {
+	node := testNodes(name)[0]
+	node.Labels = labels
+	return node
+}
+
+func TestSpotPriority(t *testing.T) {
+	nodes := []v1.Node{
+		labeledNode("spot", map[string]string{capacityTypeLabel: spotCapacityType}),
+		labeledNode("on-demand", map[string]string{capacityTypeLabel: "on-demand"}),
+		labeledNode("unlabeled", nil),
+	}
+	annotated := testPod("default", "p", nil)
+	annotated.Annotations = map[string]string{workloadClassKey: tolerantWorkloadClass}
+	// the label wins over the annotation
+	both := testPod("default", "p", map[string]string{workloadClassKey: "web"})
+	both.Annotations = map[string]string{workloadClassKey: tolerantWorkloadClass}
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		expected map[string]int
+	}{
+		{"tolerant pod", testPod("default", "p", map[string]string{workloadClassKey: tolerantWorkloadClass}), map[string]int{"spot": 10, "on-demand": 0, "unlabeled": 0}},
+		{"tolerant pod from the annotation", annotated, map[string]int{"spot": 10, "on-demand": 0, "unlabeled": 0}},
+		{"other class", testPod("default", "p", map[string]string{workloadClassKey: "web"}), map[string]int{"spot": 0, "on-demand": 10, "unlabeled": 10}},
+		{"label over annotation", both, map[string]int{"spot": 0, "on-demand": 10, "unlabeled": 10}},
+		{"empty class", testPod("default", "p", map[string]string{workloadClassKey: ""}), map[string]int{"spot": 0, "on-demand": 10, "unlabeled": 10}},
+		{"unclassified pod", testPod("default", "p", nil), map[string]int{"spot": neutralScore, "on-demand": neutralScore, "unlabeled": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, SpotPriority, test.pod, nodes), test.expected)
+		})
+	}
+}
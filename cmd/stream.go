@@ -0,0 +1,123 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+const (
+	// streamTopNodes is the number of best scored nodes sent for each decision
+	streamTopNodes = 5
+	// streamBuffer is the number of decisions queued for a subscriber before new ones are dropped
+	streamBuffer = 64
+)
+
+// DecisionEvent is a scoring decision as pushed to the /debug/stream subscribers
+type DecisionEvent struct {
+	Time         time.Time                      `json:"time"`
+	Method       string                         `json:"method"`
+	PodNamespace string                         `json:"podNamespace"`
+	PodName      string                         `json:"podName"`
+	TopNodes     schedulingapi.HostPriorityList `json:"topNodes"`
+}
+
+// decisionBroadcaster fans the decisions out to the subscribers without ever blocking the publisher,
+// a subscriber too slow to drain its buffer misses decisions
+type decisionBroadcaster struct {
+	lock        sync.RWMutex
+	subscribers map[chan DecisionEvent]bool
+}
+
+// decisionFeed feeds the /debug/stream subscribers
+var decisionFeed = &decisionBroadcaster{subscribers: make(map[chan DecisionEvent]bool)}
+
+func (b *decisionBroadcaster) subscribe() chan DecisionEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	events := make(chan DecisionEvent, streamBuffer)
+	b.subscribers[events] = true
+	return events
+}
+
+func (b *decisionBroadcaster) unsubscribe(events chan DecisionEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subscribers, events)
+}
+
+// publish sends the decision to the subscribers, it is a no-op when nobody listens
+func (b *decisionBroadcaster) publish(method string, pod *v1.Pod, scores schedulingapi.HostPriorityList) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+	top := append(schedulingapi.HostPriorityList{}, scores...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Score > top[j].Score })
+	if len(top) > streamTopNodes {
+		top = top[:streamTopNodes]
+	}
+	event := DecisionEvent{Time: time.Now(), Method: method, PodNamespace: pod.Namespace, PodName: pod.Name, TopNodes: top}
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// DebugStreamRoute streams the scoring decisions as server-sent events, one JSON object per event,
+// e.g. `curl -N http://localhost/debug/stream`
+func DebugStreamRoute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	events := decisionFeed.subscribe()
+	defer decisionFeed.unsubscribe(events)
+	glog.V(2).Infof("debug stream subscriber %v connected\n", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	done := r.Context().Done()
+	for {
+		select {
+		case <-done:
+			glog.V(2).Infof("debug stream subscriber %v disconnected\n", r.RemoteAddr)
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write(append(append([]byte("data: "), data...), '\n', '\n')); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
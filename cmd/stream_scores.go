@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// streamChunkSize is the size of the chunks the streamed scores are written in
+const streamChunkSize = 32 * 1024
+
+var streamResponses bool
+
+func init() {
+	flag.BoolVar(&streamResponses, "stream-responses", false, "Encode the prioritize responses host by host straight to the connection instead of marshaling the whole list first, lowering the peak memory of large node sets. The scores of the methods scoring each node on its own are written as they are produced")
+}
+
+// streamScores writes the scores as a single JSON array, one host at a time in chunks of streamChunkSize,
+// so the encoded list is never held in memory as a whole. Once the status is sent a failure can't be
+// reported anymore, the connection is aborted instead so the scheduler never decodes a truncated list
+func streamScores(w http.ResponseWriter, list schedulingapi.HostPriorityList) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	buffered := bufio.NewWriterSize(w, streamChunkSize)
+	if err := encodeScores(buffered, list); err != nil {
+		glog.Errorf("streaming the scores of %v hosts failed after the status was sent, aborting the response: %v", len(list), err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// encodeScores encodes the list to the buffered writer element by element and flushes it
+func encodeScores(buffered *bufio.Writer, list schedulingapi.HostPriorityList) error {
+	encoder := json.NewEncoder(buffered)
+	if err := buffered.WriteByte('['); err != nil {
+		return err
+	}
+	for i := range list {
+		if i > 0 {
+			if err := buffered.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(&list[i]); err != nil {
+			return err
+		}
+	}
+	if err := buffered.WriteByte(']'); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// scoreStream writes the scores of a priority method to the scheduler as they are produced, for the
+// methods with a Prepare: each score is encoded as soon as the nodes before it are scored, instead of
+// once the whole list is built. The status is only sent with the first score, a failure before it is
+// answered as usual, a failure after it aborts the connection
+type scoreStream struct {
+	w          http.ResponseWriter
+	methodName string
+	podName    string
+	// begin sets the headers of the response, it is called right before the status is sent
+	begin    func()
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+	// active is set once a method scores into the stream, the caller must then finish it
+	active bool
+	// sent are the scores written, after the score floor and the veto translation
+	sent schedulingapi.HostPriorityList
+}
+
+// newScoreStream returns the stream of the scores of the method for the pod, nil when the scores can't be
+// streamed as they are produced: without -stream-responses, or when the scores are reordered or rewritten
+// as a whole by -all-poor-threshold, -stable-tiebreak or -prioritize-top-k
+func newScoreStream(w http.ResponseWriter, methodName, podName string, begin func()) *scoreStream {
+	if !streamResponses || allPoorThreshold != 0 || stableTiebreak || prioritizeTopK != 0 {
+		return nil
+	}
+	return &scoreStream{w: w, methodName: methodName, podName: podName, begin: begin}
+}
+
+// accepts reports whether the scores of the method can be streamed as they are produced: the method must
+// have a Prepare, and no timeout since the neutral scores of a method running out of time would follow the
+// scores already sent
+func (s *scoreStream) accepts(ctx context.Context, priorityMethod PrioritizeMethod) bool {
+	if s == nil || priorityMethod.Prepare == nil || methodTimeout(priorityMethod) > 0 {
+		return false
+	}
+	_, hasDeadline := ctx.Deadline()
+	return !hasDeadline
+}
+
+// streamed reports whether a method scored into the stream
+func (s *scoreStream) streamed() bool {
+	return s != nil && s.active
+}
+
+// started reports whether the status was sent, the response can't be changed anymore then
+func (s *scoreStream) started() bool {
+	return s != nil && s.encoder != nil
+}
+
+// write sends the score, with the score floor and the veto translation PrioritizeRoute applies to the list
+func (s *scoreStream) write(hp schedulingapi.HostPriority) error {
+	final, kept := translateVeto(s.methodName, s.podName, floorScore(hp))
+	if !kept {
+		return nil
+	}
+	separator := byte(',')
+	if !s.started() {
+		s.start()
+		separator = '['
+	}
+	if err := s.buffered.WriteByte(separator); err != nil {
+		return err
+	}
+	s.sent = append(s.sent, final)
+	return s.encoder.Encode(&s.sent[len(s.sent)-1])
+}
+
+// start sends the headers and the status
+func (s *scoreStream) start() {
+	s.begin()
+	s.w.Header().Set("Content-Type", "application/json")
+	s.w.WriteHeader(http.StatusOK)
+	s.buffered = bufio.NewWriterSize(s.w, streamChunkSize)
+	s.encoder = json.NewEncoder(s.buffered)
+}
+
+// finish closes the array, a stream without any score sends an empty one, and returns the scores sent
+func (s *scoreStream) finish() schedulingapi.HostPriorityList {
+	closing := []byte("]")
+	if !s.started() {
+		s.start()
+		closing = []byte("[]")
+	}
+	if _, err := s.buffered.Write(closing); err != nil {
+		s.abort(err)
+	}
+	if err := s.buffered.Flush(); err != nil {
+		s.abort(err)
+	}
+	return s.sent
+}
+
+// abort gives up on a stream whose status was sent
+func (s *scoreStream) abort(err error) {
+	glog.Errorf("streaming the scores of %v for pod %v failed after the status was sent, aborting the response: %v", s.methodName, s.podName, err)
+	panic(http.ErrAbortHandler)
+}
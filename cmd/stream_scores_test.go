@@ -0,0 +1,207 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// streamOptions are the flags changing how the scores are streamed
+type streamOptions struct {
+	stream      bool
+	vetoMode    string
+	floor       int
+	maxScored   int
+	topK        int
+	concurrency int
+}
+
+// withStreamOptions sets the flags until the end of the test
+func withStreamOptions(tb testing.TB, options streamOptions) {
+	saved := streamOptions{streamResponses, vetoMode, scoreFloor, maxNodesScored, prioritizeTopK, nodeScoringConcurrency}
+	tb.Cleanup(func() {
+		streamResponses, vetoMode, scoreFloor, maxNodesScored, prioritizeTopK, nodeScoringConcurrency = saved.stream, saved.vetoMode, saved.floor, saved.maxScored, saved.topK, saved.concurrency
+	})
+	if options.vetoMode == "" {
+		options.vetoMode = vetoModeZero
+	}
+	if options.concurrency == 0 {
+		options.concurrency = 1
+	}
+	streamResponses, vetoMode, scoreFloor, maxNodesScored, prioritizeTopK, nodeScoringConcurrency = options.stream, options.vetoMode, options.floor, options.maxScored, options.topK, options.concurrency
+}
+
+// digitPriority scores each node by the last digit of its name, vetoing the nodes ending with 9
+var digitPriority = PrioritizeMethod{
+	Name: "digit",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			digit := int(node.Name[len(node.Name)-1] - '0')
+			if digit == 9 {
+				return UnfitScore, nil
+			}
+			return digit, nil
+		}
+	},
+}
+
+// serveScores posts the request to the route of the method and returns the recorded response
+func serveScores(router http.Handler, method string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+method, bytes.NewReader(body)))
+	return w
+}
+
+func TestStreamedScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		options streamOptions
+		nodes   []v1.Node
+	}{
+		{"plain", streamOptions{}, numberedNodes(25)},
+		{"concurrent", streamOptions{concurrency: 4}, numberedNodes(25)},
+		{"omitted vetoes", streamOptions{vetoMode: vetoModeOmit}, numberedNodes(25)},
+		{"score floor", streamOptions{floor: 3}, numberedNodes(25)},
+		{"sampled", streamOptions{maxScored: 10, concurrency: 4}, numberedNodes(25)},
+		{"top k", streamOptions{topK: 5}, numberedNodes(25)},
+		{"no node", streamOptions{}, nil},
+		{"only vetoes", streamOptions{vetoMode: vetoModeOmit}, testNodes("node-9", "node-19")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter(t, digitPriority)
+			body := argsBody(t, testPod("default", "p", nil), test.nodes)
+			withStreamOptions(t, test.options)
+			expected := serveScores(router, "digit", body)
+			streamed := test.options
+			streamed.stream = true
+			withStreamOptions(t, streamed)
+			got := serveScores(router, "digit", body)
+			if got.Code != http.StatusOK || got.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("expected a JSON answer, got %v %v", got.Code, got.Header())
+			}
+			var expectedList, gotList schedulingapi.HostPriorityList
+			if err := json.Unmarshal(expected.Body.Bytes(), &expectedList); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(got.Body.Bytes(), &gotList); err != nil {
+				t.Fatalf("the streamed answer %q is not a JSON array: %v", got.Body.String(), err)
+			}
+			if !reflect.DeepEqual(gotList, expectedList) {
+				t.Errorf("streamed %v, expected %v", gotList, expectedList)
+			}
+		})
+	}
+}
+
+func TestStreamedScoresFailure(t *testing.T) {
+	withStreamOptions(t, streamOptions{stream: true})
+	failing := func(failed string) PrioritizeMethod {
+		return PrioritizeMethod{
+			Name: "failing",
+			Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+				return func(pod v1.Pod, node v1.Node) (int, error) {
+					if node.Name == failed {
+						return 0, errors.New("unreachable")
+					}
+					return neutralScore, nil
+				}
+			},
+		}
+	}
+	body := argsBody(t, testPod("default", "p", nil), numberedNodes(5))
+
+	// nothing was sent yet, the error is answered as usual
+	router := newTestRouter(t, failing("node-0"))
+	if w := serveScores(router, "failing", body); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the error to be answered, got %v %q", w.Code, w.Body.String())
+	}
+
+	// the status was sent with the first scores, the connection is aborted
+	router = newTestRouter(t, failing("node-3"))
+	func() {
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("expected the response to be aborted, recovered %v", r)
+			}
+		}()
+		serveScores(router, "failing", body)
+	}()
+}
+
+// countingWriter is a ResponseWriter counting and discarding the body
+type countingWriter struct {
+	header  http.Header
+	written int
+}
+
+func (w *countingWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.written += len(b)
+	return len(b), nil
+}
+
+func (w *countingWriter) WriteHeader(int) {}
+
+// TestStreamedWhileScoring checks the first scores reach the connection before the last node is scored
+func TestStreamedWhileScoring(t *testing.T) {
+	withStreamOptions(t, streamOptions{stream: true})
+	nodes := numberedNodes(5000)
+	last := nodes[len(nodes)-1].Name
+	w := &countingWriter{header: make(http.Header)}
+	var writtenBeforeLast int
+	router := newTestRouter(t, PrioritizeMethod{
+		Name: "watching",
+		Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+			return func(pod v1.Pod, node v1.Node) (int, error) {
+				if node.Name == last {
+					writtenBeforeLast = w.written
+				}
+				return neutralScore, nil
+			}
+		},
+	})
+	body := argsBody(t, testPod("default", "p", nil), nodes)
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/watching", bytes.NewReader(body)))
+	if writtenBeforeLast == 0 || writtenBeforeLast >= w.written {
+		t.Errorf("expected part of the %v bytes to be written before the last node was scored, got %v", w.written, writtenBeforeLast)
+	}
+}
+
+func BenchmarkStreamedScores(b *testing.B) {
+	body := argsBody(b, testPod("default", "p", nil), numberedNodes(5000))
+	for _, stream := range []bool{false, true} {
+		b.Run(fmt.Sprintf("stream=%v", stream), func(b *testing.B) {
+			withStreamOptions(b, streamOptions{stream: stream})
+			router := newTestRouter(b, digitPriority)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := &countingWriter{header: make(http.Header)}
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/digit", bytes.NewReader(body)))
+			}
+		})
+	}
+}
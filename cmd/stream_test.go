@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+func TestDecisionBroadcaster(t *testing.T) {
+	feed := &decisionBroadcaster{subscribers: make(map[chan DecisionEvent]bool)}
+	pod := testPod("team-a", "web", nil)
+	scores := schedulingapi.HostPriorityList{{Host: "a", Score: 1}, {Host: "b", Score: 9}, {Host: "c", Score: 5}, {Host: "d", Score: 9}, {Host: "e", Score: 0}, {Host: "f", Score: 7}}
+	// nobody listens
+	feed.publish("image_score", &pod, scores)
+
+	events := feed.subscribe()
+	slow := feed.subscribe()
+	feed.publish("image_score", &pod, scores)
+	event := <-events
+	expected := schedulingapi.HostPriorityList{{Host: "b", Score: 9}, {Host: "d", Score: 9}, {Host: "f", Score: 7}, {Host: "c", Score: 5}, {Host: "a", Score: 1}}
+	if event.Method != "image_score" || event.PodNamespace != "team-a" || event.PodName != "web" || !reflect.DeepEqual(event.TopNodes, expected) {
+		t.Errorf("published %+v, expected the top %v nodes %v", event, streamTopNodes, expected)
+	}
+	if scores[0].Host != "a" {
+		t.Errorf("publishing sorted the scores of the caller")
+	}
+
+	// the slow subscriber never drains, its decisions are dropped once its buffer is full
+	published := make(chan bool)
+	go func() {
+		for i := 0; i < 2*streamBuffer; i++ {
+			feed.publish("image_score", &pod, scores)
+			<-events
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("a slow subscriber blocked the publisher")
+	}
+	if len(slow) != streamBuffer {
+		t.Errorf("the slow subscriber has %v queued decisions, expected %v", len(slow), streamBuffer)
+	}
+
+	feed.unsubscribe(events)
+	feed.unsubscribe(slow)
+	if len(feed.subscribers) != 0 {
+		t.Errorf("%v subscribers left", len(feed.subscribers))
+	}
+}
+
+func TestDebugStreamRoute(t *testing.T) {
+	withExplainBuffer(t, true, 0)
+	router := newTestRouter(t, constantPriority("constant", 1, 4))
+	AddDebugRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/debug/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("answered %v with %v", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	// the headers come once the subscriber is registered, the decision cannot be missed
+	prioritize(t, router, "constant", testPod("default", "web", nil), testNodes("a", "b"))
+
+	received := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(response.Body).ReadString('\n')
+		received <- line
+	}()
+	var line string
+	select {
+	case line = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no decision event received")
+	}
+	if !strings.HasPrefix(line, "data: ") {
+		t.Fatalf("received %q, expected a server-sent event", line)
+	}
+	var event DecisionEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Method != "constant" || event.PodName != "web" || len(event.TopNodes) != 2 || event.TopNodes[0].Score != 4 {
+		t.Errorf("received %+v", event)
+	}
+}
+
+func TestDebugStreamRouteDisabled(t *testing.T) {
+	withExplainBuffer(t, false, 0)
+	router := newTestRouter(t)
+	AddDebugRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/stream", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("answered %v without -enable-debug", w.Code)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"hash/fnv"
+	"sort"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var stableTiebreak bool
+
+func init() {
+	flag.BoolVar(&stableTiebreak, "stable-tiebreak", false, "Order the nodes of the same score by a hash of their names, so identical requests get an identical list")
+}
+
+// breakTies sorts the list by decreasing score when -stable-tiebreak is set, nodes of the same score
+// ordered by a stable hash of their names. The scores themselves are left as they are: they are integers,
+// so any offset is a whole point that either leaves part of a tied group tied or collides with the next
+// score level. The ordering is what makes the result reproducible, including which tied nodes survive
+// the -prioritize-top-k truncation
+func breakTies(list schedulingapi.HostPriorityList) schedulingapi.HostPriorityList {
+	if !stableTiebreak {
+		return list
+	}
+	sorted := make(schedulingapi.HostPriorityList, len(list))
+	copy(sorted, list)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		hostI, hostJ := sorted[i].Host, sorted[j].Host
+		if hashI, hashJ := nodeNameHash(hostI), nodeNameHash(hostJ); hashI != hashJ {
+			return hashI < hashJ
+		}
+		return hostI < hostJ
+	})
+	return sorted
+}
+
+// nodeNameHash is the stable hash ordering tied nodes
+func nodeNameHash(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32()
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withStableTiebreak sets -stable-tiebreak until the end of the test
+func withStableTiebreak(t *testing.T, enabled bool) {
+	saved := stableTiebreak
+	t.Cleanup(func() { stableTiebreak = saved })
+	stableTiebreak = enabled
+}
+
+func TestBreakTies(t *testing.T) {
+	withStableTiebreak(t, true)
+	tests := []struct {
+		name string
+		list schedulingapi.HostPriorityList
+	}{
+		{"three-way tie", schedulingapi.HostPriorityList{{Host: "a", Score: 5}, {Host: "b", Score: 5}, {Host: "c", Score: 5}}},
+		{"tie at the max", schedulingapi.HostPriorityList{{Host: "a", Score: 10}, {Host: "b", Score: 10}, {Host: "c", Score: 9}}},
+		{"adjacent levels", schedulingapi.HostPriorityList{{Host: "a", Score: 6}, {Host: "b", Score: 5}, {Host: "c", Score: 5}, {Host: "d", Score: 4}}},
+		{"vetoed and zero", schedulingapi.HostPriorityList{{Host: "a", Score: UnfitScore}, {Host: "b", Score: 0}, {Host: "c", Score: 0}, {Host: "d", Score: UnfitScore}}},
+		{"no tie", schedulingapi.HostPriorityList{{Host: "a", Score: 1}, {Host: "b", Score: 2}}},
+		{"empty", nil},
+	}
+	for _, test := range tests {
+		expected := scoresByHost(test.list)
+		broken := breakTies(test.list)
+		if got := scoresByHost(broken); len(broken) != len(test.list) || !reflect.DeepEqual(got, expected) {
+			t.Errorf("%v: expected the scores %v to be kept, got %v", test.name, test.list, broken)
+		}
+		for i := 1; i < len(broken); i++ {
+			previous, hp := broken[i-1], broken[i]
+			if previous.Score < hp.Score {
+				t.Errorf("%v: %v is not sorted by decreasing score", test.name, broken)
+			}
+			if previous.Score == hp.Score && nodeNameHash(previous.Host) > nodeNameHash(hp.Host) {
+				t.Errorf("%v: the tied %v and %v are not in hash order", test.name, previous.Host, hp.Host)
+			}
+		}
+	}
+}
+
+func TestBreakTiesDisabled(t *testing.T) {
+	withStableTiebreak(t, false)
+	list := schedulingapi.HostPriorityList{{Host: "a", Score: 5}, {Host: "b", Score: 7}, {Host: "c", Score: 5}}
+	if broken := breakTies(list); !reflect.DeepEqual(broken, list) {
+		t.Errorf("expected %v to be left as it is, got %v", list, broken)
+	}
+}
+
+// TestStableTiebreakRepeated checks identical requests, in whatever node order, get an identical list
+func TestStableTiebreakRepeated(t *testing.T) {
+	withStableTiebreak(t, true)
+	tied := PrioritizeMethod{
+		Name: "tied",
+		Func: func(_ v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			list := make(schedulingapi.HostPriorityList, len(nodes))
+			for i, node := range nodes {
+				list[i] = schedulingapi.HostPriority{Host: node.Name, Score: 5}
+				if node.Name == "node-3" {
+					list[i].Score = 8
+				}
+			}
+			return &list, nil
+		},
+	}
+	router := newTestRouter(t, tied)
+	pod := testPod("default", "p", nil)
+	first := prioritize(t, router, "tied", pod, testNodes("node-1", "node-2", "node-3", "node-4", "node-5"))
+	if first[0].Host != "node-3" {
+		t.Fatalf("expected the best node first, got %v", first)
+	}
+	for _, nodes := range [][]v1.Node{
+		testNodes("node-1", "node-2", "node-3", "node-4", "node-5"),
+		testNodes("node-5", "node-4", "node-3", "node-2", "node-1"),
+		testNodes("node-2", "node-5", "node-1", "node-3", "node-4"),
+	} {
+		if list := prioritize(t, router, "tied", pod, nodes); !reflect.DeepEqual(list, first) {
+			t.Errorf("expected %v, got %v", first, list)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var priorityTimeout time.Duration
+
+func init() {
+	flag.DurationVar(&priorityTimeout, "priority-timeout", 0, "How long a priority method may score a request before its nodes get the neutral score, for methods not declaring their own timeout, 0 means no timeout")
+}
+
+// methodTimeout returns the timeout of the method, falling back on -priority-timeout
+func methodTimeout(priorityMethod PrioritizeMethod) time.Duration {
+	if priorityMethod.Timeout > 0 {
+		return priorityMethod.Timeout
+	}
+	return priorityTimeout
+}
+
+// handleWithTimeout runs the handler of the method, the nodes get the neutral score when it does not
+// answer within the method timeout or before the deadline of the request context, whichever comes first.
+// The handler keeps running in the background until it returns, its late result is dropped and the
+// timeout is added to the request warnings
+func handleWithTimeout(ctx context.Context, priorityMethod PrioritizeMethod, extenderArgs schedulingapi.ExtenderArgs, warnings *requestWarnings) (*schedulingapi.HostPriorityList, error) {
+	timeout := methodTimeout(priorityMethod)
+	deadline, hasDeadline := ctx.Deadline()
+	if (timeout <= 0 && !hasDeadline) || extenderArgs.Nodes == nil {
+		return priorityMethod.Handler(ctx, extenderArgs)
+	}
+	limit := fmt.Sprintf("the %v timeout", timeout)
+	if hasDeadline && (timeout <= 0 || time.Until(deadline) < timeout) {
+		limit = fmt.Sprintf("the request deadline of %v", deadline.Format(time.RFC3339Nano))
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if ctx.Err() != nil {
+		return neutralAfterTimeout(priorityMethod, extenderArgs, warnings, limit), nil
+	}
+	type result struct {
+		list *schedulingapi.HostPriorityList
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			// the callers recover the panics of their own goroutine only
+			if r := recover(); r != nil {
+				recordPanic(priorityMethod.Name, r)
+				done <- result{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		list, err := priorityMethod.Handler(ctx, extenderArgs)
+		done <- result{list: list, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.list, r.err
+	case <-ctx.Done():
+		return neutralAfterTimeout(priorityMethod, extenderArgs, warnings, limit), nil
+	}
+}
+
+// neutralAfterTimeout returns the neutral scores of a method that ran out of time
+func neutralAfterTimeout(priorityMethod PrioritizeMethod, extenderArgs schedulingapi.ExtenderArgs, warnings *requestWarnings, limit string) *schedulingapi.HostPriorityList {
+	glog.Warningf("priority method %v did not answer before %v for pod %v, returning neutral scores", priorityMethod.Name, limit, extenderArgs.Pod.Name)
+	warnings.add(priorityMethod.Name, warningTimeout, "no answer before %v, neutral scores", limit)
+	scores := neutralScores(extenderArgs.Nodes.Items)
+	return &scores
+}
@@ -0,0 +1,138 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// blockedPriority gives every node the score once the test ends, it times out before that
+func blockedPriority(t *testing.T, name string, timeout time.Duration, score int) PrioritizeMethod {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	return PrioritizeMethod{
+		Name:    name,
+		Timeout: timeout,
+		Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+			<-release
+			list := make(schedulingapi.HostPriorityList, len(nodes))
+			for i, node := range nodes {
+				list[i] = schedulingapi.HostPriority{Host: node.Name, Score: score}
+			}
+			return &list, nil
+		},
+	}
+}
+
+func TestMethodTimeout(t *testing.T) {
+	defer func(saved time.Duration) { priorityTimeout = saved }(priorityTimeout)
+	tests := []struct {
+		method, flag, expected time.Duration
+	}{
+		{time.Second, 0, time.Second},
+		{time.Second, time.Minute, time.Second},
+		{0, time.Minute, time.Minute},
+		{0, 0, 0},
+	}
+	for _, test := range tests {
+		priorityTimeout = test.flag
+		if timeout := methodTimeout(PrioritizeMethod{Timeout: test.method}); timeout != test.expected {
+			t.Errorf("a method timeout of %v with -priority-timeout=%v gave %v", test.method, test.flag, timeout)
+		}
+	}
+}
+
+func TestHandleWithTimeout(t *testing.T) {
+	args := extenderArgsOf(testPod("default", "p", nil), testNodes("a", "b"))
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	panicking := constantPriority("panicking", 1, 0)
+	panicking.Timeout = time.Second
+	panicking.Prepare = func(pod v1.Pod, nodes []v1.Node) NodeScorer { panic("boom") }
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		method  PrioritizeMethod
+		score   int
+		warning string
+		err     string
+	}{
+		{"fast method", context.Background(), constantPriority("fast", 1, 9), 9, "", ""},
+		{"fast method with a timeout", context.Background(), func() PrioritizeMethod {
+			method := constantPriority("fast", 1, 9)
+			method.Timeout = time.Second
+			return method
+		}(), 9, "", ""},
+		{"slow method", context.Background(), blockedPriority(t, "slow", 20*time.Millisecond, 9), neutralScore, "no answer before the 20ms timeout", ""},
+		{"request deadline first", short, blockedPriority(t, "slow", time.Minute, 9), neutralScore, "no answer before the request deadline", ""},
+		{"request deadline without method timeout", short, blockedPriority(t, "slow", 0, 9), neutralScore, "no answer before the request deadline", ""},
+		{"request already over", expired, blockedPriority(t, "slow", time.Minute, 9), neutralScore, "no answer before the 1m0s timeout", ""},
+		{"panicking method", context.Background(), panicking, 0, "", "panic: boom"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings := newRequestWarnings()
+			list, err := handleWithTimeout(test.ctx, test.method, args, warnings)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("returned %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkScores(t, *list, map[string]int{"a": test.score, "b": test.score})
+			recorded := warnings.list()
+			if test.warning == "" {
+				if len(recorded) > 0 {
+					t.Errorf("warned %v", recorded)
+				}
+				return
+			}
+			if len(recorded) != 1 || recorded[0].Code != warningTimeout || !strings.Contains(recorded[0].Message, test.warning) {
+				t.Errorf("warned %+v, expected a timeout warning with %q", recorded, test.warning)
+			}
+		})
+	}
+}
+
+func TestTimeoutCombined(t *testing.T) {
+	router := newTestRouter(t, blockedPriority(t, "slow", 20*time.Millisecond, 9), constantPriority("fast", 1, 9))
+	AddCombinedRoute(router)
+	start := time.Now()
+	w := combine(t, router, "", testNodes("a", "b"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the combined request waited %v for the slow method", elapsed)
+	}
+	var list schedulingapi.HostPriorityList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	// the neutral scores of the slow method are averaged with the scores of the fast one
+	expected := (neutralScore + 9) / 2
+	checkScores(t, list, map[string]int{"a": expected, "b": expected})
+}
@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var prioritizeTopK int
+
+func init() {
+	flag.IntVar(&prioritizeTopK, "prioritize-top-k", 0, "Return only the K best scored nodes, highest first, 0 returns every node. The scheduler scores the unlisted nodes 0 for the extender")
+}
+
+// validateTopK makes sure the -prioritize-top-k flag is not negative
+func validateTopK() error {
+	if prioritizeTopK < 0 {
+		return fmt.Errorf("the -prioritize-top-k flag value must not be negative, got %v", prioritizeTopK)
+	}
+	return nil
+}
+
+// topK returns the -prioritize-top-k nodes with the highest scores, sorted by decreasing score even when
+// the list holds fewer than K nodes. Nodes of the same score keep the order of the list, so the truncation
+// is stable across identical requests. The scheduler treats the nodes left out as if the extender had
+// scored them 0, as with vetoes in omit mode
+func topK(list schedulingapi.HostPriorityList) schedulingapi.HostPriorityList {
+	if prioritizeTopK == 0 {
+		return list
+	}
+	sorted := make(schedulingapi.HostPriorityList, len(list))
+	copy(sorted, list)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+	if len(sorted) > prioritizeTopK {
+		sorted = sorted[:prioritizeTopK]
+	}
+	return sorted
+}
@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withTopK sets -prioritize-top-k for the test
+func withTopK(t *testing.T, k int) {
+	saved := prioritizeTopK
+	t.Cleanup(func() { prioritizeTopK = saved })
+	prioritizeTopK = k
+}
+
+func TestValidateTopK(t *testing.T) {
+	for k, valid := range map[int]bool{0: true, 1: true, 20: true, -1: false} {
+		withTopK(t, k)
+		if err := validateTopK(); (err == nil) != valid {
+			t.Errorf("validateTopK(%v) returned %v", k, err)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	list := schedulingapi.HostPriorityList{{Host: "a", Score: 3}, {Host: "b", Score: 9}, {Host: "c", Score: 5}, {Host: "d", Score: 9}}
+	tests := []struct {
+		k        int
+		expected []string
+	}{
+		{0, []string{"a", "b", "c", "d"}},
+		{1, []string{"b"}},
+		{2, []string{"b", "d"}},
+		{3, []string{"b", "d", "c"}},
+		{4, []string{"b", "d", "c", "a"}},
+		{10, []string{"b", "d", "c", "a"}},
+	}
+	for _, test := range tests {
+		withTopK(t, test.k)
+		result := topK(list)
+		if len(result) != len(test.expected) {
+			t.Errorf("top %v returned %v", test.k, result)
+			continue
+		}
+		for i, host := range test.expected {
+			if result[i].Host != host {
+				t.Errorf("top %v returned %v, expected the hosts %v", test.k, result, test.expected)
+				break
+			}
+		}
+	}
+	if list[0].Host != "a" || list[1].Host != "b" {
+		t.Errorf("topK reordered its input %v", list)
+	}
+}
+
+func TestTopKRoute(t *testing.T) {
+	withTopK(t, 2)
+	router := newTestRouter(t, digitPriority)
+	list := prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("n1", "n7", "n4"))
+	checkScores(t, list, map[string]int{"n7": 7, "n4": 4})
+}
+
+// TestTopKFewerNodes checks a list shorter than K comes back whole, sorted by decreasing score
+func TestTopKFewerNodes(t *testing.T) {
+	withTopK(t, 5)
+	router := newTestRouter(t, digitPriority)
+	list := prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("n1", "n7", "n4"))
+	for i, host := range []string{"n7", "n4", "n1"} {
+		if len(list) != 3 || list[i].Host != host {
+			t.Fatalf("top 5 of 3 nodes returned %v, expected n7, n4, n1", list)
+		}
+	}
+}
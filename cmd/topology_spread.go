@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var spreadTopologyKey string
+var spreadMaxSkew int
+
+func init() {
+	flag.StringVar(&spreadTopologyKey, "spread-topology-key", "topology.kubernetes.io/zone", "The node label defining the topology domains topology_spread spreads sibling pods across, e.g. a rack label")
+	flag.IntVar(&spreadMaxSkew, "spread-max-skew", 1, "How many sibling pods a domain may hold above the least populated one before topology_spread lowers its score")
+}
+
+// TopologySpreadPriority spreads the pods of an owner across the domains of -spread-topology-key: nodes in
+// domains within -spread-max-skew of the least populated domain get the max score, the score then falls
+// off linearly down to 0 for the most populated domain
+var TopologySpreadPriority = PrioritizeMethod{
+	Name:              "topology_spread",
+	RequiresInformers: true,
+	Func: func(pod v1.Pod, nodes []v1.Node) (*schedulingapi.HostPriorityList, error) {
+		list := spreadScores(pod, nodes, spreadTopologyKey, spreadMaxSkew)
+		return &list, nil
+	},
+}
+
+// siblingPods returns the other pods of the lister controlled by the owner of the pod, nil when the pod has no owner
+func siblingPods(pod v1.Pod, lister PodLister) []v1.Pod {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return nil
+	}
+	var siblings []v1.Pod
+	for _, other := range lister.List() {
+		if other.Namespace != pod.Namespace || other.UID == pod.UID {
+			continue
+		}
+		if otherOwner := metav1.GetControllerOf(&other); otherOwner != nil && otherOwner.UID == owner.UID {
+			siblings = append(siblings, other)
+		}
+	}
+	return siblings
+}
+
+// domainCounts counts the pods placed in each domain of the topology key, the domains come from the
+// candidate nodes so a domain with no pod counts 0
+func domainCounts(pods []v1.Pod, nodes []v1.Node, key string) map[string]int {
+	nodeDomains := make(map[string]string)
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		if domain, ok := node.Labels[key]; ok {
+			nodeDomains[normalizeNodeName(node.Name)] = domain
+			if _, seen := counts[domain]; !seen {
+				counts[domain] = 0
+			}
+		}
+	}
+	for _, pod := range pods {
+		if domain, ok := nodeDomains[normalizeNodeName(pod.Spec.NodeName)]; ok {
+			counts[domain]++
+		}
+	}
+	return counts
+}
+
+// spreadScores scores the nodes by how populated their domain is with the siblings of the pod, nodes
+// without the topology key and pods without siblings get the neutral score
+func spreadScores(pod v1.Pod, nodes []v1.Node, key string, maxSkew int) schedulingapi.HostPriorityList {
+	counts := domainCounts(siblingPods(pod, podLister), nodes, key)
+	minCount, maxCount := -1, 0
+	for _, count := range counts {
+		if minCount < 0 || count < minCount {
+			minCount = count
+		}
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+	priorityList := make(schedulingapi.HostPriorityList, len(nodes))
+	for i, node := range nodes {
+		score := neutralScore
+		if domain, ok := node.Labels[key]; ok && maxCount > 0 {
+			excess := counts[domain] - minCount - maxSkew
+			tolerated := maxCount - minCount - maxSkew
+			switch {
+			case excess <= 0:
+				score = schedulingapi.MaxPriority
+			default:
+				score = schedulingapi.MaxPriority * (tolerated - excess) / tolerated
+			}
+		}
+		priorityList[i] = schedulingapi.HostPriority{
+			Host:  node.Name,
+			Score: score,
+		}
+		glog.V(6).Infof("node %v has priority score of %v for pod %v (topology key %v)\n", node.Name, score, pod.Name, key)
+	}
+	return priorityList
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestSpreadScores(t *testing.T) {
+	nodes := []v1.Node{
+		labeledNode("a1", map[string]string{"rack": "r1"}),
+		labeledNode("a2", map[string]string{"rack": "r1"}),
+		labeledNode("b1", map[string]string{"rack": "r2"}),
+		labeledNode("c1", map[string]string{"rack": "r3"}),
+		labeledNode("d1", map[string]string{"topology.kubernetes.io/zone": "z1"}),
+	}
+	created := time.Now()
+	pod := ownedPod("new", "rs", "", created, time.Time{}, 0)
+	// 3 siblings in r1, 1 in r2 and none in r3, the pod itself, a pod of another owner, another
+	// namespace and a node that is not a candidate do not count
+	siblings := []v1.Pod{
+		ownedPod("s1", "rs", "a1", created, time.Time{}, 0),
+		ownedPod("s2", "rs", "a1", created, time.Time{}, 0),
+		ownedPod("s3", "rs", "a2", created, time.Time{}, 0),
+		ownedPod("s4", "rs", "b1", created, time.Time{}, 0),
+		ownedPod("s5", "rs", "elsewhere", created, time.Time{}, 0),
+		ownedPod("new", "rs", "c1", created, time.Time{}, 0),
+		ownedPod("other", "other-rs", "c1", created, time.Time{}, 0),
+		ownedPod("unowned", "", "c1", created, time.Time{}, 0),
+	}
+	foreign := ownedPod("foreign", "rs", "c1", created, time.Time{}, 0)
+	foreign.Namespace = "other"
+	siblings = append(siblings, foreign)
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		key      string
+		maxSkew  int
+		expected map[string]int
+	}{
+		{"even spread", pod, "rack", 0, map[string]int{"a1": 0, "a2": 0, "b1": 6, "c1": 10, "d1": neutralScore}},
+		{"skew of 1", pod, "rack", 1, map[string]int{"a1": 0, "a2": 0, "b1": 10, "c1": 10, "d1": neutralScore}},
+		{"skew of 2", pod, "rack", 2, map[string]int{"a1": 0, "a2": 0, "b1": 10, "c1": 10, "d1": neutralScore}},
+		{"tolerated skew", pod, "rack", 3, map[string]int{"a1": 10, "a2": 10, "b1": 10, "c1": 10, "d1": neutralScore}},
+		{"no sibling in the domains", pod, "topology.kubernetes.io/zone", 0, map[string]int{"a1": neutralScore, "a2": neutralScore, "b1": neutralScore, "c1": neutralScore, "d1": neutralScore}},
+		{"pod without owner", testPod("default", "lonely", nil), "rack", 0, map[string]int{"a1": neutralScore, "a2": neutralScore, "b1": neutralScore, "c1": neutralScore, "d1": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withPods(t, siblings...)
+			checkScores(t, spreadScores(test.pod, nodes, test.key, test.maxSkew), test.expected)
+		})
+	}
+}
+
+func TestTopologySpreadPriority(t *testing.T) {
+	savedKey, savedSkew := spreadTopologyKey, spreadMaxSkew
+	defer func() { spreadTopologyKey, spreadMaxSkew = savedKey, savedSkew }()
+	spreadTopologyKey, spreadMaxSkew = "hypervisor", 0
+	created := time.Now()
+	withPods(t, ownedPod("s1", "rs", "a", created, time.Time{}, 0))
+	nodes := []v1.Node{
+		labeledNode("a", map[string]string{"hypervisor": "h1"}),
+		labeledNode("b", map[string]string{"hypervisor": "h2"}),
+	}
+	list := scoreMethod(t, TopologySpreadPriority, ownedPod("new", "rs", "", created, time.Time{}, 0), nodes)
+	checkScores(t, list, map[string]int{"a": 0, "b": 10})
+}
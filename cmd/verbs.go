@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/julienschmidt/httprouter"
+)
+
+// the paths of the bind and preempt verbs under each api prefix
+const (
+	bindPath    = "/bind"
+	preemptPath = "/preempt"
+)
+
+var enableFilter, enablePrioritize, enableBind, enablePreempt bool
+
+func init() {
+	flag.BoolVar(&enableFilter, "enable-filter", false, "Serve the filter verb, the scheduler policy must list the extender filterVerb")
+	flag.BoolVar(&enablePrioritize, "enable-prioritize", true, "Serve the prioritize verb, the priority methods and the combined priorities")
+	flag.BoolVar(&enableBind, "enable-bind", false, "Serve the bind verb at "+bindPath+", the extender does not implement it yet and answers 501")
+	flag.BoolVar(&enablePreempt, "enable-preempt", false, "Serve the preempt verb at "+preemptPath+", the extender does not implement it yet and answers 501")
+}
+
+// activeVerbs returns the extender verbs served
+func activeVerbs() []string {
+	var verbs []string
+	if enableFilter {
+		verbs = append(verbs, "filter")
+	}
+	if enablePrioritize {
+		verbs = append(verbs, "prioritize")
+	}
+	if enableBind {
+		verbs = append(verbs, "bind")
+	}
+	if enablePreempt {
+		verbs = append(verbs, "preempt")
+	}
+	return verbs
+}
+
+// addNotImplemented serves a verb the extender does not implement under each api prefix, a scheduler
+// policy listing it gets a 501 instead of a 404 it could take for a wrong path
+func addNotImplemented(router *httprouter.Router, verb, path string) {
+	handle := requireAuth(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		http.Error(w, "the "+verb+" verb is not implemented by the extender", http.StatusNotImplemented)
+	})
+	for _, prefixed := range prefixedPaths(path) {
+		router.POST(prefixed, handle)
+		glog.Warningf("the %v verb is not implemented, %v answers 501", verb, prefixed)
+	}
+}
+
+// addVerbRoutes registers the routes of the enabled verbs, the paths of the others answer 404
+func addVerbRoutes(router *httprouter.Router, priorities []PrioritizeMethod, filters []FilterMethod) {
+	if enablePrioritize {
+		for _, p := range priorities {
+			if p.RequiresInformers && !enableInformers {
+				glog.Warningf("priority method %v needs -enable-informers, it is not served", p.Name)
+				continue
+			}
+			AddPrioritizeFunc(router, p)
+		}
+		AddCombinedRoute(router)
+	}
+	if enableFilter {
+		for _, f := range filters {
+			if f.RequiresInformers && !enableInformers {
+				glog.Warningf("filter method %v needs -enable-informers, it is not served", f.Name)
+				continue
+			}
+			AddFilterFunc(router, f)
+		}
+	}
+	if enableBind {
+		addNotImplemented(router, "bind", bindPath)
+	}
+	if enablePreempt {
+		addNotImplemented(router, "preempt", preemptPath)
+	}
+}
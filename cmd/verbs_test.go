@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withVerbs enables the verbs for the test
+func withVerbs(t *testing.T, filter, prioritize, bind, preempt bool) {
+	savedFilter, savedPrioritize, savedBind, savedPreempt := enableFilter, enablePrioritize, enableBind, enablePreempt
+	t.Cleanup(func() {
+		enableFilter, enablePrioritize, enableBind, enablePreempt = savedFilter, savedPrioritize, savedBind, savedPreempt
+	})
+	enableFilter, enablePrioritize, enableBind, enablePreempt = filter, prioritize, bind, preempt
+}
+
+func TestVerbRoutes(t *testing.T) {
+	for _, test := range []struct {
+		name                              string
+		filter, prioritize, bind, preempt bool
+	}{
+		{name: "default", prioritize: true},
+		{name: "filter only", filter: true},
+		{name: "bind and preempt", bind: true, preempt: true},
+		{name: "all", filter: true, prioritize: true, bind: true, preempt: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withVerbs(t, test.filter, test.prioritize, test.bind, test.preempt)
+			router := newTestRouter(t)
+			addVerbRoutes(router, []PrioritizeMethod{SpotPriority}, []FilterMethod{GPUModelFilter})
+			activeSnapshot.Store(newSnapshot(nil))
+			for path, enabled := range map[string]bool{
+				filtersPrefix + "/" + GPUModelFilter.Name:  test.filter,
+				prioritiesPrefix + "/" + SpotPriority.Name: test.prioritize,
+				prioritiesPrefix: test.prioritize,
+				bindPath:         test.bind,
+				preemptPath:      test.preempt,
+			} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+path, strings.NewReader("{}")))
+				if notFound := w.Code == http.StatusNotFound; notFound == enabled {
+					t.Errorf("%v answered %v, enabled = %v", path, w.Code, enabled)
+				}
+				if implemented := path != bindPath && path != preemptPath; enabled && !implemented && w.Code != http.StatusNotImplemented {
+					t.Errorf("%v answered %v, expected %v as the verb is not implemented", path, w.Code, http.StatusNotImplemented)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// UnfitScore is the sentinel a priority function returns for a node the pod should never be placed on.
+// It is never sent to the scheduler as is: the default scheduler does not validate extender scores, a
+// negative value would silently be multiplied by the extender weight and subtracted from the node total.
+// PrioritizeRoute translates it according to the -veto-mode flag before answering.
+const UnfitScore = -1
+
+const (
+	// vetoModeOmit drops vetoed nodes from the returned list. Schedulers up to (at least) v1.15 treat a
+	// missing host as if the extender scored it 0, newer versions may log a warning about the missing host.
+	vetoModeOmit = "omit"
+	// vetoModeZero keeps vetoed nodes in the list with a score of 0 and logs the veto, it is understood
+	// by every scheduler version
+	vetoModeZero = "zero"
+)
+
+var vetoMode string
+
+func init() {
+	flag.StringVar(&vetoMode, "veto-mode", vetoModeZero, "How nodes scored as unfit by a priority are returned to the scheduler, one of: omit, zero")
+}
+
+// Unfit returns the HostPriority marking the host as unfit for the pod
+func Unfit(host string) schedulingapi.HostPriority {
+	return schedulingapi.HostPriority{Host: host, Score: UnfitScore}
+}
+
+// validateVetoMode makes sure the -veto-mode flag holds a known mode
+func validateVetoMode() error {
+	switch vetoMode {
+	case vetoModeOmit, vetoModeZero:
+		return nil
+	}
+	return fmt.Errorf("unknown -veto-mode %q, expecting one of: %v, %v", vetoMode, vetoModeOmit, vetoModeZero)
+}
+
+// translateVetoes replaces the UnfitScore sentinels in the list according to the configured veto mode
+func translateVetoes(methodName, podName string, list schedulingapi.HostPriorityList) schedulingapi.HostPriorityList {
+	translated := make(schedulingapi.HostPriorityList, 0, len(list))
+	for _, hp := range list {
+		if hp, kept := translateVeto(methodName, podName, hp); kept {
+			translated = append(translated, hp)
+		}
+	}
+	return translated
+}
+
+// translateVeto replaces a single UnfitScore sentinel as translateVetoes does, kept is false for a node
+// left out of the list
+func translateVeto(methodName, podName string, hp schedulingapi.HostPriority) (translated schedulingapi.HostPriority, kept bool) {
+	if hp.Score != UnfitScore {
+		return hp, true
+	}
+	glog.V(2).Infof("priorityMethod %v vetoed node %v for pod %v (veto-mode=%v)\n", methodName, hp.Host, podName, vetoMode)
+	if vetoMode == vetoModeOmit {
+		return hp, false
+	}
+	return schedulingapi.HostPriority{Host: hp.Host, Score: 0}, true
+}
@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withVetoMode sets -veto-mode until the end of the test
+func withVetoMode(t *testing.T, mode string) {
+	saved := vetoMode
+	t.Cleanup(func() { vetoMode = saved })
+	vetoMode = mode
+}
+
+func TestValidateVetoMode(t *testing.T) {
+	tests := []struct {
+		mode  string
+		valid bool
+	}{
+		{vetoModeOmit, true},
+		{vetoModeZero, true},
+		{"", false},
+		{"drop", false},
+	}
+	for _, test := range tests {
+		withVetoMode(t, test.mode)
+		if err := validateVetoMode(); (err == nil) != test.valid {
+			t.Errorf("validateVetoMode(%q) returned %v", test.mode, err)
+		}
+	}
+}
+
+func TestTranslateVetoes(t *testing.T) {
+	list := schedulingapi.HostPriorityList{{Host: "a", Score: 7}, Unfit("b"), {Host: "c", Score: 0}, Unfit("d")}
+	tests := []struct {
+		mode     string
+		expected schedulingapi.HostPriorityList
+	}{
+		{vetoModeZero, schedulingapi.HostPriorityList{{Host: "a", Score: 7}, {Host: "b", Score: 0}, {Host: "c", Score: 0}, {Host: "d", Score: 0}}},
+		{vetoModeOmit, schedulingapi.HostPriorityList{{Host: "a", Score: 7}, {Host: "c", Score: 0}}},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			withVetoMode(t, test.mode)
+			if translated := translateVetoes("test", "p", list); !reflect.DeepEqual(translated, test.expected) {
+				t.Errorf("translated %v, expected %v", translated, test.expected)
+			}
+			if list[1].Score != UnfitScore {
+				t.Errorf("the input list was modified: %v", list)
+			}
+		})
+	}
+}
+
+func TestVetoesNeverAnswered(t *testing.T) {
+	for _, mode := range []string{vetoModeZero, vetoModeOmit} {
+		t.Run(mode, func(t *testing.T) {
+			withVetoMode(t, mode)
+			router := newTestRouter(t, digitPriority)
+			list := prioritize(t, router, digitPriority.Name, testPod("default", "p", nil), testNodes("node-1", "node-9", "node-4"))
+			expected := map[string]int{"node-1": 1, "node-4": 4}
+			if mode == vetoModeZero {
+				expected["node-9"] = 0
+			}
+			checkScores(t, list, expected)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+var warmPoolAnnotation, latencySensitiveLabel string
+
+func init() {
+	flag.StringVar(&warmPoolAnnotation, "warm-pool-annotation", "node.example.com/warm-pool", "The node annotation reporting how many pre-warmed sandboxes the node keeps available")
+	flag.StringVar(&latencySensitiveLabel, "latency-sensitive-label", "scheduler.extender/latency-sensitive", "The pod label marking, when set to true, a pod whose startup latency matters")
+}
+
+// WarmPoolPriority favors, for latency sensitive pods, the nodes keeping the most pre-warmed sandboxes,
+// relative to the best provisioned candidate node. The other pods, or every pod when no node reports a
+// warm pool, get the neutral score
+var WarmPoolPriority = PrioritizeMethod{
+	Name: "warm_pool",
+	Prepare: func(pod v1.Pod, nodes []v1.Node) NodeScorer {
+		sensitive := pod.Labels[latencySensitiveLabel] == "true"
+		warm := make(map[string]int, len(nodes))
+		var maxWarm int
+		for _, node := range nodes {
+			warm[node.Name] = nodeWarmPool(node)
+			if warm[node.Name] > maxWarm {
+				maxWarm = warm[node.Name]
+			}
+		}
+		return func(pod v1.Pod, node v1.Node) (int, error) {
+			if !sensitive || maxWarm == 0 {
+				return neutralScore, nil
+			}
+			return schedulingapi.MaxPriority * warm[node.Name] / maxWarm, nil
+		}
+	},
+}
+
+// nodeWarmPool returns the warm pool size reported by the node, 0 when missing or malformed
+func nodeWarmPool(node v1.Node) int {
+	value, ok := node.Annotations[warmPoolAnnotation]
+	if !ok {
+		return 0
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		glog.Warningf("ignoring invalid %v annotation %q on node %v", warmPoolAnnotation, value, node.Name)
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestNodeWarmPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    int
+	}{
+		{"warm pool", map[string]string{warmPoolAnnotation: "5"}, 5},
+		{"empty pool", map[string]string{warmPoolAnnotation: "0"}, 0},
+		{"missing", nil, 0},
+		{"malformed", map[string]string{warmPoolAnnotation: "five"}, 0},
+		{"fraction", map[string]string{warmPoolAnnotation: "2.5"}, 0},
+		{"negative", map[string]string{warmPoolAnnotation: "-3"}, 0},
+	}
+	for _, test := range tests {
+		if count := nodeWarmPool(annotatedNode("n", test.annotations)); count != test.expected {
+			t.Errorf("%v: read %v, expected %v", test.name, count, test.expected)
+		}
+	}
+}
+
+func TestWarmPoolPriority(t *testing.T) {
+	warm := func(counts ...string) []v1.Node {
+		nodes := make([]v1.Node, len(counts))
+		for i, count := range counts {
+			var annotations map[string]string
+			if count != "" {
+				annotations = map[string]string{warmPoolAnnotation: count}
+			}
+			nodes[i] = annotatedNode(string(rune('a'+i)), annotations)
+		}
+		return nodes
+	}
+	sensitive := testPod("default", "p", map[string]string{latencySensitiveLabel: "true"})
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		nodes    []v1.Node
+		expected map[string]int
+	}{
+		{"varying warm pools", sensitive, warm("8", "4", "2", "0"), map[string]int{"a": 10, "b": 5, "c": 2, "d": 0}},
+		{"missing and malformed", sensitive, warm("3", "", "lots"), map[string]int{"a": 10, "b": 0, "c": 0}},
+		{"no warm pool", sensitive, warm("0", "", "none"), map[string]int{"a": neutralScore, "b": neutralScore, "c": neutralScore}},
+		{"not latency sensitive", testPod("default", "p", nil), warm("8", "0"), map[string]int{"a": neutralScore, "b": neutralScore}},
+		{"label not true", testPod("default", "p", map[string]string{latencySensitiveLabel: "yes"}), warm("8", "0"), map[string]int{"a": neutralScore, "b": neutralScore}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkScores(t, scoreMethod(t, WarmPoolPriority, test.pod, test.nodes), test.expected)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+const (
+	// warmupModeNeutral answers with neutral scores until the informers are synced
+	warmupModeNeutral = "neutral"
+	// warmupModeUnavailable answers 503 with a Retry-After header until the informers are synced,
+	// so the scheduler retries instead of placing the pod on incomplete data
+	warmupModeUnavailable = "unavailable"
+)
+
+var warmupMode string
+var warmupRetryAfter int
+
+func init() {
+	flag.StringVar(&warmupMode, "warmup-mode", warmupModeNeutral, "How the methods needing the informers answer before the first sync, one of: neutral, unavailable")
+	flag.IntVar(&warmupRetryAfter, "warmup-retry-after", 5, "The Retry-After seconds sent with the 503 of the unavailable warmup mode")
+}
+
+// validateWarmup makes sure the warmup flags hold valid values
+func validateWarmup() error {
+	if warmupMode != warmupModeNeutral && warmupMode != warmupModeUnavailable {
+		return fmt.Errorf("unknown -warmup-mode %q, expecting one of: %v, %v", warmupMode, warmupModeNeutral, warmupModeUnavailable)
+	}
+	if warmupRetryAfter <= 0 {
+		return fmt.Errorf("the -warmup-retry-after flag value must be positive, got %v", warmupRetryAfter)
+	}
+	return nil
+}
+
+// warmingUp reports whether the priority method can't be trusted yet since the informers it needs are not synced
+func warmingUp(priorityMethod PrioritizeMethod) bool {
+	return priorityMethod.RequiresInformers && !informersSynced()
+}
+
+// filterWarmingUp reports whether the filter method can't be trusted yet, it would reject the nodes
+// running pods the informers have not listed yet
+func filterWarmingUp(filterMethod FilterMethod) bool {
+	return filterMethod.RequiresInformers && !informersSynced()
+}
+
+// informersSynced reports whether the pods have been listed at least once
+func informersSynced() bool {
+	return podLister != nil && podLister.HasSynced()
+}
@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withWarmup sets the warmup flags until the end of the test
+func withWarmup(t *testing.T, mode string, retryAfter int) {
+	savedMode, savedRetryAfter := warmupMode, warmupRetryAfter
+	t.Cleanup(func() { warmupMode, warmupRetryAfter = savedMode, savedRetryAfter })
+	warmupMode, warmupRetryAfter = mode, retryAfter
+}
+
+// withPodLister sets the pod lister until the end of the test
+func withPodLister(t *testing.T, lister PodLister) {
+	saved := podLister
+	t.Cleanup(func() { podLister = saved })
+	podLister = lister
+}
+
+func TestValidateWarmup(t *testing.T) {
+	tests := []struct {
+		mode       string
+		retryAfter int
+		valid      bool
+	}{
+		{warmupModeNeutral, 5, true},
+		{warmupModeUnavailable, 1, true},
+		{"", 5, false},
+		{"Unavailable", 5, false},
+		{warmupModeUnavailable, 0, false},
+		{warmupModeNeutral, -1, false},
+	}
+	for _, test := range tests {
+		withWarmup(t, test.mode, test.retryAfter)
+		if err := validateWarmup(); (err == nil) != test.valid {
+			t.Errorf("validateWarmup with -warmup-mode=%q and -warmup-retry-after=%v returned %v", test.mode, test.retryAfter, err)
+		}
+	}
+}
+
+func TestWarmupMode(t *testing.T) {
+	informed := constantPriority("informed", 1, 9)
+	informed.RequiresInformers = true
+	router := newTestRouter(t, informed, constantPriority("static", 1, 9))
+	AddCombinedRoute(router)
+	nodes := testNodes("a", "b")
+	pod := testPod("default", "p", nil)
+	body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		mode   string
+		lister PodLister
+		path   string
+		status int
+		score  int
+	}{
+		{"neutral without lister", warmupModeNeutral, nil, "/informed", http.StatusOK, neutralScore},
+		{"neutral before the sync", warmupModeNeutral, &testPodLister{unsynced: true}, "/informed", http.StatusOK, neutralScore},
+		{"unavailable without lister", warmupModeUnavailable, nil, "/informed", http.StatusServiceUnavailable, 0},
+		{"unavailable before the sync", warmupModeUnavailable, &testPodLister{unsynced: true}, "/informed", http.StatusServiceUnavailable, 0},
+		{"unavailable combined", warmupModeUnavailable, &testPodLister{unsynced: true}, "", http.StatusServiceUnavailable, 0},
+		{"unavailable without informers", warmupModeUnavailable, &testPodLister{unsynced: true}, "/static", http.StatusOK, 9},
+		{"neutral synced", warmupModeNeutral, &testPodLister{}, "/informed", http.StatusOK, 9},
+		{"unavailable synced", warmupModeUnavailable, &testPodLister{}, "/informed", http.StatusOK, 9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withWarmup(t, test.mode, 7)
+			withPodLister(t, test.lister)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+test.path, bytes.NewReader(body)))
+			if w.Code != test.status {
+				t.Fatalf("answered %v: %v, expected %v", w.Code, w.Body.String(), test.status)
+			}
+			if test.status == http.StatusServiceUnavailable {
+				if retryAfter := w.Header().Get("Retry-After"); retryAfter != "7" {
+					t.Errorf("sent Retry-After %q, expected 7", retryAfter)
+				}
+				return
+			}
+			var list schedulingapi.HostPriorityList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			checkScores(t, list, map[string]int{"a": test.score, "b": test.score})
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// extenderWarningsHeader carries the number of warnings of a prioritize request, the warnings themselves
+// are logged since the body has to stay a HostPriorityList for the scheduler to decode it
+const extenderWarningsHeader = "X-Extender-Warnings"
+
+// the codes of the warnings, each names a way the scores of a request were degraded
+const (
+	warningStaleCache  = "stale_cache"
+	warningWarmingUp   = "warming_up"
+	warningCircuitOpen = "circuit_open"
+	warningTimeout     = "timeout"
+	warningFailOpen    = "fail_open"
+	warningAllFailed   = "all_failed"
+)
+
+var enableWarningsHeader bool
+var staleCacheAge time.Duration
+
+func init() {
+	flag.BoolVar(&enableWarningsHeader, "warnings-header", false, "Add an X-Extender-Warnings header with the number of warnings to the prioritize responses, e.g. scores computed on a stale cluster view")
+	flag.DurationVar(&staleCacheAge, "stale-cache-age", 0, "The age of the cluster view past which the scores of the methods needing the informers are flagged as stale, 0 means 3 times -informer-resync")
+}
+
+// Warning notes a valid but degraded decision of a priority method
+type Warning struct {
+	Method  string `json:"method"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// requestWarnings collects the warnings of a request, a nil *requestWarnings collects nothing
+type requestWarnings struct {
+	lock     sync.Mutex
+	warnings []Warning
+}
+
+func newRequestWarnings() *requestWarnings {
+	return &requestWarnings{}
+}
+
+// add records a warning of the method
+func (rw *requestWarnings) add(method, code, format string, args ...interface{}) {
+	if rw == nil {
+		return
+	}
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.warnings = append(rw.warnings, Warning{Method: method, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// list returns the warnings recorded so far
+func (rw *requestWarnings) list() []Warning {
+	if rw == nil {
+		return nil
+	}
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return append([]Warning(nil), rw.warnings...)
+}
+
+// emit logs the warnings of the request as a json list, counts them and, with -warnings-header, sets the
+// X-Extender-Warnings header. It must be called before the response status is written
+func (rw *requestWarnings) emit(w http.ResponseWriter, route, podName string) {
+	warnings := rw.list()
+	if len(warnings) == 0 {
+		return
+	}
+	if encoded, err := json.Marshal(warnings); err == nil {
+		glog.Warningf("%v scored pod %v with %v warnings: %s", route, podName, len(warnings), encoded)
+	}
+	countWarnings(warnings)
+	if enableWarningsHeader {
+		w.Header().Set(extenderWarningsHeader, strconv.Itoa(len(warnings)))
+	}
+}
+
+// staleCache reports whether the cluster view is older than -stale-cache-age, and its age. The listers
+// not telling when they last synced are never stale
+func staleCache(now time.Time) (time.Duration, bool) {
+	informer, ok := podLister.(interface {
+		LastSync() time.Time
+	})
+	if !ok {
+		return 0, false
+	}
+	lastSync := informer.LastSync()
+	if lastSync.IsZero() {
+		return 0, false
+	}
+	maxAge := staleCacheAge
+	if maxAge <= 0 {
+		maxAge = 3 * informerResync
+	}
+	age := now.Sub(lastSync)
+	return age, age > maxAge
+}
+
+var warningsLock sync.Mutex
+
+type warningKey struct {
+	method, code string
+}
+
+// warningCounts counts the warnings per method and code, exposed on /metrics
+var warningCounts = make(map[warningKey]int)
+
+func countWarnings(warnings []Warning) {
+	warningsLock.Lock()
+	defer warningsLock.Unlock()
+	for _, warning := range warnings {
+		warningCounts[warningKey{warning.Method, warning.Code}]++
+	}
+}
+
+// writeWarningMetrics writes the number of warnings per method and code
+func writeWarningMetrics(w io.Writer) {
+	warningsLock.Lock()
+	defer warningsLock.Unlock()
+	if len(warningCounts) == 0 {
+		return
+	}
+	var samples []metricSample
+	for key, count := range warningCounts {
+		samples = append(samples, metricSample{fmt.Sprintf(`method=%q,code=%q`, key.method, key.code), float64(count)})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+	writeMetric(w, "extender_warnings_total", "counter", "Number of warnings, degraded but valid scores, per method and code.", samples...)
+}
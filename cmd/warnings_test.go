@@ -0,0 +1,201 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	schedulingapi "k8s.io/kubernetes/pkg/scheduler/api"
+)
+
+// withWarnings sets -warnings-header, -stale-cache-age and -informer-resync and clears the warning
+// counts until the end of the test
+func withWarnings(t *testing.T, header bool, maxAge, resync time.Duration) {
+	savedHeader, savedMaxAge, savedResync := enableWarningsHeader, staleCacheAge, informerResync
+	t.Cleanup(func() {
+		enableWarningsHeader, staleCacheAge, informerResync = savedHeader, savedMaxAge, savedResync
+		warningsLock.Lock()
+		warningCounts = make(map[warningKey]int)
+		warningsLock.Unlock()
+	})
+	enableWarningsHeader, staleCacheAge, informerResync = header, maxAge, resync
+	warningsLock.Lock()
+	warningCounts = make(map[warningKey]int)
+	warningsLock.Unlock()
+}
+
+// countedWarnings lists the counted warnings as method/code
+func countedWarnings() []string {
+	warningsLock.Lock()
+	defer warningsLock.Unlock()
+	var counted []string
+	for key := range warningCounts {
+		counted = append(counted, key.method+"/"+key.code)
+	}
+	sort.Strings(counted)
+	return counted
+}
+
+// informerPriority is a constant priority needing the informers
+func informerPriority(name string) PrioritizeMethod {
+	method := constantPriority(name, 1, 5)
+	method.RequiresInformers = true
+	return method
+}
+
+func TestRequestWarnings(t *testing.T) {
+	var none *requestWarnings
+	none.add("m", warningTimeout, "ignored")
+	if warnings := none.list(); warnings != nil {
+		t.Errorf("a nil collector listed %v", warnings)
+	}
+
+	rw := newRequestWarnings()
+	rw.add("m", warningTimeout, "no answer before %v", "1s")
+	rw.add("n", warningCircuitOpen, "open")
+	listed := rw.list()
+	expected := []Warning{{"m", warningTimeout, "no answer before 1s"}, {"n", warningCircuitOpen, "open"}}
+	if !reflect.DeepEqual(listed, expected) {
+		t.Errorf("listed %v, expected %v", listed, expected)
+	}
+	listed[0].Code = "changed"
+	if rw.list()[0].Code != warningTimeout {
+		t.Error("the listed warnings share the collector storage")
+	}
+}
+
+func TestStaleCache(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name   string
+		lister PodLister
+		maxAge time.Duration
+		resync time.Duration
+		stale  bool
+	}{
+		{"no last sync", &testPodLister{}, 0, time.Second, false},
+		{"never synced", &syncedPodLister{}, 0, time.Second, false},
+		{"fresh", &syncedPodLister{lastSync: now.Add(-2 * time.Second)}, 0, time.Second, false},
+		{"older than 3 resyncs", &syncedPodLister{lastSync: now.Add(-4 * time.Second)}, 0, time.Second, true},
+		{"within -stale-cache-age", &syncedPodLister{lastSync: now.Add(-time.Minute)}, 5 * time.Minute, time.Second, false},
+		{"older than -stale-cache-age", &syncedPodLister{lastSync: now.Add(-time.Minute)}, 30 * time.Second, time.Hour, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withWarnings(t, false, test.maxAge, test.resync)
+			withPodLister(t, test.lister)
+			if _, stale := staleCache(now); stale != test.stale {
+				t.Errorf("stale %v, expected %v", stale, test.stale)
+			}
+		})
+	}
+}
+
+func TestWarningsRoute(t *testing.T) {
+	nodes := testNodes("a", "b")
+	pod := testPod("default", "p", nil)
+	for _, test := range []struct {
+		name     string
+		method   PrioritizeMethod
+		lister   PodLister
+		header   bool
+		failOpen bool
+		expected []string
+	}{
+		{"fresh view", informerPriority("informed"), &syncedPodLister{lastSync: time.Now()}, true, false, nil},
+		{"stale view", informerPriority("informed"), &syncedPodLister{lastSync: time.Now().Add(-time.Minute)}, true, false, []string{"informed/stale_cache"}},
+		{"stale view without the header", informerPriority("informed"), &syncedPodLister{lastSync: time.Now().Add(-time.Minute)}, false, false, []string{"informed/stale_cache"}},
+		{"stale view ignored without informers", constantPriority("plain", 1, 5), &syncedPodLister{lastSync: time.Now().Add(-time.Minute)}, true, false, nil},
+		{"warming up", informerPriority("informed"), &testPodLister{unsynced: true}, true, false, []string{"informed/warming_up"}},
+		{"fail open", failingPriority("broken"), &testPodLister{}, true, true, []string{"broken/fail_open"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			withWarnings(t, test.header, 0, time.Second)
+			withWarmup(t, warmupModeNeutral, 5)
+			withPodLister(t, test.lister)
+			withFilterFailOpen(t, false, test.failOpen)
+			withFailOpenCounts(t)
+			router := newTestRouter(t, test.method)
+
+			body, err := json.Marshal(schedulingapi.ExtenderArgs{Pod: &pod, Nodes: &v1.NodeList{Items: nodes}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefixes[0]+prioritiesPrefix+"/"+test.method.Name, bytes.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+			}
+			var list schedulingapi.HostPriorityList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != len(nodes) {
+				t.Fatalf("answered an invalid list %q: %v", w.Body.String(), err)
+			}
+			if counted := countedWarnings(); !reflect.DeepEqual(counted, test.expected) {
+				t.Errorf("counted warnings %v, expected %v", counted, test.expected)
+			}
+			expected := ""
+			if test.header && len(test.expected) > 0 {
+				expected = "1"
+			}
+			if header := w.Header().Get(extenderWarningsHeader); header != expected {
+				t.Errorf("%v header %q, expected %q", extenderWarningsHeader, header, expected)
+			}
+		})
+	}
+}
+
+func TestCombinedAllFailedWarning(t *testing.T) {
+	withWarnings(t, true, 0, time.Second)
+	withFilterFailOpen(t, false, true)
+	withFailOpenCounts(t)
+	router := newTestRouter(t, failingPriority("broken"), failingPriority("down"))
+	AddCombinedRoute(router)
+
+	w := combine(t, router, "", testNodes("a", "b"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("answered %v: %v", w.Code, w.Body.String())
+	}
+	expected := []string{combinedMethodName + "/" + warningAllFailed}
+	if counted := countedWarnings(); !reflect.DeepEqual(counted, expected) {
+		t.Errorf("counted warnings %v, expected %v", counted, expected)
+	}
+	if header := w.Header().Get(extenderWarningsHeader); header != "1" {
+		t.Errorf("%v header %q, expected 1", extenderWarningsHeader, header)
+	}
+}
+
+func TestWriteWarningMetrics(t *testing.T) {
+	withWarnings(t, false, 0, time.Second)
+	var empty bytes.Buffer
+	writeWarningMetrics(&empty)
+	if empty.Len() != 0 {
+		t.Errorf("wrote %q without warnings", empty.String())
+	}
+
+	countWarnings([]Warning{{Method: "m", Code: warningTimeout}, {Method: "m", Code: warningTimeout}, {Method: "a", Code: warningStaleCache}})
+	var out bytes.Buffer
+	writeWarningMetrics(&out)
+	first := strings.Index(out.String(), `extender_warnings_total{method="a",code="stale_cache"} 1`)
+	second := strings.Index(out.String(), `extender_warnings_total{method="m",code="timeout"} 2`)
+	if first < 0 || second < first {
+		t.Errorf("wrote %q, expected the sorted counts per method and code", out.String())
+	}
+}